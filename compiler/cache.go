@@ -0,0 +1,168 @@
+package compiler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// With WithCache, the compiler records a fingerprint of the module after
+// compiling it in place, so that compiling it again can be skipped entirely if
+// nothing changed since then.
+//
+// The fingerprint covers the Go source files of the module (including the
+// files generated by the compiler), go.mod and go.sum, the options and
+// environment that influence which files are loaded, the Go version, and the
+// compiler itself. Hidden directories and nested modules are skipped, and so
+// are vendored trees: the vendor directory, whose content is determined by
+// go.mod, and the goroot directory where the compiler vendors GOROOT packages.
+// Changes to dependencies outside of the module are captured by go.sum, with
+// the exception of local replace directives pointing outside of the module.
+//
+// Fingerprints are stored in the user cache directory rather than in the
+// module, to avoid adding files that would need to be ignored by version
+// control.
+
+// fingerprintModule computes the fingerprint of the module in dir compiled
+// with the given package pattern.
+func (c *compiler) fingerprintModule(dir, pattern string) ([]byte, error) {
+	h := sha256.New()
+
+	fmt.Fprintf(h, "go: %s\n", runtime.Version())
+	fmt.Fprintf(h, "pattern: %s\n", pattern)
+	fmt.Fprintf(h, "tags: %s\n", c.buildTags)
+	fmt.Fprintf(h, "tag: %s\n", c.buildTag)
+	fmt.Fprintf(h, "functions: %s\n", strings.Join(c.functions, ","))
+	fmt.Fprintf(h, "line directives: %t\n", c.lineDirectives)
+	for _, env := range []string{"GOOS", "GOARCH", "GOFLAGS", "CGO_ENABLED"} {
+		fmt.Fprintf(h, "%s: %s\n", env, os.Getenv(env))
+	}
+
+	// The compiler executable is part of the fingerprint so that changes to
+	// the compiler invalidate the output, even for development builds that
+	// do not have a version.
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if err := hashFile(h, "compiler", exe); err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && skipFingerprintDir(path, name) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") && name != "go.mod" && name != "go.sum" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return hashFile(h, filepath.ToSlash(rel), path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func skipFingerprintDir(path, name string) bool {
+	if strings.HasPrefix(name, ".") || name == "vendor" || name == "goroot" {
+		return true
+	}
+	// Nested modules are compiled on their own.
+	_, err := os.Stat(filepath.Join(path, "go.mod"))
+	return err == nil
+}
+
+func hashFile(w io.Writer, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "file: %s %d\n", name, s.Size())
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// fingerprintPath returns the path of the file recording the fingerprint of
+// the module in dir, or an empty string if there is no cache directory.
+func fingerprintPath(dir string) string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	key := sha256.Sum256([]byte(dir))
+	return filepath.Join(cacheDir, "coroutine", hex.EncodeToString(key[:]))
+}
+
+// moduleUnchanged returns true if the module has the fingerprint recorded
+// after it was last compiled.
+func (c *compiler) moduleUnchanged(dir, pattern string) bool {
+	path := fingerprintPath(dir)
+	if path == "" {
+		return false
+	}
+	recorded, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	fingerprint, err := c.fingerprintModule(dir, pattern)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(recorded, fingerprint)
+}
+
+// recordFingerprint records the fingerprint of the module after it has been
+// compiled. Failing to record it only means that the next compilation cannot
+// be skipped, so errors are not reported.
+func (c *compiler) recordFingerprint(dir, pattern string) {
+	path := fingerprintPath(dir)
+	if path == "" {
+		return
+	}
+	fingerprint, err := c.fingerprintModule(dir, pattern)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, fingerprint, 0666)
+}
+
+// findModuleDir returns the directory of the module containing dir.
+func findModuleDir(dir string) (string, bool) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,630 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// Variables declared in the body of a loop are distinct on each iteration,
+// which is observable when they are captured by function literals: each
+// closure created by the loop refers to its own variable.
+//
+// Hoisting variables to the coroutine frame would share a single variable
+// across iterations, and closures referencing the frame would observe the
+// value assigned on the last iteration. To preserve the semantics of the
+// program, those variables are instead hoisted as pointers to a value that is
+// allocated each time the declaration is executed:
+//
+//	for ... {                      for ... {
+//	    x := f()                       _p0 := new(int)
+//	    fns = append(fns,     =>       *_p0 = f()
+//	        func() { use(x) })         {
+//	}                                      var _p1 *int = _p0
+//	                                       fns = append(fns, func() { use(*_p1) })
+//	                                   }
+//	                               }
+//
+// Closures capture a local copy of the pointer taken when they are created
+// rather than the frame, since the frame holds the pointer of the current
+// iteration. The pointers are shared by the frame and the closures, so they
+// are preserved by serialization.
+//
+// Since Go 1.22, the variables declared by the init statement of for loops
+// (and by range clauses, which are desugared into for loops) are distinct on
+// each iteration as well: before the post statement runs, a new variable is
+// initialized with the value of the variable of the previous iteration. Those
+// variables are hoisted as pointers allocated by the init statement, and the
+// loop is rewritten to allocate a copy before running the post statement,
+// which is moved at the start of the body since it must run before the
+// condition:
+//
+//	for i := 0; i < n; i++ {       _p0 := new(int)
+//	    fns = append(fns,          *_p0 = 0
+//	        func() { use(i) })     _p1 := true
+//	}                          =>  for {
+//	                                   if _p1 {
+//	                                       _p1 = false
+//	                                   } else {
+//	                                       _p0 = &[]int{*_p0}[0]
+//	                                       *_p0++
+//	                                   }
+//	                                   if !(*_p0 < n) {
+//	                                       break
+//	                                   }
+//	                                   ...
+//	                               }
+//
+// A continue statement proceeds to the next iteration of the rewritten loop,
+// which copies the variables as well.
+
+// perIterationLoopVars reports whether the variables declared by the init
+// statement of for loops in a file of p with the given build constraints are
+// distinct on each iteration, which depends on the version of the Go language
+// the file is written in. The version is the one required by the build
+// constraints of the file if any, the version of its module otherwise, or the
+// version of the toolchain for packages of the standard library.
+func perIterationLoopVars(p *packages.Package, buildTags constraint.Expr) bool {
+	var version string
+	if p.Module != nil {
+		version = p.Module.GoVersion
+	} else if tags := build.Default.ReleaseTags; len(tags) > 0 {
+		version = tags[len(tags)-1]
+	}
+	if buildTags != nil {
+		if v := constraint.GoVersion(buildTags); v != "" {
+			version = v
+		}
+	}
+	return goMinorVersion(version) >= 22
+}
+
+// goMinorVersion returns the minor version of a Go version string, formatted
+// like the go directive of a go.mod file (e.g. 1.21.0) or a release tag (e.g.
+// go1.22), or zero if the string is not a valid version.
+func goMinorVersion(version string) int {
+	parts := strings.Split(strings.TrimPrefix(version, "go"), ".")
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0
+	}
+	minor, _ := strconv.Atoi(parts[1])
+	return minor
+}
+
+// loopCaptures returns the variables declared in the body of loops that are
+// captured by function literals. If perIteration is true, the variables
+// declared by the init statement of loops are included, and also returned
+// as headers.
+func loopCaptures(body *ast.BlockStmt, info *types.Info, perIteration bool) (vars, headers map[types.Object]struct{}) {
+	v := &loopCaptureVisitor{
+		info:         info,
+		perIteration: perIteration,
+		declared:     map[types.Object]struct{}{},
+		headers:      map[types.Object]struct{}{},
+		captured:     map[types.Object]struct{}{},
+	}
+	ast.Walk(v, body)
+
+	vars = map[types.Object]struct{}{}
+	headers = map[types.Object]struct{}{}
+	for obj := range v.declared {
+		if _, ok := v.captured[obj]; ok {
+			vars[obj] = struct{}{}
+			if _, ok := v.headers[obj]; ok {
+				headers[obj] = struct{}{}
+			}
+		}
+	}
+	return vars, headers
+}
+
+type loopCaptureVisitor struct {
+	info         *types.Info
+	perIteration bool
+	loops        int
+	funcs        int
+	header       bool
+	declared     map[types.Object]struct{}
+	headers      map[types.Object]struct{}
+	captured     map[types.Object]struct{}
+}
+
+func (v *loopCaptureVisitor) with(loops, funcs int) *loopCaptureVisitor {
+	w := *v
+	w.loops, w.funcs, w.header = loops, funcs, false
+	return &w
+}
+
+// loopHeader returns the visitor of the init statement of a loop, or of the
+// key and value of a range clause.
+func (v *loopCaptureVisitor) loopHeader() *loopCaptureVisitor {
+	if !v.perIteration {
+		// The variables are shared by all the iterations.
+		return v
+	}
+	w := v.with(v.loops+1, v.funcs)
+	w.header = true
+	return w
+}
+
+func (v *loopCaptureVisitor) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.ForStmt:
+		if n.Init != nil {
+			ast.Walk(v.loopHeader(), n.Init)
+		}
+		for _, c := range []ast.Node{n.Cond, n.Post} {
+			if c != nil {
+				ast.Walk(v, c)
+			}
+		}
+		ast.Walk(v.with(v.loops+1, v.funcs), n.Body)
+		return nil
+	case *ast.RangeStmt:
+		for _, c := range []ast.Node{n.Key, n.Value} {
+			if c != nil && n.Tok == token.DEFINE {
+				ast.Walk(v.loopHeader(), c)
+			} else if c != nil {
+				ast.Walk(v, c)
+			}
+		}
+		ast.Walk(v, n.X)
+		ast.Walk(v.with(v.loops+1, v.funcs), n.Body)
+		return nil
+	case *ast.FuncLit:
+		ast.Walk(v.with(v.loops, v.funcs+1), n.Body)
+		return nil
+	case *ast.Ident:
+		if v.funcs > 0 {
+			if obj := v.info.Uses[n]; obj != nil {
+				v.captured[obj] = struct{}{}
+			}
+		} else if v.loops > 0 {
+			if obj, ok := v.info.Defs[n].(*types.Var); ok {
+				v.declared[obj] = struct{}{}
+				if v.header {
+					v.headers[obj] = struct{}{}
+				}
+			}
+		}
+	}
+	return v
+}
+
+// allocateLoopCaptures rewrites the declarations and uses of the variables
+// returned by loopCaptures, as well as the loops declaring headers, as
+// described above. The statements binding the pointers captured by closures
+// are returned as assignments, so that they are not hoisted to the frame; they
+// must be turned into declarations with declareLoopCaptures once the variables
+// of the function have been renamed.
+func allocateLoopCaptures(p *packages.Package, body *ast.BlockStmt, vars, headers map[types.Object]struct{}, mayYield map[ast.Node]struct{}) (bindings map[ast.Stmt]struct{}) {
+	if len(vars) == 0 {
+		return nil
+	}
+	info := p.TypesInfo
+	pointers := map[types.Object]*types.Var{}
+
+	index := 0
+	newVar := func(t types.Type) *ast.Ident {
+		ident := ast.NewIdent("_p" + strconv.Itoa(index))
+		index++
+		info.Defs[ident] = types.NewVar(0, p.Types, ident.Name, t)
+		return ident
+	}
+	ref := func(v *types.Var) *ast.Ident {
+		ident := ast.NewIdent(v.Name())
+		info.Uses[ident] = v
+		return ident
+	}
+	deref := func(v *types.Var, pos token.Pos) ast.Expr {
+		// The position of the rewritten identifier is retained so the
+		// expressions it appears in are formatted the same way.
+		star := &ast.StarExpr{Star: pos, X: ref(v)}
+		info.Types[star] = types.TypeAndValue{Type: v.Type().(*types.Pointer).Elem()}
+		return star
+	}
+	allocate := func(obj types.Object) (*ast.Ident, ast.Stmt) {
+		ptr := newVar(types.NewPointer(obj.Type()))
+		pointers[obj] = info.Defs[ptr].(*types.Var)
+		// _p := new(T)
+		return ptr, &ast.AssignStmt{
+			Lhs: []ast.Expr{ptr},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{&ast.CallExpr{
+				Fun:  ast.NewIdent("new"),
+				Args: []ast.Expr{typeExpr(p, obj.Type())},
+			}},
+		}
+	}
+	captured := func(ident *ast.Ident) types.Object {
+		if obj := info.Defs[ident]; obj != nil {
+			if _, ok := vars[obj]; ok {
+				return obj
+			}
+		}
+		return nil
+	}
+
+	loopHeaders := func(stmt ast.Stmt) (objs []types.Object) {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.Ident:
+				if obj := captured(n); obj != nil {
+					if _, ok := headers[obj]; ok {
+						objs = append(objs, obj)
+					}
+				}
+			}
+			return true
+		})
+		return objs
+	}
+
+	// Move the headers out of the loops declaring them, so that they are
+	// rewritten like other declarations. The variables declared by range
+	// clauses are assigned to variables declared at the start of the body,
+	// the init statements of for loops are moved before the loops, which is
+	// how the desugarer rewrites loops that may yield.
+	astutil.Apply(body, func(cursor *astutil.Cursor) bool {
+		switch n := cursor.Node().(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.RangeStmt:
+			if n.Tok != token.DEFINE {
+				break
+			}
+			var decls []ast.Stmt
+			for _, x := range []*ast.Expr{&n.Key, &n.Value} {
+				if *x == nil {
+					continue
+				}
+				if obj := captured((*x).(*ast.Ident)); obj != nil {
+					// for k := range x { => for _p := range x { k := _p
+					tmp := newVar(obj.Type())
+					decls = append(decls, &ast.AssignStmt{
+						Lhs: []ast.Expr{*x},
+						Tok: token.DEFINE,
+						Rhs: []ast.Expr{ref(info.Defs[tmp].(*types.Var))},
+					})
+					*x = tmp
+				}
+			}
+			n.Body.List = append(decls, n.Body.List...)
+		case *ast.LabeledStmt, *ast.ForStmt:
+			loop, ok := unlabel(n).(*ast.ForStmt)
+			if !ok || loop.Init == nil || cursor.Index() < 0 || len(loopHeaders(loop.Init)) == 0 {
+				break
+			}
+			// L: for init; ... => { init; L: for ; ... }
+			block := &ast.BlockStmt{List: []ast.Stmt{loop.Init, n.(ast.Stmt)}}
+			loop.Init = nil
+			cursor.Replace(block)
+		}
+		return true
+	}, nil)
+
+	// The headers of a loop are declared by the statements preceding it in
+	// the block that it ends.
+	loops := map[*ast.ForStmt][]types.Object{}
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.BlockStmt:
+			if len(n.List) == 0 {
+				break
+			}
+			if loop, ok := unlabel(n.List[len(n.List)-1]).(*ast.ForStmt); ok {
+				for _, stmt := range n.List[:len(n.List)-1] {
+					loops[loop] = append(loops[loop], loopHeaders(stmt)...)
+				}
+			}
+		}
+		return true
+	})
+
+	// Rewrite declarations. Only declarations that are statements of a block
+	// can be expanded into multiple statements; variables declared elsewhere
+	// (e.g. in the init statement of an if statement) keep sharing a single
+	// variable across iterations.
+	astutil.Apply(body, func(cursor *astutil.Cursor) bool {
+		switch n := cursor.Node().(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE || cursor.Index() < 0 {
+				break
+			}
+			if len(n.Lhs) == 1 && len(n.Rhs) == 1 {
+				// x := v => _p := new(T); *_p = v
+				if obj := captured(n.Lhs[0].(*ast.Ident)); obj != nil {
+					_, alloc := allocate(obj)
+					cursor.InsertBefore(alloc)
+					n.Tok = token.ASSIGN
+					n.Lhs[0] = deref(pointers[obj], n.Lhs[0].Pos())
+				}
+				break
+			}
+			// x, y := v => _p0, y := v; _p1 := new(T); *_p1 = _p0
+			var after []ast.Stmt
+			for i, lhs := range n.Lhs {
+				if obj := captured(lhs.(*ast.Ident)); obj != nil {
+					tmp := newVar(obj.Type())
+					n.Lhs[i] = tmp
+					_, alloc := allocate(obj)
+					after = append(after, alloc, &ast.AssignStmt{
+						Lhs: []ast.Expr{deref(pointers[obj], lhs.Pos())},
+						Tok: token.ASSIGN,
+						Rhs: []ast.Expr{ref(info.Defs[tmp].(*types.Var))},
+					})
+				}
+			}
+			for i := len(after) - 1; i >= 0; i-- {
+				cursor.InsertAfter(after[i])
+			}
+		case *ast.DeclStmt:
+			decl, ok := n.Decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.VAR || cursor.Index() < 0 {
+				break
+			}
+			// var x, y T = a, b => _p := new(T); *_p = a; var y T = b
+			var stmts []ast.Stmt
+			var rewritten bool
+			for _, spec := range decl.Specs {
+				s := spec.(*ast.ValueSpec)
+				if len(s.Values) != 0 && len(s.Values) != len(s.Names) {
+					stmts = append(stmts, &ast.DeclStmt{Decl: &ast.GenDecl{Tok: token.VAR, Specs: []ast.Spec{s}}})
+					continue
+				}
+				for i, name := range s.Names {
+					obj := captured(name)
+					if obj == nil {
+						v := &ast.ValueSpec{Names: []*ast.Ident{name}, Type: s.Type}
+						if len(s.Values) != 0 {
+							v.Values = []ast.Expr{s.Values[i]}
+						}
+						stmts = append(stmts, &ast.DeclStmt{Decl: &ast.GenDecl{Tok: token.VAR, Specs: []ast.Spec{v}}})
+						continue
+					}
+					rewritten = true
+					_, alloc := allocate(obj)
+					stmts = append(stmts, alloc)
+					if len(s.Values) != 0 {
+						stmts = append(stmts, &ast.AssignStmt{
+							Lhs: []ast.Expr{deref(pointers[obj], name.Pos())},
+							Tok: token.ASSIGN,
+							Rhs: []ast.Expr{s.Values[i]},
+						})
+					}
+				}
+			}
+			if rewritten {
+				for _, stmt := range stmts {
+					cursor.InsertBefore(stmt)
+				}
+				cursor.Delete()
+			}
+		}
+		return true
+	}, nil)
+
+	if len(pointers) == 0 {
+		return nil
+	}
+
+	// Rewrite the loops declaring headers, the variables are copied before
+	// the post statement runs.
+	astutil.Apply(body, func(cursor *astutil.Cursor) bool {
+		switch n := cursor.Node().(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.LabeledStmt, *ast.ForStmt:
+			loop, ok := unlabel(n).(*ast.ForStmt)
+			if !ok || len(loops[loop]) == 0 || cursor.Index() < 0 {
+				break
+			}
+			loopVars := loops[loop]
+			delete(loops, loop)
+
+			// _p0 = &[]T{*_p0}[0]
+			copies := &ast.AssignStmt{Tok: token.ASSIGN}
+			for _, obj := range loopVars {
+				ptr := pointers[obj]
+				sliceType := types.NewSlice(obj.Type())
+				lit := &ast.CompositeLit{Type: typeExpr(p, sliceType), Elts: []ast.Expr{deref(ptr, token.NoPos)}}
+				zero := &ast.BasicLit{Kind: token.INT, Value: "0"}
+				elem := &ast.IndexExpr{X: lit, Index: zero}
+				addr := &ast.UnaryExpr{Op: token.AND, X: elem}
+				info.Types[lit] = types.TypeAndValue{Type: sliceType}
+				info.Types[zero] = types.TypeAndValue{Type: types.Typ[types.Int], Value: constant.MakeInt64(0)}
+				info.Types[elem] = types.TypeAndValue{Type: obj.Type()}
+				info.Types[addr] = types.TypeAndValue{Type: ptr.Type()}
+				copies.Lhs = append(copies.Lhs, ref(ptr))
+				copies.Rhs = append(copies.Rhs, addr)
+			}
+
+			if loop.Post == nil {
+				// Nothing modifies the variables between iterations, they
+				// can be copied at the start of each iteration.
+				loop.Body.List = append([]ast.Stmt{copies}, loop.Body.List...)
+				break
+			}
+
+			// The break statement exiting the loop is in the body, which is
+			// turned into a switch if the loop yields: it needs a label.
+			labeled, ok := n.(*ast.LabeledStmt)
+			if !ok {
+				label := ast.NewIdent("_p" + strconv.Itoa(index))
+				index++
+				labeled = &ast.LabeledStmt{Label: label, Stmt: loop}
+				cursor.Replace(labeled)
+			}
+
+			// if _f { _f = false } else { copies; post }
+			first := newVar(types.Typ[types.Bool])
+			firstVar := info.Defs[first].(*types.Var)
+			cursor.InsertBefore(&ast.AssignStmt{
+				Lhs: []ast.Expr{first},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{universe(info, "true")},
+			})
+			prologue := []ast.Stmt{&ast.IfStmt{
+				Cond: ref(firstVar),
+				Body: &ast.BlockStmt{List: []ast.Stmt{&ast.AssignStmt{
+					Lhs: []ast.Expr{ref(firstVar)},
+					Tok: token.ASSIGN,
+					Rhs: []ast.Expr{universe(info, "false")},
+				}}},
+				Else: &ast.BlockStmt{List: []ast.Stmt{copies, loop.Post}},
+			}}
+			if loop.Cond != nil {
+				// if !(cond) { break }
+				prologue = append(prologue, &ast.IfStmt{
+					Cond: &ast.UnaryExpr{Op: token.NOT, X: loop.Cond},
+					Body: &ast.BlockStmt{List: []ast.Stmt{&ast.BranchStmt{
+						Tok:   token.BREAK,
+						Label: ast.NewIdent(labeled.Label.Name),
+					}}},
+				})
+			}
+			loop.Post, loop.Cond = nil, nil
+			loop.Body.List = append(prologue, loop.Body.List...)
+		}
+		return true
+	}, nil)
+
+	// Rewrite uses. Within function literals, the pointers are read from a
+	// local variable bound by the innermost statement of the function body
+	// containing the literal.
+	type binding struct {
+		obj  types.Object
+		copy *types.Var
+	}
+	var stmts []ast.Stmt
+	var funcs int
+	bound := map[ast.Stmt][]binding{}
+	bindings = map[ast.Stmt]struct{}{}
+
+	astutil.Apply(body, func(cursor *astutil.Cursor) bool {
+		switch n := cursor.Node().(type) {
+		case *ast.FuncLit:
+			funcs++
+		case ast.Stmt:
+			if funcs == 0 && cursor.Index() >= 0 {
+				stmts = append(stmts, n)
+			}
+		case *ast.Ident:
+			obj := info.Uses[n]
+			if obj == nil {
+				// The desugarer reuses the identifier declaring the key
+				// of range loops to reference it.
+				obj = info.Defs[n]
+			}
+			ptr := pointers[obj]
+			if ptr == nil {
+				break
+			}
+			if funcs > 0 && len(stmts) > 0 {
+				stmt := stmts[len(stmts)-1]
+				// Statements that may yield are split by the dispatch,
+				// local variables bound before them would not be in
+				// scope when they are resumed.
+				if _, yield := mayYield[stmt]; !yield {
+					i := 0
+					for i < len(bound[stmt]) && bound[stmt][i].obj != obj {
+						i++
+					}
+					if i == len(bound[stmt]) {
+						copy := info.Defs[newVar(ptr.Type())].(*types.Var)
+						bound[stmt] = append(bound[stmt], binding{obj, copy})
+					}
+					ptr = bound[stmt][i].copy
+				}
+			}
+			cursor.Replace(deref(ptr, n.Pos()))
+		}
+		return true
+	}, func(cursor *astutil.Cursor) bool {
+		switch n := cursor.Node().(type) {
+		case *ast.FuncLit:
+			funcs--
+		case ast.Stmt:
+			if funcs != 0 || cursor.Index() < 0 {
+				break
+			}
+			stmts = stmts[:len(stmts)-1]
+			if b := bound[n]; len(b) > 0 {
+				block := &ast.BlockStmt{}
+				for _, b := range b {
+					// _p1 = _p0 (see declareLoopCaptures)
+					bind := &ast.AssignStmt{
+						Lhs: []ast.Expr{ref(b.copy)},
+						Tok: token.ASSIGN,
+						Rhs: []ast.Expr{ref(pointers[b.obj])},
+					}
+					bindings[bind] = struct{}{}
+					block.List = append(block.List, bind)
+				}
+				block.List = append(block.List, n)
+				cursor.Replace(block)
+			}
+		}
+		return true
+	})
+
+	return bindings
+}
+
+// declareLoopCaptures turns the assignments returned by allocateLoopCaptures
+// into declarations of local variables captured by function literals.
+func declareLoopCaptures(p *packages.Package, body *ast.BlockStmt, bindings map[ast.Stmt]struct{}) {
+	if len(bindings) == 0 {
+		return
+	}
+	astutil.Apply(body, func(cursor *astutil.Cursor) bool {
+		if bind, ok := cursor.Node().(*ast.AssignStmt); ok {
+			if _, ok := bindings[bind]; !ok {
+				return true
+			}
+			name := bind.Lhs[0].(*ast.Ident)
+			// var _p1 *T = _p0
+			cursor.Replace(&ast.DeclStmt{Decl: &ast.GenDecl{
+				Tok: token.VAR,
+				Specs: []ast.Spec{&ast.ValueSpec{
+					Names:  []*ast.Ident{name},
+					Type:   typeExpr(p, p.TypesInfo.TypeOf(name)),
+					Values: bind.Rhs,
+				}},
+			}})
+			return false
+		}
+		return true
+	}, nil)
+}
+
+// universe returns an identifier referencing the object of the universe scope
+// with the given name (e.g. true).
+func universe(info *types.Info, name string) *ast.Ident {
+	ident := ast.NewIdent(name)
+	info.Uses[ident] = types.Universe.Lookup(name)
+	return ident
+}
+
+// unlabel returns the statement labeled by stmt, or stmt if it is not a
+// labeled statement.
+func unlabel(stmt ast.Node) ast.Node {
+	if l, ok := stmt.(*ast.LabeledStmt); ok {
+		return l.Stmt
+	}
+	return stmt
+}
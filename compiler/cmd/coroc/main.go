@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"runtime/debug"
+	"strings"
 
 	"github.com/stealthrocket/coroutine/compiler"
 )
@@ -16,8 +17,16 @@ USAGE:
   coroc [OPTIONS] [PATH]
 
 OPTIONS:
-  -h, --help      Show this help information
-  -v, --version   Show the compiler version
+  -h, --help           Show this help information
+  -v, --version        Show the compiler version
+  -o, --output DIR     Write the compiled module to DIR instead of modifying it in place
+      --functions F    Comma-separated list of functions to compile (defaults to all)
+      --tags TAGS      Comma-separated list of build tags to use when loading packages
+      --tag TAG        Build tag selecting the compiled files (defaults to durable)
+      --trace          Write the intermediate AST of compiled functions to stderr
+      --max-nesting N  Maximum depth of nested statements in compiled functions (defaults to 1000, 0 for no limit)
+      --line           Add //line directives mapping generated files to the original source
+      --cache          Skip compiling the module if it did not change since it was last compiled
 `
 
 func main() {
@@ -34,6 +43,31 @@ func run() error {
 	flag.BoolVar(&showVersion, "v", false, "")
 	flag.BoolVar(&showVersion, "version", false, "")
 
+	var outputDir string
+	flag.StringVar(&outputDir, "o", "", "")
+	flag.StringVar(&outputDir, "output", "", "")
+
+	var buildTags string
+	flag.StringVar(&buildTags, "tags", "", "")
+
+	var buildTag string
+	flag.StringVar(&buildTag, "tag", "durable", "")
+
+	var trace bool
+	flag.BoolVar(&trace, "trace", false, "")
+
+	var functions string
+	flag.StringVar(&functions, "functions", "", "")
+
+	var maxNesting int
+	flag.IntVar(&maxNesting, "max-nesting", 1000, "")
+
+	var lineDirectives bool
+	flag.BoolVar(&lineDirectives, "line", false, "")
+
+	var cache bool
+	flag.BoolVar(&cache, "cache", false, "")
+
 	flag.Parse()
 
 	if showVersion {
@@ -55,7 +89,25 @@ func run() error {
 		}
 	}
 
-	return compiler.Compile(path)
+	options := []compiler.Option{
+		compiler.WithBuildTags(buildTags),
+		compiler.WithBuildTag(buildTag),
+		compiler.WithOutputDir(outputDir),
+		compiler.WithMaxNesting(maxNesting),
+	}
+	if trace {
+		options = append(options, compiler.WithTrace(os.Stderr))
+	}
+	if lineDirectives {
+		options = append(options, compiler.WithLineDirectives())
+	}
+	if cache {
+		options = append(options, compiler.WithCache())
+	}
+	if functions != "" {
+		options = append(options, compiler.WithFunctions(strings.Split(functions, ",")...))
+	}
+	return compiler.Compile(path, options...)
 }
 
 func version() (version string) {
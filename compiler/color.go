@@ -2,12 +2,82 @@ package compiler
 
 import (
 	"fmt"
+	"go/token"
 	"go/types"
 
 	"golang.org/x/tools/go/callgraph"
 	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
 )
 
+// Color describes a function that yields or may yield, which the compiler
+// compiles to a durable coroutine.
+type Color struct {
+	// Signature of the coroutine.Yield instantiation that the function may
+	// reach, which determines the types of values that the coroutine yields
+	// and receives (e.g. func(v int) any).
+	Yield *types.Signature
+
+	// Name of a function called by the colored function that makes it reach
+	// coroutine.Yield; it is either another colored function or Yield itself.
+	Calls string
+
+	// Position of the call to the function named by Calls.
+	Pos token.Position
+}
+
+// Analyze loads and analyzes the module like Compile does, and returns the
+// functions that would be compiled, without writing any files.
+//
+// The map is keyed by function names, which have the form <package>.<function>
+// or (<receiver>).<method>. Generic functions are reported once regardless of
+// the number of instantiations.
+func Analyze(path string, options ...Option) (map[string]Color, error) {
+	c := &compiler{
+		fset: token.NewFileSet(),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	a, err := c.analyze(path)
+	if err != nil || a == nil {
+		return nil, err
+	}
+
+	colors := make(map[string]Color, len(a.colors))
+	for fn, color := range a.colors {
+		name := functionName(fn)
+		if _, ok := colors[name]; ok {
+			continue
+		}
+		result := Color{Yield: color}
+
+		// Report the first call in the function that may yield.
+		var call *callgraph.Edge
+		for _, edge := range a.callgraph.Nodes[fn].Out {
+			_, colored := a.colors[edge.Callee.Func]
+			_, yield := a.yieldInstances[edge.Callee.Func]
+			if (colored || yield) && (call == nil || edge.Pos() < call.Pos()) {
+				call = edge
+			}
+		}
+		if call != nil {
+			result.Calls = functionName(call.Callee.Func)
+			result.Pos = a.prog.Fset.Position(call.Pos())
+		}
+
+		colors[name] = result
+	}
+	return colors, nil
+}
+
+func functionName(fn *ssa.Function) string {
+	if origin := fn.Origin(); origin != nil {
+		fn = origin
+	}
+	return fn.String()
+}
+
 // colorFunctions walks the call graph, coloring functions that yield (or may
 // yield) by their yield type. It's an error if a function has more than one
 // yield type.
@@ -26,6 +96,58 @@ func colorFunctions(cg *callgraph.Graph, yieldInstances functionColors) (functio
 
 type functionColors map[*ssa.Function]*types.Signature
 
+// selectFunctions restricts colors to the named functions and the colored
+// functions that they reach in the call graph.
+func selectFunctions(prog *ssa.Program, cg *callgraph.Graph, colors functionColors, names []string) (functionColors, error) {
+	byName := map[string][]*ssa.Function{}
+	for fn := range colors {
+		name := functionName(fn)
+		byName[name] = append(byName[name], fn)
+	}
+
+	selected := functionColors{}
+	for _, name := range names {
+		fns, ok := byName[name]
+		if !ok {
+			for fn := range ssautil.AllFunctions(prog) {
+				if functionName(fn) == name {
+					return nil, fmt.Errorf("function %s does not yield", name)
+				}
+			}
+			return nil, fmt.Errorf("function %s not found", name)
+		}
+		for _, fn := range fns {
+			selectFunctions0(cg, colors, selected, fn)
+		}
+	}
+	return selected, nil
+}
+
+func selectFunctions0(cg *callgraph.Graph, colors, selected functionColors, fn *ssa.Function) {
+	color, ok := colors[fn]
+	if !ok {
+		return
+	}
+	if _, ok := selected[fn]; ok {
+		return // already walked
+	}
+	selected[fn] = color
+
+	// Closures are compiled along with the function that declares them, so
+	// selecting one selects its enclosing function, and vice versa.
+	if parent := fn.Parent(); parent != nil {
+		selectFunctions0(cg, colors, selected, parent)
+	}
+	for _, anon := range fn.AnonFuncs {
+		selectFunctions0(cg, colors, selected, anon)
+	}
+	if node := cg.Nodes[fn]; node != nil {
+		for _, edge := range node.Out {
+			selectFunctions0(cg, colors, selected, edge.Callee.Func)
+		}
+	}
+}
+
 func colorFunctions0(cg *callgraph.Graph, colors functionColors, fn *ssa.Function, color *types.Signature) error {
 	if origin := fn.Origin(); origin != nil && origin.Pkg != nil {
 		// Don't follow edges into and through the coroutine package.
@@ -49,3 +171,74 @@ func colorFunctions0(cg *callgraph.Graph, colors functionColors, fn *ssa.Functio
 	}
 	return nil
 }
+
+// unsupportedCallbacks checks that none of the functions that yield or may
+// yield belong to deps, the packages that the coroutine package depends on.
+//
+// Functions of the standard library that call functions passed as arguments
+// (e.g. sort.Slice with a less function that yields) need to be compiled as
+// well, so they can be suspended and resumed with the coroutine. This is not
+// possible for packages that the coroutine package imports, since the compiled
+// code would have to import it back, and the program would not build. The
+// error reports the call to the first of those functions from a package that
+// can be compiled, and the function passed that yields.
+func unsupportedCallbacks(a *analysis, deps map[string]bool) error {
+	pkgPath := func(fn *ssa.Function) string {
+		if origin := fn.Origin(); origin != nil {
+			fn = origin
+		}
+		if fn.Pkg == nil {
+			return ""
+		}
+		return fn.Pkg.Pkg.Path()
+	}
+
+	var call *callgraph.Edge
+	for fn := range a.colors {
+		if deps[pkgPath(fn)] {
+			continue
+		}
+		for _, edge := range a.callgraph.Nodes[fn].Out {
+			if _, ok := a.colors[edge.Callee.Func]; !ok || !deps[pkgPath(edge.Callee.Func)] {
+				continue
+			}
+			if call == nil || edge.Pos() < call.Pos() {
+				call = edge
+			}
+		}
+	}
+	if call == nil {
+		return nil
+	}
+
+	// Find the function that yields, which the function called reaches
+	// through other functions of the packages that cannot be compiled.
+	callee := call.Callee.Func
+	var callback *ssa.Function
+	seen := map[*ssa.Function]bool{callee: true}
+search:
+	for queue := []*ssa.Function{callee}; len(queue) > 0; queue = queue[1:] {
+		for _, edge := range a.callgraph.Nodes[queue[0]].Out {
+			fn := edge.Callee.Func
+			if _, ok := a.colors[fn]; !ok || seen[fn] {
+				continue
+			}
+			if !deps[pkgPath(fn)] {
+				callback = fn
+				break search
+			}
+			seen[fn] = true
+			queue = append(queue, fn)
+		}
+	}
+	name := "a function"
+	if callback != nil {
+		name = functionName(callback)
+	}
+
+	return &UnsupportedError{
+		Pos: a.prog.Fset.Position(call.Pos()),
+		Msg: fmt.Sprintf("not implemented: %s calls %s that yields (package %s cannot be compiled because the coroutine package imports it)",
+			functionName(callee), name, pkgPath(callee)),
+	}
+}
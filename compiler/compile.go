@@ -1,12 +1,14 @@
 package compiler
 
 import (
+	"bytes"
 	"fmt"
 	"go/ast"
 	"go/build/constraint"
 	"go/format"
 	"go/token"
 	"go/types"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -16,6 +18,7 @@ import (
 	"strings"
 
 	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/callgraph"
 	"golang.org/x/tools/go/callgraph/cha"
 	"golang.org/x/tools/go/callgraph/vta"
 	"golang.org/x/tools/go/packages"
@@ -25,6 +28,10 @@ import (
 
 const coroutinePackage = "github.com/stealthrocket/coroutine"
 
+// durableTag is the build tag selecting the durable runtime of the coroutine
+// package, and the compiled files unless configured otherwise.
+const durableTag = "durable"
+
 // Compile compiles coroutines in a module.
 //
 // The path argument can either be a path to a package within
@@ -33,9 +40,23 @@ const coroutinePackage = "github.com/stealthrocket/coroutine"
 // nearest module is located and compiled as a whole.
 //
 // The path can be absolute, or relative to the current working directory.
+//
+// Test files are not compiled. The functions they declare are left as is in
+// durable mode, so coroutines exercised by tests should only yield from
+// functions declared in the other files of a package.
+//
+// Errors caused by the module being compiled match ErrNotModule,
+// ErrNeedsVendoring, or ErrUnsupportedFeature (see UnsupportedError), which
+// can be tested with errors.Is.
+//
+// Files are only written when their content changes. With WithCache, the
+// compilation of a module in place is skipped entirely when the module has not
+// changed since it was last compiled.
 func Compile(path string, options ...Option) error {
 	c := &compiler{
-		fset: token.NewFileSet(),
+		fset:       token.NewFileSet(),
+		buildTag:   durableTag,
+		maxNesting: defaultMaxNesting,
 	}
 	for _, option := range options {
 		option(c)
@@ -46,32 +67,315 @@ func Compile(path string, options ...Option) error {
 // Option configures the compiler.
 type Option func(*compiler)
 
+// WithBuildTags sets the build tags used when loading packages, using the
+// same comma-separated syntax as the -tags flag of the go command.
+//
+// Build tags allow the compiler to select files that are only part of the
+// build under certain constraints (e.g. platform-specific files).
+func WithBuildTags(tags string) Option {
+	return func(c *compiler) {
+		c.buildTags = tags
+	}
+}
+
+// WithBuildTag sets the build tag that selects the compiled files, instead of
+// the default "durable" tag. The generated files are only part of the build
+// when the tag is set, and the source files only when it is not.
+//
+// The durable runtime of the coroutine package is selected by the "durable"
+// tag. When compiling with another tag, the compiler rewrites the build
+// constraints of the runtime so that it is selected by the tag instead, which
+// requires the coroutine module to be vendored (go mod vendor).
+func WithBuildTag(tag string) Option {
+	return func(c *compiler) {
+		c.buildTag = tag
+	}
+}
+
+// WithTrace writes the intermediate AST of compiled functions to w, which is
+// useful to debug how the compiler transforms a coroutine.
+//
+// For each compiled function, the function body is written after desugaring,
+// and the function declaration is written once it has been fully compiled.
+func WithTrace(w io.Writer) Option {
+	return func(c *compiler) {
+		c.trace = w
+	}
+}
+
+// WithOutputDir writes the compiled module to dir instead of modifying the
+// source files in place.
+//
+// The module is copied to dir before being compiled, so the output is a tree
+// that can be built on its own, with the same module path and import paths as
+// the original. GOROOT packages that need to be compiled are vendored in the
+// goroot directory of the output tree. The original module directory is left
+// untouched.
+func WithOutputDir(dir string) Option {
+	return func(c *compiler) {
+		c.outputDir = dir
+	}
+}
+
+// WithFunctions restricts compilation to the named functions, and to the
+// functions they call that yield or may yield. Other functions are left
+// unmodified, even if they yield.
+//
+// Names have the same form as the keys of the map returned by Analyze (e.g.
+// example.com/pkg.Func or (*example.com/pkg.T).Method). It is an error if one
+// of the functions cannot be found or does not yield.
+func WithFunctions(names ...string) Option {
+	return func(c *compiler) {
+		c.functions = append(c.functions, names...)
+	}
+}
+
+// WithMaxNesting sets the maximum depth of nested statements in the functions
+// that the compiler rewrites, instead of the default of 1000. Compiling a
+// function nested deeper returns an error rather than overflowing the stack of
+// the compiler. Zero or a negative depth removes the limit.
+//
+// The limit is only reached by generated or otherwise pathological code, the
+// statements of hand-written functions are rarely nested more than a few tens
+// of levels deep.
+func WithMaxNesting(depth int) Option {
+	return func(c *compiler) {
+		c.maxNesting = depth
+	}
+}
+
+const defaultMaxNesting = 1000
+
+// WithCache records the state of the module in the user cache directory after
+// compiling it in place, and skips the next compilation if the module has not
+// changed since then (for example when the compiler is invoked by go generate
+// on every build).
+//
+// The state covers the Go files of the module and its go.mod and go.sum files,
+// but not nested modules or vendored packages (including the GOROOT packages
+// vendored by the compiler). Changes to dependencies are captured by go.sum,
+// with the exception of local replace directives. The cache is not used when
+// compiling to an output directory.
+func WithCache() Option {
+	return func(c *compiler) {
+		c.cache = true
+	}
+}
+
+// WithLineDirectives adds //line directives to the generated files, mapping
+// their statements to the lines of the original source. Positions reported by
+// the Go toolchain, such as those of stack traces and compile errors, then
+// refer to the source of the compiled functions instead of the generated code.
+//
+// Statements generated by the compiler are mapped to the line of the
+// statement or function they were generated from.
+func WithLineDirectives() Option {
+	return func(c *compiler) {
+		c.lineDirectives = true
+	}
+}
+
 type compiler struct {
 	coroutinePkg *packages.Package
+	defersType   types.Type
+
+	fset      *token.FileSet
+	buildTags string
+	buildTag  string
+	trace     io.Writer
+	outputDir string
+	functions []string
+
+	maxNesting     int
+	lineDirectives bool
+	cache          bool
+	sourceLines    map[string]lineMap
+}
 
-	fset *token.FileSet
+// analysis is the result of loading and analyzing a module, which determines
+// the functions that the compiler needs to compile.
+type analysis struct {
+	pkgs      []*packages.Package
+	moduleDir string
+	prog      *ssa.Program
+	callgraph *callgraph.Graph
+
+	// Instantiations of coroutine.Yield, and functions that yield or may
+	// yield, colored by the signature of the Yield instantiation.
+	yieldInstances functionColors
+	colors         functionColors
 }
 
 func (c *compiler) compile(path string) error {
 	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
 
-	absPath, err := filepath.Abs(path)
+	// When compiling in place with the cache enabled, the module is left
+	// untouched if it has not changed since it was last compiled.
+	var fingerprintDir, fingerprintPattern string
+	if c.cache && c.outputDir == "" {
+		dir, pattern, err := resolvePath(path)
+		if err != nil {
+			return err
+		}
+		if moduleDir, ok := findModuleDir(dir); ok {
+			fingerprintDir, fingerprintPattern = moduleDir, filepath.Join(dir, pattern)
+			if c.moduleUnchanged(fingerprintDir, fingerprintPattern) {
+				log.Printf("module unchanged since last compilation. Nothing to do")
+				return nil
+			}
+		}
+	}
+
+	a, err := c.analyze(path)
+	if err != nil || a == nil {
+		return err
+	}
+	pkgs, moduleDir, prog, colors := a.pkgs, a.moduleDir, a.prog, a.colors
+
+	deps, err := c.coroutineDeps(moduleDir)
 	if err != nil {
 		return err
 	}
-	var dotdotdot bool
-	absPath, dotdotdot = strings.CutSuffix(absPath, "...")
-	if s, err := os.Stat(absPath); err != nil {
+	if err := unsupportedCallbacks(a, deps); err != nil {
 		return err
-	} else if !s.IsDir() {
-		// Make sure we're loading whole packages.
-		absPath = filepath.Dir(absPath)
 	}
-	var pattern string
-	if dotdotdot {
-		pattern = "./..."
-	} else {
-		pattern = "."
+
+	// The durable runtime is selected by the build tag of the compiled
+	// files, which requires rewriting the runtime unless it is the default.
+	retagRuntime := c.buildTag != durableTag
+	if retagRuntime {
+		dir := packageDir(c.coroutinePkg)
+		if rel, err := filepath.Rel(moduleDir, dir); err != nil || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("cannot select the durable runtime of package %s (%s) with the %q build tag: %w", coroutinePackage, dir, c.buildTag, ErrNeedsVendoring)
+		}
+	}
+
+	pkgsByTypes := map[*types.Package]*packages.Package{}
+	packages.Visit(pkgs, func(p *packages.Package) bool {
+		pkgsByTypes[p.Types] = p
+		return true
+	}, nil)
+	colorsByPkg := map[*packages.Package]functionColors{}
+	for fn, color := range colors {
+		// Instantiations of generic functions share the syntax of the
+		// generic function they originate from, which is the one that
+		// gets compiled (with its type parameters intact).
+		if origin := fn.Origin(); origin != nil {
+			fn = origin
+		}
+		if fn.Synthetic != "" && fn.Syntax() == nil {
+			// Wrappers of methods (e.g. the methods of *T promoted from
+			// those of T, called through interfaces) only forward the call
+			// to the method they wrap, which gets compiled. They have no
+			// state to restore, and are called again with the same receiver
+			// when the coroutine resumes.
+			if strings.HasPrefix(fn.Synthetic, "wrapper for ") {
+				continue
+			}
+			return unsupportedSyntheticFunction(prog, fn)
+		}
+		if fn.Pkg == nil {
+			return &UnsupportedError{Msg: fmt.Sprintf("unsupported yield function %s (Pkg is nil)", fn)}
+		}
+
+		p := pkgsByTypes[fn.Pkg.Pkg]
+		pkgColors := colorsByPkg[p]
+		if pkgColors == nil {
+			pkgColors = functionColors{}
+			colorsByPkg[p] = pkgColors
+		}
+		if existing, ok := pkgColors[fn]; ok && !types.Identical(existing, color) {
+			return fmt.Errorf("function %s has more than one color (%v + %v)", fn, existing, color)
+		}
+		pkgColors[fn] = color
+	}
+
+	// Before mutating packages, we need to ensure that packages exist in a
+	// location where mutations can be made safely (without affecting other
+	// builds).
+	var needVendoring []*packages.Package
+	goroot := runtime.GOROOT()
+	for p := range colorsByPkg {
+		dir := packageDir(p)
+
+		// The input module can be mutated, and so can nested
+		// packages (including those in the ./vendor directory).
+		moduleRel, err := filepath.Rel(moduleDir, dir)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(moduleRel, "..") {
+			continue
+		}
+
+		// Collect GOROOT packages and vendor them below.
+		gorootRel, err := filepath.Rel(goroot, dir)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(gorootRel, "..") {
+			needVendoring = append(needVendoring, p)
+			continue
+		}
+
+		// Reject packages without an associated module.
+		if p.Module == nil {
+			return fmt.Errorf("cannot mutate package %s (%s): %w", p.PkgPath, dir, ErrNotModule)
+		}
+
+		// Reject packages outside ./vendor.
+		return fmt.Errorf("cannot mutate package %s (%s) safely: %w", p.PkgPath, dir, ErrNeedsVendoring)
+	}
+
+	if c.outputDir != "" {
+		outputDir, err := filepath.Abs(c.outputDir)
+		if err != nil {
+			return err
+		}
+		if outputDir != moduleDir {
+			log.Printf("copying module to %s", outputDir)
+			if err := copyModule(outputDir, moduleDir, pkgs); err != nil {
+				return err
+			}
+			moduleDir = outputDir
+		}
+	}
+
+	if len(needVendoring) > 0 {
+		log.Printf("vendoring GOROOT packages")
+		newRoot := filepath.Join(moduleDir, "goroot")
+		if err := vendorGOROOT(newRoot, needVendoring); err != nil {
+			return err
+		}
+	}
+
+	if retagRuntime {
+		log.Printf("selecting the durable runtime with the %q build tag", c.buildTag)
+		if err := retagPackage(packageDir(c.coroutinePkg), durableTag, c.buildTag); err != nil {
+			return err
+		}
+	}
+
+	for p, colors := range colorsByPkg {
+		if err := c.compilePackage(p, colors); err != nil {
+			return err
+		}
+	}
+
+	if fingerprintDir != "" {
+		c.recordFingerprint(fingerprintDir, fingerprintPattern)
+	}
+
+	log.Printf("done")
+	return nil
+}
+
+// analyze loads the module at path and colors the functions that yield or may
+// yield. It returns a nil analysis if the module does not use coroutines.
+func (c *compiler) analyze(path string) (*analysis, error) {
+	absPath, pattern, err := resolvePath(path)
+	if err != nil {
+		return nil, err
 	}
 
 	log.Printf("reading, parsing and type-checking")
@@ -83,20 +387,27 @@ func (c *compiler) compile(path string) error {
 		Fset: c.fset,
 		Dir:  absPath,
 		Env:  os.Environ(),
+		// Test files are only part of the build of test binaries, they
+		// must not be rewritten or have compiled counterparts generated
+		// (a foo_test_durable.go file would not be a test file).
+		Tests: false,
+	}
+	if c.buildTags != "" {
+		conf.BuildFlags = []string{"-tags=" + c.buildTags}
 	}
 	pkgs, err := packages.Load(conf, pattern)
 	if err != nil {
-		return fmt.Errorf("packages.Load %q: %w", path, err)
+		return nil, fmt.Errorf("packages.Load %q: %w", path, err)
 	}
 	var moduleDir string
 	for _, p := range pkgs {
 		if p.Module == nil {
-			return fmt.Errorf("package %s is not part of a module", p.PkgPath)
+			return nil, fmt.Errorf("package %s: %w", p.PkgPath, ErrNotModule)
 		}
 		if moduleDir == "" {
 			moduleDir = p.Module.Dir
 		} else if moduleDir != p.Module.Dir {
-			return fmt.Errorf("pattern more than one module (%s + %s)", moduleDir, p.Module.Dir)
+			return nil, fmt.Errorf("pattern more than one module (%s + %s)", moduleDir, p.Module.Dir)
 		}
 	}
 	err = nil
@@ -108,7 +419,7 @@ func (c *compiler) compile(path string) error {
 		return err == nil
 	}, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	log.Printf("building SSA program")
@@ -119,6 +430,8 @@ func (c *compiler) compile(path string) error {
 	cg := vta.CallGraph(ssautil.AllFunctions(prog), cha.CallGraph(prog))
 
 	log.Printf("finding generic yield instantiations")
+	// The visit walks transitive imports, so modules that only use
+	// coroutines through an intermediate package are compiled as well.
 	packages.Visit(pkgs, func(p *packages.Package) bool {
 		if p.PkgPath == coroutinePackage {
 			c.coroutinePkg = p
@@ -127,7 +440,7 @@ func (c *compiler) compile(path string) error {
 	}, nil)
 	if c.coroutinePkg == nil {
 		log.Printf("%s not imported by the module. Nothing to do", coroutinePackage)
-		return nil
+		return nil, nil
 	}
 	yieldFunc := prog.FuncValue(c.coroutinePkg.Types.Scope().Lookup("Yield").(*types.Func))
 	yieldInstances := functionColors{}
@@ -140,113 +453,224 @@ func (c *compiler) compile(path string) error {
 	log.Printf("coloring functions")
 	colors, err := colorFunctions(cg, yieldInstances)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	pkgsByTypes := map[*types.Package]*packages.Package{}
+	if len(c.functions) > 0 {
+		colors, err = selectFunctions(prog, cg, colors, c.functions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &analysis{
+		pkgs:           pkgs,
+		moduleDir:      moduleDir,
+		prog:           prog,
+		callgraph:      cg,
+		yieldInstances: yieldInstances,
+		colors:         colors,
+	}, nil
+}
+
+// coroutineDeps returns the import paths of the packages that the coroutine
+// package depends on when built in durable mode, which is how the compiled
+// module is built.
+func (c *compiler) coroutineDeps(dir string) (map[string]bool, error) {
+	tags := durableTag
+	if c.buildTags != "" {
+		tags = c.buildTags + "," + tags
+	}
+	conf := &packages.Config{
+		Mode:       packages.NeedName | packages.NeedImports | packages.NeedDeps,
+		Dir:        dir,
+		Env:        os.Environ(),
+		BuildFlags: []string{"-tags=" + tags},
+	}
+	pkgs, err := packages.Load(conf, coroutinePackage)
+	if err != nil {
+		return nil, fmt.Errorf("packages.Load %q: %w", coroutinePackage, err)
+	}
+	deps := map[string]bool{}
 	packages.Visit(pkgs, func(p *packages.Package) bool {
-		pkgsByTypes[p.Types] = p
+		deps[p.PkgPath] = true
 		return true
 	}, nil)
-	colorsByPkg := map[*packages.Package]functionColors{}
-	for fn, color := range colors {
-		if fn.Pkg == nil {
-			return fmt.Errorf("unsupported yield function %s (Pkg is nil)", fn)
-		}
+	return deps, nil
+}
 
-		p := pkgsByTypes[fn.Pkg.Pkg]
-		pkgColors := colorsByPkg[p]
-		if pkgColors == nil {
-			pkgColors = functionColors{}
-			colorsByPkg[p] = pkgColors
-		}
-		pkgColors[fn] = color
+// bindDeferArgs returns a function without arguments that performs the
+// deferred call.
+//
+// The arguments of a deferred call are evaluated when the defer statement is
+// executed. The desugaring pass assigns them to temporary variables, but those
+// get hoisted to the coroutine frame, and are overwritten when the defer
+// statement is executed multiple times (e.g. in a loop). Closures returned by
+// other closures cannot be registered for serialization either, so the values
+// cannot be bound to the function with a closure. Instead, they are pushed to
+// stacks in the coroutine frame, and popped by the function returned, relying
+// on deferred functions being called in reverse order.
+//
+// The function returns the declarations of the stacks, which must be hoisted
+// to the top of the function, and the statements pushing the arguments, which
+// must be executed in place of the defer statement.
+func bindDeferArgs(p *packages.Package, call *ast.CallExpr, index int) (decls, push []ast.Stmt, fn ast.Expr) {
+	if len(call.Args) == 0 {
+		return nil, nil, call.Fun
 	}
 
-	// Before mutating packages, we need to ensure that packages exist in a
-	// location where mutations can be made safely (without affecting other
-	// builds).
-	var needVendoring []*packages.Package
-	goroot := runtime.GOROOT()
-	for p := range colorsByPkg {
-		dir := packageDir(p)
+	deferredCall := &ast.CallExpr{Fun: call.Fun, Ellipsis: call.Ellipsis}
+	var pop []ast.Stmt
 
-		// The input module can be mutated, and so can nested
-		// packages (including those in the ./vendor directory).
-		moduleRel, err := filepath.Rel(moduleDir, dir)
-		if err != nil {
-			return err
-		}
-		if !strings.HasPrefix(moduleRel, "..") {
-			continue
-		}
+	ident := func(obj types.Object) *ast.Ident {
+		id := ast.NewIdent(obj.Name())
+		p.TypesInfo.Uses[id] = obj
+		return id
+	}
 
-		// Collect GOROOT packages and vendor them below.
-		gorootRel, err := filepath.Rel(goroot, dir)
-		if err != nil {
-			return err
-		}
-		if !strings.HasPrefix(gorootRel, "..") {
-			needVendoring = append(needVendoring, p)
-			continue
-		}
+	for i, arg := range call.Args {
+		stackType := types.NewSlice(p.TypesInfo.TypeOf(arg))
+		stack := types.NewVar(0, p.Types, "_d"+strconv.Itoa(index+i), stackType)
+		local := "_a" + strconv.Itoa(i)
 
-		// Reject packages without an associated module.
-		if p.Module == nil {
-			return fmt.Errorf("cannot mutate package %s (%s) without a Go module", p.PkgPath, dir)
+		top := func() ast.Expr {
+			return &ast.BinaryExpr{
+				X:  &ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{ident(stack)}},
+				Op: token.SUB,
+				Y:  &ast.BasicLit{Kind: token.INT, Value: "1"},
+			}
 		}
 
-		// Reject packages outside ./vendor.
-		return fmt.Errorf("cannot mutate package %s (%s) safely. Please vendor dependencies: go mod vendor", p.PkgPath, dir)
-	}
-	if len(needVendoring) > 0 {
-		log.Printf("vendoring GOROOT packages")
-		newRoot := filepath.Join(moduleDir, "goroot")
-		if err := vendorGOROOT(newRoot, needVendoring); err != nil {
-			return err
-		}
-	}
+		// var _dN []T
+		name := ast.NewIdent(stack.Name())
+		p.TypesInfo.Defs[name] = stack
+		decls = append(decls, &ast.DeclStmt{Decl: &ast.GenDecl{
+			Tok: token.VAR,
+			Specs: []ast.Spec{&ast.ValueSpec{
+				Names: []*ast.Ident{name},
+				Type:  typeExpr(p, stackType),
+			}},
+		}})
 
-	for p, colors := range colorsByPkg {
-		if err := c.compilePackage(p, colors); err != nil {
-			return err
-		}
+		// _dN = append(_dN, arg)
+		push = append(push, &ast.AssignStmt{
+			Lhs: []ast.Expr{ident(stack)},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{&ast.CallExpr{
+				Fun:  ast.NewIdent("append"),
+				Args: []ast.Expr{ident(stack), arg},
+			}},
+		})
+
+		// _aN := _dN[len(_dN)-1]
+		// _dN = _dN[:len(_dN)-1]
+		pop = append(pop,
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{ast.NewIdent(local)},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{&ast.IndexExpr{X: ident(stack), Index: top()}},
+			},
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{ident(stack)},
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{&ast.SliceExpr{X: ident(stack), High: top()}},
+			},
+		)
+
+		deferredCall.Args = append(deferredCall.Args, ast.NewIdent(local))
 	}
 
-	log.Printf("done")
-	return nil
+	fn = &ast.FuncLit{
+		Type: &ast.FuncType{Params: &ast.FieldList{}},
+		Body: &ast.BlockStmt{List: append(pop, &ast.ExprStmt{X: deferredCall})},
+	}
+	return decls, push, fn
 }
 
-func (c *compiler) writeFile(path string, file *ast.File, changeBuildTags func(constraint.Expr) constraint.Expr) error {
-	buildTags, err := parseBuildTags(file)
+// resolvePath returns the absolute path of the directory to load packages
+// from, and the pattern matching the packages to load.
+func resolvePath(path string) (dir, pattern string, err error) {
+	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return err
+		return "", "", err
 	}
-	buildTags = changeBuildTags(buildTags)
+	var dotdotdot bool
+	absPath, dotdotdot = strings.CutSuffix(absPath, "...")
+	if s, err := os.Stat(absPath); err != nil {
+		return "", "", err
+	} else if !s.IsDir() {
+		// Make sure we're loading whole packages.
+		absPath = filepath.Dir(absPath)
+	}
+	if dotdotdot {
+		pattern = "./..."
+	} else {
+		pattern = "."
+	}
+	return absPath, pattern, nil
+}
+
+// writeFile formats file and writes it to path. When the compiler emits line
+// directives, they are added to generated files, and the lines of source files
+// are recorded to map the generated files to them.
+func (c *compiler) writeFile(path string, file *ast.File, buildTags constraint.Expr, generated bool) error {
 	stripBuildTagsOf(file, path)
 
 	// Comments are awkward to attach to the tree (they rely on token.Pos, which
 	// is coupled to a token.FileSet). Instead, just write out the raw strings.
-	var b strings.Builder
+	var b bytes.Buffer
 	if buildTags != nil {
 		b.WriteString(`//go:build `)
 		b.WriteString(buildTags.String())
 		b.WriteString("\n\n")
 	}
 
-	f, err := os.Create(path)
-	if err != nil {
+	// Format/write the remainder of the AST.
+	if err := format.Node(&b, c.fset, file); err != nil {
 		return err
 	}
-	defer f.Close()
 
-	if _, err := f.WriteString(b.String()); err != nil {
-		return err
+	// Printing the AST does not produce the same output as gofmt (e.g. the
+	// generated declarations have no positions to separate them by blank
+	// lines), so the result is formatted again for the output to be stable
+	// when the files are run through gofmt, or compiled again.
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting %s: %w", path, err)
 	}
-	// Format/write the remainder of the AST.
-	if err := format.Node(f, c.fset, file); err != nil {
-		return err
+
+	if c.lineDirectives {
+		if generated {
+			src, err = addLineDirectives(c.fset, file, path, src, c.sourceLines)
+		} else {
+			if c.sourceLines == nil {
+				c.sourceLines = map[string]lineMap{}
+			}
+			c.sourceLines[path], err = sourceLines(c.fset, file, path, src)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Leave files untouched if their content did not change, so compiling
+	// a module again does not modify files needlessly.
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, src) {
+		return nil
 	}
-	return f.Close()
+	return os.WriteFile(path, src, 0666)
+}
+
+func (c *compiler) traceNode(stage, name string, node ast.Node) {
+	if c.trace == nil {
+		return
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// %s: %s\n", stage, name)
+	if err := format.Node(&b, c.fset, node); err != nil {
+		fmt.Fprintf(&b, "// error: %v", err)
+	}
+	b.WriteString("\n\n")
+	c.trace.Write(b.Bytes())
 }
 
 func (c *compiler) compilePackage(p *packages.Package, colors functionColors) error {
@@ -259,19 +683,24 @@ func (c *compiler) compilePackage(p *packages.Package, colors functionColors) er
 		case *ast.FuncDecl:
 		case *ast.FuncLit:
 		default:
-			return fmt.Errorf("unsupported yield function %s (Syntax is %T, not *ast.FuncDecl or *ast.FuncLit)", fn, decl)
+			return &UnsupportedError{Msg: fmt.Sprintf("unsupported yield function %s (Syntax is %T, not *ast.FuncDecl or *ast.FuncLit)", fn, decl)}
 		}
 		colorsByFunc[decl] = color
 	}
 
 	buildTag := &constraint.TagExpr{
-		Tag: "durable",
+		Tag: c.buildTag,
 	}
 
 	for i, f := range p.Syntax {
-		if err := c.writeFile(p.GoFiles[i], f, func(expr constraint.Expr) constraint.Expr {
-			return withoutBuildTag(expr, buildTag)
-		}); err != nil {
+		// The build constraints of the source file are carried over to the
+		// generated file, so files only part of the build under certain tags
+		// (e.g. platform-specific files) remain so in durable mode.
+		buildTags, err := parseBuildTags(f)
+		if err != nil {
+			return err
+		}
+		if err := c.writeFile(p.GoFiles[i], f, withoutBuildTag(buildTags, buildTag), false); err != nil {
 			return err
 		}
 
@@ -297,16 +726,31 @@ func (c *compiler) compilePackage(p *packages.Package, colors functionColors) er
 					continue
 				}
 				// Reject certain language features for now.
-				if err := unsupported(decl, p.TypesInfo); err != nil {
+				if err := unsupported(p.Fset, decl, p.TypesInfo); err != nil {
 					return err
 				}
 
-				scope := &scope{compiler: c, colors: colorsByFunc}
-				gen.Decls = append(gen.Decls, scope.compileFuncDecl(p, decl, color))
+				scope := &scope{
+					compiler:             c,
+					colors:               colorsByFunc,
+					perIterationLoopVars: perIterationLoopVars(p, buildTags),
+				}
+				compiled, err := recoverUnsupported(p.Fset, decl, func() *ast.FuncDecl {
+					return scope.compileFuncDecl(p, decl, color)
+				})
+				if err != nil {
+					return err
+				}
+				gen.Decls = append(gen.Decls, compiled)
 			}
 		}
 
-		generateFunctypes(p, gen, colorsByFunc)
+		if _, err := recoverUnsupported(p.Fset, gen, func() struct{} {
+			generateFunctypes(p, gen, colorsByFunc)
+			return struct{}{}
+		}); err != nil {
+			return err
+		}
 
 		// Find all the required imports for this file.
 		gen = addImports(p, gen)
@@ -314,9 +758,7 @@ func (c *compiler) compilePackage(p *packages.Package, colors functionColors) er
 		outputPath := strings.TrimSuffix(p.GoFiles[i], ".go")
 		outputPath += "_durable.go"
 
-		if err := c.writeFile(outputPath, gen, func(expr constraint.Expr) constraint.Expr {
-			return withBuildTag(expr, buildTag)
-		}); err != nil {
+		if err := c.writeFile(outputPath, gen, withBuildTag(buildTags, buildTag), true); err != nil {
 			return err
 		}
 	}
@@ -394,6 +836,10 @@ type scope struct {
 	//
 	// Unique names are necessary to allow closures to reference
 	frameIndex int
+	// Whether the variables declared by for loops are distinct on each
+	// iteration, which depends on the Go version of the file (see
+	// capture.go).
+	perIterationLoopVars bool
 }
 
 func (scope *scope) compileFuncDecl(p *packages.Package, fn *ast.FuncDecl, color *types.Signature) *ast.FuncDecl {
@@ -408,7 +854,7 @@ func (scope *scope) compileFuncDecl(p *packages.Package, fn *ast.FuncDecl, color
 		Doc:  &ast.CommentGroup{},
 		Name: fn.Name,
 		Type: fnType,
-		Body: scope.compileFuncBody(p, fnType, fn.Body, fn.Recv, color),
+		Body: scope.compileFuncBody(p, p.TypesInfo.Defs[fn.Name].(*types.Func).FullName(), fnType, fn.Body, fn.Recv, color),
 	}
 
 	// If the function declaration contains function literals, we have to
@@ -430,18 +876,25 @@ func (scope *scope) compileFuncDecl(p *packages.Package, fn *ast.FuncDecl, color
 	gen.Doc.List = appendCommentGroup(gen.Doc.List, fn.Doc)
 	gen.Doc.List = appendComment(gen.Doc.List, "//go:noinline\n")
 
+	scope.compiler.traceNode("compiled", p.Name+"."+fn.Name.Name, gen)
+
 	if !isExpr(gen.Body) {
 		scope.colors[gen] = color
 	}
 	return gen
 }
 
-func (scope *scope) compileFuncLit(p *packages.Package, fn *ast.FuncLit, color *types.Signature) *ast.FuncLit {
+// compileFuncLit compiles a function literal of the function named parent.
+// The literal is named after its parent and its position in the source file,
+// e.g. example.com/pkg.F.func@f.go:12:7.
+func (scope *scope) compileFuncLit(p *packages.Package, parent string, fn *ast.FuncLit, color *types.Signature) *ast.FuncLit {
 	log.Printf("compiling function literal %s", p.Name)
 
+	pos := scope.compiler.fset.Position(fn.Pos())
+	name := fmt.Sprintf("%s.func@%s:%d:%d", parent, filepath.Base(pos.Filename), pos.Line, pos.Column)
 	gen := &ast.FuncLit{
 		Type: funcTypeWithNamedResults(fn.Type),
-		Body: scope.compileFuncBody(p, fn.Type, fn.Body, nil, color),
+		Body: scope.compileFuncBody(p, name, fn.Type, fn.Body, nil, color),
 	}
 
 	if !isExpr(gen.Body) {
@@ -450,20 +903,35 @@ func (scope *scope) compileFuncLit(p *packages.Package, fn *ast.FuncLit, color *
 	return gen
 }
 
-func (scope *scope) compileFuncBody(p *packages.Package, typ *ast.FuncType, body *ast.BlockStmt, recv *ast.FieldList, color *types.Signature) *ast.BlockStmt {
+func (scope *scope) compileFuncBody(p *packages.Package, name string, typ *ast.FuncType, body *ast.BlockStmt, recv *ast.FieldList, color *types.Signature) *ast.BlockStmt {
 	var defers *ast.Ident
+	var deferArgs []ast.Stmt
 
 	mayYield := findCalls(body, p.TypesInfo)
 	markBranchStmt(body, mayYield)
+	captures, headers := loopCaptures(body, p.TypesInfo, scope.perIterationLoopVars)
+
+	body = desugar(p, body, mayYield, scope.compiler.maxNesting).(*ast.BlockStmt)
+	scope.compiler.traceNode("desugared", name, body)
+	bindings := allocateLoopCaptures(p, body, captures, headers, mayYield)
+
+	// Deferred functions may read and assign named results, which are
+	// hoisted to the coroutine frame. Return statements of functions with
+	// defers store their values in the frame, and the results are copied from
+	// the frame to the result variables of the function after the deferred
+	// functions ran.
+	var results []ast.Expr
+	if hasDefers(body) {
+		results = namedResults(typ, p.TypesInfo)
+	}
 
-	body = desugar(p, body, mayYield).(*ast.BlockStmt)
 	body = astutil.Apply(body,
 		func(cursor *astutil.Cursor) bool {
 			switch n := cursor.Node().(type) {
 			case *ast.FuncLit:
 				color, ok := scope.colors[n]
 				if ok {
-					cursor.Replace(scope.compileFuncLit(p, n, color))
+					cursor.Replace(scope.compileFuncLit(p, name, n, color))
 				}
 				return false
 			case *ast.DeferStmt:
@@ -473,26 +941,70 @@ func (scope *scope) compileFuncBody(p *packages.Package, typ *ast.FuncType, body
 					// use a field on the stack frame so the list of defers
 					// can be captured by the coroutine.
 					defers = ast.NewIdent("_defers")
-					p.TypesInfo.Defs[defers] = types.NewVar(0, p.Types, defers.Name,
-						types.NewSlice(types.NewSignatureType(nil, nil, nil, nil, nil, false)),
-					)
+					p.TypesInfo.Defs[defers] = types.NewVar(0, p.Types, defers.Name, scope.defersType())
+				}
+				// The replacement of the defer statement is not walked,
+				// deferred function literals that may yield are compiled
+				// here.
+				call := n.Call
+				if lit, ok := call.Fun.(*ast.FuncLit); ok {
+					if color, ok := scope.colors[lit]; ok {
+						c := *call
+						c.Fun = scope.compileFuncLit(p, name, lit, color)
+						call = &c
+					}
 				}
-				cursor.Replace(&ast.AssignStmt{
-					Lhs: []ast.Expr{defers},
+				decls, stmts, fn := bindDeferArgs(p, call, len(deferArgs))
+				deferArgs = append(deferArgs, decls...)
+				// _defers.Funcs = append(_defers.Funcs, fn)
+				funcs := &ast.SelectorExpr{X: defers, Sel: ast.NewIdent("Funcs")}
+				stmts = append(stmts, &ast.AssignStmt{
+					Lhs: []ast.Expr{funcs},
 					Tok: token.ASSIGN,
 					Rhs: []ast.Expr{
 						&ast.CallExpr{
 							Fun:  ast.NewIdent("append"),
-							Args: []ast.Expr{defers, n.Call.Fun},
+							Args: []ast.Expr{funcs, fn},
 						},
 					},
 				})
+				if len(stmts) == 1 {
+					cursor.Replace(stmts[0])
+				} else {
+					cursor.Replace(&ast.BlockStmt{List: stmts})
+				}
+			case *ast.ReturnStmt:
+				if results != nil {
+					if len(n.Results) > 0 {
+						cursor.Replace(&ast.BlockStmt{List: []ast.Stmt{
+							&ast.AssignStmt{
+								Lhs: assignableResults(namedResults(typ, p.TypesInfo)),
+								Tok: token.ASSIGN,
+								Rhs: n.Results,
+							},
+							&ast.ReturnStmt{Return: n.Return},
+						}})
+					}
+				} else if len(n.Results) == 0 {
+					// Named results are hoisted to the coroutine frame, so
+					// bare returns have to return the values stored in the
+					// frame rather than the result variables of the
+					// function, which are not restored when the coroutine
+					// is resumed.
+					n.Results = namedResults(typ, p.TypesInfo)
+				}
 			}
 			return true
 		},
 		nil,
 	).(*ast.BlockStmt)
 
+	// The stacks of deferred function arguments are declared at the top of
+	// the function, which hoists them to the coroutine frame.
+	if len(deferArgs) > 0 {
+		body.List = append(deferArgs, body.List...)
+	}
+
 	if isExpr(body) {
 		return body
 	}
@@ -542,8 +1054,34 @@ func (scope *scope) compileFuncBody(p *packages.Package, typ *ast.FuncType, body
 	// declarations to the function prologue. We downgrade inline var decls and
 	// assignments that use := to assignments that use =. Constant decls are
 	// hoisted and also have their value assigned in the function prologue.
-	decls, frameType, frameInit := extractDecls(p, typ, body, recv, defers, p.TypesInfo)
-	renameObjects(body, p.TypesInfo, decls, frameName, frameType, frameInit, scope)
+	//
+	// The statement restoring the named results from the frame is renamed
+	// with the body: _fn0, _fn1 = _f.X0, _f.X1
+	var restoreResults *ast.AssignStmt
+	if results != nil {
+		restoreResults = &ast.AssignStmt{Tok: token.ASSIGN}
+		for _, field := range typ.Results.List {
+			for _, name := range field.Names {
+				if name.Name != "_" {
+					result := ast.NewIdent(name.Name)
+					p.TypesInfo.Uses[result] = p.TypesInfo.ObjectOf(name)
+					restoreResults.Lhs = append(restoreResults.Lhs, ast.NewIdent(name.Name))
+					restoreResults.Rhs = append(restoreResults.Rhs, result)
+				}
+			}
+		}
+		if len(restoreResults.Lhs) == 0 {
+			restoreResults = nil
+		}
+	}
+
+	decls, frameType, frameInit := extractDecls(p, name, typ, body, recv, defers, p.TypesInfo)
+	if restoreResults != nil {
+		renameObjects(&ast.BlockStmt{List: []ast.Stmt{body, restoreResults}}, p.TypesInfo, decls, frameName, frameType, frameInit, scope)
+	} else {
+		renameObjects(body, p.TypesInfo, decls, frameName, frameType, frameInit, scope)
+	}
+	declareLoopCaptures(p, body, bindings)
 
 	// var _f{n} F = coroutine.Push[F](&_c.Stack)
 	gen.List = append(gen.List, &ast.DeclStmt{Decl: &ast.GenDecl{
@@ -589,23 +1127,38 @@ func (scope *scope) compileFuncBody(p *packages.Package, typ *ast.FuncType, body
 	}
 
 	var popFrame []ast.Stmt
+	var popFrameCond ast.Expr = &ast.UnaryExpr{Op: token.NOT, X: &ast.CallExpr{
+		Fun: &ast.SelectorExpr{X: ctx, Sel: ast.NewIdent("Unwinding")},
+	}}
+	var deferring ast.Expr
 	if defers == nil {
 		popFrame = []ast.Stmt{&ast.ExprStmt{X: popExpr}}
 	} else {
+		// _f{n}.X{m}
+		defersField := func() ast.Expr {
+			return &ast.SelectorExpr{
+				X:   frameName,
+				Sel: frameType.Fields.List[len(frameType.Fields.List)-1].Names[0],
+			}
+		}
+		deferring = &ast.SelectorExpr{X: defersField(), Sel: ast.NewIdent("Running")}
+		// The deferred functions are passed the value returned by recover,
+		// which has to be called directly by the deferred function literal,
+		// so they can observe panics. When the coroutine resumes from a
+		// yield in a deferred function, the function body is skipped and
+		// the deferred functions resume running (see Context.RunDefers).
+		popFrameCond = &ast.BinaryExpr{X: popFrameCond, Op: token.LOR, Y: deferring}
 		popFrame = []ast.Stmt{
-			&ast.DeferStmt{Call: popExpr},
-			&ast.RangeStmt{
-				Key:   ast.NewIdent("_"),
-				Value: ast.NewIdent("f"),
-				Tok:   token.DEFINE,
-				X: &ast.SelectorExpr{
-					X:   frameName,
-					Sel: frameType.Fields.List[len(frameType.Fields.List)-1].Names[0],
+			&ast.ExprStmt{X: &ast.CallExpr{
+				Fun: &ast.SelectorExpr{X: ctx, Sel: ast.NewIdent("RunDefers")},
+				Args: []ast.Expr{
+					&ast.UnaryExpr{Op: token.AND, X: defersField()},
+					&ast.CallExpr{Fun: ast.NewIdent("recover")},
 				},
-				Body: &ast.BlockStmt{List: []ast.Stmt{
-					&ast.DeferStmt{Call: &ast.CallExpr{Fun: ast.NewIdent("f")}},
-				}},
-			},
+			}},
+		}
+		if restoreResults != nil {
+			popFrame = append(popFrame, restoreResults)
 		}
 	}
 
@@ -616,9 +1169,7 @@ func (scope *scope) compileFuncBody(p *packages.Package, typ *ast.FuncType, body
 				Body: &ast.BlockStmt{
 					List: []ast.Stmt{
 						&ast.IfStmt{
-							Cond: &ast.UnaryExpr{Op: token.NOT, X: &ast.CallExpr{
-								Fun: &ast.SelectorExpr{X: ctx, Sel: ast.NewIdent("Unwinding")},
-							}},
+							Cond: popFrameCond,
 							Body: &ast.BlockStmt{List: popFrame},
 						},
 					},
@@ -627,6 +1178,13 @@ func (scope *scope) compileFuncBody(p *packages.Package, typ *ast.FuncType, body
 		},
 	})
 
+	if deferring != nil {
+		gen.List = append(gen.List, &ast.IfStmt{
+			Cond: deferring,
+			Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ReturnStmt{}}},
+		})
+	}
+
 	spans := trackDispatchSpans(body)
 	mayYield = findCalls(body, p.TypesInfo)
 	compiledBody := compileDispatch(body, frameName, spans, mayYield).(*ast.BlockStmt)
@@ -680,3 +1238,116 @@ func funcTypeWithNamedResults(t *ast.FuncType) *ast.FuncType {
 	}
 	return &funcType
 }
+
+// namedResults returns expressions referencing the named results of a
+// function, or nil if its results are unnamed. Results named _ cannot be
+// referenced and are always the zero value of their type.
+func namedResults(t *ast.FuncType, info *types.Info) []ast.Expr {
+	if t.Results == nil {
+		return nil
+	}
+	var results []ast.Expr
+	for _, field := range t.Results.List {
+		if len(field.Names) == 0 {
+			return nil
+		}
+		for _, name := range field.Names {
+			if name.Name == "_" {
+				results = append(results, &ast.StarExpr{
+					X: &ast.CallExpr{Fun: ast.NewIdent("new"), Args: []ast.Expr{field.Type}},
+				})
+				continue
+			}
+			ident := ast.NewIdent(name.Name)
+			info.Uses[ident] = info.ObjectOf(name)
+			results = append(results, ident)
+		}
+	}
+	return results
+}
+
+// assignableResults returns the expressions returned by namedResults, with
+// the results named _ replaced by blank identifiers so they can be assigned.
+func assignableResults(results []ast.Expr) []ast.Expr {
+	for i, result := range results {
+		if _, ok := result.(*ast.Ident); !ok {
+			results[i] = ast.NewIdent("_")
+		}
+	}
+	return results
+}
+
+// defersType returns the type of the frame field holding the functions
+// deferred by a compiled function, coroutine.Defers. The type is only declared
+// in durable builds of the coroutine package, so it is synthesized when the
+// package was loaded without the durable tag.
+func (scope *scope) defersType() types.Type {
+	c := scope.compiler
+	if c.defersType == nil {
+		pkg := c.coroutinePkg.Types
+		if obj, ok := pkg.Scope().Lookup("Defers").(*types.TypeName); ok {
+			c.defersType = obj.Type()
+		} else {
+			obj := types.NewTypeName(token.NoPos, pkg, "Defers", nil)
+			c.defersType = types.NewNamed(obj, types.NewStruct(nil, nil), nil)
+		}
+	}
+	return c.defersType
+}
+
+// hasDefers returns true if the function body has defer statements, not
+// counting those of function literals.
+func hasDefers(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// unsupportedSyntheticFunction returns an error explaining that a function
+// synthesized by SSA (e.g. the wrapper of a method value), which has no syntax
+// to compile, was found to yield. The error reports where the function is
+// referenced in the program so it can be refactored.
+func unsupportedSyntheticFunction(prog *ssa.Program, fn *ssa.Function) error {
+	var sites []string
+	for f := range ssautil.AllFunctions(prog) {
+		for _, b := range f.Blocks {
+			for _, instr := range b.Instrs {
+				if !instr.Pos().IsValid() {
+					continue
+				}
+				for _, op := range instr.Operands(nil) {
+					if *op == fn {
+						sites = append(sites, prog.Fset.Position(instr.Pos()).String())
+					}
+				}
+			}
+		}
+	}
+	slices.Sort(sites)
+	sites = slices.Compact(sites)
+
+	var what, hint string
+	switch name := fn.String(); {
+	case strings.HasSuffix(name, "$bound"):
+		what = "method value of " + strings.TrimSuffix(name, "$bound")
+		hint = "call the method directly instead, for example in a function literal"
+	case strings.HasSuffix(name, "$thunk"):
+		what = "method expression " + strings.TrimSuffix(name, "$thunk")
+		hint = "call the method directly on its receiver instead, for example in a function literal"
+	default:
+		what = fmt.Sprintf("function %s (%s)", name, fn.Synthetic)
+		hint = "call the function it wraps directly instead"
+	}
+	if len(sites) > 0 {
+		what += " used at " + strings.Join(sites, ", ")
+	}
+	return &UnsupportedError{Msg: fmt.Sprintf("unsupported yield function: %s cannot be compiled; %s", what, hint)}
+}
@@ -0,0 +1,874 @@
+package compiler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileOutputDir(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the module is slow")
+	}
+
+	// Files compiled in place would be rewritten with the same content, so
+	// modification times are compared to verify that they were not written.
+	sources := []string{
+		filepath.Join("testdata", "coroutine.go"),
+		filepath.Join("testdata", "coroutine_durable.go"),
+	}
+	modTimes := make([]time.Time, len(sources))
+	for i, source := range sources {
+		s, err := os.Stat(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		modTimes[i] = s.ModTime()
+	}
+
+	outputDir := t.TempDir()
+	if err := Compile("./testdata", WithOutputDir(outputDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, source := range sources {
+		s, err := os.Stat(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !s.ModTime().Equal(modTimes[i]) {
+			t.Errorf("%s was modified", source)
+		}
+	}
+
+	for _, name := range []string{"go.mod", "compiler/testdata/coroutine.go", "compiler/testdata/coroutine_durable.go"} {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, ".git")); !os.IsNotExist(err) {
+		t.Errorf("hidden directories must not be copied: %v", err)
+	}
+}
+
+func TestCompileUnchanged(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the module is slow")
+	}
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	outputDir := t.TempDir()
+	if err := Compile("./testdata", WithOutputDir(outputDir)); err != nil {
+		t.Fatal(err)
+	}
+	testdata := filepath.Join(outputDir, "compiler", "testdata")
+
+	modTimes := map[string]time.Time{}
+	err := filepath.WalkDir(testdata, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			var info fs.FileInfo
+			if info, err = d.Info(); err == nil {
+				modTimes[path] = info.ModTime()
+			}
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const unchanged = "module unchanged since last compilation"
+
+	// Compiling the output again produces the same files, which must not be
+	// written, and records the state of the module.
+	logs.Reset()
+	if err := Compile(testdata, WithCache()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(logs.String(), unchanged) {
+		t.Fatal("module was not compiled")
+	}
+	for path, modTime := range modTimes {
+		s, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !s.ModTime().Equal(modTime) {
+			t.Errorf("%s was modified", path)
+		}
+	}
+
+	// Compiling the module again is skipped.
+	logs.Reset()
+	if err := Compile(testdata, WithCache()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), unchanged) {
+		t.Errorf("module was compiled again:\n%s", logs.String())
+	}
+
+	// Changes to the module invalidate the recorded state.
+	source := filepath.Join(testdata, "coroutine.go")
+	b, err := os.ReadFile(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append(b, "\nfunc Unchanged() {}\n"...)
+	if err := os.WriteFile(source, b, 0666); err != nil {
+		t.Fatal(err)
+	}
+	logs.Reset()
+	if err := Compile(testdata, WithCache()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(logs.String(), unchanged) {
+		t.Error("module was not compiled after being modified")
+	}
+}
+
+func TestCompileCache(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the module is slow")
+	}
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	dir := writeModule(t, "cache", `package main
+
+import "github.com/stealthrocket/coroutine"
+
+func run() {
+	coroutine.Yield[int, any](1)
+}
+
+func main() {
+	c := coroutine.New[int, any](run)
+	for c.Next() {
+	}
+}
+`)
+	writeFile := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const unchanged = "module unchanged since last compilation"
+	compile := func(skipped bool, options ...Option) {
+		t.Helper()
+		logs.Reset()
+		if err := Compile(dir, options...); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(logs.String(), unchanged); got != skipped {
+			t.Fatalf("compilation skipped: want=%t got=%t\n%s", skipped, got, logs.String())
+		}
+	}
+
+	compile(false, WithCache())
+	compile(true, WithCache())
+
+	// The cache is only used when enabled.
+	compile(false)
+
+	// Nested modules and vendored trees are not part of the state of the
+	// module.
+	writeFile("nested/go.mod", "module nested\n")
+	writeFile("nested/nested.go", "package nested\n")
+	writeFile("goroot/src/fmt/print.go", "package fmt\n")
+	compile(true, WithCache())
+
+	// Changing a file of the module invalidates the cache.
+	writeFile("util.go", "package main\n\nfunc util() {}\n")
+	compile(false, WithCache())
+	compile(true, WithCache())
+}
+
+func TestCompileMethodValueError(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the module is slow")
+	}
+
+	dir := writeModule(t, "methodvalue", `package main
+
+import "github.com/stealthrocket/coroutine"
+
+type T struct{}
+
+func (*T) Run() { coroutine.Yield[int, any](0) }
+
+func call(f func()) { f() }
+
+func main() {
+	t := &T{}
+	c := coroutine.New[int, any](func() { call(t.Run) })
+	for c.Next() {
+	}
+}
+`)
+
+	err := Compile(dir)
+	if err == nil {
+		t.Fatal("expected an error compiling a method value that yields")
+	}
+	for _, want := range []string{"method value of (*methodvalue.T).Run", "main.go:13:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not contain %q: %v", want, err)
+		}
+	}
+}
+
+func TestCompileGoStatementError(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the module is slow")
+	}
+
+	dir := writeModule(t, "gostmt", `package main
+
+import "github.com/stealthrocket/coroutine"
+
+func work() {}
+
+func run() {
+	go work()
+	coroutine.Yield[int, any](0)
+}
+
+func main() {
+	c := coroutine.New[int, any](run)
+	for c.Next() {
+	}
+}
+`)
+
+	err := Compile(dir)
+	if err == nil {
+		t.Fatal("expected an error compiling a go statement in a function that yields")
+	}
+	for _, want := range []string{"main.go:8:2:", "go statement in a function that yields"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not contain %q: %v", want, err)
+		}
+	}
+	if !errors.Is(err, ErrUnsupportedFeature) {
+		t.Errorf("error does not match ErrUnsupportedFeature: %v", err)
+	}
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("error is not an *UnsupportedError: %v", err)
+	}
+	if pos := unsupported.Pos; filepath.Base(pos.Filename) != "main.go" || pos.Line != 8 {
+		t.Errorf("unexpected position of the unsupported feature: %s", pos)
+	}
+}
+
+func TestCompileRangeChannelError(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the module is slow")
+	}
+
+	dir := writeModule(t, "rangechan", `package main
+
+import "github.com/stealthrocket/coroutine"
+
+func run() {
+	ch := make(chan int, 1)
+	ch <- 1
+	close(ch)
+	for v := range ch {
+		coroutine.Yield[int, any](v)
+	}
+}
+
+func main() {
+	c := coroutine.New[int, any](run)
+	for c.Next() {
+	}
+}
+`)
+
+	err := Compile(dir)
+	if err == nil {
+		t.Fatal("expected an error compiling a range over a channel in a function that yields")
+	}
+	for _, want := range []string{"main.go:9:2:", "not implemented: for range over chan int"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not contain %q: %v", want, err)
+		}
+	}
+}
+
+func TestCompileUnsupportedTypeError(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the module is slow")
+	}
+
+	dir := writeModule(t, "unsupportedtype", `package main
+
+import "github.com/stealthrocket/coroutine"
+
+type stringer struct{}
+
+func (stringer) String() string { return "" }
+
+func value() interface{ String() string } { return stringer{} }
+
+func run() {
+	v := value()
+	coroutine.Yield[int, any](1)
+	_ = v.String()
+}
+
+func main() {
+	c := coroutine.New[int, any](run)
+	for c.Next() {
+	}
+}
+`)
+
+	err := Compile(dir)
+	if !errors.Is(err, ErrUnsupportedFeature) {
+		t.Fatalf("error does not match ErrUnsupportedFeature: %v", err)
+	}
+	for _, want := range []string{"main.go:11:1:", "not implemented: type interface{String() string}"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not contain %q: %v", want, err)
+		}
+	}
+}
+
+func TestCompileYieldingCallbackError(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the module is slow")
+	}
+
+	for _, test := range []struct {
+		name string
+		call string
+		want string
+	}{
+		{
+			name: "sort",
+			call: `sort.Slice(s, func(i, j int) bool {
+		coroutine.Yield[int, any](s[i])
+		return s[i] < s[j]
+	})`,
+			want: "sort.Slice calls sortcallback.run$1 that yields",
+		},
+		{
+			name: "slices",
+			call: `slices.SortFunc(s, func(a, b int) int {
+		coroutine.Yield[int, any](a)
+		return a - b
+	})`,
+			want: "slices.SortFunc calls slicescallback.run$1 that yields",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeModule(t, test.name+"callback", `package main
+
+import (
+	"`+test.name+`"
+
+	"github.com/stealthrocket/coroutine"
+)
+
+func run() {
+	s := []int{3, 1, 2}
+	`+test.call+`
+}
+
+func main() {
+	c := coroutine.New[int, any](run)
+	for c.Next() {
+	}
+}
+`)
+
+			err := Compile(dir)
+			if !errors.Is(err, ErrUnsupportedFeature) {
+				t.Fatalf("error does not match ErrUnsupportedFeature: %v", err)
+			}
+			for _, want := range []string{"main.go:11:", test.want} {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error does not contain %q: %v", want, err)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, "main_durable.go")); err == nil {
+				t.Error("compiled files written despite the error")
+			}
+		})
+	}
+}
+
+func TestCompileNeedsVendoringError(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the module is slow")
+	}
+
+	// The main module depends on a module with a function that yields, which
+	// cannot be compiled without vendoring it.
+	dep := writeModule(t, "dep", `package dep
+
+import "github.com/stealthrocket/coroutine"
+
+func Yield(v int) { coroutine.Yield[int, any](v) }
+`)
+	dir := writeModule(t, "vendoring", `package main
+
+import (
+	"dep"
+
+	"github.com/stealthrocket/coroutine"
+)
+
+func main() {
+	c := coroutine.New[int, any](func() { dep.Yield(1) })
+	for c.Next() {
+	}
+}
+`)
+	f, err := os.OpenFile(filepath.Join(dir, "go.mod"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteString("\nrequire dep v0.0.0\n\nreplace dep => " + dep + "\n")
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Compile(dir)
+	if !errors.Is(err, ErrNeedsVendoring) {
+		t.Errorf("error does not match ErrNeedsVendoring: %v", err)
+	}
+}
+
+func TestCompileNestingError(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the module is slow")
+	}
+
+	// Statements nested 50 levels deep, which is more than hand-written code
+	// does but still well within the default limit.
+	const depth = 50
+	var body strings.Builder
+	for i := 0; i < depth; i++ {
+		fmt.Fprintf(&body, "if n > %d {\n", i)
+	}
+	body.WriteString("coroutine.Yield[int, any](n)\n")
+	body.WriteString(strings.Repeat("}\n", depth))
+
+	dir := writeModule(t, "nesting", `package main
+
+import "github.com/stealthrocket/coroutine"
+
+func run() {
+	n := 100
+	`+body.String()+`
+}
+
+func main() {
+	c := coroutine.New[int, any](run)
+	for c.Next() {
+	}
+}
+`)
+
+	err := Compile(dir, WithMaxNesting(depth))
+	if err == nil {
+		t.Fatal("expected an error compiling a function nested deeper than the limit")
+	}
+	for _, want := range []string{"main.go:", "coroutine body too deeply nested"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not contain %q: %v", want, err)
+		}
+	}
+
+	if err := Compile(dir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompileLineDirectives(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the module is slow")
+	}
+
+	dir := writeModule(t, "lines", `package main
+
+import "github.com/stealthrocket/coroutine"
+
+func run(n int) {
+	for i := 0; i < n; i++ {
+		coroutine.Yield[int, any](i)
+	}
+	panic("done")
+}
+
+func main() {
+	c := coroutine.New[int, any](func() { run(2) })
+	for c.Next() {
+	}
+}
+`)
+
+	if err := Compile(dir, WithLineDirectives()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The parser resolves positions with the line directives, like the
+	// compiler does when reporting positions in stack traces.
+	path := filepath.Join(dir, "main_durable.go")
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	positions := map[string]token.Position{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			positions["func "+n.Name.Name] = fset.Position(n.Pos())
+		case *ast.CallExpr:
+			switch fn := n.Fun.(type) {
+			case *ast.Ident:
+				positions[fn.Name] = fset.Position(n.Pos())
+			case *ast.IndexListExpr:
+				if sel, ok := fn.X.(*ast.SelectorExpr); ok {
+					positions[sel.Sel.Name] = fset.Position(n.Pos())
+				}
+			}
+		}
+		return true
+	})
+
+	// Lines are shifted by the build constraint added to the source file.
+	main := filepath.Join(dir, "main.go")
+	for name, line := range map[string]int{"func run": 7, "Yield": 9, "panic": 11, "func main": 14, "run": 15} {
+		pos := positions[name]
+		if pos.Filename != main || pos.Line != line {
+			t.Errorf("%s: expected %s:%d, got %s", name, main, line, pos)
+		}
+	}
+	// Functions generated by the compiler are mapped to the generated file.
+	if pos := positions["func init"]; pos.Filename != path {
+		t.Errorf("init: expected %s, got %s", path, pos)
+	}
+}
+
+func TestCompileTransitiveImport(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the module is slow")
+	}
+
+	// The main package only uses coroutines through a package of the module
+	// which imports the coroutine package.
+	dir := writeModule(t, "transitive", `package main
+
+import "transitive/helper"
+
+func run() {
+	helper.Yield(1)
+	helper.Yield(2)
+}
+
+func main() {
+	helper.Run(run)
+}
+`)
+	helperDir := filepath.Join(dir, "helper")
+	if err := os.Mkdir(helperDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	helper := `package helper
+
+import "github.com/stealthrocket/coroutine"
+
+func Yield(v int) { coroutine.Yield[int, any](v) }
+
+func Run(f func()) {
+	c := coroutine.New[int, any](f)
+	for c.Next() {
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(helperDir, "helper.go"), []byte(helper), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Compile(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"main_durable.go", filepath.Join("helper", "helper_durable.go")} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCompileBuildTag(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the module is slow")
+	}
+
+	dir := writeModule(t, "buildtag", `//go:build !windows
+
+package main
+
+import "github.com/stealthrocket/coroutine"
+
+func run() {
+	coroutine.Yield[int, any](1)
+}
+
+func main() {
+	c := coroutine.New[int, any](run)
+	for c.Next() {
+	}
+	if !coroutine.Durable {
+		panic("volatile runtime")
+	}
+}
+`)
+
+	// The runtime can only be selected by another tag when vendored.
+	if err := Compile(dir, WithBuildTag("coro")); !errors.Is(err, ErrNeedsVendoring) {
+		t.Fatalf("error does not match ErrNeedsVendoring: %v", err)
+	}
+
+	// The vendor directory is ignored with -mod=mod.
+	t.Setenv("GOFLAGS", "")
+	goCommand(t, dir, "mod", "vendor")
+	if err := Compile(dir, WithBuildTag("coro"), WithBuildTags("netgo")); err != nil {
+		t.Fatal(err)
+	}
+	runtime := filepath.Join("vendor", "github.com", "stealthrocket", "coroutine")
+	for name, want := range map[string]string{
+		"main.go":         "//go:build !windows && !coro\n",
+		"main_durable.go": "//go:build !windows && coro\n",
+		filepath.Join(runtime, "coroutine_durable.go"):  "//go:build coro\n",
+		filepath.Join(runtime, "coroutine_volatile.go"): "//go:build !coro\n",
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(b), want) {
+			t.Errorf("%s does not start with %q:\n%s", name, want, b)
+		}
+	}
+
+	// The program runs in durable mode when built with the tag alone.
+	goCommand(t, dir, "run", "-tags=coro,netgo", ".")
+}
+
+// goCommand runs the go command with args in dir.
+func goCommand(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func TestCompileTestFiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the module is slow")
+	}
+
+	dir := writeModule(t, "testfiles", `package main
+
+import "github.com/stealthrocket/coroutine"
+
+func run() {
+	coroutine.Yield[int, any](1)
+}
+
+func main() {
+	c := coroutine.New[int, any](run)
+	for c.Next() {
+	}
+}
+`)
+
+	test := `package main
+
+import (
+	"testing"
+
+	"github.com/stealthrocket/coroutine"
+)
+
+func yieldTwice() {
+	run()
+	coroutine.Yield[int, any](2)
+}
+
+func TestRun(t *testing.T) {
+	c := coroutine.New[int, any](yieldTwice)
+	for c.Next() {
+	}
+}
+`
+	testPath := filepath.Join(dir, "main_test.go")
+	if err := os.WriteFile(testPath, []byte(test), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Compile(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "main_durable.go")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "main_test_durable.go")); !os.IsNotExist(err) {
+		t.Errorf("test files must not be compiled: %v", err)
+	}
+	b, err := os.ReadFile(testPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != test {
+		t.Errorf("main_test.go was modified:\n%s", b)
+	}
+}
+
+// writeModule writes a module with a main package to a temporary directory.
+// The module depends on the coroutine module being tested.
+func writeModule(t *testing.T, name, main string) string {
+	t.Helper()
+
+	root, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": `module ` + name + `
+
+go 1.21.0
+
+require github.com/stealthrocket/coroutine v0.0.0
+
+replace github.com/stealthrocket/coroutine => ` + root + `
+`,
+		"go.sum":  string(sum),
+		"main.go": main,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestAnalyze(t *testing.T) {
+	if testing.Short() {
+		t.Skip("analyzing the module is slow")
+	}
+
+	const testdata = "github.com/stealthrocket/coroutine/compiler/testdata"
+
+	colors, err := Analyze("./testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	square, ok := colors[testdata+".SquareGenerator"]
+	if !ok {
+		t.Fatal("SquareGenerator is not colored")
+	}
+	if square.Calls != "github.com/stealthrocket/coroutine.Yield" {
+		t.Errorf("wrong function called by SquareGenerator: %s", square.Calls)
+	}
+	if got := square.Yield.String(); got != "func(v int) any" {
+		t.Errorf("wrong yield signature: %s", got)
+	}
+	if filepath.Base(square.Pos.Filename) != "coroutine.go" {
+		t.Errorf("wrong position of the call to Yield: %s", square.Pos)
+	}
+
+	if generic, ok := colors[testdata+".IdentityGeneric"]; !ok {
+		t.Error("IdentityGeneric is not colored")
+	} else if generic.Calls != "github.com/stealthrocket/coroutine.Yield" {
+		t.Errorf("wrong function called by IdentityGeneric: %s", generic.Calls)
+	}
+
+	if caller, ok := colors[testdata+".IdentityGenericInt"]; !ok {
+		t.Error("IdentityGenericInt is not colored")
+	} else if caller.Calls != testdata+".IdentityGeneric" {
+		t.Errorf("wrong function called by IdentityGenericInt: %s", caller.Calls)
+	}
+
+	if _, ok := colors[testdata+".SomeFunctionThatShouldExistInTheCompiledFile"]; ok {
+		t.Error("function that does not yield is colored")
+	}
+}
+
+func TestAnalyzeFunctions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("analyzing the module is slow")
+	}
+
+	const testdata = "github.com/stealthrocket/coroutine/compiler/testdata"
+
+	colors, err := Analyze("./testdata", WithFunctions(testdata+".IdentityGenericInt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name := range colors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	want := []string{testdata + ".IdentityGeneric", testdata + ".IdentityGenericInt"}
+	if !slices.Equal(names, want) {
+		t.Errorf("wrong functions selected:\nwant: %q\ngot:  %q", want, names)
+	}
+
+	for _, test := range []struct {
+		name string
+		err  string
+	}{
+		{testdata + ".NoSuchFunction", "not found"},
+		{testdata + ".SomeFunctionThatShouldExistInTheCompiledFile", "does not yield"},
+	} {
+		_, err := Analyze("./testdata", WithFunctions(test.name))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.name, test.err, err)
+		}
+	}
+}
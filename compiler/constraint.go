@@ -1,10 +1,12 @@
 package compiler
 
 import (
+	"bytes"
 	"go/ast"
 	"go/build/constraint"
 	"reflect"
 	"slices"
+	"strings"
 )
 
 func containsExpr(expr, contains constraint.Expr) bool {
@@ -18,7 +20,28 @@ func containsExpr(expr, contains constraint.Expr) bool {
 	}
 }
 
+func withoutExpr(expr, remove constraint.Expr) constraint.Expr {
+	switch x := expr.(type) {
+	case *constraint.AndExpr:
+		l, r := withoutExpr(x.X, remove), withoutExpr(x.Y, remove)
+		switch {
+		case l == nil:
+			return r
+		case r == nil:
+			return l
+		default:
+			return &constraint.AndExpr{X: l, Y: r}
+		}
+	default:
+		if reflect.DeepEqual(expr, remove) {
+			return nil
+		}
+		return expr
+	}
+}
+
 func withBuildTag(expr constraint.Expr, buildTag *constraint.TagExpr) constraint.Expr {
+	expr = withoutExpr(expr, &constraint.NotExpr{X: buildTag})
 	if buildTag == nil || containsExpr(expr, buildTag) {
 		return expr
 	} else if expr == nil {
@@ -77,3 +100,48 @@ func stripBuildTagsOf(file *ast.File, path string) {
 		})
 	}
 }
+
+// renameBuildTag returns the source of a Go file with the build tag from
+// renamed to the tag to in its //go:build line. The source is returned as is
+// if it has no //go:build line, or the line does not reference the tag.
+func renameBuildTag(src []byte, from, to string) ([]byte, error) {
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	for i, line := range lines {
+		text := strings.TrimSpace(string(line))
+		if strings.HasPrefix(text, "package ") {
+			break
+		}
+		if !constraint.IsGoBuild(text) {
+			continue
+		}
+		expr, err := constraint.Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		if !renameTag(expr, from, to) {
+			return src, nil
+		}
+		lines[i] = []byte("//go:build " + expr.String() + "\n")
+		return bytes.Join(lines, nil), nil
+	}
+	return src, nil
+}
+
+func renameTag(expr constraint.Expr, from, to string) bool {
+	switch x := expr.(type) {
+	case *constraint.AndExpr:
+		l, r := renameTag(x.X, from, to), renameTag(x.Y, from, to)
+		return l || r
+	case *constraint.OrExpr:
+		l, r := renameTag(x.X, from, to), renameTag(x.Y, from, to)
+		return l || r
+	case *constraint.NotExpr:
+		return renameTag(x.X, from, to)
+	case *constraint.TagExpr:
+		if x.Tag == from {
+			x.Tag = to
+			return true
+		}
+	}
+	return false
+}
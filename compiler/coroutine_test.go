@@ -1,8 +1,13 @@
 package compiler
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
 	"slices"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stealthrocket/coroutine"
 	. "github.com/stealthrocket/coroutine/compiler/testdata"
@@ -15,15 +20,17 @@ func init() {
 	SomeFunctionThatShouldExistInTheCompiledFile()
 }
 
+type coroutineTest struct {
+	name   string
+	coro   func()
+	coroR  func() int
+	yields []int
+	result int
+	skip   bool
+}
+
 func TestCoroutineYield(t *testing.T) {
-	tests := []struct {
-		name   string
-		coro   func()
-		coroR  func() int
-		yields []int
-		result int
-		skip   bool
-	}{
+	testCoroutineYield(t, []coroutineTest{
 		{
 			name:   "identity",
 			coro:   func() { Identity(11) },
@@ -78,6 +85,12 @@ func TestCoroutineYield(t *testing.T) {
 			yields: []int{0, 1, 0, 1, 2, 0, 2, 1, 0, 2, 1, 0, 1, 0, 13, 12, 11, 4, 2, 1, 2, 1},
 		},
 
+		{
+			name:   "shadowing with different types",
+			coro:   func() { ShadowingTypes(2) },
+			yields: []int{2, 2, 3, 15, 3, 5, 3, 2, 3, 7, 14, 4},
+		},
+
 		{
 			name:   "range over slice indices",
 			coro:   func() { RangeSliceIndexGenerator(0) },
@@ -90,6 +103,168 @@ func TestCoroutineYield(t *testing.T) {
 			yields: []int{0, 10, 1, 20, 2, 30},
 		},
 
+		{
+			name:   "range over pointer to array indices and values",
+			coro:   func() { RangeArrayPointerIndexValueGenerator(0) },
+			yields: []int{0, 10, 1, 21, 2, 31},
+		},
+
+		{
+			name:   "range over string indices and runes",
+			coro:   func() { RangeStringGenerator(0) },
+			yields: []int{0, 'a', 1, 'é', 3, '世', 6, utf8.RuneError, 7, '!', 0, 3},
+		},
+
+		{
+			name:   "closures capturing per-iteration copies of loop variables",
+			coro:   func() { LoopClosuresCapture(3) },
+			yields: []int{0, 1, 2, 1, 100, 23},
+		},
+
+		{
+			name:   "local constants declared with iota",
+			coro:   func() { LocalIotaConsts(10) },
+			yields: []int{10, 11, 12, 14, 1, 1 << 10, 1 << 20, 3, 6},
+		},
+
+		{
+			name:   "variadic function",
+			coro:   func() { VariadicYield(10) },
+			yields: []int{1, 2, 3, 16, 3, -1, -2, -3, 10, 0},
+		},
+
+		{
+			name:   "generic function instantiated with int",
+			coro:   func() { IdentityGenericInt(3) },
+			yields: []int{0, 1, 2},
+		},
+
+		{
+			name:   "generic function instantiated with int8",
+			coro:   func() { IdentityGenericInt8(2) },
+			yields: []int{0, 1},
+		},
+
+		{
+			name:   "recover panic after yield",
+			coro:   func() { YieldAndRecover(42) },
+			yields: []int{42},
+		},
+
+		{
+			name:   "recover panics in functions that yield",
+			coro:   func() { RecoverPanicIsolation(4) },
+			yields: []int{0, 1, 0, 10, -101, 20, 21, 2, 30, -103},
+		},
+
+		{
+			name:   "anonymous struct local",
+			coro:   func() { AnonymousStructLocal(3) },
+			yields: []int{3, 4, 5, 9},
+		},
+
+		{
+			name:   "tagless switch resumes in selected case",
+			coro:   func() { TaglessSwitchResume(10) },
+			yields: []int{10, 11, 10, -1},
+		},
+
+		{
+			name:   "defer in loop",
+			coro:   func() { LoopDefers(4) },
+			yields: []int{0, 1, 2, 3, 30, 20, 10, 0},
+		},
+
+		{
+			name:   "min, max and clear builtins",
+			coro:   func() { MinMaxClear(5) },
+			yields: []int{5, 5, 6, 5, 0, 0},
+		},
+
+		{
+			name:   "copy with yielding operands",
+			coro:   func() { CopyYieldingOperands(3) },
+			yields: []int{-3, 3, 10, 20, 30, 2, 1, 2, 50},
+		},
+
+		{
+			name:   "local pointer to package-level variable",
+			coro:   func() { GlobalPointer(2) },
+			yields: []int{0, 10, 11, 11, 21, 22},
+		},
+
+		{
+			name:   "yielding methods called through an interface",
+			coro:   func() { InterfaceMethodCall(4) },
+			yields: []int{1, 1, 4, 4, 3, 3, 4, 5},
+		},
+
+		{
+			name:   "deferred method calls on local values",
+			coro:   func() { DeferredMethodOnLocal(2) },
+			yields: []int{2, -1, 21, 2},
+		},
+
+		{
+			name:   "promoted methods of embedded interfaces",
+			coro:   func() { EmbeddedInterfaceMethodCall(3) },
+			yields: []int{1, 1, 2, 3, 3, 6, 6, 6, 5, 15},
+		},
+
+		{
+			name:   "make with sizes that yield",
+			coro:   func() { MakeYieldingSize(3) },
+			yields: []int{-3, -6, 3, 6, 6, 100, -3, 4},
+		},
+
+		{
+			name:   "recover panics after yielding in deferred functions",
+			coro:   func() { RecoverAfterYield(3) },
+			yields: []int{0, 0, 1000, 0, 10, -1, 1100, 100, 20, -2, 1002, 2},
+		},
+
+		{
+			name:   "infinite loop with conditional break",
+			coro:   func() { InfiniteLoopConditionalBreak(3) },
+			yields: []int{0, 10, 1, 20, 2, -3, 300},
+		},
+
+		{
+			name:   "named results read after yield",
+			coro:   func() { NamedResultsAfterYield(3) },
+			yields: []int{0, 1, 10, 11, 20, 21},
+		},
+
+		{
+			name:   "slice and index expressions evaluated in order",
+			coro:   func() { YieldingSliceAndIndexOrder() },
+			yields: []int{1, 3, 2, 2, -4, 6, 2, 3, -5, 3},
+		},
+
+		{
+			name:   "tuple assignment across yields",
+			coro:   func() { TupleAssignmentAcrossYields(3) },
+			yields: []int{0, 1, 10, -1, 20, 3, 30, 0},
+		},
+
+		{
+			name:   "error checks across yields",
+			coro:   func() { ErrorCheckAcrossYields(2) },
+			yields: []int{0, 1, 20, 3, 10, 0, 20, -11, 10, 0, -3},
+		},
+
+		{
+			name:   "map of mixed values",
+			coro:   func() { MixedMapValues(2) },
+			yields: []int{2, 3, 3, 4},
+		},
+
+		{
+			name:   "variadic library call with yielding arguments",
+			coro:   func() { VariadicLibraryCall(1) },
+			yields: []int{1, -2, 3, 5, 7, -8, 3},
+		},
+
 		{
 			name:   "range over deferred function",
 			coro:   func() { RangeYieldAndDeferAssign(5) },
@@ -102,12 +277,24 @@ func TestCoroutineYield(t *testing.T) {
 			yields: []int{1, 10, 2, 20, 4, 30, 8, 40},
 		},
 
+		{
+			name:   "type switch binding modified before yielding",
+			coro:   func() { TypeSwitchBindingGenerator(0) },
+			yields: []int{10, 11, 3, 4, -1},
+		},
+
 		{
 			name:   "loop break and continue",
 			coro:   func() { LoopBreakAndContinue(0) },
 			yields: []int{1, 3, 5, 0, 1, 0, 1},
 		},
 
+		{
+			name:   "labeled break from switch",
+			coro:   func() { LabeledBreakFromSwitch(5) },
+			yields: []int{0, 0, 1, 10, -1, 3, 100},
+		},
+
 		{
 			name:   "range over maps",
 			coro:   func() { RangeOverMaps(5) },
@@ -207,8 +394,13 @@ func TestCoroutineYield(t *testing.T) {
 			yields: []int{1, 2, 3, 2, 4, 6, 3, 6, 9, 2, 4, 6, 4, 8, 12, 6, 12, 18, 3, 6, 9, 6, 12, 18, 9, 18, 27},
 			result: 27,
 		},
-	}
+	})
+}
 
+// testCoroutineYield runs the coroutines of tests, checking the values that
+// they yield. If the coroutines are durable, they are marshaled and unmarshaled
+// at each yield point.
+func testCoroutineYield(t *testing.T, tests []coroutineTest) {
 	// This emulates the installation of function type information by the
 	// compiler because we are not doing codegen for the test files in this
 	// package.
@@ -259,6 +451,11 @@ func TestCoroutineYield(t *testing.T) {
 					}
 					t.Fatal(err)
 				}
+				if n, err := g.Context().MarshalSize(); err != nil {
+					t.Fatal(err)
+				} else if n != len(b) {
+					t.Fatalf("marshaled size mismatch: want=%d got=%d", len(b), n)
+				}
 
 				reconstructed := coroutine.New[int, any](test.coro)
 				if n, err := reconstructed.Context().Unmarshal(b); err != nil {
@@ -297,3 +494,271 @@ func TestCoroutineStop(t *testing.T) {
 		t.Errorf("wrong values yield by coroutine: %#v", values)
 	}
 }
+
+func TestCoroutineSelectFair(t *testing.T) {
+	// Channels cannot be serialized, so the coroutine is not marshaled
+	// between yields.
+	const n = 1000
+	coro := coroutine.New[int, any](func() { SelectFair(n) })
+
+	var counts [2]int
+	for coro.Next() {
+		counts[coro.Recv()]++
+	}
+	if counts[0]+counts[1] != n {
+		t.Fatalf("wrong number of values yield by coroutine: %v", counts)
+	}
+	// The probability of either case being selected less than 10% of the
+	// time is negligible if the choice is uniform.
+	for i, c := range counts {
+		if c < n/10 {
+			t.Errorf("select case %d was starved: %v", i, counts)
+		}
+	}
+}
+
+func TestCoroutineReset(t *testing.T) {
+	coro := coroutine.New[int, any](func() { SquareGenerator(4) })
+
+	for i := 0; i < 2; i++ {
+		values := []int{}
+		for coro.Next() {
+			values = append(values, coro.Recv())
+		}
+		if !slices.Equal(values, []int{1, 4, 9, 16}) {
+			t.Errorf("wrong values yield by coroutine at run %d: %#v", i, values)
+		}
+		coro.Context().Reset()
+	}
+}
+
+func TestCoroutineClosureEntrypoint(t *testing.T) {
+	n := 4
+	f := func() { SquareGenerator(n) }
+
+	// Closures capturing values are registered with the type of their memory
+	// layout so their captured values get serialized as well.
+	types.RegisterClosure[func(), struct {
+		F  uintptr
+		X0 int
+	}](types.FuncByAddr(types.FuncAddr(f)).Name)
+
+	coro := coroutine.New[int, any](f)
+
+	values := []int{}
+	for coro.Next() {
+		values = append(values, coro.Recv())
+
+		b, err := coro.Context().Marshal()
+		if err != nil {
+			if err == coroutine.ErrNotDurable {
+				continue
+			}
+			t.Fatal(err)
+		}
+
+		// The entry point is restored from the serialized state, not from the
+		// function passed to the constructor.
+		coro = coroutine.New[int, any](func() {})
+		if _, err := coro.Context().Unmarshal(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !slices.Equal(values, []int{1, 4, 9, 16}) {
+		t.Errorf("wrong values yield by coroutine: %#v", values)
+	}
+}
+
+func TestCoroutineClone(t *testing.T) {
+	entry := func() { SharedPointers(5) }
+	types.RegisterFunc[func()](types.FuncByAddr(types.FuncAddr(entry)).Name)
+
+	coro := coroutine.New[int, any](entry)
+	coro.Next()
+	coro.Next()
+
+	clone, err := coro.Context().Clone()
+	if err != nil {
+		if err == coroutine.ErrNotDurable {
+			t.Skip(err)
+		}
+		t.Fatal(err)
+	}
+
+	// The two coroutines yield the same values until they are sent
+	// different ones. Both locals of the function point to the same memory
+	// in each coroutine, but not across coroutines.
+	var values, cloneValues []int
+	for coro.Next() {
+		values = append(values, coro.Recv())
+		coro.Send(10)
+	}
+	for clone.Next() {
+		cloneValues = append(cloneValues, clone.Recv())
+		clone.Send(100)
+	}
+
+	if !slices.Equal(values, []int{2, 12, 22}) {
+		t.Errorf("wrong values yield by coroutine: %#v", values)
+	}
+	if !slices.Equal(cloneValues, []int{2, 102, 202}) {
+		t.Errorf("wrong values yield by clone: %#v", cloneValues)
+	}
+}
+
+func TestCoroutineHooks(t *testing.T) {
+	var events []string
+	coro := coroutine.New[int, any](func() { InterfaceMethodCall(2) })
+	coro.Context().OnYield(func(depth int) {
+		events = append(events, fmt.Sprintf("yield@%d", depth))
+	})
+	coro.Context().OnResume(func(depth int) {
+		events = append(events, fmt.Sprintf("resume@%d", depth))
+	})
+
+	for coro.Next() {
+		events = append(events, fmt.Sprintf("recv %d", coro.Recv()))
+	}
+
+	// Methods called by the function yield one frame deeper.
+	depths := []int{2, 1, 2, 1}
+	if !coroutine.Durable {
+		depths = []int{0, 0, 0, 0}
+	}
+	var want []string
+	for i, v := range []int{1, 1, 4, 4} {
+		want = append(want,
+			fmt.Sprintf("yield@%d", depths[i]),
+			fmt.Sprintf("recv %d", v),
+			fmt.Sprintf("resume@%d", depths[i]),
+		)
+	}
+	if !slices.Equal(events, want) {
+		t.Errorf("wrong events:\nwant=%v\ngot= %v", want, events)
+	}
+}
+
+func TestCoroutineFrames(t *testing.T) {
+	const pkg = "github.com/stealthrocket/coroutine/compiler/testdata"
+
+	tests := []struct {
+		name  string
+		entry func()
+		funcs []string
+	}{
+		{
+			name:  "function literal",
+			entry: func() { Range10ClosureCapturingValues() },
+			funcs: []string{
+				pkg + ".Range10ClosureCapturingValues",
+				pkg + ".Range10ClosureCapturingValues.func@coroutine.go:432:7",
+			},
+		},
+		{
+			name:  "method",
+			entry: func() { new(MethodGeneratorState).MethodGenerator(1) },
+			funcs: []string{
+				"(*" + pkg + ".MethodGeneratorState).MethodGenerator",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			types.RegisterFunc[func()](types.FuncByAddr(types.FuncAddr(test.entry)).Name)
+
+			coro := coroutine.New[int, any](test.entry)
+			if !coro.Next() {
+				t.Fatal("coroutine did not yield")
+			}
+
+			var funcs []string
+			for _, frame := range coro.Context().Frames() {
+				funcs = append(funcs, frame.Func)
+			}
+			want := test.funcs
+			if !coroutine.Durable {
+				want = nil
+			}
+			if !slices.Equal(funcs, want) {
+				t.Errorf("wrong functions of the coroutine frames:\nwant=%q\ngot= %q", want, funcs)
+			}
+		})
+	}
+}
+
+func TestCoroutineContext(t *testing.T) {
+	entry := func() { ContextCancellation(5) }
+	types.RegisterFunc[func()](types.FuncByAddr(types.FuncAddr(entry)).Name)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	coro := coroutine.New[int, any](entry)
+	coro.Context().SetContext(ctx)
+
+	var values []int
+	for coro.Next() {
+		values = append(values, coro.Recv())
+		if len(values) < 2 {
+			continue
+		}
+
+		// The context is not serialized, the context held by the
+		// restored coroutine forwards to the live context set on it,
+		// canceled while it was suspended.
+		if coroutine.Durable {
+			b, err := coro.Context().Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			coro = coroutine.New[int, any](entry)
+			if _, err := coro.Context().Unmarshal(b); err != nil {
+				t.Fatal(err)
+			}
+			coro.Context().SetContext(ctx)
+		}
+		cancel()
+	}
+
+	if !slices.Equal(values, []int{0, 1, -1}) {
+		t.Errorf("wrong values yielded by coroutine: %#v", values)
+	}
+}
+
+func TestCoroutineMarshalBinary(t *testing.T) {
+	entry := func() { SquareGenerator(4) }
+	types.RegisterFunc[func()](types.FuncByAddr(types.FuncAddr(entry)).Name)
+
+	coro := coroutine.New[int, any](entry)
+
+	values := []int{}
+	for coro.Next() {
+		values = append(values, coro.Recv())
+
+		// Encode the coroutine state through its encoding.BinaryMarshaler
+		// implementation, like generic encoders do.
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(coro.Context()); err != nil {
+			if !coroutine.Durable {
+				continue
+			}
+			t.Fatal(err)
+		}
+
+		b, _ := coro.Context().MarshalBinary()
+		if err := coro.Context().UnmarshalBinary(append(b, 0)); err == nil {
+			t.Error("expected an error when unmarshaling trailing bytes")
+		}
+
+		coro = coroutine.New[int, any](entry)
+		if err := gob.NewDecoder(&buf).Decode(coro.Context()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !slices.Equal(values, []int{1, 4, 9, 16}) {
+		t.Errorf("wrong values yield by coroutine: %#v", values)
+	}
+}
@@ -23,10 +23,14 @@ import (
 // Note that declarations are extracted from all nested scopes within the
 // function body, so there may be duplicate identifiers. Identifiers can be
 // disambiguated using (*types.Info).ObjectOf(ident).
-func extractDecls(p *packages.Package, typ *ast.FuncType, body *ast.BlockStmt, recv *ast.FieldList, defers *ast.Ident, info *types.Info) (decls []*ast.GenDecl, frameType *ast.StructType, frameInit *ast.CompositeLit) {
+//
+// The IP field of the frame is tagged with the name of the function, which
+// the runtime reports in coroutine.FrameInfo.
+func extractDecls(p *packages.Package, name string, typ *ast.FuncType, body *ast.BlockStmt, recv *ast.FieldList, defers *ast.Ident, info *types.Info) (decls []*ast.GenDecl, frameType *ast.StructType, frameInit *ast.CompositeLit) {
 	IP := &ast.Field{
 		Names: []*ast.Ident{ast.NewIdent("IP")},
 		Type:  ast.NewIdent("int"),
+		Tag:   &ast.BasicLit{Kind: token.STRING, Value: "`coroutine:" + strconv.Quote(name) + "`"},
 	}
 
 	frameType = &ast.StructType{Fields: &ast.FieldList{List: []*ast.Field{IP}}}
@@ -142,7 +146,7 @@ func extractDecls(p *packages.Package, typ *ast.FuncType, body *ast.BlockStmt, r
 	if defers != nil {
 		frameType.Fields.List = append(frameType.Fields.List, &ast.Field{
 			Names: []*ast.Ident{defers},
-			Type:  &ast.ArrayType{Elt: &ast.FuncType{Params: &ast.FieldList{}}},
+			Type:  typeExpr(p, info.ObjectOf(defers).Type()),
 		})
 	}
 
@@ -40,8 +40,11 @@ import (
 // types.Info. If this gets unruly in the future, desugaring should be
 // performed after parsing AST's but before type checking so that this is
 // done automatically by the type checker.
-func desugar(p *packages.Package, stmt ast.Stmt, mayYield map[ast.Node]struct{}) ast.Stmt {
-	d := desugarer{pkg: p, info: p.TypesInfo, nodesThatMayYield: mayYield}
+//
+// When maxDepth is positive, statements nested deeper than maxDepth levels
+// cause desugar to panic with an unsupportedError (see recoverUnsupported).
+func desugar(p *packages.Package, stmt ast.Stmt, mayYield map[ast.Node]struct{}, maxDepth int) ast.Stmt {
+	d := desugarer{pkg: p, info: p.TypesInfo, nodesThatMayYield: mayYield, maxDepth: maxDepth}
 	stmt = d.desugar(stmt, nil, nil, nil)
 
 	// Unused labels cause a compile error (label X defined and not used)
@@ -64,6 +67,12 @@ type desugarer struct {
 	nodesThatMayYield map[ast.Node]struct{}
 	unusedLabels      map[*ast.Ident]struct{}
 	userLabels        map[types.Object]*ast.Ident
+
+	// The desugarer recurses into nested statements, depth limits the
+	// recursion so that pathological inputs are reported as errors instead
+	// of overflowing the stack. Zero means no limit.
+	depth    int
+	maxDepth int
 }
 
 func (d *desugarer) desugar(stmt ast.Stmt, breakTo, continueTo, userLabel *ast.Ident) ast.Stmt {
@@ -71,6 +80,11 @@ func (d *desugarer) desugar(stmt ast.Stmt, breakTo, continueTo, userLabel *ast.I
 		return stmt
 	}
 
+	if d.depth++; d.maxDepth > 0 && d.depth > d.maxDepth {
+		panic(&unsupportedError{node: stmt, msg: "coroutine body too deeply nested"})
+	}
+	defer func() { d.depth-- }()
+
 	switch s := stmt.(type) {
 	case nil:
 
@@ -107,7 +121,7 @@ func (d *desugarer) desugar(stmt ast.Stmt, breakTo, continueTo, userLabel *ast.I
 				d.useLabel(continueTo)
 				stmt = &ast.BranchStmt{Tok: token.CONTINUE, Label: continueTo}
 			default: // FALLTHROUGH / GOTO
-				panic("not implemented")
+				notImplemented(s, "%s", s.Tok)
 			}
 		}
 
@@ -124,6 +138,14 @@ func (d *desugarer) desugar(stmt ast.Stmt, breakTo, continueTo, userLabel *ast.I
 		}
 
 	case *ast.DeferStmt:
+		// The receiver of a deferred method call is evaluated when the
+		// defer statement executes. The call is rewritten to call the
+		// method expression instead, so the receiver is bound along with
+		// the arguments.
+		if fun, recv := d.methodExpr(s.Call.Fun); fun != nil {
+			s.Call.Fun = fun
+			s.Call.Args = append([]ast.Expr{recv}, s.Call.Args...)
+		}
 		var prologue []ast.Stmt
 		for i, arg := range s.Call.Args {
 			tmp := d.newVar(d.info.TypeOf(arg))
@@ -135,20 +157,18 @@ func (d *desugarer) desugar(stmt ast.Stmt, breakTo, continueTo, userLabel *ast.I
 			s.Call.Args[i] = tmp
 		}
 		prologue = d.desugarList(prologue, nil, nil)
-		fn := s.Call.Fun
-		if _, ok := fn.(*ast.FuncLit); !ok || len(s.Call.Args) > 0 {
+		// Deferred calls with arguments are left as is, the compiler binds
+		// the values of the temporary variables to the deferred function
+		// when the defer statement is executed (see compileFuncBody).
+		if _, ok := s.Call.Fun.(*ast.FuncLit); !ok && len(s.Call.Args) == 0 {
 			s.Call.Fun = &ast.FuncLit{
 				Type: &ast.FuncType{},
 				Body: &ast.BlockStmt{List: []ast.Stmt{
 					&ast.ExprStmt{
-						X: &ast.CallExpr{
-							Fun:  s.Call.Fun,
-							Args: s.Call.Args,
-						},
+						X: &ast.CallExpr{Fun: s.Call.Fun},
 					},
 				}},
 			}
-			s.Call.Args = nil
 		}
 		if len(prologue) == 0 {
 			stmt = s
@@ -205,7 +225,7 @@ func (d *desugarer) desugar(stmt ast.Stmt, breakTo, continueTo, userLabel *ast.I
 		}
 
 	case *ast.GoStmt:
-		panic("not implemented")
+		notImplemented(s, "go statement in a function that yields")
 
 	case *ast.IfStmt:
 		// Rewrite `if init; cond { ... }` => `{ init; _cond := cond; if _cond { ... } }`
@@ -239,14 +259,27 @@ func (d *desugarer) desugar(stmt ast.Stmt, breakTo, continueTo, userLabel *ast.I
 		stmt = d.desugar(s.Stmt, breakTo, continueTo, s.Label)
 
 	case *ast.RangeStmt:
-		x := d.newVar(d.info.TypeOf(s.X))
+		// Untyped constants (e.g. string literals) are hoisted with their
+		// default type.
+		x := d.newVar(types.Default(d.info.TypeOf(s.X)))
 		init := &ast.AssignStmt{Lhs: []ast.Expr{x}, Tok: token.DEFINE, Rhs: []ast.Expr{s.X}}
 		if d.mayYield(s.X) {
 			d.nodesThatMayYield[init] = struct{}{}
 		}
 		prologue := d.desugarList([]ast.Stmt{init}, nil, nil)
 
-		switch rangeElemType := d.info.TypeOf(s.X).(type) {
+		rangeType := d.info.TypeOf(s.X)
+		if p, ok := rangeType.(*types.Pointer); ok {
+			// Ranging over a pointer to an array is like ranging over the
+			// array, except that elements are read through the pointer. Since
+			// len(_x) and _x[i] are valid on pointers to arrays, the pointer
+			// is hoisted and the loop is rewritten like it is for arrays.
+			if a, ok := p.Elem().Underlying().(*types.Array); ok {
+				rangeType = a
+			}
+		}
+
+		switch rangeElemType := rangeType.Underlying().(type) {
 		case *types.Array, *types.Slice:
 			// Rewrite for range loops over arrays/slices:
 			// - `for range x {}` => `{ _x := x; for _i := 0; _i < len(_x); _i++ {} }`
@@ -381,8 +414,79 @@ func (d *desugarer) desugar(stmt ast.Stmt, breakTo, continueTo, userLabel *ast.I
 
 				stmt = &ast.BlockStmt{List: append(prologue, collectKeys, iterKeys)}
 			}
+		case *types.Basic:
+			if rangeElemType.Info()&types.IsString == 0 {
+				notImplemented(s, "for range over %s", rangeType)
+			}
+			// Rewrite for range loops over strings:
+			// - `for i, r := range s {}` => `{ _x := s; for i, _w := 0, 0; i < len(_x); i += _w { var r rune; r, _w = _utf8.DecodeRuneInString(_x[i:]); ... } }`
+			// - `for i := range s {}` => `{ _x := s; for i, _w := 0, 0; i < len(_x); i += _w { _, _w = _utf8.DecodeRuneInString(_x[i:]); ... } }`
+			// The width of the rune is decoded at the start of each
+			// iteration and stored alongside the index, so that the index
+			// advances by the width of the rune when the loop is resumed
+			// in the middle of the body.
+			// Then, desugar loops further (see ast.ForStmt case above).
+			var i *ast.Ident
+			if s.Key == nil || isUnderscore(s.Key) {
+				i = d.newVar(types.Typ[types.Int])
+			} else {
+				i = s.Key.(*ast.Ident)
+			}
+			w := d.newVar(types.Typ[types.Int])
+
+			var r ast.Expr = ast.NewIdent("_")
+			var prelude []ast.Stmt
+			if s.Value != nil && !isUnderscore(s.Value) {
+				r = s.Value
+				if s.Tok == token.DEFINE {
+					// Declare the variable first so that it can be assigned
+					// together with the width.
+					v := s.Value.(*ast.Ident)
+					ref := ast.NewIdent(v.Name)
+					if obj := d.info.Defs[v]; obj != nil {
+						d.info.Uses[ref] = obj
+					}
+					prelude = append(prelude, &ast.DeclStmt{Decl: &ast.GenDecl{
+						Tok: token.VAR,
+						Specs: []ast.Spec{&ast.ValueSpec{
+							Names: []*ast.Ident{v},
+							Type:  typeExpr(d.pkg, types.Universe.Lookup("rune").Type()),
+						}},
+					}})
+					r = ref
+				}
+			}
+			var rest ast.Expr = &ast.SliceExpr{X: x, Low: i}
+			if rangeType != types.Typ[types.String] && rangeType != types.Typ[types.UntypedString] {
+				// Values of named string types must be converted.
+				rest = &ast.CallExpr{Fun: d.builtin("string"), Args: []ast.Expr{rest}}
+			}
+			prelude = append(prelude, &ast.AssignStmt{
+				Lhs: []ast.Expr{r, w},
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{&ast.CallExpr{
+					Fun:  d.utf8("DecodeRuneInString"),
+					Args: []ast.Expr{rest},
+				}},
+			})
+			s.Body.List = append(prelude, s.Body.List...)
+
+			zero := func() ast.Expr { return &ast.BasicLit{Kind: token.INT, Value: "0"} }
+			forStmt := &ast.ForStmt{
+				Init: &ast.AssignStmt{Lhs: []ast.Expr{i, w}, Tok: token.DEFINE, Rhs: []ast.Expr{zero(), zero()}},
+				Post: &ast.AssignStmt{Lhs: []ast.Expr{i}, Tok: token.ADD_ASSIGN, Rhs: []ast.Expr{w}},
+				Cond: &ast.BinaryExpr{X: i, Op: token.LSS, Y: &ast.CallExpr{Fun: d.builtin("len"), Args: []ast.Expr{x}}},
+				Body: s.Body,
+			}
+			if d.mayYield(s.Body) {
+				d.nodesThatMayYield[forStmt] = struct{}{}
+			}
+			stmt = &ast.BlockStmt{
+				List: append(prologue, d.desugar(forStmt, breakTo, continueTo, userLabel)),
+			}
+
 		default:
-			panic(fmt.Sprintf("not implemented: for range over %T", s.X))
+			notImplemented(s, "for range over %s", rangeType)
 		}
 
 	case *ast.SelectStmt:
@@ -394,7 +498,9 @@ func (d *desugarer) desugar(stmt ast.Stmt, breakTo, continueTo, userLabel *ast.I
 		// select cases exist only to record the selection; the select
 		// case bodies are moved into the switch statement over that
 		// selection. This allows coroutines to jump back to the right
-		// case when resuming.
+		// case when resuming. Since the raw select statement retains all
+		// the communication cases, the runtime still chooses uniformly
+		// among the cases that are ready.
 		selection := d.newVar(types.Typ[types.Int])
 		prologue := []ast.Stmt{
 			&ast.AssignStmt{
@@ -641,7 +747,7 @@ func (d *desugarer) desugar(stmt ast.Stmt, breakTo, continueTo, userLabel *ast.I
 	case *ast.TypeSwitchStmt:
 		// Rewrite type switch statements:
 		// - `switch init; x.(type) { ... }` to `{ init; _x := x; switch _x.(type) { ... } }`
-		// - `switch init; x := y.(type) { ... }` to `{ init; _t := y; switch x := _y.(type) { ... } }`
+		// - `switch init; x := y.(type) { case T: ... }` to `{ init; _t := y; switch _t.(type) { case T: x := _t.(T); ... } }`
 		switchLabel := d.newLabel()
 		if userLabel != nil {
 			d.addUserLabel(userLabel, switchLabel)
@@ -653,14 +759,27 @@ func (d *desugarer) desugar(stmt ast.Stmt, breakTo, continueTo, userLabel *ast.I
 
 		// https://go.dev/ref/spec#TypeSwitchStmt
 		var t *ast.TypeAssertExpr
+		var bound []*ast.CaseClause
 		switch a := s.Assign.(type) {
 		case *ast.ExprStmt:
 			t = a.X.(*ast.TypeAssertExpr)
 		case *ast.AssignStmt:
 			t = a.Rhs[0].(*ast.TypeAssertExpr)
+			// The variable bound by the switch is a distinct object in
+			// each clause, with the type of the clause. The switch is
+			// evaluated again when resuming a coroutine in one of its
+			// clauses, so the variable is declared at the beginning of
+			// the clauses instead, which hoists it to the coroutine frame
+			// and preserves changes made to it before yielding.
+			for _, c := range s.Body.List {
+				if d.info.Implicits[c] != nil {
+					bound = append(bound, c.(*ast.CaseClause))
+				}
+			}
 		}
-		if d.mayYield(t.X) {
-			tmp := d.newVar(d.info.TypeOf(t.X))
+		xType := d.info.TypeOf(t.X)
+		if d.mayYield(t.X) || len(bound) > 0 {
+			tmp := d.newVar(xType)
 			prologue = append(prologue, &ast.AssignStmt{
 				Lhs: []ast.Expr{tmp},
 				Tok: token.DEFINE,
@@ -668,6 +787,27 @@ func (d *desugarer) desugar(stmt ast.Stmt, breakTo, continueTo, userLabel *ast.I
 			})
 			t.X = tmp
 		}
+		if len(bound) > 0 {
+			for _, c := range bound {
+				obj := d.info.Implicits[c]
+				name := ast.NewIdent(obj.Name())
+				d.info.Defs[name] = obj
+				// Clauses listing a single type bind the variable to a
+				// value of that type, other clauses bind it to the value
+				// that the switch was evaluated with.
+				var value ast.Expr = t.X
+				if !types.Identical(obj.Type(), xType) {
+					value = &ast.TypeAssertExpr{X: t.X, Type: c.List[0]}
+					d.info.Types[value] = types.TypeAndValue{Type: obj.Type()}
+				}
+				c.Body = append([]ast.Stmt{&ast.AssignStmt{
+					Lhs: []ast.Expr{name},
+					Tok: token.DEFINE,
+					Rhs: []ast.Expr{value},
+				}}, c.Body...)
+			}
+			s.Assign = &ast.ExprStmt{X: t}
+		}
 
 		prologue = d.desugarList(prologue, nil, nil)
 		stmt = &ast.BlockStmt{
@@ -681,7 +821,7 @@ func (d *desugarer) desugar(stmt ast.Stmt, breakTo, continueTo, userLabel *ast.I
 		}
 
 	default:
-		panic(fmt.Sprintf("unsupported ast.Stmt: %T", stmt))
+		notImplemented(stmt, "ast.Stmt(%T)", stmt)
 	}
 	return stmt
 }
@@ -788,21 +928,28 @@ func (d *desugarer) decomposeExpression(expr ast.Expr, flags exprFlags) (ast.Exp
 		return expr, nil
 	}
 
-	queue := []ast.Expr{expr}
-	var tmps []*ast.Ident
+	// Subexpressions that may yield are hoisted into temporary variables in
+	// the order that they are evaluated: operands from left to right, each
+	// one after the subexpressions it depends on.
+	var prereqs []ast.Stmt
+	var decomposeOperands func(ast.Expr)
 
 	decompose := func(e ast.Expr) ast.Expr {
 		if !d.mayYield(e) {
 			return e
 		}
 		tmp := d.newVar(d.info.TypeOf(e))
-		tmps = append(tmps, tmp)
-		queue = append(queue, e)
+		decomposeOperands(e)
+		prereqs = append(prereqs, &ast.AssignStmt{
+			Lhs: []ast.Expr{tmp},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{e},
+		})
 		return tmp
 	}
 
-	for i := 0; i < len(queue); i++ {
-		switch e := queue[i].(type) {
+	decomposeOperands = func(e ast.Expr) {
+		switch e := e.(type) {
 		case *ast.BadExpr:
 			panic("bad expr")
 
@@ -811,19 +958,6 @@ func (d *desugarer) decomposeExpression(expr ast.Expr, flags exprFlags) (ast.Exp
 			e.Y = decompose(e.Y)
 
 		case *ast.CallExpr:
-			if i == 0 && (flags&multiExprStmt) != 0 {
-				// Need to hoist the CallExpr out into a temporary variable in
-				// this case, so that the relative order of calls (and their
-				// prerequisites) is preserved.
-				switch d.info.TypeOf(e).(type) {
-				case *types.Tuple:
-					// TODO: can't hoist like this when it's a function
-					//  that returns multiple values
-				default:
-					queue[i] = decompose(e)
-					continue
-				}
-			}
 			if se, ok := e.Fun.(*ast.SelectorExpr); ok && d.mayYield(se.X) {
 				se.X = decompose(se.X)
 			} else {
@@ -869,8 +1003,8 @@ func (d *desugarer) decomposeExpression(expr ast.Expr, flags exprFlags) (ast.Exp
 		case *ast.SliceExpr:
 			e.X = decompose(e.X)
 			e.Low = decompose(e.Low)
-			e.Max = decompose(e.Max)
 			e.High = decompose(e.High)
+			e.Max = decompose(e.Max)
 
 		case *ast.StarExpr:
 			e.X = decompose(e.X)
@@ -883,29 +1017,25 @@ func (d *desugarer) decomposeExpression(expr ast.Expr, flags exprFlags) (ast.Exp
 			e.X = decompose(e.X)
 
 		default:
-			panic(fmt.Sprintf("unsupported ast.Expr: %T", queue[i]))
-		}
-	}
-	prereqs := make([]ast.Stmt, len(tmps))
-	for i := range tmps {
-		prereqs[i] = &ast.AssignStmt{
-			Lhs: []ast.Expr{tmps[i]},
-			Tok: token.DEFINE,
-			Rhs: []ast.Expr{queue[i+1]},
+			notImplemented(e, "ast.Expr(%T)", e)
 		}
 	}
-	reverse(prereqs)
-	return queue[0], prereqs
-}
 
-func reverse(stmts []ast.Stmt) {
-	i := 0
-	j := len(stmts) - 1
-	for i < j {
-		stmts[i], stmts[j] = stmts[j], stmts[i]
-		i++
-		j--
+	if call, ok := expr.(*ast.CallExpr); ok && (flags&multiExprStmt) != 0 {
+		// Need to hoist the CallExpr out into a temporary variable in
+		// this case, so that the relative order of calls (and their
+		// prerequisites) is preserved.
+		switch d.info.TypeOf(call).(type) {
+		case *types.Tuple:
+			// TODO: can't hoist like this when it's a function
+			//  that returns multiple values
+		default:
+			tmp := decompose(call)
+			return tmp, prereqs
+		}
 	}
+	decomposeOperands(expr)
+	return expr, prereqs
 }
 
 func (d *desugarer) builtin(name string) *ast.Ident {
@@ -914,6 +1044,67 @@ func (d *desugarer) builtin(name string) *ast.Ident {
 	return ident
 }
 
+// utf8Package is the unicode/utf8 package, which is referenced by the code
+// generated for range loops over strings. It is imported as _utf8 to avoid
+// conflicts with identifiers of the package being compiled.
+var utf8Package = types.NewPackage("unicode/utf8", "utf8")
+
+func (d *desugarer) utf8(name string) ast.Expr {
+	pkg := ast.NewIdent("_utf8")
+	d.info.Uses[pkg] = types.NewPkgName(0, nil, pkg.Name, utf8Package)
+	return &ast.SelectorExpr{X: pkg, Sel: ast.NewIdent(name)}
+}
+
+// methodExpr converts the method value x.M to the method expression T.M, or
+// (*T).M if the method is only in the method set of *T, and returns it along
+// with the receiver to pass as first argument. It returns nil if fun is not a
+// method value of a named type.
+func (d *desugarer) methodExpr(fun ast.Expr) (ast.Expr, ast.Expr) {
+	sel, ok := fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil, nil
+	}
+	selection, ok := d.info.Selections[sel]
+	if !ok || selection.Kind() != types.MethodVal {
+		return nil, nil
+	}
+	recv := sel.X
+	recvType := d.info.TypeOf(recv)
+	named := recvType
+	if ptr, ok := recvType.(*types.Pointer); ok {
+		named = ptr.Elem()
+	}
+	if _, ok := named.(*types.Named); !ok {
+		return nil, nil
+	}
+
+	method := selection.Obj().(*types.Func)
+	_, ptrRecv := method.Type().(*types.Signature).Recv().Type().(*types.Pointer)
+	switch {
+	case types.NewMethodSet(recvType).Lookup(method.Pkg(), method.Name()) == nil:
+		// x is an addressable value and M has a pointer receiver.
+		recvType = types.NewPointer(recvType)
+		recv = &ast.UnaryExpr{Op: token.AND, X: recv}
+		d.info.Types[recv] = types.TypeAndValue{Type: recvType}
+	case !ptrRecv && recvType != named && len(selection.Index()) == 1:
+		// x is a pointer and M has a value receiver, x is dereferenced
+		// when the defer statement executes.
+		recvType = named
+		recv = &ast.StarExpr{X: recv}
+		d.info.Types[recv] = types.TypeAndValue{Type: recvType}
+	}
+
+	var typ ast.Expr
+	if ptr, ok := recvType.(*types.Pointer); ok {
+		typ = &ast.ParenExpr{X: &ast.StarExpr{X: typeExpr(d.pkg, ptr.Elem())}}
+	} else {
+		typ = typeExpr(d.pkg, recvType)
+	}
+	name := ast.NewIdent(method.Name())
+	d.info.Uses[name] = method
+	return &ast.SelectorExpr{X: typ, Sel: name}, recv
+}
+
 func (d *desugarer) newVar(t types.Type) *ast.Ident {
 	v := ast.NewIdent("_v" + strconv.Itoa(d.vars))
 	d.vars++
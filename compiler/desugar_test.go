@@ -321,6 +321,25 @@ _l0:
 		}
 	}
 }
+`,
+		},
+		{
+			name: "for range over pointer to array (index and value)",
+			body: "for i, v := range &a {}",
+			info: func(stmts []ast.Stmt, info *types.Info) {
+				x := stmts[0].(*ast.RangeStmt).X
+				info.Types[x] = types.TypeAndValue{Type: types.NewPointer(types.NewArray(intType, 3))}
+			},
+			expect: `
+{
+	_v0 := &a
+	{
+		i := 0
+		for ; i < len(_v0); i++ {
+			v := _v0[i]
+		}
+	}
+}
 `,
 		},
 		{
@@ -495,6 +514,47 @@ _l0:
 		}
 	}
 }
+`,
+		},
+		{
+			name: "for range over string (index and value)",
+			body: "for i, r := range s { foo }",
+			info: func(stmts []ast.Stmt, info *types.Info) {
+				x := stmts[0].(*ast.RangeStmt).X
+				info.Types[x] = types.TypeAndValue{Type: types.Typ[types.String]}
+			},
+			expect: `
+{
+	_v0 := s
+	{
+		i, _v1 := 0, 0
+		for ; i < len(_v0); i += _v1 {
+			var r rune
+			r, _v1 = _utf8.DecodeRuneInString(_v0[i:])
+			foo
+		}
+	}
+}
+`,
+		},
+		{
+			name: "for range over string (index only)",
+			body: "for i := range s { foo }",
+			info: func(stmts []ast.Stmt, info *types.Info) {
+				x := stmts[0].(*ast.RangeStmt).X
+				info.Types[x] = types.TypeAndValue{Type: types.Typ[types.String]}
+			},
+			expect: `
+{
+	_v0 := s
+	{
+		i, _v1 := 0, 0
+		for ; i < len(_v0); i += _v1 {
+			_, _v1 = _utf8.DecodeRuneInString(_v0[i:])
+			foo
+		}
+	}
+}
 `,
 		},
 		{
@@ -844,6 +904,39 @@ case bool:
 		bar
 	}
 }
+`,
+		},
+		{
+			name: "type switch with bound variable",
+			body: `
+switch b := a.(type) {
+case int:
+	foo
+case bool, string:
+	bar
+}
+`,
+			types: map[string]types.TypeAndValue{
+				"a": {Type: types.NewInterfaceType(nil, nil)},
+			},
+			info: func(stmts []ast.Stmt, info *types.Info) {
+				info.Implicits = map[ast.Node]types.Object{}
+				clauses := stmts[0].(*ast.TypeSwitchStmt).Body.List
+				info.Implicits[clauses[0]] = types.NewVar(0, nil, "b", intType)
+				info.Implicits[clauses[1]] = types.NewVar(0, nil, "b", types.NewInterfaceType(nil, nil))
+			},
+			expect: `
+{
+	_v0 := a
+	switch _v0.(type) {
+	case int:
+		b := _v0.(int)
+		foo
+	case bool, string:
+		b := _v0
+		bar
+	}
+}
 `,
 		},
 		{
@@ -1043,6 +1136,17 @@ _l0:
 	_v2 := c(_v3)
 	_v0 <- _v2
 }
+`,
+		},
+		{
+			name: "decompose expressions in receive expressions",
+			body: "a := <-b(c())",
+			expect: `
+{
+	_v1 := c()
+	_v0 := b(_v1)
+	a := <-_v0
+}
 `,
 		},
 		{
@@ -1068,14 +1172,25 @@ _l0:
 		{
 			name: "key value expr",
 			body: "Foo{Bar: a(b()), Baz: c(d())}",
-			// TODO: fix order of evaluation here
 			expect: `
 {
+	_v1 := b()
+	_v0 := a(_v1)
 	_v3 := d()
-	_v2 := b()
-	_v1 := c(_v3)
-	_v0 := a(_v2)
-	Foo{Bar: _v0, Baz: _v1}
+	_v2 := c(_v3)
+	Foo{Bar: _v0, Baz: _v2}
+}
+`,
+		},
+		{
+			name: "slice expr",
+			body: "_ = x[a():b():c()]",
+			expect: `
+{
+	_v0 := a()
+	_v1 := b()
+	_v2 := c()
+	_ = x[_v0:_v1:_v2]
 }
 `,
 		},
@@ -1097,10 +1212,8 @@ defer func() {
 	_v1 := b
 	_v2 := c
 	defer func() {
-		func() {
-			foo()
-		}(_v0, _v1, _v2)
-	}()
+		foo()
+	}(_v0, _v1, _v2)
 }
 `,
 		},
@@ -1130,9 +1243,7 @@ defer func() {
 	_v2 := b()
 	_v0 := a(_v2)
 	_v1 := c
-	defer func() {
-		foo(_v0, _v1)
-	}()
+	defer foo(_v0, _v1)
 }
 `,
 		},
@@ -1190,7 +1301,7 @@ defer func() {
 			})
 
 			p := &packages.Package{TypesInfo: info}
-			desugared := desugar(p, body, mayYield)
+			desugared := desugar(p, body, mayYield, 0)
 			desugared = unnestBlocks(desugared)
 
 			expect := strings.TrimSpace(test.expect)
@@ -0,0 +1,47 @@
+package compiler
+
+import (
+	"errors"
+	"go/token"
+)
+
+var (
+	// ErrNotModule is an error that occurs when the packages to compile, or
+	// packages they depend on that need to be compiled, are not part of a Go
+	// module.
+	ErrNotModule = errors.New("not part of a Go module")
+
+	// ErrNeedsVendoring is an error that occurs when packages that need to
+	// be compiled belong to a dependency of the module. Vendoring the
+	// dependencies (go mod vendor) allows the compiler to modify them.
+	ErrNeedsVendoring = errors.New("dependencies need to be vendored (go mod vendor)")
+
+	// ErrUnsupportedFeature is an error that occurs when a function that
+	// yields uses a language feature that the compiler does not support.
+	// Errors matching it are of type *UnsupportedError.
+	ErrUnsupportedFeature = errors.New("unsupported feature")
+)
+
+// UnsupportedError is the error returned by Compile when a function that
+// yields cannot be compiled. It matches ErrUnsupportedFeature.
+type UnsupportedError struct {
+	// Position of the construct that cannot be compiled. It is invalid when
+	// the construct has no position in the source (e.g. functions
+	// synthesized by the Go compiler), in which case the message locates it.
+	Pos token.Position
+
+	// Message describing the construct.
+	Msg string
+}
+
+func (e *UnsupportedError) Error() string {
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Msg
+	}
+	return e.Msg
+}
+
+// Is returns true if target is ErrUnsupportedFeature.
+func (e *UnsupportedError) Is(target error) bool {
+	return target == ErrUnsupportedFeature
+}
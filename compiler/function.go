@@ -213,6 +213,12 @@ func generateFunctypes(p *packages.Package, f *ast.File, colors map[ast.Node]*ty
 	for _, decl := range f.Decls {
 		switch d := decl.(type) {
 		case *ast.FuncDecl:
+			// Generic functions are compiled to shape instantiations whose
+			// symbol names and signatures cannot be expressed in the source,
+			// so they (and the closures they contain) are not registered.
+			if d.Type.TypeParams != nil {
+				continue
+			}
 			scope := &funcscope{vars: map[string]*funcvar{}}
 			name := functionPath(p, d)
 			collectFunctypes(p, name, d, scope, colors, functypes)
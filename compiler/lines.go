@@ -0,0 +1,192 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// addLineDirectives inserts //line directives in src, the formatted source of
+// the generated file, so that positions reported by the Go toolchain (e.g. in
+// stack traces or compile errors) refer to the original source.
+//
+// Statements and declarations of the generated file are matched with those of
+// gen, the AST it was printed from, which carry the positions of the original
+// source. Each line starting a statement is mapped to the position of the
+// statement, or to that of the closest enclosing node when the statement was
+// generated by the compiler. Declarations generated by the compiler have no
+// original position, their lines are mapped to the generated file itself.
+//
+// The source files are rewritten by the compiler, sources holds the line maps
+// translating the positions of fset to the lines of the files written.
+func addLineDirectives(fset *token.FileSet, gen *ast.File, path string, src []byte, sources map[string]lineMap) ([]byte, error) {
+	srcFset := token.NewFileSet()
+	f, err := parser.ParseFile(srcFset, path, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	want, got := lineNodes(gen), lineNodes(f)
+	if len(want) != len(got) {
+		return nil, fmt.Errorf("adding line directives to %s: printed %d statements instead of %d", path, len(got), len(want))
+	}
+
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	targets := make([]lineTarget, len(lines))
+	for i, n := range got {
+		if g, w := fmt.Sprintf("%T", n.node), fmt.Sprintf("%T", want[i].node); g != w {
+			return nil, fmt.Errorf("adding line directives to %s: printed %s instead of %s", path, g, w)
+		}
+		p := srcFset.Position(n.pos)
+		l := p.Line - 1
+		if len(bytes.TrimLeft(lines[l][:p.Column-1], " \t")) != 0 {
+			continue // the node does not start the line
+		}
+		if targets[l].mapped {
+			continue // the line is mapped to the outermost node
+		}
+		t := lineTarget{mapped: true, generated: !want[i].pos.IsValid()}
+		if !t.generated {
+			t.pos = fset.Position(want[i].pos)
+			if m, ok := sources[t.pos.Filename]; ok {
+				t.pos.Line = m.translate(t.pos.Line)
+			}
+		}
+		if _, ok := n.node.(ast.Decl); ok && p.Column == 1 {
+			// Directives are inserted before the doc comments of
+			// declarations, which may hold other directives (e.g.
+			// //go:noinline) that must precede the declaration.
+			t.decl = true
+			for l > 0 && bytes.HasPrefix(lines[l-1], []byte("//")) && !targets[l-1].mapped && (t.generated || t.pos.Line > 1) {
+				l--
+				t.pos.Line--
+			}
+		}
+		targets[l] = t
+	}
+
+	// The file and line that the toolchain assigns to the next line of the
+	// output, which are those of the generated file until the first directive.
+	dir, base := filepath.Dir(path), filepath.Base(path)
+	file, line := base, 1
+	physical := 1
+
+	out := make([]byte, 0, len(src)+len(src)/4)
+	for i, text := range lines {
+		t := targets[i]
+		if t.generated {
+			t.pos = token.Position{Filename: base, Line: physical}
+		} else if rel, err := filepath.Rel(dir, t.pos.Filename); err == nil && filepath.IsLocal(rel) {
+			t.pos.Filename = rel
+		}
+		if t.mapped && (t.pos.Filename != file || t.pos.Line != line) {
+			// Declarations preceded by a directive are separated from
+			// the previous line by a blank line, as gofmt does with
+			// declarations that have doc comments.
+			if t.decl && i > 0 && len(bytes.TrimSpace(lines[i-1])) != 0 {
+				out = append(out, '\n')
+				physical++
+			}
+			if t.generated {
+				t.pos.Line = physical + 1
+			}
+			out = append(out, "//line "...)
+			out = append(out, t.pos.Filename...)
+			out = append(out, ':')
+			out = strconv.AppendInt(out, int64(t.pos.Line), 10)
+			out = append(out, '\n')
+			file, line = t.pos.Filename, t.pos.Line
+			physical++
+		}
+		out = append(out, text...)
+		line++
+		physical++
+	}
+	return out, nil
+}
+
+// lineTarget is the position that a line of the generated file is mapped to.
+type lineTarget struct {
+	pos       token.Position
+	mapped    bool // the line starts a statement or declaration
+	generated bool // the line is mapped to the generated file itself
+	decl      bool // the line starts a top-level declaration
+}
+
+// lineMap maps the lines of a source file parsed by the compiler to those of
+// the file written after stripping its build tags and formatting it. Entries
+// are sorted by line of the parsed file.
+type lineMap []struct{ from, to int }
+
+// sourceLines returns the line map of the source file f, parsed with fset and
+// printed to src.
+func sourceLines(fset *token.FileSet, f *ast.File, path string, src []byte) (lineMap, error) {
+	srcFset := token.NewFileSet()
+	printed, err := parser.ParseFile(srcFset, path, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	from, to := lineNodes(f), lineNodes(printed)
+	if len(from) != len(to) {
+		return nil, fmt.Errorf("mapping lines of %s: printed %d statements instead of %d", path, len(to), len(from))
+	}
+	var m lineMap
+	for i := range from {
+		line := fset.Position(from[i].pos).Line
+		if len(m) == 0 || m[len(m)-1].from < line {
+			m = append(m, struct{ from, to int }{line, srcFset.Position(to[i].pos).Line})
+		}
+	}
+	return m, nil
+}
+
+// translate returns the line of the written file that line of the parsed file
+// was printed to. Lines that do not start a statement or declaration are
+// assumed to be printed after the closest line that does.
+func (m lineMap) translate(line int) int {
+	i := sort.Search(len(m), func(i int) bool { return m[i].from > line })
+	if i == 0 {
+		return line
+	}
+	return m[i-1].to + line - m[i-1].from
+}
+
+type lineNode struct {
+	node ast.Node
+	pos  token.Pos
+}
+
+// lineNodes returns the statements and declarations of file, in the order they
+// appear in the source, along with their position or that of the closest
+// enclosing node with a valid position.
+func lineNodes(file *ast.File) (nodes []lineNode) {
+	var stack []token.Pos
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		pos := n.Pos()
+		if fn, ok := n.(*ast.FuncDecl); ok && !pos.IsValid() {
+			pos = fn.Name.Pos()
+		}
+		if !pos.IsValid() && len(stack) > 0 {
+			pos = stack[len(stack)-1]
+		}
+		stack = append(stack, pos)
+
+		switch n.(type) {
+		case *ast.EmptyStmt:
+			// Empty statements may not be printed.
+		case ast.Stmt, ast.Decl:
+			nodes = append(nodes, lineNode{node: n, pos: pos})
+		}
+		return true
+	})
+	return nodes
+}
@@ -0,0 +1,25 @@
+//go:build go1.22
+
+package compiler
+
+import (
+	"testing"
+
+	. "github.com/stealthrocket/coroutine/compiler/testdata"
+)
+
+func TestCoroutineLoopVars(t *testing.T) {
+	testCoroutineYield(t, []coroutineTest{
+		{
+			name:   "closures capturing per-iteration loop variables",
+			coro:   func() { LoopVarsPerIteration(3) },
+			yields: []int{0, -1, 2, 11, 112, 213},
+		},
+
+		{
+			name:   "closures capturing per-iteration range variables",
+			coro:   func() { RangeVarsPerIteration(3) },
+			yields: []int{10, 20, 30, 10, 21, 32, 0, 1, 2},
+		},
+	})
+}
@@ -3,6 +3,8 @@
 package testdata
 
 import (
+	"errors"
+	"fmt"
 	"time"
 	"unsafe"
 
@@ -156,6 +158,44 @@ func Shadowing(_ int) {
 	coroutine.Yield[int, any](int(unsafe.Sizeof(baz{}))) // 1
 }
 
+func ShadowingTypes(n int) {
+	x := n
+	coroutine.Yield[int, any](x) // n
+	{
+		x := fmt.Sprint(x * 10)
+		coroutine.Yield[int, any](len(x)) // 2
+		{
+			x, ok := []byte(x+"0"), true
+			coroutine.Yield[int, any](len(x)) // 3
+			if ok {
+				x := float64(len(x)) / 2
+				coroutine.Yield[int, any](int(x * 10)) // 15
+			}
+			coroutine.Yield[int, any](len(x)) // 3
+		}
+		x, y := x+"!", len(x)
+		coroutine.Yield[int, any](len(x) + y) // 5
+	}
+	var v any = x
+	switch x := v.(type) {
+	case int:
+		coroutine.Yield[int, any](x + 1) // n+1
+	}
+	for i := 0; i < 2; i++ {
+		n := []int{i, i}
+		coroutine.Yield[int, any](len(n) + i) // 2, 3
+	}
+	func() {
+		x := "closure"
+		coroutine.Yield[int, any](len(x)) // 7
+		{
+			x := len(x) * 2
+			coroutine.Yield[int, any](x) // 14
+		}
+	}()
+	coroutine.Yield[int, any](x + n) // 2n
+}
+
 func RangeSliceIndexGenerator(_ int) {
 	for i := range []int{10, 20, 30} {
 		coroutine.Yield[int, any](i)
@@ -169,6 +209,50 @@ func RangeArrayIndexValueGenerator(_ int) {
 	}
 }
 
+func RangeArrayPointerIndexValueGenerator(_ int) {
+	arr := [...]int{10, 20, 30}
+	for i, v := range &arr {
+		coroutine.Yield[int, any](i)
+		coroutine.Yield[int, any](v)
+		if i+1 < len(arr) {
+			arr[i+1]++
+		}
+	}
+}
+
+type text string
+
+func RangeStringGenerator(_ int) {
+	for i, r := range text("aé世\xff!") {
+		coroutine.Yield[int, any](i)
+		coroutine.Yield[int, any](int(r))
+	}
+	for i := range "世界" {
+		coroutine.Yield[int, any](i)
+	}
+}
+
+func LoopClosuresCapture(n int) {
+	var fns []func() int
+	values := map[int]int{1: 100}
+	for i := 0; i < n; i++ {
+		i := i
+		var j = i * 10
+		v, ok := values[i]
+		fns = append(fns, func() int {
+			if ok {
+				return v
+			}
+			return i + j
+		})
+		coroutine.Yield[int, any](i)
+		j++
+	}
+	for _, f := range fns {
+		coroutine.Yield[int, any](f())
+	}
+}
+
 func TypeSwitchingGenerator(_ int) {
 	for _, val := range []any{int8(10), int16(20), int32(30), int64(40)} {
 		switch val.(type) {
@@ -194,6 +278,23 @@ func TypeSwitchingGenerator(_ int) {
 	}
 }
 
+func TypeSwitchBindingGenerator(_ int) {
+	for _, val := range []any{int8(1), "abc", 2.5} {
+		switch v := val.(type) {
+		case int8:
+			v *= 10
+			coroutine.Yield[int, any](int(v))
+			coroutine.Yield[int, any](int(v) + 1)
+		case string:
+			coroutine.Yield[int, any](len(v))
+			v += "d"
+			coroutine.Yield[int, any](len(v))
+		default:
+			coroutine.Yield[int, any](-1)
+		}
+	}
+}
+
 func LoopBreakAndContinue(_ int) {
 	for i := 0; i < 10; i++ {
 		if mod2 := i % 2; mod2 == 0 {
@@ -224,6 +325,34 @@ outer:
 	}
 }
 
+func LabeledBreakFromSwitch(n int) {
+OuterLoop:
+	for i := 0; i < n; i++ {
+		for {
+			switch {
+			case i == 2:
+				coroutine.Yield[int, any](-1)
+				break OuterLoop
+			default:
+				coroutine.Yield[int, any](i)
+				break
+			}
+			break
+		}
+		coroutine.Yield[int, any](i * 10)
+	}
+
+Loop:
+	for i := 0; ; i++ {
+		switch i {
+		case 3:
+			coroutine.Yield[int, any](i)
+			break Loop
+		}
+	}
+	coroutine.Yield[int, any](100)
+}
+
 func RangeOverMaps(n int) {
 	m := map[int]int{}
 	for range m {
@@ -454,6 +583,22 @@ func Select(n int) {
 	}
 }
 
+func SelectFair(n int) {
+	a := make(chan struct{})
+	b := make(chan struct{})
+	close(a)
+	close(b)
+
+	for i := 0; i < n; i++ {
+		select {
+		case <-a:
+			coroutine.Yield[int, any](0)
+		case <-b:
+			coroutine.Yield[int, any](1)
+		}
+	}
+}
+
 func YieldingExpressionDesugaring() {
 	if x := a(b(1)); x == a(b(2)) {
 	} else if y := a(b(3)); y == a(b(4))-1 {
@@ -502,6 +647,22 @@ func b(v int) int {
 	return v
 }
 
+func YieldingSliceAndIndexOrder() {
+	s := []int{0, 1, 2, 3, 4, 5}
+	coroutine.Yield[int, any](len(s[a(1):a(3)]))
+	coroutine.Yield[int, any](s[a(2)] + s[b(4)])
+	coroutine.Yield[int, any](cap(s[a(2):a(3):b(5)]))
+}
+
+func VariadicLibraryCall(n int) {
+	s := fmt.Sprintf("%d:%d:%d", a(n), b(n+1), a(n+2))
+	coroutine.Yield[int, any](len(s))
+
+	args := []any{a(7)}
+	s = fmt.Sprint(append(args, b(8))...)
+	coroutine.Yield[int, any](len(s))
+}
+
 func YieldingDurations() {
 	t := new(time.Duration)
 	*t = time.Duration(100)
@@ -550,3 +711,487 @@ func varArgs(args ...int) {
 		coroutine.Yield[int, any](arg)
 	}
 }
+
+func VariadicYield(n int) {
+	variadicSum(n, 1, 2, 3)
+	variadicSum(n)
+}
+
+func variadicSum(base int, args ...int) {
+	sum := base
+	for i, arg := range args {
+		coroutine.Yield[int, any](arg)
+		args[i] = -arg
+		sum += arg
+	}
+	coroutine.Yield[int, any](sum)
+	coroutine.Yield[int, any](len(args))
+	for _, arg := range args {
+		coroutine.Yield[int, any](arg)
+	}
+}
+
+func LocalIotaConsts(n int) {
+	const (
+		a = iota
+		b
+		c
+		_
+		e
+	)
+	const (
+		x = 1 << (iota * 10)
+		y
+		z
+	)
+	const k = 3
+	const (
+		_ = k * iota
+		m
+		p
+	)
+	for _, v := range []int{a, b, c, e} {
+		coroutine.Yield[int, any](n + v)
+	}
+	coroutine.Yield[int, any](x)
+	coroutine.Yield[int, any](y)
+	coroutine.Yield[int, any](z)
+	coroutine.Yield[int, any](m)
+	coroutine.Yield[int, any](p)
+}
+
+type integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+func IdentityGeneric[T integer](n T) {
+	for i := T(0); i < n; i++ {
+		coroutine.Yield[int, any](int(i))
+	}
+}
+
+func IdentityGenericInt(n int) {
+	IdentityGeneric[int](n)
+}
+
+func IdentityGenericInt8(n int8) {
+	IdentityGeneric[int8](n)
+}
+
+func LoopDefers(n int) {
+	var order []int
+	loopDefers(&order, n)
+	for _, v := range order {
+		coroutine.Yield[int, any](v)
+	}
+}
+
+func loopDefers(order *[]int, n int) {
+	for i := 0; i < n; i++ {
+		defer func(v int) {
+			*order = append(*order, v)
+		}(i * 10)
+		coroutine.Yield[int, any](i)
+	}
+}
+
+func YieldAndRecover(n int) {
+	defer func() {
+		if v := recover(); v != "oops" {
+			panic(v)
+		}
+	}()
+	coroutine.Yield[int, any](n)
+	panic("oops")
+}
+
+func RecoverPanicIsolation(n int) {
+	for i := 0; i < n; i++ {
+		v, err := isolatedWork(i)
+		if err != nil {
+			coroutine.Yield[int, any](-v)
+		} else {
+			coroutine.Yield[int, any](v)
+		}
+	}
+}
+
+func isolatedWork(i int) (result int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = r.(int), errors.New("recovered")
+		}
+	}()
+	coroutine.Yield[int, any](i * 10)
+	if i%2 == 1 {
+		panic(i + 100)
+	}
+	coroutine.Yield[int, any](i*10 + 1)
+	return i, nil
+}
+
+func RecoverAfterYield(n int) {
+	for i := 0; i < n; i++ {
+		coroutine.Yield[int, any](recoverAfterYield(i))
+	}
+}
+
+func recoverAfterYield(i int) (result int) {
+	defer func() {
+		coroutine.Yield[int, any](1000 + result)
+	}()
+	defer func() {
+		// The panic is in flight while the coroutine is suspended, and
+		// is recovered after it resumes.
+		coroutine.Yield[int, any](-i)
+		if r := recover(); r != nil {
+			result = r.(int)
+		}
+	}()
+	coroutine.Yield[int, any](i * 10)
+	if i%2 == 1 {
+		panic(i * 100)
+	}
+	return i
+}
+
+func AnonymousStructLocal(n int) {
+	var x struct {
+		A int
+		B []int `json:"b"`
+	}
+	p := struct{ X, Y int }{X: n, Y: n * 2}
+	for x.A = 0; x.A < n; x.A++ {
+		x.B = append(x.B, x.A)
+		coroutine.Yield[int, any](x.A + p.X)
+	}
+	coroutine.Yield[int, any](len(x.B) + p.Y)
+}
+
+func TaglessSwitchResume(n int) {
+	x := 0
+	switch {
+	case x > 0:
+		coroutine.Yield[int, any](-1)
+	case x == 0:
+		// If the case conditions were evaluated again when resuming, the
+		// first case would be selected after this assignment.
+		x = n
+		coroutine.Yield[int, any](x)
+		coroutine.Yield[int, any](x + 1)
+	default:
+		coroutine.Yield[int, any](-2)
+	}
+
+	switch {
+	case x < 0:
+		coroutine.Yield[int, any](-3)
+	case yieldIdentity(x) == n:
+		x = -1
+		coroutine.Yield[int, any](x)
+	}
+}
+
+func yieldIdentity(n int) int {
+	coroutine.Yield[int, any](n)
+	return n
+}
+
+func NamedResultsAfterYield(n int) {
+	for i := 0; i < n; i++ {
+		coroutine.Yield[int, any](namedResults(i))
+	}
+}
+
+func namedResults(n int) (result int) {
+	result = n * 10
+	coroutine.Yield[int, any](result)
+	result++
+	return
+}
+
+func TupleAssignmentAcrossYields(n int) {
+	var x int
+	var err error
+	for i := 0; i < n; i++ {
+		x, err = tupleWork(i)
+		if err != nil {
+			coroutine.Yield[int, any](-1)
+			continue
+		}
+		coroutine.Yield[int, any](x)
+	}
+	a, b := tupleWork(n)
+	coroutine.Yield[int, any](a)
+	if b == nil {
+		coroutine.Yield[int, any](n)
+	}
+}
+
+func tupleWork(n int) (int, error) {
+	coroutine.Yield[int, any](n * 10)
+	if n%2 != 0 {
+		return 0, errors.New("odd")
+	}
+	return n + 1, nil
+}
+
+func ErrorCheckAcrossYields(n int) {
+	for i := 0; i < n; i++ {
+		if err := checkedWork(i); err != nil {
+			// The length of the message tells the errors apart.
+			coroutine.Yield[int, any](-len(err.Error()))
+		}
+	}
+}
+
+func checkedWork(n int) error {
+	x, err := tupleWork(n)
+	coroutine.Yield[int, any](x)
+	if err != nil {
+		return err
+	}
+	if y, err := tupleWork(x + 1); err != nil {
+		return err
+	} else {
+		coroutine.Yield[int, any](y)
+	}
+	z, err := tupleWork(x)
+	coroutine.Yield[int, any](z)
+	if err != nil {
+		// The error is shadowed by a call that yields, and must be intact
+		// when the call returns.
+		if _, err := tupleWork(x + 1); err != nil {
+			return err
+		}
+		return fmt.Errorf("work %d: %w", n, err)
+	}
+	return nil
+}
+
+func MinMaxClear(n int) {
+	a := 1
+	x := max(a, yieldIdentity(n))
+	coroutine.Yield[int, any](x)
+
+	y := min(yieldIdentity(x+1), x, n)
+	coroutine.Yield[int, any](y)
+
+	m := map[int]int{x: x, y + 1: y}
+	s := []int{x, y}
+	clear(m)
+	clear(s)
+	coroutine.Yield[int, any](len(m))
+	coroutine.Yield[int, any](s[0] + s[1])
+}
+
+func CopyYieldingOperands(n int) {
+	buf := make([]int, n)
+	k := copy(buf, yieldingRead(n))
+	coroutine.Yield[int, any](k)
+	for _, v := range buf {
+		coroutine.Yield[int, any](v)
+	}
+
+	dst := make([]int, 2)
+	coroutine.Yield[int, any](copy(yieldingSlice(dst), buf[yieldIdentity(1):]))
+	coroutine.Yield[int, any](dst[0] + dst[1])
+}
+
+func InfiniteLoopConditionalBreak(n int) {
+	i := 0
+	for {
+		coroutine.Yield[int, any](i)
+		i++
+		if i == n {
+			coroutine.Yield[int, any](-i)
+			break
+		}
+		coroutine.Yield[int, any](i * 10)
+	}
+	coroutine.Yield[int, any](i * 100)
+}
+
+func SharedPointers(n int) {
+	p := new(int)
+	q := p
+	for i := 0; i < n; i++ {
+		if v, ok := coroutine.Yield[int, any](*q).(int); ok {
+			*p += v
+		} else {
+			*p++
+		}
+	}
+}
+
+var globalCounter int
+
+func GlobalPointer(n int) {
+	globalCounter = 0
+	p := &globalCounter
+	for i := 0; i < n; i++ {
+		coroutine.Yield[int, any](globalCounter)
+		*p += 10
+		coroutine.Yield[int, any](globalCounter)
+		globalCounter++
+		coroutine.Yield[int, any](*p)
+	}
+}
+
+type Stepper interface {
+	Step(n int) int
+}
+
+type countStepper struct{ total int }
+
+func (s *countStepper) Step(n int) int {
+	coroutine.Yield[int, any](n)
+	s.total += n
+	return s.total
+}
+
+type doubleStepper struct{}
+
+func (doubleStepper) Step(n int) int {
+	coroutine.Yield[int, any](n * 2)
+	return n * 2
+}
+
+type embeddedStepper struct{ *countStepper }
+
+func InterfaceMethodCall(n int) {
+	steppers := []Stepper{&countStepper{}, doubleStepper{}, embeddedStepper{&countStepper{}}}
+	for i := 0; i < n; i++ {
+		var s Stepper = steppers[i%len(steppers)]
+		coroutine.Yield[int, any](s.Step(i + 1))
+	}
+}
+
+// stepperEmbedder promotes the methods of the Stepper interface it embeds.
+type stepperEmbedder struct{ Stepper }
+
+func EmbeddedInterfaceMethodCall(n int) {
+	s := stepperEmbedder{&countStepper{}}
+	for i := 0; i < n; i++ {
+		coroutine.Yield[int, any](s.Step(i + 1))
+	}
+	// The promoted methods are called through the wrappers synthesized for
+	// the embedding type when it is held in an interface.
+	var v Stepper = stepperEmbedder{doubleStepper{}}
+	coroutine.Yield[int, any](v.Step(n))
+	var p Stepper = &stepperEmbedder{&countStepper{total: 10}}
+	coroutine.Yield[int, any](p.Step(n + 2))
+}
+
+type file struct {
+	fd     int
+	closed *[]int
+}
+
+func (f *file) Close() error {
+	*f.closed = append(*f.closed, f.fd)
+	return nil
+}
+
+type handle struct {
+	id     int
+	closed *[]int
+}
+
+func (h handle) Close() { *h.closed = append(*h.closed, h.id) }
+
+func DeferredMethodOnLocal(n int) {
+	var closed []int
+	deferClose(n, &closed)
+	for _, fd := range closed {
+		coroutine.Yield[int, any](fd)
+	}
+}
+
+func deferClose(n int, closed *[]int) {
+	h := handle{id: n, closed: closed}
+	defer h.Close()
+	f := &file{fd: n * 10, closed: closed}
+	defer f.Close()
+	coroutine.Yield[int, any](h.id)
+	// The receiver of a value method is bound when the defer statement
+	// executes, while a pointer receiver observes later updates.
+	h = handle{id: -1, closed: closed}
+	f.fd++
+	coroutine.Yield[int, any](h.id)
+}
+
+func MakeYieldingSize(n int) {
+	s := make([]int, yieldingSize(n), yieldingSize(n*2))
+	for i := range s {
+		s[i] = i
+	}
+	coroutine.Yield[int, any](len(s))
+	coroutine.Yield[int, any](cap(s))
+	// The capacity is preserved across yields, appending within it does not
+	// reallocate the slice.
+	s = append(s, 100)
+	coroutine.Yield[int, any](cap(s))
+	coroutine.Yield[int, any](s[n])
+	m := make(map[int]int, yieldingSize(n))
+	m[n] = len(s)
+	coroutine.Yield[int, any](m[n])
+}
+
+func ContextCancellation(n int) {
+	// The context is held across yield points, it observes the context set
+	// when the coroutine resumes.
+	ctx := coroutine.LoadContext[int, any]().Context()
+	for i := 0; i < n; i++ {
+		coroutine.Yield[int, any](i)
+		if err := ctx.Err(); err != nil {
+			coroutine.Yield[int, any](-1)
+			return
+		}
+	}
+}
+
+type mixedPoint struct{ X, Y int }
+
+func MixedMapValues(n int) {
+	// The map is held in the frame across yields. Both entries holding p
+	// are restored as the same pointer.
+	p := &mixedPoint{X: n}
+	m := map[string]any{
+		"n":       n,
+		"half":    float64(n) / 2,
+		"name":    "mixed",
+		"even":    n%2 == 0,
+		"point":   mixedPoint{n, -n},
+		"pointer": p,
+		"alias":   p,
+		"values":  []any{"a", n},
+		"nested":  map[string]any{"n": int8(n)},
+		"nil":     nil,
+	}
+	for i := 0; i < n; i++ {
+		coroutine.Yield[int, any](m["n"].(int))
+		m["pointer"].(*mixedPoint).X++
+		coroutine.Yield[int, any](m["alias"].(*mixedPoint).X)
+		m["n"] = m["n"].(int) + 1
+	}
+}
+
+func yieldingSize(n int) int {
+	coroutine.Yield[int, any](-n)
+	return n
+}
+
+func yieldingRead(n int) []int {
+	coroutine.Yield[int, any](-n)
+	s := make([]int, n+1)
+	for i := range s {
+		s[i] = (i + 1) * 10
+	}
+	return s
+}
+
+func yieldingSlice(s []int) []int {
+	coroutine.Yield[int, any](len(s))
+	return s
+}
@@ -3,8 +3,12 @@
 package testdata
 
 import (
+	context "context"
+	errors "errors"
+	fmt "fmt"
 	coroutine "github.com/stealthrocket/coroutine"
 	time "time"
+	_utf8 "unicode/utf8"
 	unsafe "unsafe"
 )
 import _types "github.com/stealthrocket/coroutine/types"
@@ -19,17 +23,17 @@ func Identity(n int) { coroutine.Yield[int, any](n) }
 func SquareGenerator(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SquareGenerator"`
 		X0 int
 		X1 int
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SquareGenerator"`
 		X0 int
 		X1 int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SquareGenerator"`
 			X0 int
 			X1 int
 		}{X0: _fn0}
@@ -55,15 +59,15 @@ func SquareGenerator(_fn0 int) {
 func SquareGeneratorTwice(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SquareGeneratorTwice"`
 		X0 int
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SquareGeneratorTwice"`
 		X0 int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SquareGeneratorTwice"`
 			X0 int
 		}{X0: _fn0}
 	}
@@ -86,17 +90,17 @@ func SquareGeneratorTwice(_fn0 int) {
 func SquareGeneratorTwiceLoop(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SquareGeneratorTwiceLoop"`
 		X0 int
 		X1 int
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SquareGeneratorTwiceLoop"`
 		X0 int
 		X1 int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SquareGeneratorTwiceLoop"`
 			X0 int
 			X1 int
 		}{X0: _fn0}
@@ -122,19 +126,19 @@ func SquareGeneratorTwiceLoop(_fn0 int) {
 func EvenSquareGenerator(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.EvenSquareGenerator"`
 		X0 int
 		X1 int
 		X2 int
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.EvenSquareGenerator"`
 		X0 int
 		X1 int
 		X2 int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.EvenSquareGenerator"`
 			X0 int
 			X1 int
 			X2 int
@@ -170,14 +174,14 @@ func EvenSquareGenerator(_fn0 int) {
 func NestedLoops(_fn0 int) (_ int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.NestedLoops"`
 		X0 int
 		X1 int
 		X2 int
 		X3 int
 		X4 int
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.NestedLoops"`
 		X0 int
 		X1 int
 		X2 int
@@ -186,7 +190,7 @@ func NestedLoops(_fn0 int) (_ int) {
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.NestedLoops"`
 			X0 int
 			X1 int
 			X2 int
@@ -252,19 +256,19 @@ func NestedLoops(_fn0 int) (_ int) {
 func FizzBuzzIfGenerator(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.FizzBuzzIfGenerator"`
 		X0 int
 		X1 int
 		X2 int
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.FizzBuzzIfGenerator"`
 		X0 int
 		X1 int
 		X2 int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.FizzBuzzIfGenerator"`
 			X0 int
 			X1 int
 			X2 int
@@ -313,14 +317,14 @@ func FizzBuzzIfGenerator(_fn0 int) {
 func FizzBuzzSwitchGenerator(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.FizzBuzzSwitchGenerator"`
 		X0 int
 		X1 int
 		X2 bool
 		X3 bool
 		X4 bool
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.FizzBuzzSwitchGenerator"`
 		X0 int
 		X1 int
 		X2 bool
@@ -329,7 +333,7 @@ func FizzBuzzSwitchGenerator(_fn0 int) {
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.FizzBuzzSwitchGenerator"`
 			X0 int
 			X1 int
 			X2 bool
@@ -398,7 +402,7 @@ func FizzBuzzSwitchGenerator(_fn0 int) {
 func Shadowing(_ int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP  int
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Shadowing"`
 		X0  int
 		X1  int
 		X2  int
@@ -423,7 +427,7 @@ func Shadowing(_ int) {
 		X21 uintptr
 		X22 int
 	} = coroutine.Push[struct {
-		IP  int
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Shadowing"`
 		X0  int
 		X1  int
 		X2  int
@@ -466,7 +470,7 @@ func Shadowing(_ int) {
 	type _o8 [_o7]uint8
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP  int
+			IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Shadowing"`
 			X0  int
 			X1  int
 			X2  int
@@ -737,21 +741,239 @@ func Shadowing(_ int) {
 	}
 }
 
+//go:noinline
+func ShadowingTypes(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f1 *struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.ShadowingTypes"`
+		X0  int
+		X1  int
+		X2  string
+		X3  []byte
+		X4  []byte
+		X5  bool
+		X6  float64
+		X7  string
+		X8  int
+		X9  any
+		X10 any
+		X11 int
+		X12 int
+		X13 []int
+	} = coroutine.Push[struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.ShadowingTypes"`
+		X0  int
+		X1  int
+		X2  string
+		X3  []byte
+		X4  []byte
+		X5  bool
+		X6  float64
+		X7  string
+		X8  int
+		X9  any
+		X10 any
+		X11 int
+		X12 int
+		X13 []int
+	}](&_c.Stack)
+	if _f1.IP == 0 {
+		*_f1 = struct {
+			IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.ShadowingTypes"`
+			X0  int
+			X1  int
+			X2  string
+			X3  []byte
+			X4  []byte
+			X5  bool
+			X6  float64
+			X7  string
+			X8  int
+			X9  any
+			X10 any
+			X11 int
+			X12 int
+			X13 []int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f1.IP < 2:
+		_f1.X1 = _f1.X0
+		_f1.IP = 2
+		fallthrough
+	case _f1.IP < 3:
+		coroutine.Yield[int, any](_f1.X1)
+		_f1.IP = 3
+		fallthrough
+	case _f1.IP < 13:
+		switch {
+		case _f1.IP < 4:
+			_f1.X7 = fmt.Sprint(_f1.X1 * 10)
+			_f1.IP = 4
+			fallthrough
+		case _f1.IP < 5:
+			coroutine.Yield[int, any](len(_f1.X7))
+			_f1.IP = 5
+			fallthrough
+		case _f1.IP < 11:
+			switch {
+			case _f1.IP < 6:
+				_f1.X3 = []byte(_f1.X7 + "0")
+				_f1.IP = 6
+				fallthrough
+			case _f1.IP < 7:
+				_f1.X4, _f1.X5 = _f1.X3, true
+				_f1.IP = 7
+				fallthrough
+			case _f1.IP < 8:
+				coroutine.Yield[int, any](len(_f1.X4))
+				_f1.IP = 8
+				fallthrough
+			case _f1.IP < 10:
+				if _f1.X5 {
+					switch {
+					case _f1.IP < 9:
+						_f1.X6 = float64(len(_f1.X4)) / 2
+						_f1.IP = 9
+						fallthrough
+					case _f1.IP < 10:
+						coroutine.Yield[int, any](int(_f1.X6 * 10))
+					}
+				}
+				_f1.IP = 10
+				fallthrough
+			case _f1.IP < 11:
+
+				coroutine.Yield[int, any](len(_f1.X4))
+			}
+			_f1.IP = 11
+			fallthrough
+		case _f1.IP < 12:
+			_f1.X7, _f1.X8 = _f1.X7+"!", len(_f1.X7)
+			_f1.IP = 12
+			fallthrough
+		case _f1.IP < 13:
+			coroutine.Yield[int, any](len(_f1.X7) + _f1.X8)
+		}
+		_f1.IP = 13
+		fallthrough
+	case _f1.IP < 14:
+		_f1.X9 = _f1.X1
+		_f1.IP = 14
+		fallthrough
+	case _f1.IP < 17:
+		switch {
+		case _f1.IP < 15:
+			_f1.X10 = _f1.X9
+			_f1.IP = 15
+			fallthrough
+		case _f1.IP < 17:
+			switch _f1.X10.(type) {
+			case int:
+				switch {
+				case _f1.IP < 16:
+					_f1.X11 = _f1.X10.(int)
+					_f1.IP = 16
+					fallthrough
+				case _f1.IP < 17:
+					coroutine.Yield[int, any](_f1.X11 + 1)
+				}
+			}
+		}
+		_f1.IP = 17
+		fallthrough
+	case _f1.IP < 20:
+		switch {
+		case _f1.IP < 18:
+			_f1.X12 = 0
+			_f1.IP = 18
+			fallthrough
+		case _f1.IP < 20:
+			for ; _f1.X12 < 2; _f1.X12, _f1.IP = _f1.X12+1, 18 {
+				switch {
+				case _f1.IP < 19:
+					_f1.X13 = []int{_f1.X12, _f1.X12}
+					_f1.IP = 19
+					fallthrough
+				case _f1.IP < 20:
+					coroutine.Yield[int, any](len(_f1.X13) + _f1.X12)
+				}
+			}
+		}
+		_f1.IP = 20
+		fallthrough
+	case _f1.IP < 21:
+
+		func() {
+			_c := coroutine.LoadContext[int, any]()
+			var _f0 *struct {
+				IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.ShadowingTypes.func@coroutine.go:188:2"`
+				X0 string
+				X1 int
+			} = coroutine.Push[struct {
+				IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.ShadowingTypes.func@coroutine.go:188:2"`
+				X0 string
+				X1 int
+			}](&_c.Stack)
+			if _f0.IP == 0 {
+				*_f0 = struct {
+					IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.ShadowingTypes.func@coroutine.go:188:2"`
+					X0 string
+					X1 int
+				}{}
+			}
+			defer func() {
+				if !_c.Unwinding() {
+					coroutine.Pop(&_c.Stack)
+				}
+			}()
+			switch {
+			case _f0.IP < 2:
+				_f0.X0 = "closure"
+				_f0.IP = 2
+				fallthrough
+			case _f0.IP < 3:
+				coroutine.Yield[int, any](len(_f0.X0))
+				_f0.IP = 3
+				fallthrough
+			case _f0.IP < 5:
+				switch {
+				case _f0.IP < 4:
+					_f0.X1 = len(_f0.X0) * 2
+					_f0.IP = 4
+					fallthrough
+				case _f0.IP < 5:
+					coroutine.Yield[int, any](_f0.X1)
+				}
+			}
+		}()
+		_f1.IP = 21
+		fallthrough
+	case _f1.IP < 22:
+		coroutine.Yield[int, any](_f1.X1 + _f1.X0)
+	}
+}
+
 //go:noinline
 func RangeSliceIndexGenerator(_ int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeSliceIndexGenerator"`
 		X0 []int
 		X1 int
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeSliceIndexGenerator"`
 		X0 []int
 		X1 int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeSliceIndexGenerator"`
 			X0 []int
 			X1 int
 		}{}
@@ -784,19 +1006,19 @@ func RangeSliceIndexGenerator(_ int) {
 func RangeArrayIndexValueGenerator(_ int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeArrayIndexValueGenerator"`
 		X0 [3]int
 		X1 int
 		X2 int
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeArrayIndexValueGenerator"`
 		X0 [3]int
 		X1 int
 		X2 int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeArrayIndexValueGenerator"`
 			X0 [3]int
 			X1 int
 			X2 int
@@ -838,25 +1060,28 @@ func RangeArrayIndexValueGenerator(_ int) {
 }
 
 //go:noinline
-func TypeSwitchingGenerator(_ int) {
+func RangeArrayPointerIndexValueGenerator(_ int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
-		X0 []any
-		X1 int
-		X2 any
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeArrayPointerIndexValueGenerator"`
+		X0 [3]int
+		X1 *[3]int
+		X2 int
+		X3 int
 	} = coroutine.Push[struct {
-		IP int
-		X0 []any
-		X1 int
-		X2 any
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeArrayPointerIndexValueGenerator"`
+		X0 [3]int
+		X1 *[3]int
+		X2 int
+		X3 int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
-			X0 []any
-			X1 int
-			X2 any
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeArrayPointerIndexValueGenerator"`
+			X0 [3]int
+			X1 *[3]int
+			X2 int
+			X3 int
 		}{}
 	}
 	defer func() {
@@ -866,45 +1091,40 @@ func TypeSwitchingGenerator(_ int) {
 	}()
 	switch {
 	case _f0.IP < 2:
-		_f0.X0 = []any{int8(10), int16(20), int32(30), int64(40)}
+		_f0.X0 = [...]int{10, 20, 30}
 		_f0.IP = 2
 		fallthrough
-	case _f0.IP < 12:
+	case _f0.IP < 8:
 		switch {
 		case _f0.IP < 3:
-			_f0.X1 = 0
+			_f0.X1 = &_f0.X0
 			_f0.IP = 3
 			fallthrough
-		case _f0.IP < 12:
-			for ; _f0.X1 < len(_f0.X0); _f0.X1, _f0.IP = _f0.X1+1, 3 {
-				switch {
-				case _f0.IP < 4:
-					_f0.X2 = _f0.X0[_f0.X1]
-					_f0.IP = 4
-					fallthrough
-				case _f0.IP < 8:
-					switch _f0.X2.(type) {
-					case int8:
-						coroutine.Yield[int, any](1)
-					case int16:
-						coroutine.Yield[int, any](2)
-					case int32:
-						coroutine.Yield[int, any](4)
-					case int64:
-						coroutine.Yield[int, any](8)
-					}
-					_f0.IP = 8
-					fallthrough
-				case _f0.IP < 12:
-					switch v := _f0.X2.(type) {
-					case int8:
-						coroutine.Yield[int, any](int(v))
-					case int16:
-						coroutine.Yield[int, any](int(v))
-					case int32:
-						coroutine.Yield[int, any](int(v))
-					case int64:
-						coroutine.Yield[int, any](int(v))
+		case _f0.IP < 8:
+			switch {
+			case _f0.IP < 4:
+				_f0.X2 = 0
+				_f0.IP = 4
+				fallthrough
+			case _f0.IP < 8:
+				for ; _f0.X2 < len(_f0.X1); _f0.X2, _f0.IP = _f0.X2+1, 4 {
+					switch {
+					case _f0.IP < 5:
+						_f0.X3 = _f0.X1[_f0.X2]
+						_f0.IP = 5
+						fallthrough
+					case _f0.IP < 6:
+						coroutine.Yield[int, any](_f0.X2)
+						_f0.IP = 6
+						fallthrough
+					case _f0.IP < 7:
+						coroutine.Yield[int, any](_f0.X3)
+						_f0.IP = 7
+						fallthrough
+					case _f0.IP < 8:
+						if _f0.X2+1 < len(_f0.X0) {
+							_f0.X0[_f0.X2+1]++
+						}
 					}
 				}
 			}
@@ -912,47 +1132,40 @@ func TypeSwitchingGenerator(_ int) {
 	}
 }
 
+type text string
+
 //go:noinline
-func LoopBreakAndContinue(_ int) {
+func RangeStringGenerator(_ int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
-		X0 int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeStringGenerator"`
+		X0 text
 		X1 int
 		X2 int
-		X3 int
-		X4 int
-		X5 bool
-		X6 bool
-		X7 int
-		X8 bool
-		X9 bool
+		X3 rune
+		X4 string
+		X5 int
+		X6 int
 	} = coroutine.Push[struct {
-		IP int
-		X0 int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeStringGenerator"`
+		X0 text
 		X1 int
 		X2 int
-		X3 int
-		X4 int
-		X5 bool
-		X6 bool
-		X7 int
-		X8 bool
-		X9 bool
+		X3 rune
+		X4 string
+		X5 int
+		X6 int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
-			X0 int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeStringGenerator"`
+			X0 text
 			X1 int
 			X2 int
-			X3 int
-			X4 int
-			X5 bool
-			X6 bool
-			X7 int
-			X8 bool
-			X9 bool
+			X3 rune
+			X4 string
+			X5 int
+			X6 int
 		}{}
 	}
 	defer func() {
@@ -961,105 +1174,61 @@ func LoopBreakAndContinue(_ int) {
 		}
 	}()
 	switch {
-	case _f0.IP < 6:
+	case _f0.IP < 7:
 		switch {
 		case _f0.IP < 2:
-			_f0.X0 = 0
+			_f0.X0 = text("aé世\xff!")
 			_f0.IP = 2
 			fallthrough
-		case _f0.IP < 6:
-		_l0:
-			for ; _f0.X0 < 10; _f0.X0, _f0.IP = _f0.X0+1, 2 {
-				switch {
-				case _f0.IP < 4:
-					{
-						_f0.X1 = _f0.X0 % 2
-						if _f0.X1 == 0 {
-							continue _l0
-						}
-					}
-					_f0.IP = 4
-					fallthrough
-				case _f0.IP < 5:
-					if _f0.X0 >
-						5 {
-						break _l0
+		case _f0.IP < 7:
+			switch {
+			case _f0.IP < 3:
+				_f0.X1, _f0.X2 = 0, 0
+				_f0.IP = 3
+				fallthrough
+			case _f0.IP < 7:
+				for ; _f0.X1 < len(_f0.X0); _f0.X1, _f0.IP = _f0.X1+_f0.X2, 3 {
+					switch {
+					case _f0.IP < 4:
+						_f0.IP = 4
+						fallthrough
+					case _f0.IP < 5:
+						_f0.X3, _f0.X2 = _utf8.DecodeRuneInString(string(_f0.X0[_f0.X1:]))
+						_f0.IP = 5
+						fallthrough
+					case _f0.IP < 6:
+						coroutine.Yield[int, any](_f0.X1)
+						_f0.IP = 6
+						fallthrough
+					case _f0.IP < 7:
+						coroutine.Yield[int, any](int(_f0.X3))
 					}
-					_f0.IP = 5
-					fallthrough
-				case _f0.IP < 6:
-
-					coroutine.Yield[int, any](_f0.X0)
 				}
 			}
 		}
-		_f0.IP = 6
+		_f0.IP = 7
 		fallthrough
-	case _f0.IP < 18:
+	case _f0.IP < 11:
 		switch {
-		case _f0.IP < 7:
-			_f0.X2 = 0
-			_f0.IP = 7
+		case _f0.IP < 8:
+			_f0.X4 = "世界"
+			_f0.IP = 8
 			fallthrough
-		case _f0.IP < 18:
-		_l1:
-			for ; _f0.X2 < 2; _f0.X2, _f0.IP = _f0.X2+1, 7 {
-				switch {
-				case _f0.IP < 8:
-					_f0.X3 = 0
-					_f0.IP = 8
-					fallthrough
-				case _f0.IP < 18:
-				_l2:
-					for ; _f0.X3 < 3; _f0.X3, _f0.IP = _f0.X3+1, 8 {
-						switch {
-						case _f0.IP < 9:
-							coroutine.Yield[int, any](_f0.X3)
-							_f0.IP = 9
-							fallthrough
-						case _f0.IP < 18:
-							{
-								_f0.X4 = _f0.X3
-								switch {
-								default:
-									{
-										_f0.X5 = _f0.X4 ==
-
-											0
-										if _f0.X5 {
-											continue _l2
-										} else {
-											_f0.X6 = _f0.X4 ==
-
-												1
-											if _f0.X6 {
-												{
-													_f0.X7 = _f0.X2
-													switch {
-													default:
-														{
-															_f0.X8 = _f0.X7 ==
-
-																0
-															if _f0.X8 {
-																continue _l1
-															} else {
-																_f0.X9 = _f0.X7 ==
-
-																	1
-																if _f0.X9 {
-																	break _l1
-																}
-															}
-														}
-													}
-												}
-											}
-										}
-									}
-								}
-							}
-						}
+		case _f0.IP < 11:
+			switch {
+			case _f0.IP < 9:
+				_f0.X5, _f0.X6 = 0, 0
+				_f0.IP = 9
+				fallthrough
+			case _f0.IP < 11:
+				for ; _f0.X5 < len(_f0.X4); _f0.X5, _f0.IP = _f0.X5+_f0.X6, 9 {
+					switch {
+					case _f0.IP < 10:
+						_, _f0.X6 = _utf8.DecodeRuneInString(_f0.X4[_f0.X5:])
+						_f0.IP = 10
+						fallthrough
+					case _f0.IP < 11:
+						coroutine.Yield[int, any](_f0.X5)
 					}
 				}
 			}
@@ -1068,97 +1237,58 @@ func LoopBreakAndContinue(_ int) {
 }
 
 //go:noinline
-func RangeOverMaps(_fn0 int) {
+func LoopClosuresCapture(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP  int
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LoopClosuresCapture"`
 		X0  int
-		X1  map[int]int
+		X1  []func() int
 		X2  map[int]int
 		X3  int
-		X4  map[int]int
-		X5  []int
-		X6  []int
-		X7  int
-		X8  int
-		X9  bool
-		X10 map[int]int
-		X11 []int
-		X12 []int
+		X4  *int
+		X5  *int
+		X6  int
+		X7  bool
+		X8  *int
+		X9  *bool
+		X10 []func() int
+		X11 int
+		X12 func() int
 		X13 int
-		X14 int
-		X15 int
-		X16 bool
-		X17 map[int]struct {
-		}
-		X18 int
-		X19 map[int]struct {
-		}
-		X20 []int
-		X21 []int
-		X22 int
-		X23 int
-		X24 bool
 	} = coroutine.Push[struct {
-		IP  int
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LoopClosuresCapture"`
 		X0  int
-		X1  map[int]int
+		X1  []func() int
 		X2  map[int]int
 		X3  int
-		X4  map[int]int
-		X5  []int
-		X6  []int
-		X7  int
-		X8  int
-		X9  bool
-		X10 map[int]int
-		X11 []int
-		X12 []int
+		X4  *int
+		X5  *int
+		X6  int
+		X7  bool
+		X8  *int
+		X9  *bool
+		X10 []func() int
+		X11 int
+		X12 func() int
 		X13 int
-		X14 int
-		X15 int
-		X16 bool
-		X17 map[int]struct {
-		}
-		X18 int
-		X19 map[int]struct {
-		}
-		X20 []int
-		X21 []int
-		X22 int
-		X23 int
-		X24 bool
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP  int
+			IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LoopClosuresCapture"`
 			X0  int
-			X1  map[int]int
+			X1  []func() int
 			X2  map[int]int
 			X3  int
-			X4  map[int]int
-			X5  []int
-			X6  []int
-			X7  int
-			X8  int
-			X9  bool
-			X10 map[int]int
-			X11 []int
-			X12 []int
+			X4  *int
+			X5  *int
+			X6  int
+			X7  bool
+			X8  *int
+			X9  *bool
+			X10 []func() int
+			X11 int
+			X12 func() int
 			X13 int
-			X14 int
-			X15 int
-			X16 bool
-			X17 map[int]struct {
-			}
-			X18 int
-			X19 map[int]struct {
-			}
-			X20 []int
-			X21 []int
-			X22 int
-			X23 int
-			X24 bool
 		}{X0: _fn0}
 	}
 	defer func() {
@@ -1168,231 +1298,348 @@ func RangeOverMaps(_fn0 int) {
 	}()
 	switch {
 	case _f0.IP < 2:
-		_f0.X1 = map[int]int{}
 		_f0.IP = 2
 		fallthrough
 	case _f0.IP < 3:
-		for range _f0.X1 {
-			panic("unreachable")
-		}
+		_f0.X2 = map[int]int{1: 100}
 		_f0.IP = 3
 		fallthrough
-	case _f0.IP < 4:
-		for _ = range _f0.X1 {
-			panic("unreachable")
-		}
-		_f0.IP = 4
-		fallthrough
-	case _f0.IP < 5:
-		for _, _ = range _f0.X1 {
-			panic("unreachable")
+	case _f0.IP < 20:
+		switch {
+		case _f0.IP < 4:
+			_f0.X3 = 0
+			_f0.IP = 4
+			fallthrough
+		case _f0.IP < 20:
+			for ; _f0.X3 < _f0.X0; _f0.X3, _f0.IP = _f0.X3+1, 4 {
+				switch {
+				case _f0.IP < 5:
+					_f0.X4 = new(int)
+					_f0.IP = 5
+					fallthrough
+				case _f0.IP < 6:
+					*_f0.X4 = _f0.X3
+					_f0.IP = 6
+					fallthrough
+				case _f0.IP < 7:
+					_f0.X5 = new(int)
+					_f0.IP = 7
+					fallthrough
+				case _f0.IP < 8:
+					*_f0.X5 = *_f0.X4 * 10
+					_f0.IP = 8
+					fallthrough
+				case _f0.IP < 9:
+					_f0.X6, _f0.X7 = _f0.X2[*_f0.X4]
+					_f0.IP = 9
+					fallthrough
+				case _f0.IP < 10:
+					_f0.X8 = new(int)
+					_f0.IP = 10
+					fallthrough
+				case _f0.IP < 11:
+					*_f0.X8 = _f0.X6
+					_f0.IP = 11
+					fallthrough
+				case _f0.IP < 12:
+					_f0.X9 = new(bool)
+					_f0.IP = 12
+					fallthrough
+				case _f0.IP < 13:
+					*_f0.X9 = _f0.X7
+					_f0.IP = 13
+					fallthrough
+				case _f0.IP < 18:
+					{
+						var _p6 *bool = _f0.X9
+						var _p7 *int = _f0.X8
+						var _p8 *int = _f0.X4
+						var _p9 *int = _f0.X5
+						_f0.X1 = append(_f0.X1, func() int {
+							if *_p6 {
+								return *_p7
+							}
+							return *_p8 + *_p9
+						})
+					}
+					_f0.IP = 18
+					fallthrough
+				case _f0.IP < 19:
+					coroutine.Yield[int, any](*_f0.X4)
+					_f0.IP = 19
+					fallthrough
+				case _f0.IP < 20:
+					*_f0.X5++
+				}
+			}
 		}
-		_f0.IP = 5
-		fallthrough
-	case _f0.IP < 6:
-		_f0.X1[_f0.X0] = _f0.X0 * 10
-		_f0.IP = 6
+		_f0.IP = 20
 		fallthrough
-	case _f0.IP < 9:
+	case _f0.IP < 25:
 		switch {
-		case _f0.IP < 7:
-			_f0.X2 = _f0.X1
-			_f0.IP = 7
+		case _f0.IP < 21:
+			_f0.X10 = _f0.X1
+			_f0.IP = 21
 			fallthrough
-		case _f0.IP < 9:
+		case _f0.IP < 25:
 			switch {
-			case _f0.IP < 8:
-				_f0.X3 = 0
-				_f0.IP = 8
+			case _f0.IP < 22:
+				_f0.X11 = 0
+				_f0.IP = 22
 				fallthrough
-			case _f0.IP < 9:
-				for ; _f0.X3 < len(_f0.X2); _f0.X3, _f0.IP = _f0.X3+1, 8 {
-
-					coroutine.Yield[int, any](0)
+			case _f0.IP < 25:
+				for ; _f0.X11 < len(_f0.X10); _f0.X11, _f0.IP = _f0.X11+1, 22 {
+					switch {
+					case _f0.IP < 23:
+						_f0.X12 = _f0.X10[_f0.X11]
+						_f0.IP = 23
+						fallthrough
+					case _f0.IP < 24:
+						_f0.X13 = _f0.X12()
+						_f0.IP = 24
+						fallthrough
+					case _f0.IP < 25:
+						coroutine.Yield[int, any](_f0.X13)
+					}
 				}
 			}
 		}
-		_f0.IP = 9
+	}
+}
+
+//go:noinline
+func TypeSwitchingGenerator(_ int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.TypeSwitchingGenerator"`
+		X0 []any
+		X1 int
+		X2 any
+		X3 any
+		X4 int8
+		X5 int16
+		X6 int32
+		X7 int64
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.TypeSwitchingGenerator"`
+		X0 []any
+		X1 int
+		X2 any
+		X3 any
+		X4 int8
+		X5 int16
+		X6 int32
+		X7 int64
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.TypeSwitchingGenerator"`
+			X0 []any
+			X1 int
+			X2 any
+			X3 any
+			X4 int8
+			X5 int16
+			X6 int32
+			X7 int64
+		}{}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X0 = []any{int8(10), int16(20), int32(30), int64(40)}
+		_f0.IP = 2
 		fallthrough
 	case _f0.IP < 17:
 		switch {
-		case _f0.IP < 10:
-			_f0.X4 = _f0.X1
-			_f0.IP = 10
-			fallthrough
-		case _f0.IP < 12:
-			{
-				_f0.X5 = make([]int, 0, len(_f0.X4))
-				for _v4 := range _f0.X4 {
-					_f0.X5 = append(_f0.X5, _v4)
-				}
-			}
-			_f0.IP = 12
+		case _f0.IP < 3:
+			_f0.X1 = 0
+			_f0.IP = 3
 			fallthrough
 		case _f0.IP < 17:
-			switch {
-			case _f0.IP < 13:
-				_f0.X6 = _f0.X5
-				_f0.IP = 13
-				fallthrough
-			case _f0.IP < 17:
+			for ; _f0.X1 < len(_f0.X0); _f0.X1, _f0.IP = _f0.X1+1, 3 {
 				switch {
-				case _f0.IP < 14:
-					_f0.X7 = 0
-					_f0.IP = 14
+				case _f0.IP < 4:
+					_f0.X2 = _f0.X0[_f0.X1]
+					_f0.IP = 4
+					fallthrough
+				case _f0.IP < 8:
+					switch _f0.X2.(type) {
+					case int8:
+						coroutine.Yield[int, any](1)
+					case int16:
+						coroutine.Yield[int, any](2)
+					case int32:
+						coroutine.Yield[int, any](4)
+					case int64:
+						coroutine.Yield[int, any](8)
+					}
+					_f0.IP = 8
 					fallthrough
 				case _f0.IP < 17:
-					for ; _f0.X7 < len(_f0.X6); _f0.X7, _f0.IP = _f0.X7+1, 14 {
-						switch {
-						case _f0.IP < 15:
-							_f0.X8 = _f0.X6[_f0.X7]
-							_f0.IP = 15
-							fallthrough
-						case _f0.IP < 17:
+					switch {
+					case _f0.IP < 9:
+						_f0.X3 = _f0.X2
+						_f0.IP = 9
+						fallthrough
+					case _f0.IP < 17:
+						switch _f0.X3.(type) {
+						case int8:
+							switch {
+							case _f0.IP < 10:
+								_f0.X4 = _f0.X3.(int8)
+								_f0.IP = 10
+								fallthrough
+							case _f0.IP < 11:
+								coroutine.Yield[int, any](int(_f0.X4))
+							}
+						case int16:
+							switch {
+							case _f0.IP < 12:
+								_f0.X5 = _f0.X3.(int16)
+								_f0.IP = 12
+								fallthrough
+							case _f0.IP < 13:
+								coroutine.Yield[int, any](int(_f0.X5))
+							}
+						case int32:
+							switch {
+							case _f0.IP < 14:
+								_f0.X6 = _f0.X3.(int32)
+								_f0.IP = 14
+								fallthrough
+							case _f0.IP < 15:
+								coroutine.Yield[int, any](int(_f0.X6))
+							}
+						case int64:
 							switch {
 							case _f0.IP < 16:
-								_, _f0.X9 = _f0.X4[_f0.X8]
+								_f0.X7 = _f0.X3.(int64)
 								_f0.IP = 16
 								fallthrough
 							case _f0.IP < 17:
-								if _f0.X9 {
-
-									coroutine.Yield[int, any](_f0.X8)
-								}
+								coroutine.Yield[int, any](int(_f0.X7))
 							}
 						}
 					}
 				}
 			}
 		}
-		_f0.IP = 17
+	}
+}
+
+//go:noinline
+func TypeSwitchBindingGenerator(_ int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.TypeSwitchBindingGenerator"`
+		X0 []any
+		X1 int
+		X2 any
+		X3 any
+		X4 int8
+		X5 string
+		X6 any
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.TypeSwitchBindingGenerator"`
+		X0 []any
+		X1 int
+		X2 any
+		X3 any
+		X4 int8
+		X5 string
+		X6 any
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.TypeSwitchBindingGenerator"`
+			X0 []any
+			X1 int
+			X2 any
+			X3 any
+			X4 int8
+			X5 string
+			X6 any
+		}{}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X0 = []any{int8(1), "abc", 2.5}
+		_f0.IP = 2
 		fallthrough
-	case _f0.IP < 26:
+	case _f0.IP < 15:
 		switch {
-		case _f0.IP < 18:
-			_f0.X10 = _f0.X1
-			_f0.IP = 18
-			fallthrough
-		case _f0.IP < 20:
-			{
-				_f0.X11 = make([]int, 0, len(_f0.X10))
-				for _v10 := range _f0.X10 {
-					_f0.X11 = append(_f0.X11, _v10)
-				}
-			}
-			_f0.IP = 20
+		case _f0.IP < 3:
+			_f0.X1 = 0
+			_f0.IP = 3
 			fallthrough
-		case _f0.IP < 26:
-			switch {
-			case _f0.IP < 21:
-				_f0.X12 = _f0.X11
-				_f0.IP = 21
-				fallthrough
-			case _f0.IP < 26:
+		case _f0.IP < 15:
+			for ; _f0.X1 < len(_f0.X0); _f0.X1, _f0.IP = _f0.X1+1, 3 {
 				switch {
-				case _f0.IP < 22:
-					_f0.X13 = 0
-					_f0.IP = 22
+				case _f0.IP < 4:
+					_f0.X2 = _f0.X0[_f0.X1]
+					_f0.IP = 4
 					fallthrough
-				case _f0.IP < 26:
-					for ; _f0.X13 < len(_f0.X12); _f0.X13, _f0.IP = _f0.X13+1, 22 {
-						switch {
-						case _f0.IP < 23:
-							_f0.X14 = _f0.X12[_f0.X13]
-							_f0.IP = 23
-							fallthrough
-						case _f0.IP < 26:
+				case _f0.IP < 15:
+					switch {
+					case _f0.IP < 5:
+						_f0.X3 = _f0.X2
+						_f0.IP = 5
+						fallthrough
+					case _f0.IP < 15:
+						switch _f0.X3.(type) {
+						case int8:
 							switch {
-							case _f0.IP < 24:
-								_f0.X15, _f0.X16 = _f0.X10[_f0.X14]
-								_f0.IP = 24
+							case _f0.IP < 6:
+								_f0.X4 = _f0.X3.(int8)
+								_f0.IP = 6
 								fallthrough
-							case _f0.IP < 26:
-								if _f0.X16 {
-									switch {
-									case _f0.IP < 25:
-
-										coroutine.Yield[int, any](_f0.X14)
-										_f0.IP = 25
-										fallthrough
-									case _f0.IP < 26:
-										coroutine.Yield[int, any](_f0.X15)
-									}
-								}
+							case _f0.IP < 7:
+								_f0.X4 *= 10
+								_f0.IP = 7
+								fallthrough
+							case _f0.IP < 8:
+								coroutine.Yield[int, any](int(_f0.X4))
+								_f0.IP = 8
+								fallthrough
+							case _f0.IP < 9:
+								coroutine.Yield[int, any](int(_f0.X4) + 1)
 							}
-						}
-					}
-				}
-			}
-		}
-		_f0.IP = 26
-		fallthrough
-	case _f0.IP < 27:
-		_f0.X17 = make(map[int]struct{}, _f0.X0)
-		_f0.IP = 27
-		fallthrough
-	case _f0.IP < 28:
-		for _f0.X18 = 0; _f0.X18 < _f0.X0; _f0.X18++ {
-			_f0.X17[_f0.X18] = struct{}{}
-		}
-		_f0.IP = 28
-		fallthrough
-	case _f0.IP < 29:
-		coroutine.Yield[int, any](len(_f0.X17))
-		_f0.IP = 29
-		fallthrough
-	case _f0.IP < 38:
-		switch {
-		case _f0.IP < 30:
-			_f0.X19 = _f0.X17
-			_f0.IP = 30
-			fallthrough
-		case _f0.IP < 32:
-			{
-				_f0.X20 = make([]int, 0, len(_f0.X19))
-				for _v16 := range _f0.X19 {
-					_f0.X20 = append(_f0.X20, _v16)
-				}
-			}
-			_f0.IP = 32
-			fallthrough
-		case _f0.IP < 38:
-			switch {
-			case _f0.IP < 33:
-				_f0.X21 = _f0.X20
-				_f0.IP = 33
-				fallthrough
-			case _f0.IP < 38:
-				switch {
-				case _f0.IP < 34:
-					_f0.X22 = 0
-					_f0.IP = 34
-					fallthrough
-				case _f0.IP < 38:
-					for ; _f0.X22 < len(_f0.X21); _f0.X22, _f0.IP = _f0.X22+1, 34 {
-						switch {
-						case _f0.IP < 35:
-							_f0.X23 = _f0.X21[_f0.X22]
-							_f0.IP = 35
-							fallthrough
-						case _f0.IP < 38:
+						case string:
 							switch {
-							case _f0.IP < 36:
-								_, _f0.X24 = _f0.X19[_f0.X23]
-								_f0.IP = 36
+							case _f0.IP < 10:
+								_f0.X5 = _f0.X3.(string)
+								_f0.IP = 10
 								fallthrough
-							case _f0.IP < 38:
-								if _f0.X24 {
-									switch {
-									case _f0.IP < 37:
+							case _f0.IP < 11:
+								coroutine.Yield[int, any](len(_f0.X5))
+								_f0.IP = 11
+								fallthrough
+							case _f0.IP < 12:
+								_f0.X5 += "d"
+								_f0.IP = 12
+								fallthrough
+							case _f0.IP < 13:
+								coroutine.Yield[int, any](len(_f0.X5))
+							}
+						default:
+							switch {
+							case _f0.IP < 14:
+								_f0.X6 = _f0.X3
+								_f0.IP = 14
+								fallthrough
+							case _f0.IP < 15:
 
-										delete(_f0.X17, _f0.X23)
-										_f0.IP = 37
-										fallthrough
-									case _f0.IP < 38:
-										coroutine.Yield[int, any](len(_f0.X17))
-									}
-								}
+								coroutine.Yield[int, any](-1)
 							}
 						}
 					}
@@ -1403,70 +1650,47 @@ func RangeOverMaps(_fn0 int) {
 }
 
 //go:noinline
-func Range(_fn0 int, _fn1 func(int)) {
+func LoopBreakAndContinue(_ int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LoopBreakAndContinue"`
 		X0 int
-		X1 func(int)
+		X1 int
 		X2 int
+		X3 int
+		X4 int
+		X5 bool
+		X6 bool
+		X7 int
+		X8 bool
+		X9 bool
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LoopBreakAndContinue"`
 		X0 int
-		X1 func(int)
+		X1 int
 		X2 int
+		X3 int
+		X4 int
+		X5 bool
+		X6 bool
+		X7 int
+		X8 bool
+		X9 bool
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
-			X0 int
-			X1 func(int)
-			X2 int
-		}{X0: _fn0, X1: _fn1}
-	}
-	defer func() {
-		if !_c.Unwinding() {
-			coroutine.Pop(&_c.Stack)
-		}
-	}()
-	switch {
-	case _f0.IP < 2:
-		_f0.X2 = 0
-		_f0.IP = 2
-		fallthrough
-	case _f0.IP < 3:
-		for ; _f0.X2 < _f0.X0; _f0.X2, _f0.IP = _f0.X2+1, 2 {
-			_f0.X1(_f0.X2)
-		}
-	}
-}
-
-//go:noinline
-func Double(n int) { coroutine.Yield[int, any](2 * n) }
-
-//go:noinline
-func RangeTriple(n int) {
-	Range(n, func(i int) { coroutine.Yield[int, any](3 * i) })
-}
-
-//go:noinline
-func RangeTripleFuncValue(_fn0 int) {
-	_c := coroutine.LoadContext[int, any]()
-	var _f0 *struct {
-		IP int
-		X0 int
-		X1 func(int)
-	} = coroutine.Push[struct {
-		IP int
-		X0 int
-		X1 func(int)
-	}](&_c.Stack)
-	if _f0.IP == 0 {
-		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LoopBreakAndContinue"`
 			X0 int
-			X1 func(int)
-		}{X0: _fn0}
+			X1 int
+			X2 int
+			X3 int
+			X4 int
+			X5 bool
+			X6 bool
+			X7 int
+			X8 bool
+			X9 bool
+		}{}
 	}
 	defer func() {
 		if !_c.Unwinding() {
@@ -1474,36 +1698,141 @@ func RangeTripleFuncValue(_fn0 int) {
 		}
 	}()
 	switch {
-	case _f0.IP < 2:
-		_f0.X1 = func(i int) { coroutine.Yield[int, any](3 * i) }
-		_f0.IP = 2
+	case _f0.IP < 6:
+		switch {
+		case _f0.IP < 2:
+			_f0.X0 = 0
+			_f0.IP = 2
+			fallthrough
+		case _f0.IP < 6:
+		_l0:
+			for ; _f0.X0 < 10; _f0.X0, _f0.IP = _f0.X0+1, 2 {
+				switch {
+				case _f0.IP < 4:
+					{
+						_f0.X1 = _f0.X0 % 2
+						if _f0.X1 == 0 {
+							continue _l0
+						}
+					}
+					_f0.IP = 4
+					fallthrough
+				case _f0.IP < 5:
+					if _f0.X0 >
+						5 {
+						break _l0
+					}
+					_f0.IP = 5
+					fallthrough
+				case _f0.IP < 6:
+
+					coroutine.Yield[int, any](_f0.X0)
+				}
+			}
+		}
+		_f0.IP = 6
 		fallthrough
-	case _f0.IP < 3:
+	case _f0.IP < 18:
+		switch {
+		case _f0.IP < 7:
+			_f0.X2 = 0
+			_f0.IP = 7
+			fallthrough
+		case _f0.IP < 18:
+		_l1:
+			for ; _f0.X2 < 2; _f0.X2, _f0.IP = _f0.X2+1, 7 {
+				switch {
+				case _f0.IP < 8:
+					_f0.X3 = 0
+					_f0.IP = 8
+					fallthrough
+				case _f0.IP < 18:
+				_l2:
+					for ; _f0.X3 < 3; _f0.X3, _f0.IP = _f0.X3+1, 8 {
+						switch {
+						case _f0.IP < 9:
+							coroutine.Yield[int, any](_f0.X3)
+							_f0.IP = 9
+							fallthrough
+						case _f0.IP < 18:
+							{
+								_f0.X4 = _f0.X3
+								switch {
+								default:
+									{
+										_f0.X5 = _f0.X4 ==
 
-		Range(_f0.X0, _f0.X1)
+											0
+										if _f0.X5 {
+											continue _l2
+										} else {
+											_f0.X6 = _f0.X4 ==
+
+												1
+											if _f0.X6 {
+												{
+													_f0.X7 = _f0.X2
+													switch {
+													default:
+														{
+															_f0.X8 = _f0.X7 ==
+
+																0
+															if _f0.X8 {
+																continue _l1
+															} else {
+																_f0.X9 = _f0.X7 ==
+
+																	1
+																if _f0.X9 {
+																	break _l1
+																}
+															}
+														}
+													}
+												}
+											}
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
 	}
 }
 
 //go:noinline
-func RangeReverseClosureCaptureByValue(_fn0 int) {
+func LabeledBreakFromSwitch(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LabeledBreakFromSwitch"`
 		X0 int
 		X1 int
-		X2 func()
+		X2 bool
+		X3 int
+		X4 int
+		X5 bool
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LabeledBreakFromSwitch"`
 		X0 int
 		X1 int
-		X2 func()
+		X2 bool
+		X3 int
+		X4 int
+		X5 bool
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LabeledBreakFromSwitch"`
 			X0 int
 			X1 int
-			X2 func()
+			X2 bool
+			X3 int
+			X4 int
+			X5 bool
 		}{X0: _fn0}
 	}
 	defer func() {
@@ -1512,168 +1841,210 @@ func RangeReverseClosureCaptureByValue(_fn0 int) {
 		}
 	}()
 	switch {
-	case _f0.IP < 2:
-		_f0.X1 = 0
-		_f0.IP = 2
-		fallthrough
-	case _f0.IP < 3:
-		_f0.X2 = func() { coroutine.Yield[int, any](_f0.X0 - (_f0.X1 + 1)) }
-		_f0.IP = 3
-		fallthrough
-	case _f0.IP < 5:
-		for ; _f0.X1 < _f0.X0; _f0.IP = 3 {
-			switch {
-			case _f0.IP < 4:
-				_f0.X2()
-				_f0.IP = 4
-				fallthrough
-			case _f0.IP < 5:
-				_f0.X1++
+	case _f0.IP < 9:
+		switch {
+		case _f0.IP < 2:
+			_f0.X1 = 0
+			_f0.IP = 2
+			fallthrough
+		case _f0.IP < 9:
+		_l0:
+			for ; _f0.X1 < _f0.X0; _f0.X1, _f0.IP = _f0.X1+1, 2 {
+				switch {
+				case _f0.IP < 8:
+				_l1:
+					for ; ; _f0.IP = 2 {
+						switch {
+						case _f0.IP < 7:
+						_l2:
+							switch {
+							default:
+								switch {
+								case _f0.IP < 3:
+									_f0.X2 = _f0.X1 ==
+										2
+									_f0.IP = 3
+									fallthrough
+								case _f0.IP < 7:
+									if _f0.X2 {
+										switch {
+										case _f0.IP < 4:
+											coroutine.Yield[int, any](-1)
+											_f0.IP = 4
+											fallthrough
+										case _f0.IP < 5:
+											break _l0
+										}
+									} else {
+										switch {
+										case _f0.IP < 6:
+
+											coroutine.Yield[int, any](_f0.X1)
+											_f0.IP = 6
+											fallthrough
+										case _f0.IP < 7:
+											break _l2
+										}
+									}
+								}
+							}
+							_f0.IP = 7
+							fallthrough
+						case _f0.IP < 8:
+							break _l1
+						}
+					}
+					_f0.IP = 8
+					fallthrough
+				case _f0.IP < 9:
+
+					coroutine.Yield[int, any](_f0.X1 * 10)
+				}
 			}
 		}
-	}
-}
+		_f0.IP = 9
+		fallthrough
+	case _f0.IP < 14:
+		switch {
+		case _f0.IP < 10:
+			_f0.X3 = 0
+			_f0.IP = 10
+			fallthrough
+		case _f0.IP < 14:
+		_l3:
+			for ; ; _f0.X3, _f0.IP = _f0.X3+1, 10 {
+				switch {
+				case _f0.IP < 11:
+					_f0.X4 = _f0.X3
+					_f0.IP = 11
+					fallthrough
+				case _f0.IP < 14:
+					switch {
+					default:
+						switch {
+						case _f0.IP < 12:
+							_f0.X5 = _f0.X4 ==
 
-//go:noinline
-func Range10ClosureCapturingValues() {
-	_c := coroutine.LoadContext[int, any]()
-	var _f1 *struct {
-		IP int
-		X0 int
-		X1 int
-		X2 func() bool
-		X3 bool
-		X4 bool
-	} = coroutine.Push[struct {
-		IP int
-		X0 int
-		X1 int
-		X2 func() bool
-		X3 bool
-		X4 bool
-	}](&_c.Stack)
-	if _f1.IP == 0 {
-		*_f1 = struct {
-			IP int
-			X0 int
-			X1 int
-			X2 func() bool
-			X3 bool
-			X4 bool
-		}{}
-	}
-	defer func() {
-		if !_c.Unwinding() {
-			coroutine.Pop(&_c.Stack)
-		}
-	}()
-	switch {
-	case _f1.IP < 2:
-		_f1.X0 = 0
-		_f1.IP = 2
-		fallthrough
-	case _f1.IP < 3:
-		_f1.X1 = 10
-		_f1.IP = 3
-		fallthrough
-	case _f1.IP < 4:
-		_f1.X2 = func() (_ bool) {
-			_c := coroutine.LoadContext[int, any]()
-			var _f0 *struct {
-				IP int
-			} = coroutine.Push[struct {
-				IP int
-			}](&_c.Stack)
-			if _f0.IP == 0 {
-				*_f0 = struct {
-					IP int
-				}{}
-			}
-			defer func() {
-				if !_c.Unwinding() {
-					coroutine.Pop(&_c.Stack)
-				}
-			}()
-			switch {
-			case _f0.IP < 4:
-				if _f1.X0 < _f1.X1 {
-					switch {
-					case _f0.IP < 2:
-						coroutine.Yield[int, any](_f1.X0)
-						_f0.IP = 2
-						fallthrough
-					case _f0.IP < 3:
-						_f1.X0++
-						_f0.IP = 3
-						fallthrough
-					case _f0.IP < 4:
-						return true
+								3
+							_f0.IP = 12
+							fallthrough
+						case _f0.IP < 14:
+							if _f0.X5 {
+								switch {
+								case _f0.IP < 13:
+									coroutine.Yield[int, any](_f0.X3)
+									_f0.IP = 13
+									fallthrough
+								case _f0.IP < 14:
+									break _l3
+								}
+							}
+						}
 					}
 				}
-				_f0.IP = 4
-				fallthrough
-			case _f0.IP < 5:
-
-				return false
 			}
-			return
 		}
-		_f1.IP = 4
+		_f0.IP = 14
 		fallthrough
-	case _f1.IP < 7:
-	_l0:
-		for ; ; _f1.IP = 4 {
-			switch {
-			case _f1.IP < 5:
-				_f1.X3 = _f1.X2()
-				_f1.IP = 5
-				fallthrough
-			case _f1.IP < 6:
-				_f1.X4 = !_f1.X3
-				_f1.IP = 6
-				fallthrough
-			case _f1.IP < 7:
-				if _f1.X4 {
-					break _l0
-				}
-			}
-		}
+	case _f0.IP < 15:
+
+		coroutine.Yield[int, any](100)
 	}
 }
 
 //go:noinline
-func Range10ClosureCapturingPointers() {
+func RangeOverMaps(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
-	var _f1 *struct {
-		IP int
-		X0 int
-		X1 int
-		X2 *int
-		X3 *int
-		X4 func() bool
-		X5 bool
-		X6 bool
+	var _f0 *struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeOverMaps"`
+		X0  int
+		X1  map[int]int
+		X2  map[int]int
+		X3  int
+		X4  map[int]int
+		X5  []int
+		X6  []int
+		X7  int
+		X8  int
+		X9  bool
+		X10 map[int]int
+		X11 []int
+		X12 []int
+		X13 int
+		X14 int
+		X15 int
+		X16 bool
+		X17 map[int]struct {
+		}
+		X18 int
+		X19 map[int]struct {
+		}
+		X20 []int
+		X21 []int
+		X22 int
+		X23 int
+		X24 bool
 	} = coroutine.Push[struct {
-		IP int
-		X0 int
-		X1 int
-		X2 *int
-		X3 *int
-		X4 func() bool
-		X5 bool
-		X6 bool
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeOverMaps"`
+		X0  int
+		X1  map[int]int
+		X2  map[int]int
+		X3  int
+		X4  map[int]int
+		X5  []int
+		X6  []int
+		X7  int
+		X8  int
+		X9  bool
+		X10 map[int]int
+		X11 []int
+		X12 []int
+		X13 int
+		X14 int
+		X15 int
+		X16 bool
+		X17 map[int]struct {
+		}
+		X18 int
+		X19 map[int]struct {
+		}
+		X20 []int
+		X21 []int
+		X22 int
+		X23 int
+		X24 bool
 	}](&_c.Stack)
-	if _f1.IP == 0 {
-		*_f1 = struct {
-			IP int
-			X0 int
-			X1 int
-			X2 *int
-			X3 *int
-			X4 func() bool
-			X5 bool
-			X6 bool
-		}{}
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeOverMaps"`
+			X0  int
+			X1  map[int]int
+			X2  map[int]int
+			X3  int
+			X4  map[int]int
+			X5  []int
+			X6  []int
+			X7  int
+			X8  int
+			X9  bool
+			X10 map[int]int
+			X11 []int
+			X12 []int
+			X13 int
+			X14 int
+			X15 int
+			X16 bool
+			X17 map[int]struct {
+			}
+			X18 int
+			X19 map[int]struct {
+			}
+			X20 []int
+			X21 []int
+			X22 int
+			X23 int
+			X24 bool
+		}{X0: _fn0}
 	}
 	defer func() {
 		if !_c.Unwinding() {
@@ -1681,210 +2052,3153 @@ func Range10ClosureCapturingPointers() {
 		}
 	}()
 	switch {
-	case _f1.IP < 2:
-		_f1.X0, _f1.X1 = 0, 10
-		_f1.IP = 2
+	case _f0.IP < 2:
+		_f0.X1 = map[int]int{}
+		_f0.IP = 2
 		fallthrough
-	case _f1.IP < 3:
-		_f1.X2 = &_f1.X0
-		_f1.IP = 3
+	case _f0.IP < 3:
+		for range _f0.X1 {
+			panic("unreachable")
+		}
+		_f0.IP = 3
 		fallthrough
-	case _f1.IP < 4:
-		_f1.X3 = &_f1.X1
-		_f1.IP = 4
+	case _f0.IP < 4:
+		for _ = range _f0.X1 {
+			panic("unreachable")
+		}
+		_f0.IP = 4
 		fallthrough
-	case _f1.IP < 5:
-		_f1.X4 = func() (_ bool) {
-			_c := coroutine.LoadContext[int, any]()
-			var _f0 *struct {
-				IP int
-			} = coroutine.Push[struct {
-				IP int
-			}](&_c.Stack)
-			if _f0.IP == 0 {
-				*_f0 = struct {
-					IP int
-				}{}
-			}
-			defer func() {
-				if !_c.Unwinding() {
-					coroutine.Pop(&_c.Stack)
-				}
-			}()
-			switch {
-			case _f0.IP < 4:
-				if *_f1.X2 < *_f1.X3 {
-					switch {
-					case _f0.IP < 2:
-						coroutine.Yield[int, any](*_f1.X2)
-						_f0.IP = 2
-						fallthrough
-					case _f0.IP < 3:
-						(*_f1.X2)++
-						_f0.IP = 3
-						fallthrough
-					case _f0.IP < 4:
+	case _f0.IP < 5:
+		for _, _ = range _f0.X1 {
+			panic("unreachable")
+		}
+		_f0.IP = 5
+		fallthrough
+	case _f0.IP < 6:
+		_f0.X1[_f0.X0] = _f0.X0 * 10
+		_f0.IP = 6
+		fallthrough
+	case _f0.IP < 9:
+		switch {
+		case _f0.IP < 7:
+			_f0.X2 = _f0.X1
+			_f0.IP = 7
+			fallthrough
+		case _f0.IP < 9:
+			switch {
+			case _f0.IP < 8:
+				_f0.X3 = 0
+				_f0.IP = 8
+				fallthrough
+			case _f0.IP < 9:
+				for ; _f0.X3 < len(_f0.X2); _f0.X3, _f0.IP = _f0.X3+1, 8 {
+
+					coroutine.Yield[int, any](0)
+				}
+			}
+		}
+		_f0.IP = 9
+		fallthrough
+	case _f0.IP < 17:
+		switch {
+		case _f0.IP < 10:
+			_f0.X4 = _f0.X1
+			_f0.IP = 10
+			fallthrough
+		case _f0.IP < 12:
+			{
+				_f0.X5 = make([]int, 0, len(_f0.X4))
+				for _v4 := range _f0.X4 {
+					_f0.X5 = append(_f0.X5, _v4)
+				}
+			}
+			_f0.IP = 12
+			fallthrough
+		case _f0.IP < 17:
+			switch {
+			case _f0.IP < 13:
+				_f0.X6 = _f0.X5
+				_f0.IP = 13
+				fallthrough
+			case _f0.IP < 17:
+				switch {
+				case _f0.IP < 14:
+					_f0.X7 = 0
+					_f0.IP = 14
+					fallthrough
+				case _f0.IP < 17:
+					for ; _f0.X7 < len(_f0.X6); _f0.X7, _f0.IP = _f0.X7+1, 14 {
+						switch {
+						case _f0.IP < 15:
+							_f0.X8 = _f0.X6[_f0.X7]
+							_f0.IP = 15
+							fallthrough
+						case _f0.IP < 17:
+							switch {
+							case _f0.IP < 16:
+								_, _f0.X9 = _f0.X4[_f0.X8]
+								_f0.IP = 16
+								fallthrough
+							case _f0.IP < 17:
+								if _f0.X9 {
+
+									coroutine.Yield[int, any](_f0.X8)
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+		_f0.IP = 17
+		fallthrough
+	case _f0.IP < 26:
+		switch {
+		case _f0.IP < 18:
+			_f0.X10 = _f0.X1
+			_f0.IP = 18
+			fallthrough
+		case _f0.IP < 20:
+			{
+				_f0.X11 = make([]int, 0, len(_f0.X10))
+				for _v10 := range _f0.X10 {
+					_f0.X11 = append(_f0.X11, _v10)
+				}
+			}
+			_f0.IP = 20
+			fallthrough
+		case _f0.IP < 26:
+			switch {
+			case _f0.IP < 21:
+				_f0.X12 = _f0.X11
+				_f0.IP = 21
+				fallthrough
+			case _f0.IP < 26:
+				switch {
+				case _f0.IP < 22:
+					_f0.X13 = 0
+					_f0.IP = 22
+					fallthrough
+				case _f0.IP < 26:
+					for ; _f0.X13 < len(_f0.X12); _f0.X13, _f0.IP = _f0.X13+1, 22 {
+						switch {
+						case _f0.IP < 23:
+							_f0.X14 = _f0.X12[_f0.X13]
+							_f0.IP = 23
+							fallthrough
+						case _f0.IP < 26:
+							switch {
+							case _f0.IP < 24:
+								_f0.X15, _f0.X16 = _f0.X10[_f0.X14]
+								_f0.IP = 24
+								fallthrough
+							case _f0.IP < 26:
+								if _f0.X16 {
+									switch {
+									case _f0.IP < 25:
+
+										coroutine.Yield[int, any](_f0.X14)
+										_f0.IP = 25
+										fallthrough
+									case _f0.IP < 26:
+										coroutine.Yield[int, any](_f0.X15)
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+		_f0.IP = 26
+		fallthrough
+	case _f0.IP < 27:
+		_f0.X17 = make(map[int]struct{}, _f0.X0)
+		_f0.IP = 27
+		fallthrough
+	case _f0.IP < 28:
+		for _f0.X18 = 0; _f0.X18 < _f0.X0; _f0.X18++ {
+			_f0.X17[_f0.X18] = struct{}{}
+		}
+		_f0.IP = 28
+		fallthrough
+	case _f0.IP < 29:
+		coroutine.Yield[int, any](len(_f0.X17))
+		_f0.IP = 29
+		fallthrough
+	case _f0.IP < 38:
+		switch {
+		case _f0.IP < 30:
+			_f0.X19 = _f0.X17
+			_f0.IP = 30
+			fallthrough
+		case _f0.IP < 32:
+			{
+				_f0.X20 = make([]int, 0, len(_f0.X19))
+				for _v16 := range _f0.X19 {
+					_f0.X20 = append(_f0.X20, _v16)
+				}
+			}
+			_f0.IP = 32
+			fallthrough
+		case _f0.IP < 38:
+			switch {
+			case _f0.IP < 33:
+				_f0.X21 = _f0.X20
+				_f0.IP = 33
+				fallthrough
+			case _f0.IP < 38:
+				switch {
+				case _f0.IP < 34:
+					_f0.X22 = 0
+					_f0.IP = 34
+					fallthrough
+				case _f0.IP < 38:
+					for ; _f0.X22 < len(_f0.X21); _f0.X22, _f0.IP = _f0.X22+1, 34 {
+						switch {
+						case _f0.IP < 35:
+							_f0.X23 = _f0.X21[_f0.X22]
+							_f0.IP = 35
+							fallthrough
+						case _f0.IP < 38:
+							switch {
+							case _f0.IP < 36:
+								_, _f0.X24 = _f0.X19[_f0.X23]
+								_f0.IP = 36
+								fallthrough
+							case _f0.IP < 38:
+								if _f0.X24 {
+									switch {
+									case _f0.IP < 37:
+
+										delete(_f0.X17, _f0.X23)
+										_f0.IP = 37
+										fallthrough
+									case _f0.IP < 38:
+										coroutine.Yield[int, any](len(_f0.X17))
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+//go:noinline
+func Range(_fn0 int, _fn1 func(int)) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range"`
+		X0 int
+		X1 func(int)
+		X2 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range"`
+		X0 int
+		X1 func(int)
+		X2 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range"`
+			X0 int
+			X1 func(int)
+			X2 int
+		}{X0: _fn0, X1: _fn1}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X2 = 0
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		for ; _f0.X2 < _f0.X0; _f0.X2, _f0.IP = _f0.X2+1, 2 {
+			_f0.X1(_f0.X2)
+		}
+	}
+}
+
+//go:noinline
+func Double(n int) { coroutine.Yield[int, any](2 * n) }
+
+//go:noinline
+func RangeTriple(n int) {
+	Range(n, func(i int) { coroutine.Yield[int, any](3 * i) })
+}
+
+//go:noinline
+func RangeTripleFuncValue(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeTripleFuncValue"`
+		X0 int
+		X1 func(int)
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeTripleFuncValue"`
+		X0 int
+		X1 func(int)
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeTripleFuncValue"`
+			X0 int
+			X1 func(int)
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = func(i int) { coroutine.Yield[int, any](3 * i) }
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+
+		Range(_f0.X0, _f0.X1)
+	}
+}
+
+//go:noinline
+func RangeReverseClosureCaptureByValue(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeReverseClosureCaptureByValue"`
+		X0 int
+		X1 int
+		X2 func()
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeReverseClosureCaptureByValue"`
+		X0 int
+		X1 int
+		X2 func()
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeReverseClosureCaptureByValue"`
+			X0 int
+			X1 int
+			X2 func()
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = 0
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		_f0.X2 = func() { coroutine.Yield[int, any](_f0.X0 - (_f0.X1 + 1)) }
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 5:
+		for ; _f0.X1 < _f0.X0; _f0.IP = 3 {
+			switch {
+			case _f0.IP < 4:
+				_f0.X2()
+				_f0.IP = 4
+				fallthrough
+			case _f0.IP < 5:
+				_f0.X1++
+			}
+		}
+	}
+}
+
+//go:noinline
+func Range10ClosureCapturingValues() {
+	_c := coroutine.LoadContext[int, any]()
+	var _f1 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingValues"`
+		X0 int
+		X1 int
+		X2 func() bool
+		X3 bool
+		X4 bool
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingValues"`
+		X0 int
+		X1 int
+		X2 func() bool
+		X3 bool
+		X4 bool
+	}](&_c.Stack)
+	if _f1.IP == 0 {
+		*_f1 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingValues"`
+			X0 int
+			X1 int
+			X2 func() bool
+			X3 bool
+			X4 bool
+		}{}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f1.IP < 2:
+		_f1.X0 = 0
+		_f1.IP = 2
+		fallthrough
+	case _f1.IP < 3:
+		_f1.X1 = 10
+		_f1.IP = 3
+		fallthrough
+	case _f1.IP < 4:
+		_f1.X2 = func() (_ bool) {
+			_c := coroutine.LoadContext[int, any]()
+			var _f0 *struct {
+				IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingValues.func@coroutine.go:432:7"`
+			} = coroutine.Push[struct {
+				IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingValues.func@coroutine.go:432:7"`
+			}](&_c.Stack)
+			if _f0.IP == 0 {
+				*_f0 = struct {
+					IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingValues.func@coroutine.go:432:7"`
+				}{}
+			}
+			defer func() {
+				if !_c.Unwinding() {
+					coroutine.Pop(&_c.Stack)
+				}
+			}()
+			switch {
+			case _f0.IP < 4:
+				if _f1.X0 < _f1.X1 {
+					switch {
+					case _f0.IP < 2:
+						coroutine.Yield[int, any](_f1.X0)
+						_f0.IP = 2
+						fallthrough
+					case _f0.IP < 3:
+						_f1.X0++
+						_f0.IP = 3
+						fallthrough
+					case _f0.IP < 4:
+						return true
+					}
+				}
+				_f0.IP = 4
+				fallthrough
+			case _f0.IP < 5:
+
+				return false
+			}
+			return
+		}
+		_f1.IP = 4
+		fallthrough
+	case _f1.IP < 7:
+	_l0:
+		for ; ; _f1.IP = 4 {
+			switch {
+			case _f1.IP < 5:
+				_f1.X3 = _f1.X2()
+				_f1.IP = 5
+				fallthrough
+			case _f1.IP < 6:
+				_f1.X4 = !_f1.X3
+				_f1.IP = 6
+				fallthrough
+			case _f1.IP < 7:
+				if _f1.X4 {
+					break _l0
+				}
+			}
+		}
+	}
+}
+
+//go:noinline
+func Range10ClosureCapturingPointers() {
+	_c := coroutine.LoadContext[int, any]()
+	var _f1 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingPointers"`
+		X0 int
+		X1 int
+		X2 *int
+		X3 *int
+		X4 func() bool
+		X5 bool
+		X6 bool
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingPointers"`
+		X0 int
+		X1 int
+		X2 *int
+		X3 *int
+		X4 func() bool
+		X5 bool
+		X6 bool
+	}](&_c.Stack)
+	if _f1.IP == 0 {
+		*_f1 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingPointers"`
+			X0 int
+			X1 int
+			X2 *int
+			X3 *int
+			X4 func() bool
+			X5 bool
+			X6 bool
+		}{}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f1.IP < 2:
+		_f1.X0, _f1.X1 = 0, 10
+		_f1.IP = 2
+		fallthrough
+	case _f1.IP < 3:
+		_f1.X2 = &_f1.X0
+		_f1.IP = 3
+		fallthrough
+	case _f1.IP < 4:
+		_f1.X3 = &_f1.X1
+		_f1.IP = 4
+		fallthrough
+	case _f1.IP < 5:
+		_f1.X4 = func() (_ bool) {
+			_c := coroutine.LoadContext[int, any]()
+			var _f0 *struct {
+				IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingPointers.func@coroutine.go:449:7"`
+			} = coroutine.Push[struct {
+				IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingPointers.func@coroutine.go:449:7"`
+			}](&_c.Stack)
+			if _f0.IP == 0 {
+				*_f0 = struct {
+					IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingPointers.func@coroutine.go:449:7"`
+				}{}
+			}
+			defer func() {
+				if !_c.Unwinding() {
+					coroutine.Pop(&_c.Stack)
+				}
+			}()
+			switch {
+			case _f0.IP < 4:
+				if *_f1.X2 < *_f1.X3 {
+					switch {
+					case _f0.IP < 2:
+						coroutine.Yield[int, any](*_f1.X2)
+						_f0.IP = 2
+						fallthrough
+					case _f0.IP < 3:
+						(*_f1.X2)++
+						_f0.IP = 3
+						fallthrough
+					case _f0.IP < 4:
 						return true
 					}
 				}
-				_f0.IP = 4
+				_f0.IP = 4
+				fallthrough
+			case _f0.IP < 5:
+
+				return false
+			}
+			return
+		}
+		_f1.IP = 5
+		fallthrough
+	case _f1.IP < 8:
+	_l0:
+		for ; ; _f1.IP = 5 {
+			switch {
+			case _f1.IP < 6:
+				_f1.X5 = _f1.X4()
+				_f1.IP = 6
+				fallthrough
+			case _f1.IP < 7:
+				_f1.X6 = !_f1.X5
+				_f1.IP = 7
+				fallthrough
+			case _f1.IP < 8:
+				if _f1.X6 {
+					break _l0
+				}
+			}
+		}
+	}
+}
+
+//go:noinline
+func Range10ClosureHeterogenousCapture() {
+	_c := coroutine.LoadContext[int, any]()
+	var _f1 *struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureHeterogenousCapture"`
+		X0  int8
+		X1  int16
+		X2  int32
+		X3  int64
+		X4  uint8
+		X5  uint16
+		X6  uint32
+		X7  uint64
+		X8  uintptr
+		X9  func() int
+		X10 int
+		X11 func() bool
+		X12 bool
+		X13 bool
+	} = coroutine.Push[struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureHeterogenousCapture"`
+		X0  int8
+		X1  int16
+		X2  int32
+		X3  int64
+		X4  uint8
+		X5  uint16
+		X6  uint32
+		X7  uint64
+		X8  uintptr
+		X9  func() int
+		X10 int
+		X11 func() bool
+		X12 bool
+		X13 bool
+	}](&_c.Stack)
+	if _f1.IP == 0 {
+		*_f1 = struct {
+			IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureHeterogenousCapture"`
+			X0  int8
+			X1  int16
+			X2  int32
+			X3  int64
+			X4  uint8
+			X5  uint16
+			X6  uint32
+			X7  uint64
+			X8  uintptr
+			X9  func() int
+			X10 int
+			X11 func() bool
+			X12 bool
+			X13 bool
+		}{}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f1.IP < 11:
+		{
+			_f1.X0 = 0
+			_f1.X1 = 1
+			_f1.X2 = 2
+			_f1.X3 = 3
+			_f1.X4 = 4
+			_f1.X5 = 5
+			_f1.X6 = 6
+			_f1.X7 = 7
+			_f1.X8 = 8
+			_f1.X9 = func() int { return int(_f1.X8) + 1 }
+		}
+		_f1.IP = 11
+		fallthrough
+	case _f1.IP < 12:
+		_f1.X10 = 0
+		_f1.IP = 12
+		fallthrough
+	case _f1.IP < 13:
+		_f1.X11 = func() (_ bool) {
+			_c := coroutine.LoadContext[int, any]()
+			var _f0 *struct {
+				IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureHeterogenousCapture.func@coroutine.go:477:7"`
+				X0  int
+				X1  int
+				X2  bool
+				X3  bool
+				X4  bool
+				X5  bool
+				X6  bool
+				X7  bool
+				X8  bool
+				X9  bool
+				X10 bool
+				X11 bool
+			} = coroutine.Push[struct {
+				IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureHeterogenousCapture.func@coroutine.go:477:7"`
+				X0  int
+				X1  int
+				X2  bool
+				X3  bool
+				X4  bool
+				X5  bool
+				X6  bool
+				X7  bool
+				X8  bool
+				X9  bool
+				X10 bool
+				X11 bool
+			}](&_c.Stack)
+			if _f0.IP == 0 {
+				*_f0 = struct {
+					IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureHeterogenousCapture.func@coroutine.go:477:7"`
+					X0  int
+					X1  int
+					X2  bool
+					X3  bool
+					X4  bool
+					X5  bool
+					X6  bool
+					X7  bool
+					X8  bool
+					X9  bool
+					X10 bool
+					X11 bool
+				}{}
+			}
+			defer func() {
+				if !_c.Unwinding() {
+					coroutine.Pop(&_c.Stack)
+				}
+			}()
+			switch {
+			case _f0.IP < 2:
+				_f0.IP = 2
+				fallthrough
+			case _f0.IP < 13:
+				switch {
+				case _f0.IP < 3:
+					_f0.X1 = _f1.X10
+					_f0.IP = 3
+					fallthrough
+				case _f0.IP < 13:
+					switch {
+					default:
+						if _f0.X2 = _f0.X1 ==
+
+							0; _f0.X2 {
+							_f0.X0 = int(_f1.X0)
+						} else if _f0.X3 = _f0.X1 ==
+							1; _f0.X3 {
+							_f0.X0 = int(_f1.X1)
+						} else if _f0.X4 = _f0.X1 ==
+							2; _f0.X4 {
+							_f0.X0 = int(_f1.X2)
+						} else if _f0.X5 = _f0.X1 ==
+							3; _f0.X5 {
+							_f0.X0 = int(_f1.X3)
+						} else if _f0.X6 = _f0.X1 ==
+							4; _f0.X6 {
+							_f0.X0 = int(_f1.X4)
+						} else if _f0.X7 = _f0.X1 ==
+							5; _f0.X7 {
+							_f0.X0 = int(_f1.X5)
+						} else if _f0.X8 = _f0.X1 ==
+							6; _f0.X8 {
+							_f0.X0 = int(_f1.X6)
+						} else if _f0.X9 = _f0.X1 ==
+							7; _f0.X9 {
+							_f0.X0 = int(_f1.X7)
+						} else if _f0.X10 = _f0.X1 ==
+							8; _f0.X10 {
+							_f0.X0 = int(_f1.X8)
+						} else if _f0.X11 = _f0.X1 ==
+							9; _f0.X11 {
+							_f0.X0 = _f1.X9()
+						}
+					}
+				}
+				_f0.IP = 13
+				fallthrough
+			case _f0.IP < 14:
+
+				coroutine.Yield[int, any](_f0.X0)
+				_f0.IP = 14
+				fallthrough
+			case _f0.IP < 15:
+				_f1.X10++
+				_f0.IP = 15
+				fallthrough
+			case _f0.IP < 16:
+				return _f1.X10 < 10
+			}
+			return
+		}
+		_f1.IP = 13
+		fallthrough
+	case _f1.IP < 16:
+	_l0:
+		for ; ; _f1.IP = 13 {
+			switch {
+			case _f1.IP < 14:
+				_f1.X12 = _f1.X11()
+				_f1.IP = 14
+				fallthrough
+			case _f1.IP < 15:
+				_f1.X13 = !_f1.X12
+				_f1.IP = 15
+				fallthrough
+			case _f1.IP < 16:
+				if _f1.X13 {
+					break _l0
+				}
+			}
+		}
+	}
+}
+
+//go:noinline
+func Range10Heterogenous() {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10Heterogenous"`
+		X0  int8
+		X1  int16
+		X2  int32
+		X3  int64
+		X4  uint8
+		X5  uint16
+		X6  uint32
+		X7  uint64
+		X8  uintptr
+		X9  int
+		X10 int
+	} = coroutine.Push[struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10Heterogenous"`
+		X0  int8
+		X1  int16
+		X2  int32
+		X3  int64
+		X4  uint8
+		X5  uint16
+		X6  uint32
+		X7  uint64
+		X8  uintptr
+		X9  int
+		X10 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10Heterogenous"`
+			X0  int8
+			X1  int16
+			X2  int32
+			X3  int64
+			X4  uint8
+			X5  uint16
+			X6  uint32
+			X7  uint64
+			X8  uintptr
+			X9  int
+			X10 int
+		}{}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 10:
+		{
+			_f0.X0 = 0
+			_f0.X1 = 1
+			_f0.X2 = 2
+			_f0.X3 = 3
+			_f0.X4 = 4
+			_f0.X5 = 5
+			_f0.X6 = 6
+			_f0.X7 = 7
+			_f0.X8 = 8
+		}
+		_f0.IP = 10
+		fallthrough
+	case _f0.IP < 23:
+		switch {
+		case _f0.IP < 11:
+			_f0.X9 = 0
+			_f0.IP = 11
+			fallthrough
+		case _f0.IP < 23:
+			for ; _f0.X9 < 10; _f0.X9, _f0.IP = _f0.X9+1, 11 {
+				switch {
+				case _f0.IP < 12:
+					_f0.IP = 12
+					fallthrough
+				case _f0.IP < 22:
+
+					switch _f0.X9 {
+					case 0:
+						_f0.X10 = int(_f0.X0)
+					case 1:
+						_f0.X10 = int(_f0.X1)
+					case 2:
+						_f0.X10 = int(_f0.X2)
+					case 3:
+						_f0.X10 = int(_f0.X3)
+					case 4:
+						_f0.X10 = int(_f0.X4)
+					case 5:
+						_f0.X10 = int(_f0.X5)
+					case 6:
+						_f0.X10 = int(_f0.X6)
+					case 7:
+						_f0.X10 = int(_f0.X7)
+					case 8:
+						_f0.X10 = int(_f0.X8)
+					case 9:
+						_f0.X10 = int(_f0.X9)
+					}
+					_f0.IP = 22
+					fallthrough
+				case _f0.IP < 23:
+					coroutine.Yield[int, any](_f0.X10)
+				}
+			}
+		}
+	}
+}
+
+//go:noinline
+func Select(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Select"`
+		X0  int
+		X1  int
+		X2  int
+		X3  bool
+		X4  int
+		X5  int
+		X6  <-chan time.Time
+		X7  <-chan time.Time
+		X8  int
+		X9  bool
+		X10 bool
+		X11 int
+		X12 <-chan time.Time
+		X13 int
+		X14 bool
+		X15 int
+		X16 <-chan time.Time
+		X17 int
+		X18 bool
+		X19 int
+	} = coroutine.Push[struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Select"`
+		X0  int
+		X1  int
+		X2  int
+		X3  bool
+		X4  int
+		X5  int
+		X6  <-chan time.Time
+		X7  <-chan time.Time
+		X8  int
+		X9  bool
+		X10 bool
+		X11 int
+		X12 <-chan time.Time
+		X13 int
+		X14 bool
+		X15 int
+		X16 <-chan time.Time
+		X17 int
+		X18 bool
+		X19 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Select"`
+			X0  int
+			X1  int
+			X2  int
+			X3  bool
+			X4  int
+			X5  int
+			X6  <-chan time.Time
+			X7  <-chan time.Time
+			X8  int
+			X9  bool
+			X10 bool
+			X11 int
+			X12 <-chan time.Time
+			X13 int
+			X14 bool
+			X15 int
+			X16 <-chan time.Time
+			X17 int
+			X18 bool
+			X19 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 6:
+		switch {
+		case _f0.IP < 2:
+			_f0.X1 = 0
+			_f0.IP = 2
+			fallthrough
+		case _f0.IP < 3:
+			select {
+			default:
+				_f0.X1 = 1
+			}
+			_f0.IP = 3
+			fallthrough
+		case _f0.IP < 6:
+			switch {
+			case _f0.IP < 4:
+				_f0.X2 = _f0.X1
+				_f0.IP = 4
+				fallthrough
+			case _f0.IP < 6:
+				switch {
+				default:
+					switch {
+					case _f0.IP < 5:
+						_f0.X3 = _f0.X2 == 1
+						_f0.IP = 5
+						fallthrough
+					case _f0.IP < 6:
+						if _f0.X3 {
+
+							coroutine.Yield[int, any](-1)
+						}
+					}
+				}
+			}
+		}
+		_f0.IP = 6
+		fallthrough
+	case _f0.IP < 24:
+		switch {
+		case _f0.IP < 7:
+			_f0.X4 = 0
+			_f0.IP = 7
+			fallthrough
+		case _f0.IP < 24:
+			for ; _f0.X4 < _f0.X0; _f0.X4, _f0.IP = _f0.X4+1, 7 {
+				switch {
+				case _f0.IP < 17:
+					switch {
+					case _f0.IP < 8:
+						_f0.X5 = 0
+						_f0.IP = 8
+						fallthrough
+					case _f0.IP < 9:
+						_f0.X6 = time.After(0)
+						_f0.IP = 9
+						fallthrough
+					case _f0.IP < 10:
+						_f0.X7 = time.After(1 * time.Second)
+						_f0.IP = 10
+						fallthrough
+					case _f0.IP < 12:
+						select {
+						case <-_f0.X6:
+							_f0.X5 = 1
+						case <-_f0.X7:
+							_f0.X5 = 2
+						}
+						_f0.IP = 12
+						fallthrough
+					case _f0.IP < 17:
+						switch {
+						case _f0.IP < 13:
+							_f0.X8 = _f0.X5
+							_f0.IP = 13
+							fallthrough
+						case _f0.IP < 17:
+						_l2:
+							switch {
+							default:
+								switch {
+								case _f0.IP < 14:
+									_f0.X9 = _f0.X8 == 1
+									_f0.IP = 14
+									fallthrough
+								case _f0.IP < 17:
+									if _f0.X9 {
+										switch {
+										case _f0.IP < 15:
+											if _f0.X4 >=
+												5 {
+												break _l2
+											}
+											_f0.IP = 15
+											fallthrough
+										case _f0.IP < 16:
+
+											coroutine.Yield[int, any](_f0.X4)
+										}
+									} else if _f0.X10 = _f0.X8 == 2; _f0.X10 {
+
+										panic("unreachable")
+									}
+								}
+							}
+						}
+					}
+					_f0.IP = 17
+					fallthrough
+				case _f0.IP < 24:
+					switch {
+					case _f0.IP < 18:
+						_f0.X11 = 0
+						_f0.IP = 18
+						fallthrough
+					case _f0.IP < 19:
+						_f0.X12 = time.After(0)
+						_f0.IP = 19
+						fallthrough
+					case _f0.IP < 20:
+						select {
+						case <-_f0.X12:
+							_f0.X11 = 1
+						}
+						_f0.IP = 20
+						fallthrough
+					case _f0.IP < 24:
+						switch {
+						case _f0.IP < 21:
+							_f0.X13 = _f0.X11
+							_f0.IP = 21
+							fallthrough
+						case _f0.IP < 24:
+						_l3:
+							switch {
+							default:
+								switch {
+								case _f0.IP < 22:
+									_f0.X14 = _f0.X13 == 1
+									_f0.IP = 22
+									fallthrough
+								case _f0.IP < 24:
+									if _f0.X14 {
+										switch {
+										case _f0.IP < 23:
+											if _f0.X4 >=
+												6 {
+												break _l3
+											}
+											_f0.IP = 23
+											fallthrough
+										case _f0.IP < 24:
+
+											coroutine.Yield[int, any](_f0.X4 * 10)
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+		_f0.IP = 24
+		fallthrough
+	case _f0.IP < 31:
+		switch {
+		case _f0.IP < 25:
+			_f0.X15 = 0
+			_f0.IP = 25
+			fallthrough
+		case _f0.IP < 26:
+			_f0.X16 = time.After(0)
+			_f0.IP = 26
+			fallthrough
+		case _f0.IP < 27:
+			select {
+			case <-_f0.X16:
+				_f0.X15 = 1
+			}
+			_f0.IP = 27
+			fallthrough
+		case _f0.IP < 31:
+			switch {
+			case _f0.IP < 28:
+				_f0.X17 = _f0.X15
+				_f0.IP = 28
+				fallthrough
+			case _f0.IP < 31:
+				switch {
+				default:
+					switch {
+					case _f0.IP < 29:
+						_f0.X18 = _f0.X17 == 1
+						_f0.IP = 29
+						fallthrough
+					case _f0.IP < 31:
+						if _f0.X18 {
+							switch {
+							case _f0.IP < 30:
+								_f0.X19 = 0
+								_f0.IP = 30
+								fallthrough
+							case _f0.IP < 31:
+								for ; _f0.X19 < 3; _f0.X19, _f0.IP = _f0.X19+1, 30 {
+									coroutine.Yield[int, any](_f0.X19)
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+//go:noinline
+func SelectFair(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SelectFair"`
+		X0 int
+		X1 chan struct {
+		}
+		X2 chan struct {
+		}
+		X3 int
+		X4 int
+		X5 chan struct {
+		}
+		X6 chan struct {
+		}
+		X7 int
+		X8 bool
+		X9 bool
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SelectFair"`
+		X0 int
+		X1 chan struct {
+		}
+		X2 chan struct {
+		}
+		X3 int
+		X4 int
+		X5 chan struct {
+		}
+		X6 chan struct {
+		}
+		X7 int
+		X8 bool
+		X9 bool
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SelectFair"`
+			X0 int
+			X1 chan struct {
+			}
+			X2 chan struct {
+			}
+			X3 int
+			X4 int
+			X5 chan struct {
+			}
+			X6 chan struct {
+			}
+			X7 int
+			X8 bool
+			X9 bool
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = make(chan struct{})
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		_f0.X2 = make(chan struct{})
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 4:
+		close(_f0.X1)
+		_f0.IP = 4
+		fallthrough
+	case _f0.IP < 5:
+		close(_f0.X2)
+		_f0.IP = 5
+		fallthrough
+	case _f0.IP < 16:
+		switch {
+		case _f0.IP < 6:
+			_f0.X3 = 0
+			_f0.IP = 6
+			fallthrough
+		case _f0.IP < 16:
+			for ; _f0.X3 < _f0.X0; _f0.X3, _f0.IP = _f0.X3+1, 6 {
+				switch {
+				case _f0.IP < 7:
+					_f0.X4 = 0
+					_f0.IP = 7
+					fallthrough
+				case _f0.IP < 8:
+					_f0.X5 = _f0.X1
+					_f0.IP = 8
+					fallthrough
+				case _f0.IP < 9:
+					_f0.X6 = _f0.X2
+					_f0.IP = 9
+					fallthrough
+				case _f0.IP < 11:
+					select {
+					case <-_f0.X5:
+						_f0.X4 = 1
+					case <-_f0.X6:
+						_f0.X4 = 2
+					}
+					_f0.IP = 11
+					fallthrough
+				case _f0.IP < 16:
+					switch {
+					case _f0.IP < 12:
+						_f0.X7 = _f0.X4
+						_f0.IP = 12
+						fallthrough
+					case _f0.IP < 16:
+						switch {
+						default:
+							switch {
+							case _f0.IP < 13:
+								_f0.X8 = _f0.X7 == 1
+								_f0.IP = 13
+								fallthrough
+							case _f0.IP < 16:
+								if _f0.X8 {
+									coroutine.Yield[int, any](0)
+								} else {
+									switch {
+									case _f0.IP < 15:
+										_f0.X9 = _f0.X7 == 2
+										_f0.IP = 15
+										fallthrough
+									case _f0.IP < 16:
+										if _f0.X9 {
+
+											coroutine.Yield[int, any](1)
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+//go:noinline
+func YieldingExpressionDesugaring() {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldingExpressionDesugaring"`
+		X0  int
+		X1  int
+		X2  int
+		X3  int
+		X4  bool
+		X5  int
+		X6  int
+		X7  int
+		X8  int
+		X9  int
+		X10 bool
+		X11 int
+		X12 int
+		X13 int
+		X14 int
+		X15 int
+		X16 bool
+		X17 int
+		X18 int
+		X19 int
+		X20 int
+		X21 bool
+		X22 bool
+		X23 int
+		X24 int
+		X25 int
+		X26 int
+		X27 int
+		X28 bool
+		X29 int
+		X30 int
+		X31 bool
+		X32 int
+		X33 int
+		X34 int
+		X35 bool
+		X36 int
+		X37 int
+		X38 int
+		X39 bool
+		X40 int
+		X41 int
+		X42 any
+		X43 bool
+		X44 int
+		X45 any
+	} = coroutine.Push[struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldingExpressionDesugaring"`
+		X0  int
+		X1  int
+		X2  int
+		X3  int
+		X4  bool
+		X5  int
+		X6  int
+		X7  int
+		X8  int
+		X9  int
+		X10 bool
+		X11 int
+		X12 int
+		X13 int
+		X14 int
+		X15 int
+		X16 bool
+		X17 int
+		X18 int
+		X19 int
+		X20 int
+		X21 bool
+		X22 bool
+		X23 int
+		X24 int
+		X25 int
+		X26 int
+		X27 int
+		X28 bool
+		X29 int
+		X30 int
+		X31 bool
+		X32 int
+		X33 int
+		X34 int
+		X35 bool
+		X36 int
+		X37 int
+		X38 int
+		X39 bool
+		X40 int
+		X41 int
+		X42 any
+		X43 bool
+		X44 int
+		X45 any
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldingExpressionDesugaring"`
+			X0  int
+			X1  int
+			X2  int
+			X3  int
+			X4  bool
+			X5  int
+			X6  int
+			X7  int
+			X8  int
+			X9  int
+			X10 bool
+			X11 int
+			X12 int
+			X13 int
+			X14 int
+			X15 int
+			X16 bool
+			X17 int
+			X18 int
+			X19 int
+			X20 int
+			X21 bool
+			X22 bool
+			X23 int
+			X24 int
+			X25 int
+			X26 int
+			X27 int
+			X28 bool
+			X29 int
+			X30 int
+			X31 bool
+			X32 int
+			X33 int
+			X34 int
+			X35 bool
+			X36 int
+			X37 int
+			X38 int
+			X39 bool
+			X40 int
+			X41 int
+			X42 any
+			X43 bool
+			X44 int
+			X45 any
+		}{}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 21:
+		switch {
+		case _f0.IP < 2:
+			_f0.X0 = b(1)
+			_f0.IP = 2
+			fallthrough
+		case _f0.IP < 3:
+			_f0.X1 = a(_f0.X0)
+			_f0.IP = 3
+			fallthrough
+		case _f0.IP < 4:
+			_f0.X2 = b(2)
+			_f0.IP = 4
+			fallthrough
+		case _f0.IP < 5:
+			_f0.X3 = a(_f0.X2)
+			_f0.IP = 5
+			fallthrough
+		case _f0.IP < 6:
+			_f0.X4 = _f0.X1 == _f0.X3
+			_f0.IP = 6
+			fallthrough
+		case _f0.IP < 21:
+			if _f0.X4 {
+			} else {
+				switch {
+				case _f0.IP < 8:
+					_f0.X5 = b(3)
+					_f0.IP = 8
+					fallthrough
+				case _f0.IP < 9:
+					_f0.X6 = a(_f0.X5)
+					_f0.IP = 9
+					fallthrough
+				case _f0.IP < 10:
+					_f0.X7 = b(4)
+					_f0.IP = 10
+					fallthrough
+				case _f0.IP < 11:
+					_f0.X8 = a(_f0.X7)
+					_f0.IP = 11
+					fallthrough
+				case _f0.IP < 12:
+					_f0.X9 = _f0.X8 - 1
+					_f0.IP = 12
+					fallthrough
+				case _f0.IP < 13:
+					_f0.X10 = _f0.X6 == _f0.X9
+					_f0.IP = 13
+					fallthrough
+				case _f0.IP < 21:
+					if _f0.X10 {
+						switch {
+						case _f0.IP < 14:
+							_f0.X11 = b(5)
+							_f0.IP = 14
+							fallthrough
+						case _f0.IP < 15:
+							_f0.X12 = a(_f0.X11)
+							_f0.IP = 15
+							fallthrough
+						case _f0.IP < 16:
+							_f0.X13 = _f0.X12 * 10
+							_f0.IP = 16
+							fallthrough
+						case _f0.IP < 17:
+							coroutine.Yield[int, any](_f0.X13)
+						}
+					} else {
+						switch {
+						case _f0.IP < 18:
+							_f0.X14 = b(100)
+							_f0.IP = 18
+							fallthrough
+						case _f0.IP < 19:
+							_f0.X15 = a(_f0.X14)
+							_f0.IP = 19
+							fallthrough
+						case _f0.IP < 20:
+							_f0.X16 = _f0.X15 == 100
+							_f0.IP = 20
+							fallthrough
+						case _f0.IP < 21:
+							if _f0.X16 {
+								panic("unreachable")
+							}
+						}
+					}
+				}
+			}
+		}
+		_f0.IP = 21
+		fallthrough
+	case _f0.IP < 29:
+		switch {
+		case _f0.IP < 22:
+			_f0.X17 = b(6)
+			_f0.IP = 22
+			fallthrough
+		case _f0.IP < 23:
+			_f0.X18 = a(_f0.X17)
+			_f0.IP = 23
+			fallthrough
+		case _f0.IP < 29:
+		_l0:
+			for ; ; _f0.X18, _f0.IP = _f0.X18+1, 23 {
+				switch {
+				case _f0.IP < 28:
+					switch {
+					case _f0.IP < 24:
+						_f0.X19 = b(8)
+						_f0.IP = 24
+						fallthrough
+					case _f0.IP < 25:
+						_f0.X20 = a(_f0.X19)
+						_f0.IP = 25
+						fallthrough
+					case _f0.IP < 26:
+						_f0.X21 = _f0.X18 < _f0.X20
+						_f0.IP = 26
+						fallthrough
+					case _f0.IP < 27:
+						_f0.X22 = !_f0.X21
+						_f0.IP = 27
+						fallthrough
+					case _f0.IP < 28:
+						if _f0.X22 {
+							break _l0
+						}
+					}
+					_f0.IP = 28
+					fallthrough
+				case _f0.IP < 29:
+					coroutine.Yield[int, any](70)
+				}
+			}
+		}
+		_f0.IP = 29
+		fallthrough
+	case _f0.IP < 51:
+		switch {
+		case _f0.IP < 30:
+			_f0.X23 = b(9)
+			_f0.IP = 30
+			fallthrough
+		case _f0.IP < 31:
+			_f0.X24 = a(_f0.X23)
+			_f0.IP = 31
+			fallthrough
+		case _f0.IP < 32:
+			_f0.X25 = _f0.X24
+			_f0.IP = 32
+			fallthrough
+		case _f0.IP < 51:
+			switch {
+			default:
+				switch {
+				case _f0.IP < 33:
+					_f0.X26 = b(10)
+					_f0.IP = 33
+					fallthrough
+				case _f0.IP < 34:
+					_f0.X27 = a(_f0.X26)
+					_f0.IP = 34
+					fallthrough
+				case _f0.IP < 35:
+					_f0.X28 = _f0.X25 == _f0.X27
+					_f0.IP = 35
+					fallthrough
+				case _f0.IP < 51:
+					if _f0.X28 {
+						panic("unreachable")
+					} else {
+						switch {
+						case _f0.IP < 37:
+							_f0.X29 = b(11)
+							_f0.IP = 37
+							fallthrough
+						case _f0.IP < 38:
+							_f0.X30 = a(_f0.X29)
+							_f0.IP = 38
+							fallthrough
+						case _f0.IP < 39:
+							_f0.X31 = _f0.X25 == _f0.X30
+							_f0.IP = 39
+							fallthrough
+						case _f0.IP < 51:
+							if _f0.X31 {
+								panic("unreachable")
+							} else {
+								switch {
+								case _f0.IP < 41:
+									_f0.X32 = b(12)
+									_f0.IP = 41
+									fallthrough
+								case _f0.IP < 42:
+									_f0.X33 = a(_f0.X32)
+									_f0.IP = 42
+									fallthrough
+								case _f0.IP < 43:
+									_f0.X34 = _f0.X33 - 3
+									_f0.IP = 43
+									fallthrough
+								case _f0.IP < 44:
+									_f0.X35 = _f0.X25 == _f0.X34
+									_f0.IP = 44
+									fallthrough
+								case _f0.IP < 51:
+									if _f0.X35 {
+										switch {
+										case _f0.IP < 45:
+											_f0.X36 = b(13)
+											_f0.IP = 45
+											fallthrough
+										case _f0.IP < 46:
+											a(_f0.X36)
+										}
+									} else {
+										switch {
+										case _f0.IP < 47:
+											_f0.X37 = b(14)
+											_f0.IP = 47
+											fallthrough
+										case _f0.IP < 48:
+											_f0.X38 = a(_f0.X37)
+											_f0.IP = 48
+											fallthrough
+										case _f0.IP < 49:
+											_f0.X39 = _f0.X25 == _f0.X38
+											_f0.IP = 49
+											fallthrough
+										case _f0.IP < 51:
+											if _f0.X39 {
+												panic("unreachable")
+											} else {
+												panic("unreachable")
+											}
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+		_f0.IP = 51
+		fallthrough
+	case _f0.IP < 60:
+		switch {
+		case _f0.IP < 52:
+			_f0.X40 = b(15)
+			_f0.IP = 52
+			fallthrough
+		case _f0.IP < 53:
+			_f0.X41 = a(_f0.X40)
+			_f0.IP = 53
+			fallthrough
+		case _f0.IP < 54:
+			_f0.X42 = any(_f0.X41)
+			_f0.IP = 54
+			fallthrough
+		case _f0.IP < 60:
+			switch _f0.X42.(type) {
+			case bool:
+				_f0.X43 = _f0.X42.(bool)
+				panic("unreachable")
+			case int:
+				switch {
+				case _f0.IP < 57:
+					_f0.X44 = _f0.X42.(int)
+					_f0.IP = 57
+					fallthrough
+				case _f0.IP < 58:
+					coroutine.Yield[int, any](_f0.X44 * 10)
+				}
+			default:
+				_f0.X45 = _f0.X42
+				panic("unreachable")
+			}
+		}
+	}
+}
+
+//go:noinline
+func a(_fn0 int) (_ int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.a"`
+		X0 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.a"`
+		X0 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.a"`
+			X0 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		coroutine.Yield[int, any](_f0.X0)
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		return _f0.X0
+	}
+	return
+}
+
+//go:noinline
+func b(_fn0 int) (_ int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.b"`
+		X0 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.b"`
+		X0 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.b"`
+			X0 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		coroutine.Yield[int, any](-_f0.X0)
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		return _f0.X0
+	}
+	return
+}
+
+//go:noinline
+func YieldingSliceAndIndexOrder() {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldingSliceAndIndexOrder"`
+		X0  []int
+		X1  int
+		X2  int
+		X3  []int
+		X4  int
+		X5  int
+		X6  int
+		X7  int
+		X8  int
+		X9  int
+		X10 int
+		X11 int
+		X12 int
+		X13 []int
+		X14 int
+	} = coroutine.Push[struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldingSliceAndIndexOrder"`
+		X0  []int
+		X1  int
+		X2  int
+		X3  []int
+		X4  int
+		X5  int
+		X6  int
+		X7  int
+		X8  int
+		X9  int
+		X10 int
+		X11 int
+		X12 int
+		X13 []int
+		X14 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldingSliceAndIndexOrder"`
+			X0  []int
+			X1  int
+			X2  int
+			X3  []int
+			X4  int
+			X5  int
+			X6  int
+			X7  int
+			X8  int
+			X9  int
+			X10 int
+			X11 int
+			X12 int
+			X13 []int
+			X14 int
+		}{}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X0 = []int{0, 1, 2, 3, 4, 5}
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		_f0.X1 = a(1)
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 4:
+		_f0.X2 = a(3)
+		_f0.IP = 4
+		fallthrough
+	case _f0.IP < 5:
+		_f0.X3 = _f0.X0[_f0.X1:_f0.X2]
+		_f0.IP = 5
+		fallthrough
+	case _f0.IP < 6:
+		_f0.X4 = len(_f0.X3)
+		_f0.IP = 6
+		fallthrough
+	case _f0.IP < 7:
+		coroutine.Yield[int, any](_f0.X4)
+		_f0.IP = 7
+		fallthrough
+	case _f0.IP < 8:
+		_f0.X5 = a(2)
+		_f0.IP = 8
+		fallthrough
+	case _f0.IP < 9:
+		_f0.X6 = _f0.X0[_f0.X5]
+		_f0.IP = 9
+		fallthrough
+	case _f0.IP < 10:
+		_f0.X7 = b(4)
+		_f0.IP = 10
+		fallthrough
+	case _f0.IP < 11:
+		_f0.X8 = _f0.X0[_f0.X7]
+		_f0.IP = 11
+		fallthrough
+	case _f0.IP < 12:
+		_f0.X9 = _f0.X6 + _f0.X8
+		_f0.IP = 12
+		fallthrough
+	case _f0.IP < 13:
+		coroutine.Yield[int, any](_f0.X9)
+		_f0.IP = 13
+		fallthrough
+	case _f0.IP < 14:
+		_f0.X10 = a(2)
+		_f0.IP = 14
+		fallthrough
+	case _f0.IP < 15:
+		_f0.X11 = a(3)
+		_f0.IP = 15
+		fallthrough
+	case _f0.IP < 16:
+		_f0.X12 = b(5)
+		_f0.IP = 16
+		fallthrough
+	case _f0.IP < 17:
+		_f0.X13 = _f0.X0[_f0.X10:_f0.X11:_f0.X12]
+		_f0.IP = 17
+		fallthrough
+	case _f0.IP < 18:
+		_f0.X14 = cap(_f0.X13)
+		_f0.IP = 18
+		fallthrough
+	case _f0.IP < 19:
+		coroutine.Yield[int, any](_f0.X14)
+	}
+}
+
+//go:noinline
+func VariadicLibraryCall(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.VariadicLibraryCall"`
+		X0 int
+		X1 int
+		X2 int
+		X3 int
+		X4 string
+		X5 int
+		X6 []any
+		X7 int
+		X8 []any
+		X9 string
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.VariadicLibraryCall"`
+		X0 int
+		X1 int
+		X2 int
+		X3 int
+		X4 string
+		X5 int
+		X6 []any
+		X7 int
+		X8 []any
+		X9 string
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.VariadicLibraryCall"`
+			X0 int
+			X1 int
+			X2 int
+			X3 int
+			X4 string
+			X5 int
+			X6 []any
+			X7 int
+			X8 []any
+			X9 string
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = a(_f0.X0)
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		_f0.X2 = b(_f0.X0 + 1)
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 4:
+		_f0.X3 = a(_f0.X0 + 2)
+		_f0.IP = 4
+		fallthrough
+	case _f0.IP < 5:
+		_f0.X4 = fmt.Sprintf("%d:%d:%d", _f0.X1, _f0.X2, _f0.X3)
+		_f0.IP = 5
+		fallthrough
+	case _f0.IP < 6:
+		coroutine.Yield[int, any](len(_f0.X4))
+		_f0.IP = 6
+		fallthrough
+	case _f0.IP < 7:
+		_f0.X5 = a(7)
+		_f0.IP = 7
+		fallthrough
+	case _f0.IP < 8:
+		_f0.X6 = []any{_f0.X5}
+		_f0.IP = 8
+		fallthrough
+	case _f0.IP < 9:
+		_f0.X7 = b(8)
+		_f0.IP = 9
+		fallthrough
+	case _f0.IP < 10:
+		_f0.X8 = append(_f0.X6, _f0.X7)
+		_f0.IP = 10
+		fallthrough
+	case _f0.IP < 11:
+		_f0.X9 = fmt.Sprint(_f0.X8...)
+		_f0.IP = 11
+		fallthrough
+	case _f0.IP < 12:
+		_f0.X4 = _f0.X9
+		_f0.IP = 12
+		fallthrough
+	case _f0.IP < 13:
+		coroutine.Yield[int, any](len(_f0.X4))
+	}
+}
+
+//go:noinline
+func YieldingDurations() {
+	_c := coroutine.LoadContext[int, any]()
+	var _f1 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldingDurations"`
+		X0 *time.Duration
+		X1 time.Duration
+		X2 func()
+		X3 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldingDurations"`
+		X0 *time.Duration
+		X1 time.Duration
+		X2 func()
+		X3 int
+	}](&_c.Stack)
+	if _f1.IP == 0 {
+		*_f1 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldingDurations"`
+			X0 *time.Duration
+			X1 time.Duration
+			X2 func()
+			X3 int
+		}{}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f1.IP < 2:
+		_f1.X0 = new(time.Duration)
+		_f1.IP = 2
+		fallthrough
+	case _f1.IP < 3:
+		_f1.X1 = time.Duration(100)
+		_f1.IP = 3
+		fallthrough
+	case _f1.IP < 4:
+		*_f1.X0 = _f1.X1
+		_f1.IP = 4
+		fallthrough
+	case _f1.IP < 5:
+		_f1.X2 = func() {
+			_c := coroutine.LoadContext[int, any]()
+			var _f0 *struct {
+				IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldingDurations.func@coroutine.go:670:7"`
+				X0 int64
+				X1 int
+				X2 time.Duration
+			} = coroutine.Push[struct {
+				IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldingDurations.func@coroutine.go:670:7"`
+				X0 int64
+				X1 int
+				X2 time.Duration
+			}](&_c.Stack)
+			if _f0.IP == 0 {
+				*_f0 = struct {
+					IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldingDurations.func@coroutine.go:670:7"`
+					X0 int64
+					X1 int
+					X2 time.Duration
+				}{}
+			}
+			defer func() {
+				if !_c.Unwinding() {
+					coroutine.Pop(&_c.Stack)
+				}
+			}()
+			switch {
+			case _f0.IP < 2:
+				_f0.X0 = _f1.X0.
+					Nanoseconds()
+				_f0.IP = 2
+				fallthrough
+			case _f0.IP < 3:
+				_f0.X1 = int(_f0.X0)
+				_f0.IP = 3
+				fallthrough
+			case _f0.IP < 4:
+				_f0.X2 = time.Duration(_f0.X1 + 1)
+				_f0.IP = 4
+				fallthrough
+			case _f0.IP < 5:
+				*_f1.X0 = _f0.X2
+				_f0.IP = 5
+				fallthrough
+			case _f0.IP < 6:
+				coroutine.Yield[int, any](_f0.X1)
+			}
+		}
+		_f1.IP = 5
+		fallthrough
+	case _f1.IP < 7:
+		switch {
+		case _f1.IP < 6:
+			_f1.X3 = 0
+			_f1.IP = 6
+			fallthrough
+		case _f1.IP < 7:
+			for ; _f1.X3 < 10; _f1.X3, _f1.IP = _f1.X3+1, 6 {
+				_f1.X2()
+			}
+		}
+	}
+}
+
+//go:noinline
+func YieldAndDeferAssign(_fn0 *int, _fn1, _fn2 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldAndDeferAssign"`
+		X0 *int
+		X1 int
+		X2 int
+		X3 coroutine.Defers
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldAndDeferAssign"`
+		X0 *int
+		X1 int
+		X2 int
+		X3 coroutine.Defers
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldAndDeferAssign"`
+			X0 *int
+			X1 int
+			X2 int
+			X3 coroutine.Defers
+		}{X0: _fn0, X1: _fn1, X2: _fn2}
+	}
+	defer func() {
+		if !_c.Unwinding() || _f0.X3.Running {
+			_c.RunDefers(&_f0.X3, recover())
+		}
+	}()
+	if _f0.X3.Running {
+		return
+	}
+	switch {
+	case _f0.IP < 2:
+		_f0.X3.Funcs = append(_f0.X3.Funcs, func() {
+			*_f0.X0 = _f0.X2
+		})
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		coroutine.Yield[int, any](_f0.X1)
+	}
+}
+
+//go:noinline
+func RangeYieldAndDeferAssign(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeYieldAndDeferAssign"`
+		X0 int
+		X1 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeYieldAndDeferAssign"`
+		X0 int
+		X1 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeYieldAndDeferAssign"`
+			X0 int
+			X1 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = 0
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		for ; _f0.X1 < _f0.X0; _f0.IP = 2 {
+			YieldAndDeferAssign(&_f0.X1, _f0.X1, _f0.X1+1)
+		}
+	}
+}
+
+type MethodGeneratorState struct{ i int }
+
+//go:noinline
+func (_fn0 *MethodGeneratorState) MethodGenerator(_fn1 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"(*github.com/stealthrocket/coroutine/compiler/testdata.MethodGeneratorState).MethodGenerator"`
+		X0 *MethodGeneratorState
+		X1 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"(*github.com/stealthrocket/coroutine/compiler/testdata.MethodGeneratorState).MethodGenerator"`
+		X0 *MethodGeneratorState
+		X1 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"(*github.com/stealthrocket/coroutine/compiler/testdata.MethodGeneratorState).MethodGenerator"`
+			X0 *MethodGeneratorState
+			X1 int
+		}{X0: _fn0, X1: _fn1}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X0.
+			i = 0
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		for ; _f0.X0.i <= _f0.X1; _f0.X0.i, _f0.IP = _f0.X0.i+1, 2 {
+			coroutine.Yield[int, any](_f0.X0.i)
+		}
+	}
+}
+
+//go:noinline
+func VarArgs(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.VarArgs"`
+		X0 int
+		X1 []int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.VarArgs"`
+		X0 int
+		X1 []int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.VarArgs"`
+			X0 int
+			X1 []int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = make([]int, _f0.X0)
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		for i := range _f0.X1 {
+			_f0.X1[i] = i
+		}
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 4:
+		varArgs(_f0.X1...)
+	}
+}
+
+//go:noinline
+func varArgs(_fn0 ...int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.varArgs"`
+		X0 []int
+		X1 []int
+		X2 int
+		X3 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.varArgs"`
+		X0 []int
+		X1 []int
+		X2 int
+		X3 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.varArgs"`
+			X0 []int
+			X1 []int
+			X2 int
+			X3 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = _f0.X0
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 5:
+		switch {
+		case _f0.IP < 3:
+			_f0.X2 = 0
+			_f0.IP = 3
+			fallthrough
+		case _f0.IP < 5:
+			for ; _f0.X2 < len(_f0.X1); _f0.X2, _f0.IP = _f0.X2+1, 3 {
+				switch {
+				case _f0.IP < 4:
+					_f0.X3 = _f0.X1[_f0.X2]
+					_f0.IP = 4
+					fallthrough
+				case _f0.IP < 5:
+
+					coroutine.Yield[int, any](_f0.X3)
+				}
+			}
+		}
+	}
+}
+
+//go:noinline
+func VariadicYield(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.VariadicYield"`
+		X0 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.VariadicYield"`
+		X0 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.VariadicYield"`
+			X0 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		variadicSum(_f0.X0, 1, 2, 3)
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		variadicSum(_f0.X0)
+	}
+}
+
+//go:noinline
+func variadicSum(_fn0 int, _fn1 ...int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.variadicSum"`
+		X0 int
+		X1 []int
+		X2 int
+		X3 []int
+		X4 int
+		X5 int
+		X6 []int
+		X7 int
+		X8 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.variadicSum"`
+		X0 int
+		X1 []int
+		X2 int
+		X3 []int
+		X4 int
+		X5 int
+		X6 []int
+		X7 int
+		X8 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.variadicSum"`
+			X0 int
+			X1 []int
+			X2 int
+			X3 []int
+			X4 int
+			X5 int
+			X6 []int
+			X7 int
+			X8 int
+		}{X0: _fn0, X1: _fn1}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X2 = _f0.X0
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 8:
+		switch {
+		case _f0.IP < 3:
+			_f0.X3 = _f0.X1
+			_f0.IP = 3
+			fallthrough
+		case _f0.IP < 8:
+			switch {
+			case _f0.IP < 4:
+				_f0.X4 = 0
+				_f0.IP = 4
+				fallthrough
+			case _f0.IP < 8:
+				for ; _f0.X4 < len(_f0.X3); _f0.X4, _f0.IP = _f0.X4+1, 4 {
+					switch {
+					case _f0.IP < 5:
+						_f0.X5 = _f0.X3[_f0.X4]
+						_f0.IP = 5
+						fallthrough
+					case _f0.IP < 6:
+
+						coroutine.Yield[int, any](_f0.X5)
+						_f0.IP = 6
+						fallthrough
+					case _f0.IP < 7:
+						_f0.X1[_f0.X4] = -_f0.X5
+						_f0.IP = 7
+						fallthrough
+					case _f0.IP < 8:
+						_f0.X2 += _f0.X5
+					}
+				}
+			}
+		}
+		_f0.IP = 8
+		fallthrough
+	case _f0.IP < 9:
+
+		coroutine.Yield[int, any](_f0.X2)
+		_f0.IP = 9
+		fallthrough
+	case _f0.IP < 10:
+		coroutine.Yield[int, any](len(_f0.X1))
+		_f0.IP = 10
+		fallthrough
+	case _f0.IP < 14:
+		switch {
+		case _f0.IP < 11:
+			_f0.X6 = _f0.X1
+			_f0.IP = 11
+			fallthrough
+		case _f0.IP < 14:
+			switch {
+			case _f0.IP < 12:
+				_f0.X7 = 0
+				_f0.IP = 12
+				fallthrough
+			case _f0.IP < 14:
+				for ; _f0.X7 < len(_f0.X6); _f0.X7, _f0.IP = _f0.X7+1, 12 {
+					switch {
+					case _f0.IP < 13:
+						_f0.X8 = _f0.X6[_f0.X7]
+						_f0.IP = 13
+						fallthrough
+					case _f0.IP < 14:
+
+						coroutine.Yield[int, any](_f0.X8)
+					}
+				}
+			}
+		}
+	}
+}
+
+//go:noinline
+func LocalIotaConsts(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LocalIotaConsts"`
+		X0 int
+		X1 []int
+		X2 int
+		X3 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LocalIotaConsts"`
+		X0 int
+		X1 []int
+		X2 int
+		X3 int
+	}](&_c.Stack)
+	const (
+		_o0 = iota
+		_o1
+		_o2
+
+		_
+		_o3
+	)
+	const (
+		_o4 = 1 << (iota * 10)
+		_o5
+		_o6
+	)
+	const _o7 = 3
+	const (
+		_ = _o7 * iota
+		_o8
+		_o9
+	)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LocalIotaConsts"`
+			X0 int
+			X1 []int
+			X2 int
+			X3 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 5:
+		switch {
+		case _f0.IP < 2:
+			_f0.X1 = []int{_o0, _o1, _o2, _o3}
+			_f0.IP = 2
+			fallthrough
+		case _f0.IP < 5:
+			switch {
+			case _f0.IP < 3:
+				_f0.X2 = 0
+				_f0.IP = 3
+				fallthrough
+			case _f0.IP < 5:
+				for ; _f0.X2 < len(_f0.X1); _f0.X2, _f0.IP = _f0.X2+1, 3 {
+					switch {
+					case _f0.IP < 4:
+						_f0.X3 = _f0.X1[_f0.X2]
+						_f0.IP = 4
+						fallthrough
+					case _f0.IP < 5:
+						coroutine.Yield[int, any](_f0.X0 + _f0.X3)
+					}
+				}
+			}
+		}
+		_f0.IP = 5
+		fallthrough
+	case _f0.IP < 6:
+
+		coroutine.Yield[int, any](_o4)
+		_f0.IP = 6
+		fallthrough
+	case _f0.IP < 7:
+		coroutine.Yield[int, any](_o5)
+		_f0.IP = 7
+		fallthrough
+	case _f0.IP < 8:
+		coroutine.Yield[int, any](_o6)
+		_f0.IP = 8
+		fallthrough
+	case _f0.IP < 9:
+		coroutine.Yield[int, any](_o8)
+		_f0.IP = 9
+		fallthrough
+	case _f0.IP < 10:
+		coroutine.Yield[int, any](_o9)
+	}
+}
+
+type integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+//go:noinline
+func IdentityGeneric[T integer](_fn0 T) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.IdentityGeneric"`
+		X0 T
+		X1 T
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.IdentityGeneric"`
+		X0 T
+		X1 T
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.IdentityGeneric"`
+			X0 T
+			X1 T
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = T(0)
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		for ; _f0.X1 < _f0.X0; _f0.X1, _f0.IP = _f0.X1+1, 2 {
+			coroutine.Yield[int, any](int(_f0.X1))
+		}
+	}
+}
+
+//go:noinline
+func IdentityGenericInt(n int) { IdentityGeneric[int](n) }
+
+//go:noinline
+func IdentityGenericInt8(n int8) { IdentityGeneric[int8](n) }
+
+//go:noinline
+func LoopDefers(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LoopDefers"`
+		X0 int
+		X1 []int
+		X2 []int
+		X3 int
+		X4 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LoopDefers"`
+		X0 int
+		X1 []int
+		X2 []int
+		X3 int
+		X4 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LoopDefers"`
+			X0 int
+			X1 []int
+			X2 []int
+			X3 int
+			X4 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+
+		loopDefers(&_f0.X1, _f0.X0)
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 7:
+		switch {
+		case _f0.IP < 4:
+			_f0.X2 = _f0.X1
+			_f0.IP = 4
+			fallthrough
+		case _f0.IP < 7:
+			switch {
+			case _f0.IP < 5:
+				_f0.X3 = 0
+				_f0.IP = 5
+				fallthrough
+			case _f0.IP < 7:
+				for ; _f0.X3 < len(_f0.X2); _f0.X3, _f0.IP = _f0.X3+1, 5 {
+					switch {
+					case _f0.IP < 6:
+						_f0.X4 = _f0.X2[_f0.X3]
+						_f0.IP = 6
+						fallthrough
+					case _f0.IP < 7:
+
+						coroutine.Yield[int, any](_f0.X4)
+					}
+				}
+			}
+		}
+	}
+}
+
+//go:noinline
+func loopDefers(_fn0 *[]int, _fn1 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.loopDefers"`
+		X0 *[]int
+		X1 int
+		X2 []int
+		X3 int
+		X4 int
+		X5 coroutine.Defers
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.loopDefers"`
+		X0 *[]int
+		X1 int
+		X2 []int
+		X3 int
+		X4 int
+		X5 coroutine.Defers
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.loopDefers"`
+			X0 *[]int
+			X1 int
+			X2 []int
+			X3 int
+			X4 int
+			X5 coroutine.Defers
+		}{X0: _fn0, X1: _fn1}
+	}
+	defer func() {
+		if !_c.Unwinding() || _f0.X5.Running {
+			_c.RunDefers(&_f0.X5, recover())
+		}
+	}()
+	if _f0.X5.Running {
+		return
+	}
+	switch {
+	case _f0.IP < 2:
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 7:
+		switch {
+		case _f0.IP < 3:
+			_f0.X3 = 0
+			_f0.IP = 3
+			fallthrough
+		case _f0.IP < 7:
+			for ; _f0.X3 < _f0.X1; _f0.X3, _f0.IP = _f0.X3+1, 3 {
+				switch {
+				case _f0.IP < 6:
+					switch {
+					case _f0.IP < 4:
+						_f0.X4 = _f0.X3 *
+							10
+						_f0.IP = 4
+						fallthrough
+					case _f0.IP < 6:
+						switch {
+						case _f0.IP < 5:
+							_f0.X2 = append(_f0.X2, _f0.X4)
+							_f0.IP = 5
+							fallthrough
+						case _f0.IP < 6:
+							_f0.X5.Funcs = append(_f0.X5.Funcs, func() {
+								_a0 := _f0.X2[len(_f0.X2)-1]
+								_f0.X2 = _f0.X2[:len(_f0.X2)-1]
+								func(v int) {
+									*_f0.X0 = append(*_f0.X0, v)
+								}(_a0)
+							})
+						}
+					}
+					_f0.IP = 6
+					fallthrough
+				case _f0.IP < 7:
+					coroutine.Yield[int, any](_f0.X3)
+				}
+			}
+		}
+	}
+}
+
+//go:noinline
+func YieldAndRecover(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldAndRecover"`
+		X0 int
+		X1 coroutine.Defers
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldAndRecover"`
+		X0 int
+		X1 coroutine.Defers
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldAndRecover"`
+			X0 int
+			X1 coroutine.Defers
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() || _f0.X1.Running {
+			_c.RunDefers(&_f0.X1, recover())
+		}
+	}()
+	if _f0.X1.Running {
+		return
+	}
+	switch {
+	case _f0.IP < 2:
+		_f0.X1.Funcs = append(_f0.X1.Funcs, func() {
+			if v := recover(); v != "oops" {
+				panic(v)
+			}
+		})
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		coroutine.Yield[int, any](_f0.X0)
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 4:
+		panic("oops")
+	}
+}
+
+//go:noinline
+func RecoverPanicIsolation(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RecoverPanicIsolation"`
+		X0 int
+		X1 int
+		X2 int
+		X3 error
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RecoverPanicIsolation"`
+		X0 int
+		X1 int
+		X2 int
+		X3 error
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RecoverPanicIsolation"`
+			X0 int
+			X1 int
+			X2 int
+			X3 error
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = 0
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 5:
+		for ; _f0.X1 < _f0.X0; _f0.X1, _f0.IP = _f0.X1+1, 2 {
+			switch {
+			case _f0.IP < 3:
+				_f0.X2, _f0.X3 = isolatedWork(_f0.X1)
+				_f0.IP = 3
 				fallthrough
 			case _f0.IP < 5:
+				if _f0.X3 !=
+					nil {
+					coroutine.Yield[int, any](-_f0.X2)
+				} else {
 
-				return false
+					coroutine.Yield[int, any](_f0.X2)
+				}
+			}
+		}
+	}
+}
+
+//go:noinline
+func isolatedWork(_fn0 int) (_fn1 int, _fn2 error) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.isolatedWork"`
+		X0 int
+		X1 int
+		X2 error
+		X3 coroutine.Defers
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.isolatedWork"`
+		X0 int
+		X1 int
+		X2 error
+		X3 coroutine.Defers
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.isolatedWork"`
+			X0 int
+			X1 int
+			X2 error
+			X3 coroutine.Defers
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() || _f0.X3.Running {
+			_c.RunDefers(&_f0.X3, recover())
+			_fn1, _fn2 = _f0.X1, _f0.X2
+		}
+	}()
+	if _f0.X3.Running {
+		return
+	}
+	switch {
+	case _f0.IP < 2:
+		_f0.X3.Funcs = append(_f0.X3.Funcs, func() {
+			if r := recover(); r != nil {
+				_f0.X1, _f0.X2 = r.(int), errors.New("recovered")
 			}
+		})
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		coroutine.Yield[int, any](_f0.X0 * 10)
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 4:
+		if _f0.X0%2 == 1 {
+			panic(_f0.X0 + 100)
+		}
+		_f0.IP = 4
+		fallthrough
+	case _f0.IP < 5:
+		coroutine.Yield[int, any](_f0.X0*10 + 1)
+		_f0.IP = 5
+		fallthrough
+	case _f0.IP < 7:
+		{
+			_f0.X1, _f0.X2 = _f0.X0, nil
 			return
 		}
-		_f1.IP = 5
+	}
+	return
+}
+
+//go:noinline
+func RecoverAfterYield(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RecoverAfterYield"`
+		X0 int
+		X1 int
+		X2 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RecoverAfterYield"`
+		X0 int
+		X1 int
+		X2 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RecoverAfterYield"`
+			X0 int
+			X1 int
+			X2 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = 0
+		_f0.IP = 2
 		fallthrough
-	case _f1.IP < 8:
-	_l0:
-		for ; ; _f1.IP = 5 {
+	case _f0.IP < 4:
+		for ; _f0.X1 < _f0.X0; _f0.X1, _f0.IP = _f0.X1+1, 2 {
 			switch {
-			case _f1.IP < 6:
-				_f1.X5 = _f1.X4()
-				_f1.IP = 6
-				fallthrough
-			case _f1.IP < 7:
-				_f1.X6 = !_f1.X5
-				_f1.IP = 7
+			case _f0.IP < 3:
+				_f0.X2 = recoverAfterYield(_f0.X1)
+				_f0.IP = 3
 				fallthrough
-			case _f1.IP < 8:
-				if _f1.X6 {
-					break _l0
-				}
+			case _f0.IP < 4:
+				coroutine.Yield[int, any](_f0.X2)
 			}
 		}
 	}
 }
 
 //go:noinline
-func Range10ClosureHeterogenousCapture() {
+func recoverAfterYield(_fn0 int) (_fn1 int) {
 	_c := coroutine.LoadContext[int, any]()
-	var _f1 *struct {
-		IP  int
-		X0  int8
-		X1  int16
-		X2  int32
-		X3  int64
-		X4  uint8
-		X5  uint16
-		X6  uint32
-		X7  uint64
-		X8  uintptr
-		X9  func() int
-		X10 int
-		X11 func() bool
-		X12 bool
-		X13 bool
+	var _f2 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.recoverAfterYield"`
+		X0 int
+		X1 int
+		X2 coroutine.Defers
 	} = coroutine.Push[struct {
-		IP  int
-		X0  int8
-		X1  int16
-		X2  int32
-		X3  int64
-		X4  uint8
-		X5  uint16
-		X6  uint32
-		X7  uint64
-		X8  uintptr
-		X9  func() int
-		X10 int
-		X11 func() bool
-		X12 bool
-		X13 bool
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.recoverAfterYield"`
+		X0 int
+		X1 int
+		X2 coroutine.Defers
 	}](&_c.Stack)
-	if _f1.IP == 0 {
-		*_f1 = struct {
-			IP  int
-			X0  int8
-			X1  int16
-			X2  int32
-			X3  int64
-			X4  uint8
-			X5  uint16
-			X6  uint32
-			X7  uint64
-			X8  uintptr
-			X9  func() int
-			X10 int
-			X11 func() bool
-			X12 bool
-			X13 bool
-		}{}
+	if _f2.IP == 0 {
+		*_f2 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.recoverAfterYield"`
+			X0 int
+			X1 int
+			X2 coroutine.Defers
+		}{X0: _fn0}
 	}
 	defer func() {
-		if !_c.Unwinding() {
-			coroutine.Pop(&_c.Stack)
+		if !_c.Unwinding() || _f2.X2.Running {
+			_c.RunDefers(&_f2.X2, recover())
+			_fn1 = _f2.X1
 		}
 	}()
+	if _f2.X2.Running {
+		return
+	}
 	switch {
-	case _f1.IP < 11:
-		{
-			_f1.X0 = 0
-			_f1.X1 = 1
-			_f1.X2 = 2
-			_f1.X3 = 3
-			_f1.X4 = 4
-			_f1.X5 = 5
-			_f1.X6 = 6
-			_f1.X7 = 7
-			_f1.X8 = 8
-			_f1.X9 = func() int { return int(_f1.X8) + 1 }
-		}
-		_f1.IP = 11
-		fallthrough
-	case _f1.IP < 12:
-		_f1.X10 = 0
-		_f1.IP = 12
+	case _f2.IP < 2:
+		_f2.X2.Funcs = append(_f2.X2.Funcs, func() { coroutine.Yield[int, any](1000 + _f2.X1) })
+		_f2.IP = 2
 		fallthrough
-	case _f1.IP < 13:
-		_f1.X11 = func() (_ bool) {
+	case _f2.IP < 3:
+		_f2.X2.Funcs = append(_f2.X2.Funcs, func() {
 			_c := coroutine.LoadContext[int, any]()
 			var _f0 *struct {
-				IP  int
-				X0  int
-				X1  int
-				X2  bool
-				X3  bool
-				X4  bool
-				X5  bool
-				X6  bool
-				X7  bool
-				X8  bool
-				X9  bool
-				X10 bool
-				X11 bool
+				IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.recoverAfterYield.func@coroutine.go:843:8"`
+				X0 any
 			} = coroutine.Push[struct {
-				IP  int
-				X0  int
-				X1  int
-				X2  bool
-				X3  bool
-				X4  bool
-				X5  bool
-				X6  bool
-				X7  bool
-				X8  bool
-				X9  bool
-				X10 bool
-				X11 bool
+				IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.recoverAfterYield.func@coroutine.go:843:8"`
+				X0 any
 			}](&_c.Stack)
 			if _f0.IP == 0 {
 				*_f0 = struct {
-					IP  int
-					X0  int
-					X1  int
-					X2  bool
-					X3  bool
-					X4  bool
-					X5  bool
-					X6  bool
-					X7  bool
-					X8  bool
-					X9  bool
-					X10 bool
-					X11 bool
+					IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.recoverAfterYield.func@coroutine.go:843:8"`
+					X0 any
 				}{}
 			}
 			defer func() {
@@ -1894,84 +5208,240 @@ func Range10ClosureHeterogenousCapture() {
 			}()
 			switch {
 			case _f0.IP < 2:
+
+				coroutine.Yield[int, any](-_f2.X0)
 				_f0.IP = 2
 				fallthrough
-			case _f0.IP < 13:
+			case _f0.IP < 3:
+				if _f0.X0 = recover(); _f0.X0 != nil {
+					_f2.X1 = _f0.X0.(int)
+				}
+			}
+		})
+		_f2.IP = 3
+		fallthrough
+	case _f2.IP < 4:
+
+		coroutine.Yield[int, any](_f2.X0 * 10)
+		_f2.IP = 4
+		fallthrough
+	case _f2.IP < 5:
+		if _f2.X0%2 == 1 {
+			panic(_f2.X0 * 100)
+		}
+		_f2.IP = 5
+		fallthrough
+	case _f2.IP < 7:
+		{
+			_f2.X1 = _f2.X0
+			return
+		}
+	}
+	return
+}
+
+//go:noinline
+func AnonymousStructLocal(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.AnonymousStructLocal"`
+		X0 int
+		X1 struct {
+			A int
+			B []int `json:"b"`
+		}
+		X2 struct {
+			X int
+			Y int
+		}
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.AnonymousStructLocal"`
+		X0 int
+		X1 struct {
+			A int
+			B []int `json:"b"`
+		}
+		X2 struct {
+			X int
+			Y int
+		}
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.AnonymousStructLocal"`
+			X0 int
+			X1 struct {
+				A int
+				B []int `json:"b"`
+			}
+			X2 struct {
+				X int
+				Y int
+			}
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		_f0.X2 = struct{ X, Y int }{X: _f0.X0, Y: _f0.X0 * 2}
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 6:
+		switch {
+		case _f0.IP < 4:
+			_f0.X1.
+				A = 0
+			_f0.IP = 4
+			fallthrough
+		case _f0.IP < 6:
+			for ; _f0.X1.A < _f0.X0; _f0.X1.A, _f0.IP = _f0.X1.A+1, 4 {
 				switch {
-				case _f0.IP < 3:
-					_f0.X1 = _f1.X10
-					_f0.IP = 3
+				case _f0.IP < 5:
+					_f0.X1.
+						B = append(_f0.X1.B, _f0.X1.A)
+					_f0.IP = 5
 					fallthrough
-				case _f0.IP < 13:
-					switch {
-					default:
-						if _f0.X2 = _f0.X1 ==
+				case _f0.IP < 6:
+					coroutine.Yield[int, any](_f0.X1.A + _f0.X2.X)
+				}
+			}
+		}
+		_f0.IP = 6
+		fallthrough
+	case _f0.IP < 7:
 
-							0; _f0.X2 {
-							_f0.X0 = int(_f1.X0)
-						} else if _f0.X3 = _f0.X1 ==
-							1; _f0.X3 {
-							_f0.X0 = int(_f1.X1)
-						} else if _f0.X4 = _f0.X1 ==
-							2; _f0.X4 {
-							_f0.X0 = int(_f1.X2)
-						} else if _f0.X5 = _f0.X1 ==
-							3; _f0.X5 {
-							_f0.X0 = int(_f1.X3)
-						} else if _f0.X6 = _f0.X1 ==
-							4; _f0.X6 {
-							_f0.X0 = int(_f1.X4)
-						} else if _f0.X7 = _f0.X1 ==
-							5; _f0.X7 {
-							_f0.X0 = int(_f1.X5)
-						} else if _f0.X8 = _f0.X1 ==
-							6; _f0.X8 {
-							_f0.X0 = int(_f1.X6)
-						} else if _f0.X9 = _f0.X1 ==
-							7; _f0.X9 {
-							_f0.X0 = int(_f1.X7)
-						} else if _f0.X10 = _f0.X1 ==
-							8; _f0.X10 {
-							_f0.X0 = int(_f1.X8)
-						} else if _f0.X11 = _f0.X1 ==
-							9; _f0.X11 {
-							_f0.X0 = _f1.X9()
+		coroutine.Yield[int, any](len(_f0.X1.B) + _f0.X2.Y)
+	}
+}
+
+//go:noinline
+func TaglessSwitchResume(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.TaglessSwitchResume"`
+		X0 int
+		X1 int
+		X2 bool
+		X3 bool
+		X4 bool
+		X5 int
+		X6 bool
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.TaglessSwitchResume"`
+		X0 int
+		X1 int
+		X2 bool
+		X3 bool
+		X4 bool
+		X5 int
+		X6 bool
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.TaglessSwitchResume"`
+			X0 int
+			X1 int
+			X2 bool
+			X3 bool
+			X4 bool
+			X5 int
+			X6 bool
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = 0
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 9:
+		switch {
+		default:
+			switch {
+			case _f0.IP < 3:
+				_f0.X2 = _f0.X1 >
+					0
+				_f0.IP = 3
+				fallthrough
+			case _f0.IP < 9:
+				if _f0.X2 {
+					coroutine.Yield[int, any](-1)
+				} else {
+					switch {
+					case _f0.IP < 5:
+						_f0.X3 = _f0.X1 ==
+							0
+						_f0.IP = 5
+						fallthrough
+					case _f0.IP < 9:
+						if _f0.X3 {
+							switch {
+							case _f0.IP < 6:
+								_f0.X1 = _f0.X0
+								_f0.IP = 6
+								fallthrough
+							case _f0.IP < 7:
+								coroutine.Yield[int, any](_f0.X1)
+								_f0.IP = 7
+								fallthrough
+							case _f0.IP < 8:
+								coroutine.Yield[int, any](_f0.X1 + 1)
+							}
+						} else {
+
+							coroutine.Yield[int, any](-2)
 						}
 					}
 				}
-				_f0.IP = 13
-				fallthrough
-			case _f0.IP < 14:
-
-				coroutine.Yield[int, any](_f0.X0)
-				_f0.IP = 14
-				fallthrough
-			case _f0.IP < 15:
-				_f1.X10++
-				_f0.IP = 15
-				fallthrough
-			case _f0.IP < 16:
-				return _f1.X10 < 10
 			}
-			return
 		}
-		_f1.IP = 13
+		_f0.IP = 9
 		fallthrough
-	case _f1.IP < 16:
-	_l0:
-		for ; ; _f1.IP = 13 {
+	case _f0.IP < 15:
+		switch {
+		default:
 			switch {
-			case _f1.IP < 14:
-				_f1.X12 = _f1.X11()
-				_f1.IP = 14
-				fallthrough
-			case _f1.IP < 15:
-				_f1.X13 = !_f1.X12
-				_f1.IP = 15
+			case _f0.IP < 10:
+				_f0.X4 = _f0.X1 <
+					0
+				_f0.IP = 10
 				fallthrough
-			case _f1.IP < 16:
-				if _f1.X13 {
-					break _l0
+			case _f0.IP < 15:
+				if _f0.X4 {
+					coroutine.Yield[int, any](-3)
+				} else {
+					switch {
+					case _f0.IP < 12:
+						_f0.X5 = yieldIdentity(_f0.X1)
+						_f0.IP = 12
+						fallthrough
+					case _f0.IP < 13:
+						_f0.X6 = _f0.X5 == _f0.X0
+						_f0.IP = 13
+						fallthrough
+					case _f0.IP < 15:
+						if _f0.X6 {
+							switch {
+							case _f0.IP < 14:
+								_f0.X1 = -1
+								_f0.IP = 14
+								fallthrough
+							case _f0.IP < 15:
+								coroutine.Yield[int, any](_f0.X1)
+							}
+						}
+					}
 				}
 			}
 		}
@@ -1979,50 +5449,20 @@ func Range10ClosureHeterogenousCapture() {
 }
 
 //go:noinline
-func Range10Heterogenous() {
+func yieldIdentity(_fn0 int) (_ int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP  int
-		X0  int8
-		X1  int16
-		X2  int32
-		X3  int64
-		X4  uint8
-		X5  uint16
-		X6  uint32
-		X7  uint64
-		X8  uintptr
-		X9  int
-		X10 int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.yieldIdentity"`
+		X0 int
 	} = coroutine.Push[struct {
-		IP  int
-		X0  int8
-		X1  int16
-		X2  int32
-		X3  int64
-		X4  uint8
-		X5  uint16
-		X6  uint32
-		X7  uint64
-		X8  uintptr
-		X9  int
-		X10 int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.yieldIdentity"`
+		X0 int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP  int
-			X0  int8
-			X1  int16
-			X2  int32
-			X3  int64
-			X4  uint8
-			X5  uint16
-			X6  uint32
-			X7  uint64
-			X8  uintptr
-			X9  int
-			X10 int
-		}{}
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.yieldIdentity"`
+			X0 int
+		}{X0: _fn0}
 	}
 	defer func() {
 		if !_c.Unwinding() {
@@ -2030,137 +5470,217 @@ func Range10Heterogenous() {
 		}
 	}()
 	switch {
-	case _f0.IP < 10:
-		{
-			_f0.X0 = 0
-			_f0.X1 = 1
-			_f0.X2 = 2
-			_f0.X3 = 3
-			_f0.X4 = 4
-			_f0.X5 = 5
-			_f0.X6 = 6
-			_f0.X7 = 7
-			_f0.X8 = 8
+	case _f0.IP < 2:
+		coroutine.Yield[int, any](_f0.X0)
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		return _f0.X0
+	}
+	return
+}
+
+//go:noinline
+func NamedResultsAfterYield(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.NamedResultsAfterYield"`
+		X0 int
+		X1 int
+		X2 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.NamedResultsAfterYield"`
+		X0 int
+		X1 int
+		X2 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.NamedResultsAfterYield"`
+			X0 int
+			X1 int
+			X2 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
 		}
-		_f0.IP = 10
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = 0
+		_f0.IP = 2
 		fallthrough
-	case _f0.IP < 23:
-		switch {
-		case _f0.IP < 11:
-			_f0.X9 = 0
-			_f0.IP = 11
-			fallthrough
-		case _f0.IP < 23:
-			for ; _f0.X9 < 10; _f0.X9, _f0.IP = _f0.X9+1, 11 {
-				switch {
-				case _f0.IP < 12:
-					_f0.IP = 12
-					fallthrough
-				case _f0.IP < 22:
+	case _f0.IP < 4:
+		for ; _f0.X1 < _f0.X0; _f0.X1, _f0.IP = _f0.X1+1, 2 {
+			switch {
+			case _f0.IP < 3:
+				_f0.X2 = namedResults(_f0.X1)
+				_f0.IP = 3
+				fallthrough
+			case _f0.IP < 4:
+				coroutine.Yield[int, any](_f0.X2)
+			}
+		}
+	}
+}
 
-					switch _f0.X9 {
-					case 0:
-						_f0.X10 = int(_f0.X0)
-					case 1:
-						_f0.X10 = int(_f0.X1)
-					case 2:
-						_f0.X10 = int(_f0.X2)
-					case 3:
-						_f0.X10 = int(_f0.X3)
-					case 4:
-						_f0.X10 = int(_f0.X4)
-					case 5:
-						_f0.X10 = int(_f0.X5)
-					case 6:
-						_f0.X10 = int(_f0.X6)
-					case 7:
-						_f0.X10 = int(_f0.X7)
-					case 8:
-						_f0.X10 = int(_f0.X8)
-					case 9:
-						_f0.X10 = int(_f0.X9)
+//go:noinline
+func namedResults(_fn0 int) (_fn1 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.namedResults"`
+		X0 int
+		X1 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.namedResults"`
+		X0 int
+		X1 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.namedResults"`
+			X0 int
+			X1 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = _f0.X0 * 10
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		coroutine.Yield[int, any](_f0.X1)
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 4:
+		_f0.X1++
+		_f0.IP = 4
+		fallthrough
+	case _f0.IP < 5:
+		return _f0.X1
+	}
+	return
+}
+
+//go:noinline
+func TupleAssignmentAcrossYields(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.TupleAssignmentAcrossYields"`
+		X0 int
+		X1 int
+		X2 error
+		X3 int
+		X4 int
+		X5 error
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.TupleAssignmentAcrossYields"`
+		X0 int
+		X1 int
+		X2 error
+		X3 int
+		X4 int
+		X5 error
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.TupleAssignmentAcrossYields"`
+			X0 int
+			X1 int
+			X2 error
+			X3 int
+			X4 int
+			X5 error
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 8:
+		switch {
+		case _f0.IP < 4:
+			_f0.X3 = 0
+			_f0.IP = 4
+			fallthrough
+		case _f0.IP < 8:
+		_l0:
+			for ; _f0.X3 < _f0.X0; _f0.X3, _f0.IP = _f0.X3+1, 4 {
+				switch {
+				case _f0.IP < 5:
+					_f0.X1, _f0.X2 = tupleWork(_f0.X3)
+					_f0.IP = 5
+					fallthrough
+				case _f0.IP < 7:
+					if _f0.X2 !=
+						nil {
+						switch {
+						case _f0.IP < 6:
+							coroutine.Yield[int, any](-1)
+							_f0.IP = 6
+							fallthrough
+						case _f0.IP < 7:
+							continue _l0
+						}
 					}
-					_f0.IP = 22
+					_f0.IP = 7
 					fallthrough
-				case _f0.IP < 23:
-					coroutine.Yield[int, any](_f0.X10)
+				case _f0.IP < 8:
+
+					coroutine.Yield[int, any](_f0.X1)
 				}
 			}
 		}
+		_f0.IP = 8
+		fallthrough
+	case _f0.IP < 9:
+		_f0.X4, _f0.X5 = tupleWork(_f0.X0)
+		_f0.IP = 9
+		fallthrough
+	case _f0.IP < 10:
+		coroutine.Yield[int, any](_f0.X4)
+		_f0.IP = 10
+		fallthrough
+	case _f0.IP < 11:
+		if _f0.X5 ==
+			nil {
+			coroutine.Yield[int, any](_f0.X0)
+		}
 	}
 }
 
 //go:noinline
-func Select(_fn0 int) {
+func tupleWork(_fn0 int) (_ int, _ error) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP  int
-		X0  int
-		X1  int
-		X2  int
-		X3  bool
-		X4  int
-		X5  int
-		X6  <-chan time.Time
-		X7  <-chan time.Time
-		X8  int
-		X9  bool
-		X10 bool
-		X11 int
-		X12 <-chan time.Time
-		X13 int
-		X14 bool
-		X15 int
-		X16 <-chan time.Time
-		X17 int
-		X18 bool
-		X19 int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.tupleWork"`
+		X0 int
+		X1 error
 	} = coroutine.Push[struct {
-		IP  int
-		X0  int
-		X1  int
-		X2  int
-		X3  bool
-		X4  int
-		X5  int
-		X6  <-chan time.Time
-		X7  <-chan time.Time
-		X8  int
-		X9  bool
-		X10 bool
-		X11 int
-		X12 <-chan time.Time
-		X13 int
-		X14 bool
-		X15 int
-		X16 <-chan time.Time
-		X17 int
-		X18 bool
-		X19 int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.tupleWork"`
+		X0 int
+		X1 error
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP  int
-			X0  int
-			X1  int
-			X2  int
-			X3  bool
-			X4  int
-			X5  int
-			X6  <-chan time.Time
-			X7  <-chan time.Time
-			X8  int
-			X9  bool
-			X10 bool
-			X11 int
-			X12 <-chan time.Time
-			X13 int
-			X14 bool
-			X15 int
-			X16 <-chan time.Time
-			X17 int
-			X18 bool
-			X19 int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.tupleWork"`
+			X0 int
+			X1 error
 		}{X0: _fn0}
 	}
 	defer func() {
@@ -2169,363 +5689,359 @@ func Select(_fn0 int) {
 		}
 	}()
 	switch {
-	case _f0.IP < 6:
-		switch {
-		case _f0.IP < 2:
-			_f0.X1 = 0
-			_f0.IP = 2
-			fallthrough
-		case _f0.IP < 3:
-			select {
-			default:
-				_f0.X1 = 1
-			}
-			_f0.IP = 3
-			fallthrough
-		case _f0.IP < 6:
+	case _f0.IP < 2:
+		coroutine.Yield[int, any](_f0.X0 * 10)
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 4:
+		if _f0.X0%
+			2 != 0 {
 			switch {
-			case _f0.IP < 4:
-				_f0.X2 = _f0.X1
-				_f0.IP = 4
+			case _f0.IP < 3:
+				_f0.X1 = errors.New("odd")
+				_f0.IP = 3
 				fallthrough
-			case _f0.IP < 6:
-				switch {
-				default:
-					switch {
-					case _f0.IP < 5:
-						_f0.X3 = _f0.X2 == 1
-						_f0.IP = 5
-						fallthrough
-					case _f0.IP < 6:
-						if _f0.X3 {
-
-							coroutine.Yield[int, any](-1)
-						}
-					}
-				}
+			case _f0.IP < 4:
+				return 0, _f0.X1
 			}
 		}
-		_f0.IP = 6
+		_f0.IP = 4
 		fallthrough
-	case _f0.IP < 24:
-		switch {
-		case _f0.IP < 7:
-			_f0.X4 = 0
-			_f0.IP = 7
-			fallthrough
-		case _f0.IP < 24:
-			for ; _f0.X4 < _f0.X0; _f0.X4, _f0.IP = _f0.X4+1, 7 {
-				switch {
-				case _f0.IP < 17:
-					switch {
-					case _f0.IP < 8:
-						_f0.X5 = 0
-						_f0.IP = 8
-						fallthrough
-					case _f0.IP < 9:
-						_f0.X6 = time.After(0)
-						_f0.IP = 9
-						fallthrough
-					case _f0.IP < 10:
-						_f0.X7 = time.After(1 * time.Second)
-						_f0.IP = 10
-						fallthrough
-					case _f0.IP < 12:
-						select {
-						case <-_f0.X6:
-							_f0.X5 = 1
-						case <-_f0.X7:
-							_f0.X5 = 2
-						}
-						_f0.IP = 12
-						fallthrough
-					case _f0.IP < 17:
-						switch {
-						case _f0.IP < 13:
-							_f0.X8 = _f0.X5
-							_f0.IP = 13
-							fallthrough
-						case _f0.IP < 17:
-						_l2:
-							switch {
-							default:
-								switch {
-								case _f0.IP < 14:
-									_f0.X9 = _f0.X8 == 1
-									_f0.IP = 14
-									fallthrough
-								case _f0.IP < 17:
-									if _f0.X9 {
-										switch {
-										case _f0.IP < 15:
-											if _f0.X4 >=
-												5 {
-												break _l2
-											}
-											_f0.IP = 15
-											fallthrough
-										case _f0.IP < 16:
+	case _f0.IP < 5:
 
-											coroutine.Yield[int, any](_f0.X4)
-										}
-									} else if _f0.X10 = _f0.X8 == 2; _f0.X10 {
+		return _f0.X0 + 1, nil
+	}
+	return
+}
 
-										panic("unreachable")
-									}
-								}
-							}
-						}
-					}
-					_f0.IP = 17
-					fallthrough
-				case _f0.IP < 24:
+//go:noinline
+func ErrorCheckAcrossYields(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.ErrorCheckAcrossYields"`
+		X0 int
+		X1 int
+		X2 error
+		X3 string
+		X4 int
+		X5 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.ErrorCheckAcrossYields"`
+		X0 int
+		X1 int
+		X2 error
+		X3 string
+		X4 int
+		X5 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.ErrorCheckAcrossYields"`
+			X0 int
+			X1 int
+			X2 error
+			X3 string
+			X4 int
+			X5 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = 0
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 7:
+		for ; _f0.X1 < _f0.X0; _f0.X1, _f0.IP = _f0.X1+1, 2 {
+			switch {
+			case _f0.IP < 3:
+				_f0.X2 = checkedWork(_f0.X1)
+				_f0.IP = 3
+				fallthrough
+			case _f0.IP < 7:
+				if _f0.X2 != nil {
 					switch {
-					case _f0.IP < 18:
-						_f0.X11 = 0
-						_f0.IP = 18
+					case _f0.IP < 4:
+						_f0.X3 = _f0.X2.
+							Error()
+						_f0.IP = 4
 						fallthrough
-					case _f0.IP < 19:
-						_f0.X12 = time.After(0)
-						_f0.IP = 19
+					case _f0.IP < 5:
+						_f0.X4 = len(_f0.X3)
+						_f0.IP = 5
 						fallthrough
-					case _f0.IP < 20:
-						select {
-						case <-_f0.X12:
-							_f0.X11 = 1
-						}
-						_f0.IP = 20
+					case _f0.IP < 6:
+						_f0.X5 = -_f0.X4
+						_f0.IP = 6
 						fallthrough
-					case _f0.IP < 24:
-						switch {
-						case _f0.IP < 21:
-							_f0.X13 = _f0.X11
-							_f0.IP = 21
-							fallthrough
-						case _f0.IP < 24:
-						_l3:
-							switch {
-							default:
-								switch {
-								case _f0.IP < 22:
-									_f0.X14 = _f0.X13 == 1
-									_f0.IP = 22
-									fallthrough
-								case _f0.IP < 24:
-									if _f0.X14 {
-										switch {
-										case _f0.IP < 23:
-											if _f0.X4 >=
-												6 {
-												break _l3
-											}
-											_f0.IP = 23
-											fallthrough
-										case _f0.IP < 24:
-
-											coroutine.Yield[int, any](_f0.X4 * 10)
-										}
-									}
-								}
-							}
-						}
+					case _f0.IP < 7:
+						coroutine.Yield[int, any](_f0.X5)
 					}
 				}
 			}
 		}
-		_f0.IP = 24
+	}
+}
+
+//go:noinline
+func checkedWork(_fn0 int) (_ error) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.checkedWork"`
+		X0 int
+		X1 int
+		X2 error
+		X3 int
+		X4 error
+		X5 int
+		X6 error
+		X7 error
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.checkedWork"`
+		X0 int
+		X1 int
+		X2 error
+		X3 int
+		X4 error
+		X5 int
+		X6 error
+		X7 error
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.checkedWork"`
+			X0 int
+			X1 int
+			X2 error
+			X3 int
+			X4 error
+			X5 int
+			X6 error
+			X7 error
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1, _f0.X6 = tupleWork(_f0.X0)
+		_f0.IP = 2
 		fallthrough
-	case _f0.IP < 31:
+	case _f0.IP < 3:
+		coroutine.Yield[int, any](_f0.X1)
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 4:
+		if _f0.X6 != nil {
+			return _f0.X6
+		}
+		_f0.IP = 4
+		fallthrough
+	case _f0.IP < 7:
 		switch {
-		case _f0.IP < 25:
-			_f0.X15 = 0
-			_f0.IP = 25
-			fallthrough
-		case _f0.IP < 26:
-			_f0.X16 = time.After(0)
-			_f0.IP = 26
+		case _f0.IP < 5:
+			_f0.X3, _f0.X4 = tupleWork(_f0.X1 + 1)
+			_f0.IP = 5
 			fallthrough
-		case _f0.IP < 27:
-			select {
-			case <-_f0.X16:
-				_f0.X15 = 1
+		case _f0.IP < 7:
+			if _f0.X4 != nil {
+				return _f0.X4
+			} else {
+				coroutine.Yield[int, any](_f0.X3)
 			}
-			_f0.IP = 27
-			fallthrough
-		case _f0.IP < 31:
+		}
+		_f0.IP = 7
+		fallthrough
+	case _f0.IP < 8:
+		_f0.X5, _f0.X6 = tupleWork(_f0.X1)
+		_f0.IP = 8
+		fallthrough
+	case _f0.IP < 9:
+		coroutine.Yield[int, any](_f0.X5)
+		_f0.IP = 9
+		fallthrough
+	case _f0.IP < 12:
+		if _f0.X6 !=
+			nil {
 			switch {
-			case _f0.IP < 28:
-				_f0.X17 = _f0.X15
-				_f0.IP = 28
-				fallthrough
-			case _f0.IP < 31:
+			case _f0.IP < 11:
 				switch {
-				default:
-					switch {
-					case _f0.IP < 29:
-						_f0.X18 = _f0.X17 == 1
-						_f0.IP = 29
-						fallthrough
-					case _f0.IP < 31:
-						if _f0.X18 {
-							switch {
-							case _f0.IP < 30:
-								_f0.X19 = 0
-								_f0.IP = 30
-								fallthrough
-							case _f0.IP < 31:
-								for ; _f0.X19 < 3; _f0.X19, _f0.IP = _f0.X19+1, 30 {
-									coroutine.Yield[int, any](_f0.X19)
-								}
-							}
-						}
+				case _f0.IP < 10:
+
+					_, _f0.X7 = tupleWork(_f0.X1 + 1)
+					_f0.IP = 10
+					fallthrough
+				case _f0.IP < 11:
+					if _f0.X7 != nil {
+						return _f0.X7
 					}
 				}
+				_f0.IP = 11
+				fallthrough
+			case _f0.IP < 12:
+				return fmt.Errorf("work %d: %w", _f0.X0, _f0.X6)
 			}
 		}
+		_f0.IP = 12
+		fallthrough
+	case _f0.IP < 13:
+
+		return nil
 	}
+	return
 }
 
 //go:noinline
-func YieldingExpressionDesugaring() {
+func MinMaxClear(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.MinMaxClear"`
+		X0 int
+		X1 int
+		X2 int
+		X3 int
+		X4 int
+		X5 int
+		X6 map[int]int
+		X7 []int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.MinMaxClear"`
+		X0 int
+		X1 int
+		X2 int
+		X3 int
+		X4 int
+		X5 int
+		X6 map[int]int
+		X7 []int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.MinMaxClear"`
+			X0 int
+			X1 int
+			X2 int
+			X3 int
+			X4 int
+			X5 int
+			X6 map[int]int
+			X7 []int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = 1
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		_f0.X2 = yieldIdentity(_f0.X0)
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 4:
+		_f0.X3 = max(_f0.X1, _f0.X2)
+		_f0.IP = 4
+		fallthrough
+	case _f0.IP < 5:
+		coroutine.Yield[int, any](_f0.X3)
+		_f0.IP = 5
+		fallthrough
+	case _f0.IP < 6:
+		_f0.X4 = yieldIdentity(_f0.X3 + 1)
+		_f0.IP = 6
+		fallthrough
+	case _f0.IP < 7:
+		_f0.X5 = min(_f0.X4, _f0.X3, _f0.X0)
+		_f0.IP = 7
+		fallthrough
+	case _f0.IP < 8:
+		coroutine.Yield[int, any](_f0.X5)
+		_f0.IP = 8
+		fallthrough
+	case _f0.IP < 9:
+		_f0.X6 = map[int]int{_f0.X3: _f0.X3, _f0.X5 + 1: _f0.X5}
+		_f0.IP = 9
+		fallthrough
+	case _f0.IP < 10:
+		_f0.X7 = []int{_f0.X3, _f0.X5}
+		_f0.IP = 10
+		fallthrough
+	case _f0.IP < 11:
+		clear(_f0.X6)
+		_f0.IP = 11
+		fallthrough
+	case _f0.IP < 12:
+		clear(_f0.X7)
+		_f0.IP = 12
+		fallthrough
+	case _f0.IP < 13:
+		coroutine.Yield[int, any](len(_f0.X6))
+		_f0.IP = 13
+		fallthrough
+	case _f0.IP < 14:
+		coroutine.Yield[int, any](_f0.X7[0] + _f0.X7[1])
+	}
+}
+
+//go:noinline
+func CopyYieldingOperands(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP  int
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.CopyYieldingOperands"`
 		X0  int
-		X1  int
-		X2  int
+		X1  []int
+		X2  []int
 		X3  int
-		X4  bool
+		X4  []int
 		X5  int
 		X6  int
-		X7  int
-		X8  int
+		X7  []int
+		X8  []int
 		X9  int
-		X10 bool
+		X10 []int
 		X11 int
-		X12 int
-		X13 int
-		X14 int
-		X15 int
-		X16 bool
-		X17 int
-		X18 int
-		X19 int
-		X20 int
-		X21 bool
-		X22 bool
-		X23 int
-		X24 int
-		X25 int
-		X26 int
-		X27 int
-		X28 bool
-		X29 int
-		X30 int
-		X31 bool
-		X32 int
-		X33 int
-		X34 int
-		X35 bool
-		X36 int
-		X37 int
-		X38 int
-		X39 bool
-		X40 int
-		X41 int
-		X42 any
 	} = coroutine.Push[struct {
-		IP  int
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.CopyYieldingOperands"`
 		X0  int
-		X1  int
-		X2  int
+		X1  []int
+		X2  []int
 		X3  int
-		X4  bool
+		X4  []int
 		X5  int
 		X6  int
-		X7  int
-		X8  int
+		X7  []int
+		X8  []int
 		X9  int
-		X10 bool
+		X10 []int
 		X11 int
-		X12 int
-		X13 int
-		X14 int
-		X15 int
-		X16 bool
-		X17 int
-		X18 int
-		X19 int
-		X20 int
-		X21 bool
-		X22 bool
-		X23 int
-		X24 int
-		X25 int
-		X26 int
-		X27 int
-		X28 bool
-		X29 int
-		X30 int
-		X31 bool
-		X32 int
-		X33 int
-		X34 int
-		X35 bool
-		X36 int
-		X37 int
-		X38 int
-		X39 bool
-		X40 int
-		X41 int
-		X42 any
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP  int
+			IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.CopyYieldingOperands"`
 			X0  int
-			X1  int
-			X2  int
+			X1  []int
+			X2  []int
 			X3  int
-			X4  bool
+			X4  []int
 			X5  int
 			X6  int
-			X7  int
-			X8  int
+			X7  []int
+			X8  []int
 			X9  int
-			X10 bool
+			X10 []int
 			X11 int
-			X12 int
-			X13 int
-			X14 int
-			X15 int
-			X16 bool
-			X17 int
-			X18 int
-			X19 int
-			X20 int
-			X21 bool
-			X22 bool
-			X23 int
-			X24 int
-			X25 int
-			X26 int
-			X27 int
-			X28 bool
-			X29 int
-			X30 int
-			X31 bool
-			X32 int
-			X33 int
-			X34 int
-			X35 bool
-			X36 int
-			X37 int
-			X38 int
-			X39 bool
-			X40 int
-			X41 int
-			X42 any
-		}{}
+		}{X0: _fn0}
 	}
 	defer func() {
 		if !_c.Unwinding() {
@@ -2533,294 +6049,356 @@ func YieldingExpressionDesugaring() {
 		}
 	}()
 	switch {
-	case _f0.IP < 21:
+	case _f0.IP < 2:
+		_f0.X1 = make([]int, _f0.X0)
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		_f0.X2 = yieldingRead(_f0.X0)
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 4:
+		_f0.X3 = copy(_f0.X1, _f0.X2)
+		_f0.IP = 4
+		fallthrough
+	case _f0.IP < 5:
+		coroutine.Yield[int, any](_f0.X3)
+		_f0.IP = 5
+		fallthrough
+	case _f0.IP < 9:
 		switch {
-		case _f0.IP < 2:
-			_f0.X0 = b(1)
-			_f0.IP = 2
-			fallthrough
-		case _f0.IP < 3:
-			_f0.X1 = a(_f0.X0)
-			_f0.IP = 3
-			fallthrough
-		case _f0.IP < 4:
-			_f0.X2 = b(2)
-			_f0.IP = 4
-			fallthrough
-		case _f0.IP < 5:
-			_f0.X3 = a(_f0.X2)
-			_f0.IP = 5
-			fallthrough
 		case _f0.IP < 6:
-			_f0.X4 = _f0.X1 == _f0.X3
+			_f0.X4 = _f0.X1
 			_f0.IP = 6
 			fallthrough
-		case _f0.IP < 21:
-			if _f0.X4 {
-			} else {
-				switch {
-				case _f0.IP < 8:
-					_f0.X5 = b(3)
-					_f0.IP = 8
-					fallthrough
-				case _f0.IP < 9:
-					_f0.X6 = a(_f0.X5)
-					_f0.IP = 9
-					fallthrough
-				case _f0.IP < 10:
-					_f0.X7 = b(4)
-					_f0.IP = 10
-					fallthrough
-				case _f0.IP < 11:
-					_f0.X8 = a(_f0.X7)
-					_f0.IP = 11
-					fallthrough
-				case _f0.IP < 12:
-					_f0.X9 = _f0.X8 - 1
-					_f0.IP = 12
-					fallthrough
-				case _f0.IP < 13:
-					_f0.X10 = _f0.X6 == _f0.X9
-					_f0.IP = 13
-					fallthrough
-				case _f0.IP < 21:
-					if _f0.X10 {
-						switch {
-						case _f0.IP < 14:
-							_f0.X11 = b(5)
-							_f0.IP = 14
-							fallthrough
-						case _f0.IP < 15:
-							_f0.X12 = a(_f0.X11)
-							_f0.IP = 15
-							fallthrough
-						case _f0.IP < 16:
-							_f0.X13 = _f0.X12 * 10
-							_f0.IP = 16
-							fallthrough
-						case _f0.IP < 17:
-							coroutine.Yield[int, any](_f0.X13)
-						}
-					} else {
-						switch {
-						case _f0.IP < 18:
-							_f0.X14 = b(100)
-							_f0.IP = 18
-							fallthrough
-						case _f0.IP < 19:
-							_f0.X15 = a(_f0.X14)
-							_f0.IP = 19
-							fallthrough
-						case _f0.IP < 20:
-							_f0.X16 = _f0.X15 == 100
-							_f0.IP = 20
-							fallthrough
-						case _f0.IP < 21:
-							if _f0.X16 {
-								panic("unreachable")
-							}
-						}
+		case _f0.IP < 9:
+			switch {
+			case _f0.IP < 7:
+				_f0.X5 = 0
+				_f0.IP = 7
+				fallthrough
+			case _f0.IP < 9:
+				for ; _f0.X5 < len(_f0.X4); _f0.X5, _f0.IP = _f0.X5+1, 7 {
+					switch {
+					case _f0.IP < 8:
+						_f0.X6 = _f0.X4[_f0.X5]
+						_f0.IP = 8
+						fallthrough
+					case _f0.IP < 9:
+
+						coroutine.Yield[int, any](_f0.X6)
 					}
 				}
 			}
 		}
-		_f0.IP = 21
+		_f0.IP = 9
 		fallthrough
-	case _f0.IP < 29:
-		switch {
-		case _f0.IP < 22:
-			_f0.X17 = b(6)
-			_f0.IP = 22
-			fallthrough
-		case _f0.IP < 23:
-			_f0.X18 = a(_f0.X17)
-			_f0.IP = 23
-			fallthrough
-		case _f0.IP < 29:
-		_l0:
-			for ; ; _f0.X18, _f0.IP = _f0.X18+1, 23 {
-				switch {
-				case _f0.IP < 28:
-					switch {
-					case _f0.IP < 24:
-						_f0.X19 = b(8)
-						_f0.IP = 24
-						fallthrough
-					case _f0.IP < 25:
-						_f0.X20 = a(_f0.X19)
-						_f0.IP = 25
-						fallthrough
-					case _f0.IP < 26:
-						_f0.X21 = _f0.X18 < _f0.X20
-						_f0.IP = 26
-						fallthrough
-					case _f0.IP < 27:
-						_f0.X22 = !_f0.X21
-						_f0.IP = 27
+	case _f0.IP < 10:
+		_f0.X7 = make([]int, 2)
+		_f0.IP = 10
+		fallthrough
+	case _f0.IP < 11:
+		_f0.X8 = yieldingSlice(_f0.X7)
+		_f0.IP = 11
+		fallthrough
+	case _f0.IP < 12:
+		_f0.X9 = yieldIdentity(1)
+		_f0.IP = 12
+		fallthrough
+	case _f0.IP < 13:
+		_f0.X10 = _f0.X1[_f0.X9:]
+		_f0.IP = 13
+		fallthrough
+	case _f0.IP < 14:
+		_f0.X11 = copy(_f0.X8, _f0.X10)
+		_f0.IP = 14
+		fallthrough
+	case _f0.IP < 15:
+		coroutine.Yield[int, any](_f0.X11)
+		_f0.IP = 15
+		fallthrough
+	case _f0.IP < 16:
+		coroutine.Yield[int, any](_f0.X7[0] + _f0.X7[1])
+	}
+}
+
+//go:noinline
+func InfiniteLoopConditionalBreak(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.InfiniteLoopConditionalBreak"`
+		X0 int
+		X1 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.InfiniteLoopConditionalBreak"`
+		X0 int
+		X1 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.InfiniteLoopConditionalBreak"`
+			X0 int
+			X1 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = 0
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 7:
+	_l0:
+		for ; ; _f0.IP = 2 {
+			switch {
+			case _f0.IP < 3:
+
+				coroutine.Yield[int, any](_f0.X1)
+				_f0.IP = 3
+				fallthrough
+			case _f0.IP < 4:
+				_f0.X1++
+				_f0.IP = 4
+				fallthrough
+			case _f0.IP < 6:
+				if _f0.X1 == _f0.X0 {
+					switch {
+					case _f0.IP < 5:
+						coroutine.Yield[int, any](-_f0.X1)
+						_f0.IP = 5
 						fallthrough
-					case _f0.IP < 28:
-						if _f0.X22 {
-							break _l0
-						}
+					case _f0.IP < 6:
+						break _l0
 					}
-					_f0.IP = 28
-					fallthrough
-				case _f0.IP < 29:
-					coroutine.Yield[int, any](70)
 				}
+				_f0.IP = 6
+				fallthrough
+			case _f0.IP < 7:
+
+				coroutine.Yield[int, any](_f0.X1 * 10)
 			}
 		}
-		_f0.IP = 29
+		_f0.IP = 7
 		fallthrough
-	case _f0.IP < 51:
+	case _f0.IP < 8:
+
+		coroutine.Yield[int, any](_f0.X1 * 100)
+	}
+}
+
+//go:noinline
+func SharedPointers(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SharedPointers"`
+		X0 int
+		X1 *int
+		X2 *int
+		X3 int
+		X4 any
+		X5 int
+		X6 bool
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SharedPointers"`
+		X0 int
+		X1 *int
+		X2 *int
+		X3 int
+		X4 any
+		X5 int
+		X6 bool
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.SharedPointers"`
+			X0 int
+			X1 *int
+			X2 *int
+			X3 int
+			X4 any
+			X5 int
+			X6 bool
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.X1 = new(int)
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		_f0.X2 = _f0.X1
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 8:
 		switch {
-		case _f0.IP < 30:
-			_f0.X23 = b(9)
-			_f0.IP = 30
-			fallthrough
-		case _f0.IP < 31:
-			_f0.X24 = a(_f0.X23)
-			_f0.IP = 31
-			fallthrough
-		case _f0.IP < 32:
-			_f0.X25 = _f0.X24
-			_f0.IP = 32
+		case _f0.IP < 4:
+			_f0.X3 = 0
+			_f0.IP = 4
 			fallthrough
-		case _f0.IP < 51:
-			switch {
-			default:
+		case _f0.IP < 8:
+			for ; _f0.X3 < _f0.X0; _f0.X3, _f0.IP = _f0.X3+1, 4 {
 				switch {
-				case _f0.IP < 33:
-					_f0.X26 = b(10)
-					_f0.IP = 33
-					fallthrough
-				case _f0.IP < 34:
-					_f0.X27 = a(_f0.X26)
-					_f0.IP = 34
+				case _f0.IP < 5:
+					_f0.X4 = coroutine.Yield[int, any](*_f0.X2)
+					_f0.IP = 5
 					fallthrough
-				case _f0.IP < 35:
-					_f0.X28 = _f0.X25 == _f0.X27
-					_f0.IP = 35
+				case _f0.IP < 6:
+					_f0.X5, _f0.X6 = _f0.X4.(int)
+					_f0.IP = 6
 					fallthrough
-				case _f0.IP < 51:
-					if _f0.X28 {
-						panic("unreachable")
+				case _f0.IP < 8:
+					if _f0.X6 {
+						*_f0.X1 += _f0.X5
 					} else {
-						switch {
-						case _f0.IP < 37:
-							_f0.X29 = b(11)
-							_f0.IP = 37
-							fallthrough
-						case _f0.IP < 38:
-							_f0.X30 = a(_f0.X29)
-							_f0.IP = 38
-							fallthrough
-						case _f0.IP < 39:
-							_f0.X31 = _f0.X25 == _f0.X30
-							_f0.IP = 39
-							fallthrough
-						case _f0.IP < 51:
-							if _f0.X31 {
-								panic("unreachable")
-							} else {
-								switch {
-								case _f0.IP < 41:
-									_f0.X32 = b(12)
-									_f0.IP = 41
-									fallthrough
-								case _f0.IP < 42:
-									_f0.X33 = a(_f0.X32)
-									_f0.IP = 42
-									fallthrough
-								case _f0.IP < 43:
-									_f0.X34 = _f0.X33 - 3
-									_f0.IP = 43
-									fallthrough
-								case _f0.IP < 44:
-									_f0.X35 = _f0.X25 == _f0.X34
-									_f0.IP = 44
-									fallthrough
-								case _f0.IP < 51:
-									if _f0.X35 {
-										switch {
-										case _f0.IP < 45:
-											_f0.X36 = b(13)
-											_f0.IP = 45
-											fallthrough
-										case _f0.IP < 46:
-											a(_f0.X36)
-										}
-									} else {
-										switch {
-										case _f0.IP < 47:
-											_f0.X37 = b(14)
-											_f0.IP = 47
-											fallthrough
-										case _f0.IP < 48:
-											_f0.X38 = a(_f0.X37)
-											_f0.IP = 48
-											fallthrough
-										case _f0.IP < 49:
-											_f0.X39 = _f0.X25 == _f0.X38
-											_f0.IP = 49
-											fallthrough
-										case _f0.IP < 51:
-											if _f0.X39 {
-												panic("unreachable")
-											} else {
-												panic("unreachable")
-											}
-										}
-									}
-								}
-							}
-						}
+						*_f0.X1++
 					}
 				}
 			}
 		}
-		_f0.IP = 51
+	}
+}
+
+var globalCounter int
+
+//go:noinline
+func GlobalPointer(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.GlobalPointer"`
+		X0 int
+		X1 *int
+		X2 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.GlobalPointer"`
+		X0 int
+		X1 *int
+		X2 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.GlobalPointer"`
+			X0 int
+			X1 *int
+			X2 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		globalCounter = 0
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		_f0.X1 = &globalCounter
+		_f0.IP = 3
 		fallthrough
-	case _f0.IP < 57:
+	case _f0.IP < 9:
 		switch {
-		case _f0.IP < 52:
-			_f0.X40 = b(15)
-			_f0.IP = 52
-			fallthrough
-		case _f0.IP < 53:
-			_f0.X41 = a(_f0.X40)
-			_f0.IP = 53
-			fallthrough
-		case _f0.IP < 54:
-			_f0.X42 = any(_f0.X41)
-			_f0.IP = 54
+		case _f0.IP < 4:
+			_f0.X2 = 0
+			_f0.IP = 4
 			fallthrough
-		case _f0.IP < 57:
-			switch x := _f0.X42.(type) {
-			case bool:
-				panic("unreachable")
-			case int:
-				coroutine.Yield[int, any](x * 10)
-			default:
-				panic("unreachable")
+		case _f0.IP < 9:
+			for ; _f0.X2 < _f0.X0; _f0.X2, _f0.IP = _f0.X2+1, 4 {
+				switch {
+				case _f0.IP < 5:
+					coroutine.Yield[int, any](globalCounter)
+					_f0.IP = 5
+					fallthrough
+				case _f0.IP < 6:
+					*_f0.X1 += 10
+					_f0.IP = 6
+					fallthrough
+				case _f0.IP < 7:
+					coroutine.Yield[int, any](globalCounter)
+					_f0.IP = 7
+					fallthrough
+				case _f0.IP < 8:
+					globalCounter++
+					_f0.IP = 8
+					fallthrough
+				case _f0.IP < 9:
+					coroutine.Yield[int, any](*_f0.X1)
+				}
 			}
 		}
 	}
 }
 
+type Stepper interface {
+	Step(n int) int
+}
+
+type countStepper struct{ total int }
+
+//go:noinline
+func (_fn0 *countStepper) Step(_fn1 int) (_ int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"(*github.com/stealthrocket/coroutine/compiler/testdata.countStepper).Step"`
+		X0 *countStepper
+		X1 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"(*github.com/stealthrocket/coroutine/compiler/testdata.countStepper).Step"`
+		X0 *countStepper
+		X1 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"(*github.com/stealthrocket/coroutine/compiler/testdata.countStepper).Step"`
+			X0 *countStepper
+			X1 int
+		}{X0: _fn0, X1: _fn1}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		coroutine.Yield[int, any](_f0.X1)
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		_f0.X0.
+			total += _f0.X1
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 4:
+		return _f0.X0.total
+	}
+	return
+}
+
+type doubleStepper struct{}
+
 //go:noinline
-func a(_fn0 int) (_ int) {
+func (doubleStepper) Step(_fn0 int) (_ int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"(github.com/stealthrocket/coroutine/compiler/testdata.doubleStepper).Step"`
 		X0 int
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"(github.com/stealthrocket/coroutine/compiler/testdata.doubleStepper).Step"`
 		X0 int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"(github.com/stealthrocket/coroutine/compiler/testdata.doubleStepper).Step"`
 			X0 int
 		}{X0: _fn0}
 	}
@@ -2831,29 +6409,43 @@ func a(_fn0 int) (_ int) {
 	}()
 	switch {
 	case _f0.IP < 2:
-		coroutine.Yield[int, any](_f0.X0)
+		coroutine.Yield[int, any](_f0.X0 * 2)
 		_f0.IP = 2
 		fallthrough
 	case _f0.IP < 3:
-		return _f0.X0
+		return _f0.X0 * 2
 	}
 	return
 }
 
+type embeddedStepper struct{ *countStepper }
+
 //go:noinline
-func b(_fn0 int) (_ int) {
+func InterfaceMethodCall(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.InterfaceMethodCall"`
 		X0 int
+		X1 []Stepper
+		X2 int
+		X3 Stepper
+		X4 int
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.InterfaceMethodCall"`
 		X0 int
+		X1 []Stepper
+		X2 int
+		X3 Stepper
+		X4 int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.InterfaceMethodCall"`
 			X0 int
+			X1 []Stepper
+			X2 int
+			X3 Stepper
+			X4 int
 		}{X0: _fn0}
 	}
 	defer func() {
@@ -2863,39 +6455,74 @@ func b(_fn0 int) (_ int) {
 	}()
 	switch {
 	case _f0.IP < 2:
-		coroutine.Yield[int, any](-_f0.X0)
+		_f0.X1 = []Stepper{&countStepper{}, doubleStepper{}, embeddedStepper{&countStepper{}}}
 		_f0.IP = 2
 		fallthrough
-	case _f0.IP < 3:
-		return _f0.X0
+	case _f0.IP < 6:
+		switch {
+		case _f0.IP < 3:
+			_f0.X2 = 0
+			_f0.IP = 3
+			fallthrough
+		case _f0.IP < 6:
+			for ; _f0.X2 < _f0.X0; _f0.X2, _f0.IP = _f0.X2+1, 3 {
+				switch {
+				case _f0.IP < 4:
+					_f0.X3 = _f0.X1[_f0.X2%len(_f0.X1)]
+					_f0.IP = 4
+					fallthrough
+				case _f0.IP < 5:
+					_f0.X4 = _f0.X3.
+						Step(_f0.X2 + 1)
+					_f0.IP = 5
+					fallthrough
+				case _f0.IP < 6:
+					coroutine.Yield[int, any](_f0.X4)
+				}
+			}
+		}
 	}
-	return
 }
 
+// stepperEmbedder promotes the methods of the Stepper interface it embeds.
+type stepperEmbedder struct{ Stepper }
+
 //go:noinline
-func YieldingDurations() {
+func EmbeddedInterfaceMethodCall(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
-	var _f1 *struct {
-		IP int
-		X0 *time.Duration
-		X1 time.Duration
-		X2 func()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.EmbeddedInterfaceMethodCall"`
+		X0 int
+		X1 stepperEmbedder
+		X2 int
 		X3 int
+		X4 Stepper
+		X5 int
+		X6 Stepper
+		X7 int
 	} = coroutine.Push[struct {
-		IP int
-		X0 *time.Duration
-		X1 time.Duration
-		X2 func()
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.EmbeddedInterfaceMethodCall"`
+		X0 int
+		X1 stepperEmbedder
+		X2 int
 		X3 int
+		X4 Stepper
+		X5 int
+		X6 Stepper
+		X7 int
 	}](&_c.Stack)
-	if _f1.IP == 0 {
-		*_f1 = struct {
-			IP int
-			X0 *time.Duration
-			X1 time.Duration
-			X2 func()
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.EmbeddedInterfaceMethodCall"`
+			X0 int
+			X1 stepperEmbedder
+			X2 int
 			X3 int
-		}{}
+			X4 Stepper
+			X5 int
+			X6 Stepper
+			X7 int
+		}{X0: _fn0}
 	}
 	defer func() {
 		if !_c.Unwinding() {
@@ -2903,145 +6530,390 @@ func YieldingDurations() {
 		}
 	}()
 	switch {
-	case _f1.IP < 2:
-		_f1.X0 = new(time.Duration)
-		_f1.IP = 2
+	case _f0.IP < 2:
+		_f0.X1 = stepperEmbedder{&countStepper{}}
+		_f0.IP = 2
 		fallthrough
-	case _f1.IP < 3:
-		_f1.X1 = time.Duration(100)
-		_f1.IP = 3
+	case _f0.IP < 5:
+		switch {
+		case _f0.IP < 3:
+			_f0.X2 = 0
+			_f0.IP = 3
+			fallthrough
+		case _f0.IP < 5:
+			for ; _f0.X2 < _f0.X0; _f0.X2, _f0.IP = _f0.X2+1, 3 {
+				switch {
+				case _f0.IP < 4:
+					_f0.X3 = _f0.X1.
+						Step(_f0.X2 + 1)
+					_f0.IP = 4
+					fallthrough
+				case _f0.IP < 5:
+					coroutine.Yield[int, any](_f0.X3)
+				}
+			}
+		}
+		_f0.IP = 5
 		fallthrough
-	case _f1.IP < 4:
-		*_f1.X0 = _f1.X1
-		_f1.IP = 4
+	case _f0.IP < 6:
+		_f0.X4 = stepperEmbedder{doubleStepper{}}
+		_f0.IP = 6
 		fallthrough
-	case _f1.IP < 5:
-		_f1.X2 = func() {
-			_c := coroutine.LoadContext[int, any]()
-			var _f0 *struct {
-				IP int
-				X0 int64
-				X1 int
-				X2 time.Duration
-			} = coroutine.Push[struct {
-				IP int
-				X0 int64
-				X1 int
-				X2 time.Duration
-			}](&_c.Stack)
-			if _f0.IP == 0 {
-				*_f0 = struct {
-					IP int
-					X0 int64
-					X1 int
-					X2 time.Duration
-				}{}
-			}
-			defer func() {
-				if !_c.Unwinding() {
-					coroutine.Pop(&_c.Stack)
-				}
-			}()
+	case _f0.IP < 7:
+		_f0.X5 = _f0.X4.
+			Step(_f0.X0)
+		_f0.IP = 7
+		fallthrough
+	case _f0.IP < 8:
+		coroutine.Yield[int, any](_f0.X5)
+		_f0.IP = 8
+		fallthrough
+	case _f0.IP < 9:
+		_f0.X6 = &stepperEmbedder{&countStepper{total: 10}}
+		_f0.IP = 9
+		fallthrough
+	case _f0.IP < 10:
+		_f0.X7 = _f0.X6.
+			Step(_f0.X0 + 2)
+		_f0.IP = 10
+		fallthrough
+	case _f0.IP < 11:
+		coroutine.Yield[int, any](_f0.X7)
+	}
+}
+
+type file struct {
+	fd     int
+	closed *[]int
+}
+
+func (f *file) Close() error {
+	*f.closed = append(*f.closed, f.fd)
+	return nil
+}
+
+type handle struct {
+	id     int
+	closed *[]int
+}
+
+func (h handle) Close() { *h.closed = append(*h.closed, h.id) }
+
+//go:noinline
+func DeferredMethodOnLocal(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.DeferredMethodOnLocal"`
+		X0 int
+		X1 []int
+		X2 []int
+		X3 int
+		X4 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.DeferredMethodOnLocal"`
+		X0 int
+		X1 []int
+		X2 []int
+		X3 int
+		X4 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.DeferredMethodOnLocal"`
+			X0 int
+			X1 []int
+			X2 []int
+			X3 int
+			X4 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+
+		deferClose(_f0.X0, &_f0.X1)
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 7:
+		switch {
+		case _f0.IP < 4:
+			_f0.X2 = _f0.X1
+			_f0.IP = 4
+			fallthrough
+		case _f0.IP < 7:
 			switch {
-			case _f0.IP < 2:
-				_f0.X0 = _f1.X0.
-					Nanoseconds()
-				_f0.IP = 2
-				fallthrough
-			case _f0.IP < 3:
-				_f0.X1 = int(_f0.X0)
-				_f0.IP = 3
-				fallthrough
-			case _f0.IP < 4:
-				_f0.X2 = time.Duration(_f0.X1 + 1)
-				_f0.IP = 4
-				fallthrough
 			case _f0.IP < 5:
-				*_f1.X0 = _f0.X2
+				_f0.X3 = 0
 				_f0.IP = 5
 				fallthrough
+			case _f0.IP < 7:
+				for ; _f0.X3 < len(_f0.X2); _f0.X3, _f0.IP = _f0.X3+1, 5 {
+					switch {
+					case _f0.IP < 6:
+						_f0.X4 = _f0.X2[_f0.X3]
+						_f0.IP = 6
+						fallthrough
+					case _f0.IP < 7:
+
+						coroutine.Yield[int, any](_f0.X4)
+					}
+				}
+			}
+		}
+	}
+}
+
+//go:noinline
+func deferClose(_fn0 int, _fn1 *[]int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.deferClose"`
+		X0 int
+		X1 *[]int
+		X2 []handle
+		X3 []*file
+		X4 handle
+		X5 handle
+		X6 *file
+		X7 *file
+		X8 coroutine.Defers
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.deferClose"`
+		X0 int
+		X1 *[]int
+		X2 []handle
+		X3 []*file
+		X4 handle
+		X5 handle
+		X6 *file
+		X7 *file
+		X8 coroutine.Defers
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.deferClose"`
+			X0 int
+			X1 *[]int
+			X2 []handle
+			X3 []*file
+			X4 handle
+			X5 handle
+			X6 *file
+			X7 *file
+			X8 coroutine.Defers
+		}{X0: _fn0, X1: _fn1}
+	}
+	defer func() {
+		if !_c.Unwinding() || _f0.X8.Running {
+			_c.RunDefers(&_f0.X8, recover())
+		}
+	}()
+	if _f0.X8.Running {
+		return
+	}
+	switch {
+	case _f0.IP < 2:
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 4:
+		_f0.X4 = handle{id: _f0.X0, closed: _f0.X1}
+		_f0.IP = 4
+		fallthrough
+	case _f0.IP < 7:
+		switch {
+		case _f0.IP < 5:
+			_f0.X5 = _f0.X4
+			_f0.IP = 5
+			fallthrough
+		case _f0.IP < 7:
+			switch {
 			case _f0.IP < 6:
-				coroutine.Yield[int, any](_f0.X1)
+				_f0.X2 = append(_f0.X2, _f0.X5)
+				_f0.IP = 6
+				fallthrough
+			case _f0.IP < 7:
+				_f0.X8.Funcs = append(_f0.X8.Funcs, func() {
+					_a0 := _f0.X2[len(_f0.X2)-1]
+					_f0.X2 = _f0.X2[:len(_f0.X2)-1]
+					handle.Close(_a0)
+				})
 			}
 		}
-		_f1.IP = 5
+		_f0.IP = 7
 		fallthrough
-	case _f1.IP < 7:
+	case _f0.IP < 8:
+		_f0.X6 = &file{fd: _f0.X0 * 10, closed: _f0.X1}
+		_f0.IP = 8
+		fallthrough
+	case _f0.IP < 11:
 		switch {
-		case _f1.IP < 6:
-			_f1.X3 = 0
-			_f1.IP = 6
+		case _f0.IP < 9:
+			_f0.X7 = _f0.X6
+			_f0.IP = 9
 			fallthrough
-		case _f1.IP < 7:
-			for ; _f1.X3 < 10; _f1.X3, _f1.IP = _f1.X3+1, 6 {
-				_f1.X2()
+		case _f0.IP < 11:
+			switch {
+			case _f0.IP < 10:
+				_f0.X3 = append(_f0.X3, _f0.X7)
+				_f0.IP = 10
+				fallthrough
+			case _f0.IP < 11:
+				_f0.X8.Funcs = append(_f0.X8.Funcs, func() {
+					_a0 := _f0.X3[len(_f0.X3)-1]
+					_f0.X3 = _f0.X3[:len(_f0.X3)-1]
+					(*file).Close(_a0)
+				})
 			}
 		}
+		_f0.IP = 11
+		fallthrough
+	case _f0.IP < 12:
+
+		coroutine.Yield[int, any](_f0.X4.id)
+		_f0.IP = 12
+		fallthrough
+	case _f0.IP < 13:
+		_f0.X4 = handle{id: -1, closed: _f0.X1}
+		_f0.IP = 13
+		fallthrough
+	case _f0.IP < 14:
+		_f0.X6.
+			fd++
+		_f0.IP = 14
+		fallthrough
+	case _f0.IP < 15:
+		coroutine.Yield[int, any](_f0.X4.id)
 	}
 }
 
 //go:noinline
-func YieldAndDeferAssign(_fn0 *int, _fn1, _fn2 int) {
+func MakeYieldingSize(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
-		X0 *int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.MakeYieldingSize"`
+		X0 int
 		X1 int
 		X2 int
-		X3 []func()
+		X3 []int
+		X4 int
+		X5 map[int]int
 	} = coroutine.Push[struct {
-		IP int
-		X0 *int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.MakeYieldingSize"`
+		X0 int
 		X1 int
 		X2 int
-		X3 []func()
+		X3 []int
+		X4 int
+		X5 map[int]int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
-			X0 *int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.MakeYieldingSize"`
+			X0 int
 			X1 int
 			X2 int
-			X3 []func()
-		}{X0: _fn0, X1: _fn1, X2: _fn2}
+			X3 []int
+			X4 int
+			X5 map[int]int
+		}{X0: _fn0}
 	}
 	defer func() {
 		if !_c.Unwinding() {
-			defer coroutine.Pop(&_c.Stack)
-			for _, f := range _f0.X3 {
-				defer f()
-			}
+			coroutine.Pop(&_c.Stack)
 		}
 	}()
 	switch {
 	case _f0.IP < 2:
-		_f0.X3 = append(_f0.X3, func() {
-			*_f0.X0 = _f0.X2
-		})
+		_f0.X1 = yieldingSize(_f0.X0)
 		_f0.IP = 2
 		fallthrough
 	case _f0.IP < 3:
-		coroutine.Yield[int, any](_f0.X1)
+		_f0.X2 = yieldingSize(_f0.X0 * 2)
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 4:
+		_f0.X3 = make([]int, _f0.X1, _f0.X2)
+		_f0.IP = 4
+		fallthrough
+	case _f0.IP < 5:
+		for i := range _f0.X3 {
+			_f0.X3[i] = i
+		}
+		_f0.IP = 5
+		fallthrough
+	case _f0.IP < 6:
+		coroutine.Yield[int, any](len(_f0.X3))
+		_f0.IP = 6
+		fallthrough
+	case _f0.IP < 7:
+		coroutine.Yield[int, any](cap(_f0.X3))
+		_f0.IP = 7
+		fallthrough
+	case _f0.IP < 8:
+		_f0.X3 = append(_f0.X3, 100)
+		_f0.IP = 8
+		fallthrough
+	case _f0.IP < 9:
+		coroutine.Yield[int, any](cap(_f0.X3))
+		_f0.IP = 9
+		fallthrough
+	case _f0.IP < 10:
+		coroutine.Yield[int, any](_f0.X3[_f0.X0])
+		_f0.IP = 10
+		fallthrough
+	case _f0.IP < 11:
+		_f0.X4 = yieldingSize(_f0.X0)
+		_f0.IP = 11
+		fallthrough
+	case _f0.IP < 12:
+		_f0.X5 = make(map[int]int, _f0.X4)
+		_f0.IP = 12
+		fallthrough
+	case _f0.IP < 13:
+		_f0.X5[_f0.X0] = len(_f0.X3)
+		_f0.IP = 13
+		fallthrough
+	case _f0.IP < 14:
+		coroutine.Yield[int, any](_f0.X5[_f0.X0])
 	}
 }
 
 //go:noinline
-func RangeYieldAndDeferAssign(_fn0 int) {
+func ContextCancellation(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.ContextCancellation"`
 		X0 int
-		X1 int
+		X1 *coroutine.Context[int, any]
+		X2 context.Context
+		X3 int
+		X4 error
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.ContextCancellation"`
 		X0 int
-		X1 int
+		X1 *coroutine.Context[int, any]
+		X2 context.Context
+		X3 int
+		X4 error
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.ContextCancellation"`
 			X0 int
-			X1 int
+			X1 *coroutine.Context[int, any]
+			X2 context.Context
+			X3 int
+			X4 error
 		}{X0: _fn0}
 	}
 	defer func() {
@@ -3051,36 +6923,76 @@ func RangeYieldAndDeferAssign(_fn0 int) {
 	}()
 	switch {
 	case _f0.IP < 2:
-		_f0.X1 = 0
+		_f0.X1 = coroutine.LoadContext[int, any]()
 		_f0.IP = 2
 		fallthrough
 	case _f0.IP < 3:
-		for ; _f0.X1 < _f0.X0; _f0.IP = 2 {
-			YieldAndDeferAssign(&_f0.X1, _f0.X1, _f0.X1+1)
+		_f0.X2 = _f0.X1.Context()
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 8:
+		switch {
+		case _f0.IP < 4:
+			_f0.X3 = 0
+			_f0.IP = 4
+			fallthrough
+		case _f0.IP < 8:
+			for ; _f0.X3 < _f0.X0; _f0.X3, _f0.IP = _f0.X3+1, 4 {
+				switch {
+				case _f0.IP < 5:
+					coroutine.Yield[int, any](_f0.X3)
+					_f0.IP = 5
+					fallthrough
+				case _f0.IP < 8:
+					switch {
+					case _f0.IP < 6:
+						_f0.X4 = _f0.X2.Err()
+						_f0.IP = 6
+						fallthrough
+					case _f0.IP < 8:
+						if _f0.X4 != nil {
+							switch {
+							case _f0.IP < 7:
+								coroutine.Yield[int, any](-1)
+								_f0.IP = 7
+								fallthrough
+							case _f0.IP < 8:
+								return
+							}
+						}
+					}
+				}
+			}
 		}
 	}
 }
 
-type MethodGeneratorState struct{ i int }
+type mixedPoint struct{ X, Y int }
 
 //go:noinline
-func (_fn0 *MethodGeneratorState) MethodGenerator(_fn1 int) {
+func MixedMapValues(_fn0 int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
-		X0 *MethodGeneratorState
-		X1 int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.MixedMapValues"`
+		X0 int
+		X1 *mixedPoint
+		X2 map[string]any
+		X3 int
 	} = coroutine.Push[struct {
-		IP int
-		X0 *MethodGeneratorState
-		X1 int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.MixedMapValues"`
+		X0 int
+		X1 *mixedPoint
+		X2 map[string]any
+		X3 int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
-			X0 *MethodGeneratorState
-			X1 int
-		}{X0: _fn0, X1: _fn1}
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.MixedMapValues"`
+			X0 int
+			X1 *mixedPoint
+			X2 map[string]any
+			X3 int
+		}{X0: _fn0}
 	}
 	defer func() {
 		if !_c.Unwinding() {
@@ -3089,32 +7001,100 @@ func (_fn0 *MethodGeneratorState) MethodGenerator(_fn1 int) {
 	}()
 	switch {
 	case _f0.IP < 2:
-		_f0.X0.
-			i = 0
+		_f0.X1 = &mixedPoint{X: _f0.X0}
 		_f0.IP = 2
 		fallthrough
 	case _f0.IP < 3:
-		for ; _f0.X0.i <= _f0.X1; _f0.X0.i, _f0.IP = _f0.X0.i+1, 2 {
-			coroutine.Yield[int, any](_f0.X0.i)
+		_f0.X2 = map[string]any{
+			"n":       _f0.X0,
+			"half":    float64(_f0.X0) / 2,
+			"name":    "mixed",
+			"even":    _f0.X0%2 == 0,
+			"point":   mixedPoint{_f0.X0, -_f0.X0},
+			"pointer": _f0.X1,
+			"alias":   _f0.X1,
+			"values":  []any{"a", _f0.X0},
+			"nested":  map[string]any{"n": int8(_f0.X0)},
+			"nil":     nil,
+		}
+		_f0.IP = 3
+		fallthrough
+	case _f0.IP < 8:
+		switch {
+		case _f0.IP < 4:
+			_f0.X3 = 0
+			_f0.IP = 4
+			fallthrough
+		case _f0.IP < 8:
+			for ; _f0.X3 < _f0.X0; _f0.X3, _f0.IP = _f0.X3+1, 4 {
+				switch {
+				case _f0.IP < 5:
+					coroutine.Yield[int, any](_f0.X2["n"].(int))
+					_f0.IP = 5
+					fallthrough
+				case _f0.IP < 6:
+					_f0.X2["pointer"].(*mixedPoint).X++
+					_f0.IP = 6
+					fallthrough
+				case _f0.IP < 7:
+					coroutine.Yield[int, any](_f0.X2["alias"].(*mixedPoint).X)
+					_f0.IP = 7
+					fallthrough
+				case _f0.IP < 8:
+					_f0.X2["n"] = _f0.X2["n"].(int) + 1
+				}
+			}
 		}
 	}
 }
 
 //go:noinline
-func VarArgs(_fn0 int) {
+func yieldingSize(_fn0 int) (_ int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.yieldingSize"`
+		X0 int
+	} = coroutine.Push[struct {
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.yieldingSize"`
+		X0 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.yieldingSize"`
+			X0 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		coroutine.Yield[int, any](-_f0.X0)
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 3:
+		return _f0.X0
+	}
+	return
+}
+
+//go:noinline
+func yieldingRead(_fn0 int) (_ []int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.yieldingRead"`
 		X0 int
 		X1 []int
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.yieldingRead"`
 		X0 int
 		X1 []int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.yieldingRead"`
 			X0 int
 			X1 []int
 		}{X0: _fn0}
@@ -3126,43 +7106,39 @@ func VarArgs(_fn0 int) {
 	}()
 	switch {
 	case _f0.IP < 2:
-		_f0.X1 = make([]int, _f0.X0)
+		coroutine.Yield[int, any](-_f0.X0)
 		_f0.IP = 2
 		fallthrough
 	case _f0.IP < 3:
-		for i := range _f0.X1 {
-			_f0.X1[i] = i
-		}
+		_f0.X1 = make([]int, _f0.X0+1)
 		_f0.IP = 3
 		fallthrough
 	case _f0.IP < 4:
-		varArgs(_f0.X1...)
+		for i := range _f0.X1 {
+			_f0.X1[i] = (i + 1) * 10
+		}
+		_f0.IP = 4
+		fallthrough
+	case _f0.IP < 5:
+		return _f0.X1
 	}
+	return
 }
 
 //go:noinline
-func varArgs(_fn0 ...int) {
+func yieldingSlice(_fn0 []int) (_ []int) {
 	_c := coroutine.LoadContext[int, any]()
 	var _f0 *struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.yieldingSlice"`
 		X0 []int
-		X1 []int
-		X2 int
-		X3 int
 	} = coroutine.Push[struct {
-		IP int
+		IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.yieldingSlice"`
 		X0 []int
-		X1 []int
-		X2 int
-		X3 int
 	}](&_c.Stack)
 	if _f0.IP == 0 {
 		*_f0 = struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.yieldingSlice"`
 			X0 []int
-			X1 []int
-			X2 int
-			X3 int
 		}{X0: _fn0}
 	}
 	defer func() {
@@ -3172,45 +7148,56 @@ func varArgs(_fn0 ...int) {
 	}()
 	switch {
 	case _f0.IP < 2:
-		_f0.X1 = _f0.X0
+		coroutine.Yield[int, any](len(_f0.X0))
 		_f0.IP = 2
 		fallthrough
-	case _f0.IP < 5:
-		switch {
-		case _f0.IP < 3:
-			_f0.X2 = 0
-			_f0.IP = 3
-			fallthrough
-		case _f0.IP < 5:
-			for ; _f0.X2 < len(_f0.X1); _f0.X2, _f0.IP = _f0.X2+1, 3 {
-				switch {
-				case _f0.IP < 4:
-					_f0.X3 = _f0.X1[_f0.X2]
-					_f0.IP = 4
-					fallthrough
-				case _f0.IP < 5:
-
-					coroutine.Yield[int, any](_f0.X3)
-				}
-			}
-		}
+	case _f0.IP < 3:
+		return _f0.X0
 	}
+	return
 }
 func init() {
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.AnonymousStructLocal")
+	_types.RegisterFunc[func()]("github.com/stealthrocket/coroutine/compiler/testdata.Close")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.ContextCancellation")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.CopyYieldingOperands")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.DeferredMethodOnLocal")
 	_types.RegisterFunc[func(n int)]("github.com/stealthrocket/coroutine/compiler/testdata.Double")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.EmbeddedInterfaceMethodCall")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.ErrorCheckAcrossYields")
 	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.EvenSquareGenerator")
 	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.FizzBuzzIfGenerator")
 	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.FizzBuzzSwitchGenerator")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.GlobalPointer")
 	_types.RegisterFunc[func(n int)]("github.com/stealthrocket/coroutine/compiler/testdata.Identity")
+	_types.RegisterFunc[func(n int)]("github.com/stealthrocket/coroutine/compiler/testdata.IdentityGenericInt")
+	_types.RegisterFunc[func(n int8)]("github.com/stealthrocket/coroutine/compiler/testdata.IdentityGenericInt8")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.InfiniteLoopConditionalBreak")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.InterfaceMethodCall")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.LabeledBreakFromSwitch")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.LocalIotaConsts")
 	_types.RegisterFunc[func(_ int)]("github.com/stealthrocket/coroutine/compiler/testdata.LoopBreakAndContinue")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.LoopClosuresCapture")
+	_types.RegisterClosure[func() int, struct {
+		F  uintptr
+		X0 *bool
+		X1 *int
+		X2 *int
+		X3 *int
+	}]("github.com/stealthrocket/coroutine/compiler/testdata.LoopClosuresCapture.func2")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.LoopDefers")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.MakeYieldingSize")
 	_types.RegisterFunc[func(_fn1 int)]("github.com/stealthrocket/coroutine/compiler/testdata.MethodGenerator")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.MinMaxClear")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.MixedMapValues")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.NamedResultsAfterYield")
 	_types.RegisterFunc[func(_fn0 int) (_ int)]("github.com/stealthrocket/coroutine/compiler/testdata.NestedLoops")
 	_types.RegisterFunc[func(_fn0 int, _fn1 func(int))]("github.com/stealthrocket/coroutine/compiler/testdata.Range")
 	_types.RegisterFunc[func()]("github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingPointers")
 	_types.RegisterClosure[func() (_ bool), struct {
 		F  uintptr
 		X0 *struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingPointers"`
 			X0 int
 			X1 int
 			X2 *int
@@ -3224,7 +7211,7 @@ func init() {
 	_types.RegisterClosure[func() (_ bool), struct {
 		F  uintptr
 		X0 *struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureCapturingValues"`
 			X0 int
 			X1 int
 			X2 func() bool
@@ -3236,7 +7223,7 @@ func init() {
 	_types.RegisterClosure[func() int, struct {
 		F  uintptr
 		X0 *struct {
-			IP  int
+			IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureHeterogenousCapture"`
 			X0  int8
 			X1  int16
 			X2  int32
@@ -3256,7 +7243,7 @@ func init() {
 	_types.RegisterClosure[func() (_ bool), struct {
 		F  uintptr
 		X0 *struct {
-			IP  int
+			IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureHeterogenousCapture"`
 			X0  int8
 			X1  int16
 			X2  int32
@@ -3275,47 +7262,63 @@ func init() {
 	}]("github.com/stealthrocket/coroutine/compiler/testdata.Range10ClosureHeterogenousCapture.func3")
 	_types.RegisterFunc[func()]("github.com/stealthrocket/coroutine/compiler/testdata.Range10Heterogenous")
 	_types.RegisterFunc[func(_ int)]("github.com/stealthrocket/coroutine/compiler/testdata.RangeArrayIndexValueGenerator")
+	_types.RegisterFunc[func(_ int)]("github.com/stealthrocket/coroutine/compiler/testdata.RangeArrayPointerIndexValueGenerator")
 	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.RangeOverMaps")
 	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.RangeReverseClosureCaptureByValue")
 	_types.RegisterClosure[func(), struct {
 		F  uintptr
 		X0 *struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeReverseClosureCaptureByValue"`
 			X0 int
 			X1 int
 			X2 func()
 		}
 	}]("github.com/stealthrocket/coroutine/compiler/testdata.RangeReverseClosureCaptureByValue.func2")
 	_types.RegisterFunc[func(_ int)]("github.com/stealthrocket/coroutine/compiler/testdata.RangeSliceIndexGenerator")
+	_types.RegisterFunc[func(_ int)]("github.com/stealthrocket/coroutine/compiler/testdata.RangeStringGenerator")
 	_types.RegisterFunc[func(n int)]("github.com/stealthrocket/coroutine/compiler/testdata.RangeTriple")
 	_types.RegisterFunc[func(i int)]("github.com/stealthrocket/coroutine/compiler/testdata.RangeTriple.func1")
 	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.RangeTripleFuncValue")
 	_types.RegisterFunc[func(i int)]("github.com/stealthrocket/coroutine/compiler/testdata.RangeTripleFuncValue.func2")
 	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.RangeYieldAndDeferAssign")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.RecoverAfterYield")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.RecoverPanicIsolation")
 	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.Select")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.SelectFair")
 	_types.RegisterFunc[func(_ int)]("github.com/stealthrocket/coroutine/compiler/testdata.Shadowing")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.ShadowingTypes")
+	_types.RegisterFunc[func()]("github.com/stealthrocket/coroutine/compiler/testdata.ShadowingTypes.func2")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.SharedPointers")
 	_types.RegisterFunc[func()]("github.com/stealthrocket/coroutine/compiler/testdata.SomeFunctionThatShouldExistInTheCompiledFile")
 	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.SquareGenerator")
 	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.SquareGeneratorTwice")
 	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.SquareGeneratorTwiceLoop")
+	_types.RegisterFunc[func(_fn0 int) (_ int)]("github.com/stealthrocket/coroutine/compiler/testdata.Step")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.TaglessSwitchResume")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.TupleAssignmentAcrossYields")
+	_types.RegisterFunc[func(_ int)]("github.com/stealthrocket/coroutine/compiler/testdata.TypeSwitchBindingGenerator")
 	_types.RegisterFunc[func(_ int)]("github.com/stealthrocket/coroutine/compiler/testdata.TypeSwitchingGenerator")
 	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.VarArgs")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.VariadicLibraryCall")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.VariadicYield")
 	_types.RegisterFunc[func(_fn0 *int, _fn1, _fn2 int)]("github.com/stealthrocket/coroutine/compiler/testdata.YieldAndDeferAssign")
 	_types.RegisterClosure[func(), struct {
 		F  uintptr
 		X0 *struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldAndDeferAssign"`
 			X0 *int
 			X1 int
 			X2 int
-			X3 []func()
+			X3 coroutine.Defers
 		}
 	}]("github.com/stealthrocket/coroutine/compiler/testdata.YieldAndDeferAssign.func2")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.YieldAndRecover")
+	_types.RegisterFunc[func()]("github.com/stealthrocket/coroutine/compiler/testdata.YieldAndRecover.func2")
 	_types.RegisterFunc[func()]("github.com/stealthrocket/coroutine/compiler/testdata.YieldingDurations")
 	_types.RegisterClosure[func(), struct {
 		F  uintptr
 		X0 *struct {
-			IP int
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.YieldingDurations"`
 			X0 *time.Duration
 			X1 time.Duration
 			X2 func()
@@ -3323,7 +7326,102 @@ func init() {
 		}
 	}]("github.com/stealthrocket/coroutine/compiler/testdata.YieldingDurations.func2")
 	_types.RegisterFunc[func()]("github.com/stealthrocket/coroutine/compiler/testdata.YieldingExpressionDesugaring")
+	_types.RegisterFunc[func()]("github.com/stealthrocket/coroutine/compiler/testdata.YieldingSliceAndIndexOrder")
 	_types.RegisterFunc[func(_fn0 int) (_ int)]("github.com/stealthrocket/coroutine/compiler/testdata.a")
 	_types.RegisterFunc[func(_fn0 int) (_ int)]("github.com/stealthrocket/coroutine/compiler/testdata.b")
+	_types.RegisterFunc[func(_fn0 int) (_ error)]("github.com/stealthrocket/coroutine/compiler/testdata.checkedWork")
+	_types.RegisterFunc[func(_fn0 int, _fn1 *[]int)]("github.com/stealthrocket/coroutine/compiler/testdata.deferClose")
+	_types.RegisterClosure[func(), struct {
+		F  uintptr
+		X0 *struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.deferClose"`
+			X0 int
+			X1 *[]int
+			X2 []handle
+			X3 []*file
+			X4 handle
+			X5 handle
+			X6 *file
+			X7 *file
+			X8 coroutine.Defers
+		}
+	}]("github.com/stealthrocket/coroutine/compiler/testdata.deferClose.func2")
+	_types.RegisterClosure[func(), struct {
+		F  uintptr
+		X0 *struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.deferClose"`
+			X0 int
+			X1 *[]int
+			X2 []handle
+			X3 []*file
+			X4 handle
+			X5 handle
+			X6 *file
+			X7 *file
+			X8 coroutine.Defers
+		}
+	}]("github.com/stealthrocket/coroutine/compiler/testdata.deferClose.func3")
+	_types.RegisterFunc[func(_fn0 int) (_fn1 int, _fn2 error)]("github.com/stealthrocket/coroutine/compiler/testdata.isolatedWork")
+	_types.RegisterClosure[func(), struct {
+		F  uintptr
+		X0 *struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.isolatedWork"`
+			X0 int
+			X1 int
+			X2 error
+			X3 coroutine.Defers
+		}
+	}]("github.com/stealthrocket/coroutine/compiler/testdata.isolatedWork.func2")
+	_types.RegisterFunc[func(_fn0 *[]int, _fn1 int)]("github.com/stealthrocket/coroutine/compiler/testdata.loopDefers")
+	_types.RegisterClosure[func(), struct {
+		F  uintptr
+		X0 *struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.loopDefers"`
+			X0 *[]int
+			X1 int
+			X2 []int
+			X3 int
+			X4 int
+			X5 coroutine.Defers
+		}
+	}]("github.com/stealthrocket/coroutine/compiler/testdata.loopDefers.func2")
+	_types.RegisterClosure[func(v int), struct {
+		F  uintptr
+		X0 *struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.loopDefers"`
+			X0 *[]int
+			X1 int
+			X2 []int
+			X3 int
+			X4 int
+			X5 coroutine.Defers
+		}
+	}]("github.com/stealthrocket/coroutine/compiler/testdata.loopDefers.func2.func1")
+	_types.RegisterFunc[func(_fn0 int) (_fn1 int)]("github.com/stealthrocket/coroutine/compiler/testdata.namedResults")
+	_types.RegisterFunc[func(_fn0 int) (_fn1 int)]("github.com/stealthrocket/coroutine/compiler/testdata.recoverAfterYield")
+	_types.RegisterClosure[func(), struct {
+		F  uintptr
+		X0 *struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.recoverAfterYield"`
+			X0 int
+			X1 int
+			X2 coroutine.Defers
+		}
+	}]("github.com/stealthrocket/coroutine/compiler/testdata.recoverAfterYield.func2")
+	_types.RegisterClosure[func(), struct {
+		F  uintptr
+		X0 *struct {
+			IP int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.recoverAfterYield"`
+			X0 int
+			X1 int
+			X2 coroutine.Defers
+		}
+	}]("github.com/stealthrocket/coroutine/compiler/testdata.recoverAfterYield.func3")
+	_types.RegisterFunc[func(_fn0 int) (_ int, _ error)]("github.com/stealthrocket/coroutine/compiler/testdata.tupleWork")
 	_types.RegisterFunc[func(_fn0 ...int)]("github.com/stealthrocket/coroutine/compiler/testdata.varArgs")
+	_types.RegisterFunc[func(_fn0 int, _fn1 ...int)]("github.com/stealthrocket/coroutine/compiler/testdata.variadicSum")
+	_types.RegisterFunc[func(_fn0 int) (_ int)]("github.com/stealthrocket/coroutine/compiler/testdata.yieldIdentity")
+	_types.RegisterFunc[func(_fn0 int) (_ []int)]("github.com/stealthrocket/coroutine/compiler/testdata.yieldingRead")
+	_types.RegisterFunc[func(_fn0 int) (_ int)]("github.com/stealthrocket/coroutine/compiler/testdata.yieldingSize")
+	_types.RegisterFunc[func(_fn0 []int) (_ []int)]("github.com/stealthrocket/coroutine/compiler/testdata.yieldingSlice")
 }
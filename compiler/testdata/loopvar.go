@@ -0,0 +1,38 @@
+//go:build !durable && go1.22
+
+package testdata
+
+import "github.com/stealthrocket/coroutine"
+
+// The build constraint sets the version of the Go language of this file to
+// 1.22, which declares the variables of for loops once per iteration.
+
+func LoopVarsPerIteration(n int) {
+	var fns []func() int
+	for i, j := 0, 10; i < n; i++ {
+		fns = append(fns, func() int { return i*100 + j })
+		j++
+		if i%2 == 0 {
+			coroutine.Yield[int, any](i)
+			continue
+		}
+		coroutine.Yield[int, any](-i)
+	}
+	for _, f := range fns {
+		coroutine.Yield[int, any](f())
+	}
+}
+
+func RangeVarsPerIteration(n int) {
+	var fns []func() int
+	for i, v := range []int{10, 20, 30}[:n] {
+		fns = append(fns, func() int { return i + v })
+		coroutine.Yield[int, any](v)
+	}
+	for k := range n {
+		fns = append(fns, func() int { return k })
+	}
+	for _, f := range fns {
+		coroutine.Yield[int, any](f())
+	}
+}
@@ -0,0 +1,351 @@
+//go:build go1.22 && durable
+
+package testdata
+
+import coroutine "github.com/stealthrocket/coroutine"
+import _types "github.com/stealthrocket/coroutine/types"
+
+//go:noinline
+func LoopVarsPerIteration(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LoopVarsPerIteration"`
+		X0  int
+		X1  []func() int
+		X2  int
+		X3  int
+		X4  *int
+		X5  *int
+		X6  bool
+		X7  []func() int
+		X8  int
+		X9  func() int
+		X10 int
+	} = coroutine.Push[struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LoopVarsPerIteration"`
+		X0  int
+		X1  []func() int
+		X2  int
+		X3  int
+		X4  *int
+		X5  *int
+		X6  bool
+		X7  []func() int
+		X8  int
+		X9  func() int
+		X10 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.LoopVarsPerIteration"`
+			X0  int
+			X1  []func() int
+			X2  int
+			X3  int
+			X4  *int
+			X5  *int
+			X6  bool
+			X7  []func() int
+			X8  int
+			X9  func() int
+			X10 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 19:
+		switch {
+		case _f0.IP < 3:
+			_f0.X2, _f0.X3 = 0, 10
+			_f0.IP = 3
+			fallthrough
+		case _f0.IP < 4:
+			_f0.X4 = new(int)
+			_f0.IP = 4
+			fallthrough
+		case _f0.IP < 5:
+			*_f0.X4 = _f0.X2
+			_f0.IP = 5
+			fallthrough
+		case _f0.IP < 6:
+			_f0.X5 = new(int)
+			_f0.IP = 6
+			fallthrough
+		case _f0.IP < 7:
+			*_f0.X5 = _f0.X3
+			_f0.IP = 7
+			fallthrough
+		case _f0.IP < 8:
+			_f0.X6 = true
+			_f0.IP = 8
+			fallthrough
+		case _f0.IP < 19:
+		_l0:
+			for ; ; _f0.IP = 8 {
+				switch {
+				case _f0.IP < 11:
+					if _f0.X6 {
+						_f0.X6 = false
+					} else {
+						_f0.X4, _f0.X5 = &[]int{*_f0.X4}[0], &[]int{*_f0.X5}[0]
+						*_f0.X4++
+					}
+					_f0.IP = 11
+					fallthrough
+				case _f0.IP < 12:
+					if !(*_f0.X4 < _f0.X0) {
+						break _l0
+					}
+					_f0.IP = 12
+					fallthrough
+				case _f0.IP < 15:
+					{
+						var _p5 *int = _f0.X4
+						var _p6 *int = _f0.X5
+						_f0.X1 = append(_f0.X1, func() int { return *_p5*100 + *_p6 })
+					}
+					_f0.IP = 15
+					fallthrough
+				case _f0.IP < 16:
+					*_f0.X5++
+					_f0.IP = 16
+					fallthrough
+				case _f0.IP < 18:
+					if *_f0.X4%2 == 0 {
+						switch {
+						case _f0.IP < 17:
+							coroutine.Yield[int, any](*_f0.X4)
+							_f0.IP = 17
+							fallthrough
+						case _f0.IP < 18:
+							continue _l0
+						}
+					}
+					_f0.IP = 18
+					fallthrough
+				case _f0.IP < 19:
+
+					coroutine.Yield[int, any](-*_f0.X4)
+				}
+			}
+		}
+		_f0.IP = 19
+		fallthrough
+	case _f0.IP < 24:
+		switch {
+		case _f0.IP < 20:
+			_f0.X7 = _f0.X1
+			_f0.IP = 20
+			fallthrough
+		case _f0.IP < 24:
+			switch {
+			case _f0.IP < 21:
+				_f0.X8 = 0
+				_f0.IP = 21
+				fallthrough
+			case _f0.IP < 24:
+				for ; _f0.X8 < len(_f0.X7); _f0.X8, _f0.IP = _f0.X8+1, 21 {
+					switch {
+					case _f0.IP < 22:
+						_f0.X9 = _f0.X7[_f0.X8]
+						_f0.IP = 22
+						fallthrough
+					case _f0.IP < 23:
+						_f0.X10 = _f0.X9()
+						_f0.IP = 23
+						fallthrough
+					case _f0.IP < 24:
+						coroutine.Yield[int, any](_f0.X10)
+					}
+				}
+			}
+		}
+	}
+}
+
+//go:noinline
+func RangeVarsPerIteration(_fn0 int) {
+	_c := coroutine.LoadContext[int, any]()
+	var _f0 *struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeVarsPerIteration"`
+		X0  int
+		X1  []func() int
+		X2  []int
+		X3  *int
+		X4  bool
+		X5  *int
+		X6  *int
+		X7  []func() int
+		X8  int
+		X9  func() int
+		X10 int
+	} = coroutine.Push[struct {
+		IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeVarsPerIteration"`
+		X0  int
+		X1  []func() int
+		X2  []int
+		X3  *int
+		X4  bool
+		X5  *int
+		X6  *int
+		X7  []func() int
+		X8  int
+		X9  func() int
+		X10 int
+	}](&_c.Stack)
+	if _f0.IP == 0 {
+		*_f0 = struct {
+			IP  int `coroutine:"github.com/stealthrocket/coroutine/compiler/testdata.RangeVarsPerIteration"`
+			X0  int
+			X1  []func() int
+			X2  []int
+			X3  *int
+			X4  bool
+			X5  *int
+			X6  *int
+			X7  []func() int
+			X8  int
+			X9  func() int
+			X10 int
+		}{X0: _fn0}
+	}
+	defer func() {
+		if !_c.Unwinding() {
+			coroutine.Pop(&_c.Stack)
+		}
+	}()
+	switch {
+	case _f0.IP < 2:
+		_f0.IP = 2
+		fallthrough
+	case _f0.IP < 16:
+		switch {
+		case _f0.IP < 3:
+			_f0.X2 = []int{10, 20, 30}[:_f0.X0]
+			_f0.IP = 3
+			fallthrough
+		case _f0.IP < 16:
+			switch {
+			case _f0.IP < 4:
+				_f0.X3 = new(int)
+				_f0.IP = 4
+				fallthrough
+			case _f0.IP < 5:
+				*_f0.X3 = 0
+				_f0.IP = 5
+				fallthrough
+			case _f0.IP < 6:
+				_f0.X4 = true
+				_f0.IP = 6
+				fallthrough
+			case _f0.IP < 16:
+			_p4:
+				for ; ; _f0.IP = 6 {
+					switch {
+					case _f0.IP < 9:
+						if _f0.X4 {
+							_f0.X4 = false
+						} else {
+							_f0.X3 = &[]int{*_f0.X3}[0]
+							*_f0.X3++
+						}
+						_f0.IP = 9
+						fallthrough
+					case _f0.IP < 10:
+						if !(*_f0.X3 < len(_f0.X2)) {
+							break _p4
+						}
+						_f0.IP = 10
+						fallthrough
+					case _f0.IP < 11:
+						_f0.X5 = new(int)
+						_f0.IP = 11
+						fallthrough
+					case _f0.IP < 12:
+						*_f0.X5 = _f0.X2[*_f0.X3]
+						_f0.IP = 12
+						fallthrough
+					case _f0.IP < 15:
+						{
+							var _p6 *int = _f0.X3
+							var _p7 *int = _f0.X5
+							_f0.X1 = append(_f0.X1, func() int { return *_p6 + *_p7 })
+						}
+						_f0.IP = 15
+						fallthrough
+					case _f0.IP < 16:
+						coroutine.Yield[int, any](*_f0.X5)
+					}
+				}
+			}
+		}
+		_f0.IP = 16
+		fallthrough
+	case _f0.IP < 17:
+
+		for _p0 := range _f0.X0 {
+			_f0.X6 = new(int)
+			*_f0.X6 = _p0
+			{
+				var _p8 *int = _f0.X6
+				_f0.X1 = append(_f0.X1, func() int { return *_p8 })
+			}
+		}
+		_f0.IP = 17
+		fallthrough
+	case _f0.IP < 22:
+		switch {
+		case _f0.IP < 18:
+			_f0.X7 = _f0.X1
+			_f0.IP = 18
+			fallthrough
+		case _f0.IP < 22:
+			switch {
+			case _f0.IP < 19:
+				_f0.X8 = 0
+				_f0.IP = 19
+				fallthrough
+			case _f0.IP < 22:
+				for ; _f0.X8 < len(_f0.X7); _f0.X8, _f0.IP = _f0.X8+1, 19 {
+					switch {
+					case _f0.IP < 20:
+						_f0.X9 = _f0.X7[_f0.X8]
+						_f0.IP = 20
+						fallthrough
+					case _f0.IP < 21:
+						_f0.X10 = _f0.X9()
+						_f0.IP = 21
+						fallthrough
+					case _f0.IP < 22:
+						coroutine.Yield[int, any](_f0.X10)
+					}
+				}
+			}
+		}
+	}
+}
+func init() {
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.LoopVarsPerIteration")
+	_types.RegisterClosure[func() int, struct {
+		F  uintptr
+		X0 *int
+		X1 *int
+	}]("github.com/stealthrocket/coroutine/compiler/testdata.LoopVarsPerIteration.func2")
+	_types.RegisterFunc[func(_fn0 int)]("github.com/stealthrocket/coroutine/compiler/testdata.RangeVarsPerIteration")
+	_types.RegisterClosure[func() int, struct {
+		F  uintptr
+		X0 *int
+		X1 *int
+	}]("github.com/stealthrocket/coroutine/compiler/testdata.RangeVarsPerIteration.func2")
+	_types.RegisterClosure[func() int, struct {
+		F  uintptr
+		X0 *int
+	}]("github.com/stealthrocket/coroutine/compiler/testdata.RangeVarsPerIteration.func3")
+}
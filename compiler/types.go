@@ -1,7 +1,6 @@
 package compiler
 
 import (
-	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
@@ -39,7 +38,11 @@ func typeExpr(p *packages.Package, typ types.Type) ast.Expr {
 				fields[i].Names = []*ast.Ident{ast.NewIdent(f.Name())}
 			}
 			if tag := t.Tag(i); tag != "" {
-				panic("not implemented: struct tags")
+				value := strconv.Quote(tag)
+				if strconv.CanBackquote(tag) {
+					value = "`" + tag + "`"
+				}
+				fields[i].Tag = &ast.BasicLit{Kind: token.STRING, Value: value}
 			}
 		}
 		return &ast.StructType{Fields: &ast.FieldList{List: fields}}
@@ -79,6 +82,9 @@ func typeExpr(p *packages.Package, typ types.Type) ast.Expr {
 		}
 		return namedExpr
 
+	case *types.TypeParam:
+		return ast.NewIdent(t.Obj().Name())
+
 	case *types.Chan:
 		c := &ast.ChanType{
 			Value: typeExpr(p, t.Elem()),
@@ -93,7 +99,8 @@ func typeExpr(p *packages.Package, typ types.Type) ast.Expr {
 		}
 		return c
 	}
-	panic(fmt.Sprintf("not implemented: %T", typ))
+	notImplemented(nil, "type %s (%T)", typ, typ)
+	return nil
 }
 
 func newFuncType(p *packages.Package, signature *types.Signature) *ast.FuncType {
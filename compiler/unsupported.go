@@ -7,15 +7,30 @@ import (
 	"go/types"
 )
 
-// unsupported checks a function for unsupported language features.
-func unsupported(decl ast.Node, info *types.Info) (err error) {
+// unsupported checks a function for unsupported language features. The error
+// reports the position of the first unsupported statement found.
+func unsupported(fset *token.FileSet, decl ast.Node, info *types.Info) (err error) {
+	var pos token.Pos
+	defer func() {
+		if err != nil {
+			err = &UnsupportedError{Pos: fset.Position(pos), Msg: err.Error()}
+		}
+	}()
+
 	ast.Inspect(decl, func(node ast.Node) bool {
+		if err != nil || node == nil {
+			return false
+		}
+		pos = node.Pos()
+
 		switch nn := node.(type) {
 		case ast.Stmt:
 			switch n := nn.(type) {
 			// Not yet supported:
 			case *ast.GoStmt:
-				err = fmt.Errorf("not implemented: go")
+				// The goroutine would not be suspended and resumed with
+				// the coroutine, nor would its state be serialized.
+				err = fmt.Errorf("not implemented: go statement in a function that yields (start the goroutine from a function that does not yield instead)")
 
 			// Partially supported:
 			case *ast.BranchStmt:
@@ -69,13 +84,16 @@ func unsupported(decl ast.Node, info *types.Info) (err error) {
 			case *ast.RangeStmt:
 			case *ast.ReturnStmt:
 			case *ast.SelectStmt:
+			// Channel operations are not yield points; a blocking send or
+			// receive blocks the goroutine driving the coroutine, just like
+			// in volatile mode.
 			case *ast.SendStmt:
 			case *ast.SwitchStmt:
 			case *ast.TypeSwitchStmt:
 
 			// Catch all in case new statements are added:
 			default:
-				err = fmt.Errorf("not implmemented: ast.Stmt(%T)", n)
+				err = fmt.Errorf("not implemented: ast.Stmt(%T)", n)
 			}
 		}
 		return err == nil
@@ -83,6 +101,42 @@ func unsupported(decl ast.Node, info *types.Info) (err error) {
 	return
 }
 
+// unsupportedError is raised by the compiler passes that run after
+// unsupported, when they find a construct that they cannot compile. The node
+// locates the construct in the source.
+type unsupportedError struct {
+	node ast.Node
+	msg  string
+}
+
+func (e *unsupportedError) Error() string {
+	return e.msg
+}
+
+func notImplemented(node ast.Node, format string, args ...any) {
+	panic(&unsupportedError{node: node, msg: "not implemented: " + fmt.Sprintf(format, args...)})
+}
+
+// recoverUnsupported calls f to compile decl, and converts the unsupported
+// errors raised by f to errors reporting the position of the construct, or of
+// decl if the node was generated by the compiler.
+func recoverUnsupported[T any](fset *token.FileSet, decl ast.Node, f func() T) (v T, err error) {
+	defer func() {
+		switch r := recover().(type) {
+		case nil:
+		case *unsupportedError:
+			pos := decl.Pos()
+			if r.node != nil && r.node.Pos().IsValid() {
+				pos = r.node.Pos()
+			}
+			err = &UnsupportedError{Pos: fset.Position(pos), Msg: r.Error()}
+		default:
+			panic(r)
+		}
+	}()
+	return f(), nil
+}
+
 func countFunctionCalls(expr ast.Expr, info *types.Info) (count int) {
 	ast.Inspect(expr, func(node ast.Node) bool {
 		c, ok := node.(*ast.CallExpr)
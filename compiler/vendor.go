@@ -1,10 +1,13 @@
 package compiler
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -38,18 +41,12 @@ func vendorGOROOT(newRoot string, pkgs []*packages.Package) error {
 	}
 
 	// Copy the entire GOROOT/src directory.
-	if err := copyDir(filepath.Join(newRoot, "src"), filepath.Join(goroot, "src")); err != nil {
+	if err := copyDir(filepath.Join(newRoot, "src"), filepath.Join(goroot, "src"), nil); err != nil {
 		return err
 	}
 
 	// Rewrite GoFiles paths.
-	packages.Visit(pkgs, func(p *packages.Package) bool {
-		for i, path := range p.GoFiles {
-			rel, _ := filepath.Rel(goroot, path)
-			p.GoFiles[i] = filepath.Join(newRoot, rel)
-		}
-		return true
-	}, nil)
+	relocatePackages(pkgs, goroot, newRoot)
 
 	// Symlink $GOROOT/pkg, which contains directories required
 	// at compile time.
@@ -60,6 +57,117 @@ func vendorGOROOT(newRoot string, pkgs []*packages.Package) error {
 	return err
 }
 
+// copyModule copies the module in src to a new directory, so the module can be
+// compiled without mutating the original.
+//
+// The copy has the same module path as the original, therefore import paths
+// remain valid in the new directory. Directories which are ignored by the go
+// command (those starting with a dot, such as .git) are not copied, nor is the
+// destination directory when nested in the module.
+func copyModule(dst, src string, pkgs []*packages.Package) error {
+	err := copyDir(dst, src, func(path string) bool {
+		return strings.HasPrefix(filepath.Base(path), ".") || path == dst
+	})
+	if err != nil {
+		return err
+	}
+	relocatePackages(pkgs, src, dst)
+	return relocateReplaceDirectives(dst, src)
+}
+
+// relocateReplaceDirectives rewrites the replace directives of the go.mod file
+// in dst which reference directories outside of the module copied from src, so
+// that relative paths resolve to the same directories.
+func relocateReplaceDirectives(dst, src string) error {
+	cmd := exec.Command("go", "mod", "edit", "-json")
+	cmd.Dir = dst
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("go mod edit -json: %w", err)
+	}
+
+	type module struct{ Path, Version string }
+	var gomod struct {
+		Replace []struct{ Old, New module }
+	}
+	if err := json.Unmarshal(out, &gomod); err != nil {
+		return err
+	}
+
+	for _, r := range gomod.Replace {
+		// Module replacements have a version, directory replacements do not.
+		if r.New.Version != "" || filepath.IsAbs(r.New.Path) {
+			continue
+		}
+		dir := filepath.Join(src, r.New.Path)
+		if rel, err := filepath.Rel(src, dir); err == nil && !strings.HasPrefix(rel, "..") {
+			continue // copied along with the module
+		}
+		newPath, err := filepath.Rel(dst, dir)
+		if err != nil {
+			newPath = dir
+		} else if !strings.HasPrefix(newPath, "..") {
+			newPath = "." + string(filepath.Separator) + newPath
+		}
+		oldPath := r.Old.Path
+		if r.Old.Version != "" {
+			oldPath += "@" + r.Old.Version
+		}
+		cmd := exec.Command("go", "mod", "edit", "-replace", oldPath+"="+newPath)
+		cmd.Dir = dst
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("go mod edit -replace: %w: %s", err, out)
+		}
+	}
+	return nil
+}
+
+// relocatePackages rewrites the GoFiles paths of packages located under the
+// oldRoot directory to point at the same files under newRoot.
+func relocatePackages(pkgs []*packages.Package, oldRoot, newRoot string) {
+	packages.Visit(pkgs, func(p *packages.Package) bool {
+		for i, path := range p.GoFiles {
+			rel, err := filepath.Rel(oldRoot, path)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			p.GoFiles[i] = filepath.Join(newRoot, rel)
+		}
+		return true
+	}, nil)
+}
+
+// retagPackage renames the build tag from to the tag to in the build
+// constraints of the Go files in dir, including those that are excluded from
+// the build by the current tags.
+func retagPackage(dir, from, to string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		out, err := renameBuildTag(src, from, to)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if !bytes.Equal(out, src) {
+			if err := os.WriteFile(path, out, 0666); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func packageDir(p *packages.Package) string {
 	var f string
 	switch {
@@ -75,12 +183,15 @@ func packageDir(p *packages.Package) string {
 
 type copyOperation struct{ src, dst string }
 
-func copyDir(dst, src string) error {
+// copyDir copies the content of src to dst. If skip is not nil, it is called
+// with the path of each directory entry in src, and entries for which it
+// returns true are not copied.
+func copyDir(dst, src string, skip func(string) bool) error {
 	ops := make(chan copyOperation, 256)
 
 	var group errgroup.Group
 	group.Go(func() error {
-		err := scanDir(dst, src, ops)
+		err := scanDir(dst, src, skip, ops)
 		close(ops)
 		return err
 	})
@@ -97,7 +208,7 @@ func copyDir(dst, src string) error {
 	return group.Wait()
 }
 
-func scanDir(dst, src string, ops chan<- copyOperation) error {
+func scanDir(dst, src string, skip func(string) bool, ops chan<- copyOperation) error {
 	if err := os.MkdirAll(dst, 0755); err != nil && !errors.Is(err, os.ErrExist) {
 		return err
 	}
@@ -107,10 +218,13 @@ func scanDir(dst, src string, ops chan<- copyOperation) error {
 	}
 	for _, entry := range entries {
 		name := entry.Name()
+		if skip != nil && skip(filepath.Join(src, name)) {
+			continue
+		}
 		if entry.IsDir() {
 			dstChild := filepath.Join(dst, name)
 			srcChild := filepath.Join(src, name)
-			if err := scanDir(dstChild, srcChild, ops); err != nil {
+			if err := scanDir(dstChild, srcChild, skip, ops); err != nil {
 				return err
 			}
 		} else {
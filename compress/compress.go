@@ -0,0 +1,114 @@
+// Package compress contains the compression algorithms that can be applied to
+// the state of durable coroutines with Context.MarshalCompressed.
+//
+// Compressions are not registered by default: programs that unmarshal
+// compressed state call Register with the compressions that were used to
+// marshal it (e.g. Register(Gzip)) during initialization.
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Compression is a compression algorithm that can be applied to the state of
+// durable coroutines with Context.MarshalCompressed.
+//
+// The output of MarshalCompressed records the ID of the compression, which
+// Unmarshal uses to select the decompressor, so compressions must be
+// registered with Register by programs that unmarshal their output.
+type Compression struct {
+	// ID identifies the compression in the serialized state. It must be
+	// positive and unique among registered compressions. The ID 1 is
+	// reserved for Gzip.
+	ID int
+
+	// Compress returns a writer compressing data written to w. The state is
+	// written to the writer, then the writer is closed.
+	Compress func(w io.Writer) io.WriteCloser
+
+	// Decompress returns a reader decompressing data read from r.
+	Decompress func(r io.Reader) (io.ReadCloser, error)
+}
+
+// Gzip is the gzip compression from the standard library, with the default
+// compression level.
+var Gzip = &Compression{
+	ID: 1,
+	Compress: func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	},
+	Decompress: func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+}
+
+var compressions sync.Map // int => *Compression
+
+// Register registers c so that Unmarshal can decompress the state of
+// coroutines marshaled with it (e.g. Gzip, or zstd or another algorithm that
+// is not part of the standard library).
+//
+// The function panics if c.ID is not positive or registered for another
+// compression. Registering the same compression more than once has no effect.
+func Register(c *Compression) {
+	if c.ID <= 0 {
+		panic(fmt.Sprintf("compress.Register: invalid ID %d", c.ID))
+	}
+	if v, loaded := compressions.LoadOrStore(c.ID, c); loaded && v != c {
+		panic(fmt.Sprintf("compress.Register: ID %d already registered", c.ID))
+	}
+}
+
+// Encode compresses the serialized state of a coroutine in b.
+//
+// The serialized state of coroutines starts with the length of the build ID,
+// which is positive, so the output is prefixed with the negated ID of the
+// compression to tell them apart. It is followed by the length of the
+// compressed data, then the data itself.
+func (c *Compression) Encode(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := c.Compress(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	out := binary.AppendVarint(nil, int64(-c.ID))
+	out = binary.AppendVarint(out, int64(buf.Len()))
+	return append(out, buf.Bytes()...), nil
+}
+
+// Decode returns the decompressed state in b and the number of bytes that it
+// read, or b itself and zero if it was not compressed. The compression that
+// b was encoded with must be registered.
+func Decode(b []byte) ([]byte, int, error) {
+	id, n := binary.Varint(b)
+	if n <= 0 || id >= 0 {
+		return b, 0, nil
+	}
+	v, ok := compressions.Load(int(-id))
+	if !ok {
+		return nil, 0, fmt.Errorf("coroutine: unknown compression ID %d", -id)
+	}
+	size, m := binary.Varint(b[n:])
+	if m <= 0 || size < 0 || size > int64(len(b)-n-m) {
+		return nil, 0, fmt.Errorf("coroutine: invalid size of compressed state")
+	}
+	n += m
+	r, err := v.(*Compression).Decompress(bytes.NewReader(b[n : n+int(size)]))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer r.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, n + int(size), nil
+}
@@ -0,0 +1,60 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+func TestGzip(t *testing.T) {
+	// The serialized state of coroutines starts with the positive length
+	// of the build ID.
+	in := binary.AppendVarint(nil, 8)
+	in = append(in, bytes.Repeat([]byte("coroutine state "), 100)...)
+	b, err := Gzip.Encode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append(b, "trailing bytes"...)
+
+	if _, _, err := Decode(b); err == nil {
+		t.Fatal("decoding state compressed with an unregistered compression did not return an error")
+	}
+
+	Register(Gzip)
+	Register(Gzip)
+	out, n, err := Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, in) {
+		t.Errorf("wrong decompressed state: %q", out)
+	}
+	if want := len(b) - len("trailing bytes"); n != want {
+		t.Errorf("wrong number of bytes read: want=%d got=%d", want, n)
+	}
+
+	// State that was not compressed is returned as is.
+	if out, n, err := Decode(in); err != nil || n != 0 || !bytes.Equal(out, in) {
+		t.Errorf("uncompressed state not returned as is: n=%d err=%v", n, err)
+	}
+}
+
+func TestRegisterConflict(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering another compression with the ID of Gzip did not panic")
+		}
+	}()
+	Register(Gzip)
+	Register(&Compression{
+		ID:         Gzip.ID,
+		Compress:   func(w io.Writer) io.WriteCloser { return nopCloser{w} },
+		Decompress: func(r io.Reader) (io.ReadCloser, error) { return io.NopCloser(r), nil },
+	})
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
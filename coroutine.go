@@ -1,7 +1,14 @@
 package coroutine
 
 import (
+	gocontext "context"
 	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Coroutine instances expose APIs allowing the program to drive the execution
@@ -66,9 +73,265 @@ type Context[R, S any] struct {
 	stop   bool
 	resume bool //nolint
 
+	// Panic captured when running the coroutine (see CapturePanics).
+	capture bool
+	err     error
+
+	// Whether Unmarshal rejects trailing bytes (see StrictUnmarshal).
+	strict bool
+
+	// Whether Unmarshal references strings in its input (see
+	// UnsafeUnmarshal).
+	unsafeStrings bool
+
+	// Functions called when the coroutine yields and resumes (see OnYield
+	// and OnResume).
+	onYield  func(depth int)
+	onResume func(depth int)
+
+	// Holder of the context observed by the coroutine (see SetContext).
+	goctx *contextHolder
+
 	context[R]
 }
 
+// CapturePanics configures whether panics raised by the coroutine are captured
+// instead of propagating to the caller of Next.
+//
+// When a panic is captured, the coroutine completes and the panic is reported
+// by Err. The mechanism used to suspend coroutines at yield points is never
+// captured.
+func (c *Context[R, S]) CapturePanics(enable bool) {
+	c.capture = enable
+}
+
+// StrictUnmarshal configures whether Unmarshal returns an error when its input
+// has bytes remaining after the serialized state.
+//
+// By default, trailing bytes are left for the caller to consume, and Unmarshal
+// returns the number of bytes that it read. Strict mode helps detect corrupted
+// input, or state appended to by another version of the program.
+func (c *Context[R, S]) StrictUnmarshal(enable bool) {
+	c.strict = enable
+}
+
+// UnsafeUnmarshal configures whether the strings of the state restored by
+// Unmarshal reference its input instead of copies (see
+// types.DeserializeUnsafe), which avoids allocating memory for each of them.
+//
+// This is unsafe: the caller must guarantee that the input of Unmarshal is
+// never modified, and that it is kept alive for as long as the coroutine
+// runs. Compressed state is decompressed to a new buffer, which the strings
+// reference instead. UnmarshalBinary always copies the strings, since it must
+// not retain its input.
+func (c *Context[R, S]) UnsafeUnmarshal(enable bool) {
+	c.unsafeStrings = enable
+}
+
+// OnYield registers a function called each time the coroutine yields a value,
+// before control returns to the caller of Next. The function receives the depth
+// of the stack of the coroutine at the yield point (see Depth), which is always
+// zero for volatile coroutines. Passing nil removes the hook.
+//
+// Hooks are intended for observability (e.g. metrics and tracing). Since they
+// are attached to a context, they can capture what identifies the coroutine.
+// They are called from Yield, so they must not yield nor panic. Hooks are not
+// serialized with the state of the coroutine, and are not copied by Clone.
+func (c *Context[R, S]) OnYield(f func(depth int)) {
+	c.onYield = f
+}
+
+// OnResume registers a function called each time the coroutine resumes from a
+// yield point, before Yield returns the value sent by the program, including
+// when resuming a coroutine restored by Unmarshal. The function is not called
+// when a stopped coroutine unwinds its stack. See OnYield.
+func (c *Context[R, S]) OnResume(f func(depth int)) {
+	c.onResume = f
+}
+
+// SetContext sets the context.Context of the coroutine, which the functions
+// that yield obtain by calling Context, to observe cancellation or pass it to
+// the functions they call. Passing nil restores the default context, which is
+// context.Background.
+//
+// The context is not serialized with the state of the coroutine, since the
+// cancellation, deadline and values of a context belong to the process that
+// created it. Programs set a live context on coroutines restored by Unmarshal
+// before resuming them.
+func (c *Context[R, S]) SetContext(ctx gocontext.Context) {
+	if ctx == nil {
+		if c.goctx != nil {
+			liveContexts.Delete(c.goctx.id)
+		}
+		return
+	}
+	if c.goctx == nil {
+		c.goctx = newContextHolder()
+	}
+	liveContexts.Store(c.goctx.id, ctx)
+}
+
+// Context returns a context.Context forwarding to the context set by
+// SetContext, or to context.Background if none was set.
+//
+// The context returned forwards to the context set on the coroutine at the
+// time it is used, so functions that yield may hold it across yield points:
+// after the coroutine resumes, it observes the context set when it resumed.
+// In durable mode, this includes the contexts held in the state restored by
+// Unmarshal, which forward to the context set on the coroutine they were
+// restored into.
+func (c *Context[R, S]) Context() gocontext.Context {
+	if c.goctx == nil {
+		c.goctx = newContextHolder()
+	}
+	return liveContext{c.goctx}
+}
+
+// loadContext returns the context set by SetContext, or nil if none was set.
+func (c *Context[R, S]) loadContext() gocontext.Context {
+	if c.goctx == nil {
+		return nil
+	}
+	return c.goctx.load()
+}
+
+// liveContext is the context.Context returned by Context.Context. It holds no
+// context itself, but the holder of the context of its coroutine, which is
+// plain data that durable coroutines can serialize along with the frames that
+// hold it.
+type liveContext struct{ holder *contextHolder }
+
+func (c liveContext) context() gocontext.Context {
+	if ctx := c.holder.load(); ctx != nil {
+		return ctx
+	}
+	return gocontext.Background()
+}
+
+func (c liveContext) Deadline() (time.Time, bool) { return c.context().Deadline() }
+func (c liveContext) Done() <-chan struct{}       { return c.context().Done() }
+func (c liveContext) Err() error                  { return c.context().Err() }
+func (c liveContext) Value(key any) any           { return c.context().Value(key) }
+
+// contextHolder identifies the context of a coroutine, which is stored in
+// liveContexts under the ID of the holder rather than in the holder, so that
+// it never has to be serialized.
+type contextHolder struct{ id uint64 }
+
+var (
+	liveContexts  sync.Map // uint64 => gocontext.Context
+	lastContextID atomic.Uint64
+)
+
+func newContextHolder() *contextHolder {
+	h := new(contextHolder)
+	h.init()
+	return h
+}
+
+// init assigns a new ID to the holder, which releases its context when it is
+// garbage collected. It is also called on holders created by deserialization,
+// which hold the ID that they had in the serialized coroutine.
+func (h *contextHolder) init() {
+	h.id = lastContextID.Add(1)
+	runtime.SetFinalizer(h, func(h *contextHolder) { liveContexts.Delete(h.id) })
+}
+
+func (h *contextHolder) load() gocontext.Context {
+	if ctx, ok := liveContexts.Load(h.id); ok {
+		return ctx.(gocontext.Context)
+	}
+	return nil
+}
+
+// Err returns a *PanicError if the coroutine completed because of a panic that
+// was captured (see CapturePanics), or nil otherwise.
+func (c *Context[R, S]) Err() error {
+	return c.err
+}
+
+// PanicError is the error reported by Context.Err when a coroutine panicked.
+type PanicError struct {
+	// Value passed to panic.
+	Value any
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("coroutine panic: %v", e.Value)
+}
+
+// Unwrap returns the value passed to panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// FrameInfo describes a frame of a durable coroutine stack, as reported by
+// Context.Frames.
+type FrameInfo struct {
+	// IP is the instruction pointer of the frame, the position in the body of
+	// the function where its execution resumes. Zero means that the function
+	// has not yet executed any statement.
+	IP int
+
+	// Func is the name of the function that the frame belongs to. Functions
+	// are named like types.Func.FullName, e.g. example.com/pkg.(*T).M, and
+	// function literals after the function declaring them and their position
+	// in the source, e.g. example.com/pkg.F.func@f.go:12:7.
+	//
+	// It is empty for frames that were not generated by the compiler.
+	Func string
+
+	// Type is the type of the frame, a pointer to the struct generated by the
+	// compiler to hold the variables of the function.
+	Type reflect.Type
+}
+
+// Reset prepares a completed coroutine to be executed again from its entry
+// point, as if it had just been created.
+//
+// The values yielded, sent and returned by the previous run are cleared, so the
+// context does not retain references to them. So is the panic captured by the
+// previous run.
+//
+// The method panics if the coroutine has not completed.
+func (c *Context[R, S]) Reset() {
+	if !c.done {
+		panic("coroutine.Reset: coroutine has not completed")
+	}
+	var zeroR R
+	var zeroS S
+	c.recv, c.send, c.result = zeroR, zeroS, zeroR
+	c.done, c.stop, c.resume = false, false, false
+	c.err = nil
+	c.reset()
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, allowing the coroutine
+// state to be stored by generic encoders. It is equivalent to Marshal.
+func (c *Context[R, S]) MarshalBinary() ([]byte, error) {
+	return c.Marshal()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, restoring the state
+// of the coroutine from b.
+//
+// Unlike Unmarshal, the method expects b to hold exactly the serialized state,
+// and returns an error if it has trailing bytes.
+func (c *Context[R, S]) UnmarshalBinary(b []byte) error {
+	defer func(unsafeStrings bool) { c.unsafeStrings = unsafeStrings }(c.unsafeStrings)
+	c.unsafeStrings = false
+
+	n, err := c.Unmarshal(b)
+	if err != nil {
+		return err
+	}
+	if n != len(b) {
+		return fmt.Errorf("coroutine: %d unexpected bytes after serialized state", len(b)-n)
+	}
+	return nil
+}
+
 // Run executes a coroutine to completion, calling f for each value that the
 // coroutine yields, and sending back each value that f returns.
 func Run[R, S any](c Coroutine[R, S], f func(R) S) {
@@ -3,10 +3,14 @@
 package coroutine
 
 import (
+	gocontext "context"
 	"errors"
+	"fmt"
+	"reflect"
 	"runtime"
 	"unsafe"
 
+	"github.com/stealthrocket/coroutine/compress"
 	"github.com/stealthrocket/coroutine/types"
 )
 
@@ -85,6 +89,117 @@ func Pop(s *Stack) {
 	s.FP--
 }
 
+// RunDefers calls the functions deferred by a compiled function, in the
+// inverse order that they were declared.
+//
+// The compiled function passes the value it recovered as v. When returning
+// because of a panic, the panic is replayed for each deferred function so that
+// calls to recover behave as they would in a function that was not compiled;
+// the panic resumes if none of the functions recovered it. The unwinding of a
+// stopped coroutine cannot be recovered, just like runtime.Goexit in volatile
+// mode.
+func RunDefers(defers []func(), v any) {
+	if _, ok := v.(unwind); ok {
+		defer panic(v)
+		for _, f := range defers {
+			defer f()
+		}
+		return
+	}
+	for i := len(defers) - 1; i >= 0; i-- {
+		v = runDefer(defers[i], v)
+	}
+	if v != nil {
+		panic(v)
+	}
+}
+
+// The runDefer function calls f with v as the panic in flight, returning the
+// value of the panic still in flight after f returned.
+func runDefer(f func(), v any) (panicking any) {
+	defer func() { panicking = recover() }()
+	if v != nil {
+		// Deferring f makes it the function that recovers the panic, as
+		// recover only returns the panic value when called directly by a
+		// deferred function.
+		defer f()
+		panic(v)
+	}
+	f()
+	return nil
+}
+
+// Defers holds the functions deferred by a compiled function. It is stored in
+// the frame of the function, so that a coroutine yielding from a deferred
+// function, possibly while a panic is in flight, can be serialized and resumed.
+type Defers struct {
+	// Funcs are the deferred functions that have not returned yet, in the
+	// order that they were deferred.
+	Funcs []func()
+
+	// Running is true once the function returned or panicked, and the
+	// deferred functions are running.
+	Running bool
+
+	// Panic is the value of the panic in flight when Running is true, or
+	// nil if there is none.
+	Panic any
+}
+
+// RunDefers calls the functions deferred by a compiled function in d, which
+// is the same as the package-level RunDefers function, except that the
+// deferred functions may yield.
+//
+// When a deferred function yields, the function is left in d with the panic
+// in flight, and the coroutine unwinds. When the coroutine resumes, the
+// compiled function calls RunDefers again without executing its body, which
+// resumes the deferred function with the same value to recover, then runs the
+// functions deferred before it. The frame of the function is popped once all
+// the deferred functions returned.
+func (c *Context[R, S]) RunDefers(d *Defers, v any) {
+	if !d.Running {
+		d.Running, d.Panic = true, v
+	}
+	for len(d.Funcs) > 0 {
+		if _, ok := d.Panic.(unwind); ok {
+			break // the coroutine was stopped
+		}
+		i := len(d.Funcs) - 1
+		v := runDefer(d.Funcs[i], d.Panic)
+		if _, ok := v.(unwind); ok && c.Unwinding() {
+			panic(v) // the deferred function yielded
+		}
+		d.Funcs[i] = nil
+		d.Funcs = d.Funcs[:i]
+		d.Panic = v
+	}
+	defer Pop(&c.Stack)
+	RunDefers(d.Funcs, d.Panic)
+}
+
+// Clone returns a copy of the stack, which can be used to snapshot the state of
+// a suspended coroutine and roll it back later by assigning the clone to the
+// Stack of its Context before calling Next:
+//
+//	snapshot, err := c.Context().Stack.Clone()
+//	...
+//	c.Context().Stack, err = snapshot.Clone()
+//
+// Like Context.Clone, the stack is copied by serializing and deserializing it,
+// so the frames of the clone and the values they reference (e.g. local
+// variables captured by deferred closures, or pointers from callee frames to
+// variables of their callers) are not shared with the original stack. The
+// contexts held in the frames (see Context.Context) keep forwarding to the
+// context of the coroutine. Cloning the clone before restoring it allows the
+// snapshot to be restored more than once.
+func (s *Stack) Clone() (Stack, error) {
+	v, _, err := types.Deserialize(types.Serialize(s))
+	if err != nil {
+		return Stack{}, err
+	}
+	return *v.(*Stack), nil
+}
+
 func (s *Stack) isTop() bool {
 	return s.FP == len(s.Frames)-1
 }
@@ -94,36 +209,206 @@ type serializedCoroutine[R any] struct {
 	entryR func() R
 	stack  Stack
 	resume bool
+	goctx  *contextHolder
 }
 
 // Marshal returns a serialized Context.
 func (c *Context[R, S]) Marshal() ([]byte, error) {
-	return types.Serialize(&serializedCoroutine[R]{
+	return types.Serialize(c.serializedCoroutine()), nil
+}
+
+// MarshalCompressed returns a serialized Context, compressed with c.
+//
+// Unmarshal detects that the state was compressed and decompresses it,
+// provided that c is registered (see compress.Register).
+func (c *Context[R, S]) MarshalCompressed(compression *compress.Compression) ([]byte, error) {
+	b, err := c.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return compression.Encode(b)
+}
+
+// MarshalSize returns the number of bytes that Marshal produces for the
+// Context, without allocating the serialized output.
+func (c *Context[R, S]) MarshalSize() (int, error) {
+	return types.Size(c.serializedCoroutine()), nil
+}
+
+// FrameSizes returns the number of bytes that each frame of the coroutine
+// stack occupies in the output of Marshal, starting with the frame of the entry
+// point. It is intended to help find the frames holding large amounts of state.
+//
+// Frames are measured independently, so values referenced by more than one
+// frame (e.g. a frame captured by a closure) are counted in each of them. The
+// sizes exclude the header of the serialized output, and add up to roughly the
+// size reported by MarshalSize.
+func (c *Context[R, S]) FrameSizes() []int {
+	header := types.Size(nil)
+	sizes := make([]int, len(c.Stack.Frames))
+	for i, frame := range c.Stack.Frames {
+		sizes[i] = types.Size(frame) - header
+	}
+	return sizes
+}
+
+// Frames returns a description of each frame of the coroutine stack, starting
+// with the frame of the entry point. It is intended for debugging and tooling,
+// e.g. to inspect the state of a coroutine after a call to Unmarshal.
+func (c *Context[R, S]) Frames() []FrameInfo {
+	frames := make([]FrameInfo, len(c.Stack.Frames))
+	for i, frame := range c.Stack.Frames {
+		frames[i] = frameInfo(frame)
+	}
+	return frames
+}
+
+// Depth returns the number of frames on the coroutine stack, which is the
+// depth of the call stack where the coroutine is suspended. It is zero if the
+// coroutine has not started or has completed.
+func (c *Context[R, S]) Depth() int {
+	return len(c.Stack.Frames)
+}
+
+// Top returns a description of the topmost frame of the coroutine stack, which
+// belongs to the function where the coroutine is suspended. The boolean is
+// false if the stack is empty.
+func (c *Context[R, S]) Top() (FrameInfo, bool) {
+	if len(c.Stack.Frames) == 0 {
+		return FrameInfo{}, false
+	}
+	return frameInfo(c.Stack.Frames[len(c.Stack.Frames)-1]), true
+}
+
+// frameInfo describes a frame pushed on the stack. The compiler generates
+// frames as pointers to structs whose first field is the IP, tagged with the
+// name of the function; the IP and name of frames of other types are left
+// empty.
+func frameInfo(frame any) FrameInfo {
+	info := FrameInfo{Type: reflect.TypeOf(frame)}
+	v := reflect.ValueOf(frame)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct || v.Elem().NumField() == 0 {
+		return info
+	}
+	ip := v.Elem().Type().Field(0)
+	if ip.Name != "IP" || ip.Type.Kind() != reflect.Int {
+		return info
+	}
+	info.IP = int(v.Elem().Field(0).Int())
+	info.Func = ip.Tag.Get("coroutine")
+	return info
+}
+
+// TruncateFrames discards the frames of the coroutine stack above depth, so the
+// next call to Next resumes the coroutine in the function that owns the frame
+// at depth-1. The call that this function was executing starts over from the
+// beginning; with a depth of zero, the whole coroutine starts over from its
+// entry point.
+//
+// This is an advanced and unsafe operation: the functions called again may
+// observe the side effects of their previous execution, and the state of the
+// remaining frames may not be consistent with what the functions expect. The
+// method must only be called while the coroutine is suspended, and returns an
+// error if depth is not within the bounds of the stack. Coroutines which are
+// never truncated are not affected.
+func (c *Context[R, S]) TruncateFrames(depth int) error {
+	if depth < 0 || depth > len(c.Stack.Frames) {
+		return fmt.Errorf("coroutine: frame depth %d out of range [0:%d]", depth, len(c.Stack.Frames))
+	}
+	if depth == len(c.Stack.Frames) {
+		return nil
+	}
+	clear(c.Stack.Frames[depth:])
+	c.Stack.Frames = c.Stack.Frames[:depth]
+	c.Stack.FP = depth - 1
+	// The topmost frame is no longer the one that yielded, so the coroutine
+	// must not return the value sent to the yield point when resuming.
+	c.resume = false
+	return nil
+}
+
+// Clone returns a coroutine resuming from the same point as c, which can be
+// driven independently of c (e.g. to speculatively execute one branch of a
+// computation and retry from the same state).
+//
+// The state of the coroutine is deep copied through serialization, so every
+// value it holds must be serializable. Pointers sharing memory in c share the
+// memory of the clone, but the two coroutines do not share memory with each
+// other. The values yielded to and sent by the program are copied shallowly,
+// and so is the configuration of c (e.g. its hooks and context).
+func (c *Context[R, S]) Clone() (Coroutine[R, S], error) {
+	v, _, err := types.Deserialize(types.Serialize(c.serializedCoroutine()))
+	if err != nil {
+		return Coroutine[R, S]{}, err
+	}
+	s := v.(*serializedCoroutine[R])
+	clone := *c
+	clone.resume = s.resume
+	clone.entry = s.entry
+	clone.entryR = s.entryR
+	clone.Stack = s.stack
+	clone.restoreContext(s.goctx, c.loadContext())
+	return Coroutine[R, S]{ctx: &clone}, nil
+}
+
+func (c *Context[R, S]) serializedCoroutine() *serializedCoroutine[R] {
+	return &serializedCoroutine[R]{
 		entry:  c.entry,
 		entryR: c.entryR,
 		stack:  c.Stack,
 		resume: c.resume,
-	}), nil
+		goctx:  c.goctx,
+	}
+}
+
+// restoreContext makes h the holder of the context of c, and sets ctx on it.
+// The holder was deserialized along with the frames of the coroutine, so the
+// contexts that they hold forward to the context set on c.
+func (c *Context[R, S]) restoreContext(h *contextHolder, ctx gocontext.Context) {
+	if h != nil {
+		h.init()
+	}
+	c.goctx = h
+	c.SetContext(ctx)
 }
 
 // Unmarshal deserializes a Context from the provided buffer, returning
 // the number of bytes that were read in order to reconstruct the
-// context.
+// context. The buffer may hold the output of Marshal or MarshalCompressed.
 func (c *Context[R, S]) Unmarshal(b []byte) (int, error) {
+	size := len(b)
+	b, compressedSize, err := compress.Decode(b)
+	if err != nil {
+		return 0, err
+	}
+	deserialize := types.Deserialize
+	if c.unsafeStrings {
+		deserialize = types.DeserializeUnsafe
+	}
 	start := len(b)
-	v, b, err := types.Deserialize(b)
+	v, b, err := deserialize(b)
 	if err != nil {
 		if errors.Is(err, types.ErrBuildIDMismatch) {
 			return 0, ErrInvalidState
 		}
 		return 0, err
 	}
+	sn := start - len(b)
+	if compressedSize != 0 {
+		if len(b) != 0 {
+			return 0, fmt.Errorf("coroutine: %d unexpected bytes after compressed state", len(b))
+		}
+		sn = compressedSize
+	}
+	if c.strict && sn != size {
+		return 0, fmt.Errorf("coroutine: %d unexpected bytes after serialized state", size-sn)
+	}
 	s := v.(*serializedCoroutine[R])
 	c.entry = s.entry
 	c.entryR = s.entryR
 	c.Stack = s.stack
 	c.resume = s.resume
-	sn := start - len(b)
+	c.restoreContext(s.goctx, c.loadContext())
 	return sn, nil
 }
 
@@ -133,6 +418,9 @@ func (c *Context[R, S]) Yield(value R) S {
 		if c.stop {
 			panic(unwind{})
 		}
+		if c.onResume != nil {
+			c.onResume(c.Depth())
+		}
 		return c.send
 	} else {
 		if c.stop {
@@ -142,6 +430,9 @@ func (c *Context[R, S]) Yield(value R) S {
 		c.resume = true
 		c.send = zero
 		c.recv = value
+		if c.onYield != nil {
+			c.onYield(c.Depth())
+		}
 		panic(unwind{})
 	}
 }
@@ -163,7 +454,16 @@ func (c Coroutine[R, S]) Next() (hasNext bool) {
 			default:
 				// TODO: can we figure out a way to know when we are unwinding the
 				// stack and only recover then so we don't alter the panic stack?
-				panic(v)
+				if !c.ctx.capture {
+					panic(v)
+				}
+				// A panic raised while resuming leaves the frames of the
+				// functions being rewound on the stack, which the
+				// completed coroutine must not retain.
+				c.ctx.err = &PanicError{Value: v}
+				c.ctx.done, c.ctx.resume = true, false
+				c.ctx.reset()
+				return
 			}
 
 			if c.ctx.Unwinding() {
@@ -185,6 +485,12 @@ func (c Coroutine[R, S]) Next() (hasNext bool) {
 	return hasNext
 }
 
+func (c *Context[R, S]) reset() {
+	// Replace the stack instead of truncating it so the frames of the previous
+	// run are not retained by the backing array.
+	c.Stack = Stack{}
+}
+
 type context[R any] struct {
 	// Entry point of the coroutine, this is captured so the associated
 	// generator can call into the coroutine to start or resume it at the
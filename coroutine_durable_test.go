@@ -3,8 +3,14 @@
 package coroutine
 
 import (
+	"bytes"
+	gocontext "context"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/stealthrocket/coroutine/compress"
+	"github.com/stealthrocket/coroutine/types"
 )
 
 func TestLocalStorageStack(t *testing.T) {
@@ -36,3 +42,729 @@ func TestLocalStorageStack(t *testing.T) {
 		t.Error("test did not run")
 	}
 }
+
+func TestRunDefers(t *testing.T) {
+	run := func(v any, defers ...func()) (panicking any) {
+		defer func() { panicking = recover() }()
+		RunDefers(defers, v)
+		return nil
+	}
+
+	t.Run("order", func(t *testing.T) {
+		var calls []int
+		run(nil,
+			func() { calls = append(calls, 1) },
+			func() { calls = append(calls, 2) },
+		)
+		if !reflect.DeepEqual(calls, []int{2, 1}) {
+			t.Errorf("wrong order of deferred calls: %v", calls)
+		}
+	})
+
+	t.Run("no panic", func(t *testing.T) {
+		if v := run(nil, func() {
+			if v := recover(); v != nil {
+				t.Errorf("unexpected recovered value: %v", v)
+			}
+		}); v != nil {
+			t.Errorf("unexpected panic: %v", v)
+		}
+	})
+
+	t.Run("recover panic", func(t *testing.T) {
+		var recovered []any
+		v := run("oops",
+			func() { recovered = append(recovered, recover()) },
+			func() { recovered = append(recovered, recover()) },
+		)
+		if v != nil {
+			t.Errorf("unexpected panic: %v", v)
+		}
+		if !reflect.DeepEqual(recovered, []any{"oops", nil}) {
+			t.Errorf("wrong recovered values: %v", recovered)
+		}
+	})
+
+	t.Run("resume panic", func(t *testing.T) {
+		if v := run("oops", func() {}); v != "oops" {
+			t.Errorf("wrong panic: %v", v)
+		}
+	})
+
+	t.Run("replace panic", func(t *testing.T) {
+		var recovered any
+		v := run("oops",
+			func() { recovered = recover() },
+			func() { panic("other") },
+		)
+		if v != nil {
+			t.Errorf("unexpected panic: %v", v)
+		}
+		if recovered != "other" {
+			t.Errorf("wrong recovered value: %v", recovered)
+		}
+	})
+
+	t.Run("unwind", func(t *testing.T) {
+		var calls int
+		v := run(unwind{},
+			func() { calls++ },
+			func() {
+				calls++
+				if v := recover(); v != nil {
+					t.Errorf("unwinding must not be recovered: %v", v)
+				}
+			},
+		)
+		if _, ok := v.(unwind); !ok {
+			t.Errorf("wrong panic: %v", v)
+		}
+		if calls != 2 {
+			t.Errorf("wrong number of deferred calls: %d", calls)
+		}
+	})
+}
+
+func init() {
+	types.RegisterFunc[func()]("github.com/stealthrocket/coroutine.outer")
+}
+
+type outerFrame struct {
+	IP int `coroutine:"github.com/stealthrocket/coroutine.outer"`
+}
+
+type innerFrame struct {
+	IP int `coroutine:"github.com/stealthrocket/coroutine.inner"`
+}
+
+// outer and inner are written the way the compiler generates code for a
+// function yielding 0 and 3 around a call to a function yielding 1 and 2.
+func outer() {
+	c := LoadContext[int, any]()
+	f := Push[outerFrame](&c.Stack)
+	defer func() {
+		if !c.Unwinding() {
+			Pop(&c.Stack)
+		}
+	}()
+	switch {
+	case f.IP < 1:
+		c.Yield(0)
+		f.IP = 1
+		fallthrough
+	case f.IP < 2:
+		inner()
+		f.IP = 2
+		fallthrough
+	case f.IP < 3:
+		c.Yield(3)
+		f.IP = 3
+	}
+}
+
+func inner() {
+	c := LoadContext[int, any]()
+	f := Push[innerFrame](&c.Stack)
+	defer func() {
+		if !c.Unwinding() {
+			Pop(&c.Stack)
+		}
+	}()
+	switch {
+	case f.IP < 1:
+		c.Yield(1)
+		f.IP = 1
+		fallthrough
+	case f.IP < 2:
+		c.Yield(2)
+		f.IP = 2
+	}
+}
+
+func TestFrames(t *testing.T) {
+	c := New[int, any](outer)
+
+	next := func(want int) {
+		t.Helper()
+		if !c.Next() {
+			t.Fatal("coroutine completed")
+		}
+		if got := c.Recv(); got != want {
+			t.Fatalf("wrong value yielded: want=%d got=%d", want, got)
+		}
+	}
+
+	next(0)
+	next(1)
+	next(2)
+
+	want := []FrameInfo{
+		{IP: 1, Func: "github.com/stealthrocket/coroutine.outer", Type: reflect.TypeOf(new(outerFrame))},
+		{IP: 1, Func: "github.com/stealthrocket/coroutine.inner", Type: reflect.TypeOf(new(innerFrame))},
+	}
+	if got := c.Context().Frames(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong frames:\nwant=%+v\ngot= %+v", want, got)
+	}
+	if depth := c.Context().Depth(); depth != len(want) {
+		t.Fatalf("wrong depth: want=%d got=%d", len(want), depth)
+	}
+	if top, ok := c.Context().Top(); !ok || top != want[1] {
+		t.Fatalf("wrong top frame: want=%+v got=%+v", want[1], top)
+	}
+
+	// Frames which were not generated by the compiler have no IP or name.
+	for _, frame := range []any{new(int), new(struct{}), new(struct{ IP string }), new(struct{ X, IP int })} {
+		want := FrameInfo{Type: reflect.TypeOf(frame)}
+		if got := frameInfo(frame); got != want {
+			t.Errorf("wrong info for frame of type %T: want=%+v got=%+v", frame, want, got)
+		}
+	}
+
+	if err := c.Context().TruncateFrames(3); err == nil {
+		t.Fatal("truncating above the top of the stack did not return an error")
+	}
+	if err := c.Context().TruncateFrames(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The call to inner starts over.
+	next(1)
+	next(2)
+	next(3)
+	if c.Next() {
+		t.Fatal("coroutine did not complete")
+	}
+	if _, ok := c.Context().Top(); ok || c.Context().Depth() != 0 {
+		t.Fatal("completed coroutine has frames")
+	}
+}
+
+type panicFrame struct {
+	IP int `coroutine:"github.com/stealthrocket/coroutine.panicOnResume"`
+}
+
+// panicOnResume yields once, and panics when the coroutine resumes, before the
+// yield point is reached and the frame is popped.
+func panicOnResume() {
+	c := LoadContext[int, any]()
+	f := Push[panicFrame](&c.Stack)
+	defer func() {
+		if !c.Unwinding() {
+			Pop(&c.Stack)
+		}
+	}()
+	switch {
+	case f.IP < 1:
+		if c.Unwinding() {
+			panic("cannot resume")
+		}
+		c.Yield(0)
+		f.IP = 1
+	}
+}
+
+func TestCapturePanicsOnResume(t *testing.T) {
+	c := New[int, any](panicOnResume)
+	c.Context().CapturePanics(true)
+
+	if !c.Next() {
+		t.Fatal("coroutine did not yield")
+	}
+	if c.Next() {
+		t.Fatal("coroutine yielded after panicking")
+	}
+	if err := c.Context().Err(); err == nil {
+		t.Fatal("panic was not captured")
+	}
+	if c.Context().Unwinding() {
+		t.Error("panicked coroutine is unwinding")
+	}
+	if depth := c.Context().Depth(); depth != 0 {
+		t.Errorf("panicked coroutine has %d frames", depth)
+	}
+}
+
+func TestStackClone(t *testing.T) {
+	c := New[int, any](outer)
+
+	next := func(want int) {
+		t.Helper()
+		if !c.Next() {
+			t.Fatal("coroutine completed")
+		}
+		if got := c.Recv(); got != want {
+			t.Fatalf("wrong value yielded: want=%d got=%d", want, got)
+		}
+	}
+
+	next(0)
+	next(1)
+
+	snapshot, err := c.Context().Stack.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, frame := range snapshot.Frames {
+		if frame == c.Context().Stack.Frames[i] {
+			t.Fatalf("frame %d of the clone aliases the original frame", i)
+		}
+	}
+
+	next(2)
+	if ip := snapshot.Frames[1].(*innerFrame).IP; ip != 0 {
+		t.Fatalf("clone modified by the coroutine: want IP=0 got IP=%d", ip)
+	}
+
+	// Restoring the snapshot resumes the call to inner where it yielded 1,
+	// as many times as the snapshot is restored.
+	for i := 0; i < 2; i++ {
+		if c.Context().Stack, err = snapshot.Clone(); err != nil {
+			t.Fatal(err)
+		}
+		next(2)
+	}
+	next(3)
+	if c.Next() {
+		t.Fatal("coroutine did not complete")
+	}
+}
+
+func init() {
+	types.RegisterFunc[func()]("github.com/stealthrocket/coroutine.deferred")
+	types.RegisterClosure[func(), struct {
+		F  uintptr
+		X0 *deferredFrame
+	}]("github.com/stealthrocket/coroutine.deferred.func2")
+}
+
+type deferredFrame struct {
+	IP int `coroutine:"github.com/stealthrocket/coroutine.deferred"`
+	X0 int
+	X1 Defers
+}
+
+var deferredCalls []int
+
+// deferred is written the way the compiler generates code for a function
+// deferring a closure which reads a local variable, then yielding the value of
+// that variable.
+func deferred() {
+	c := LoadContext[int, any]()
+	f := Push[deferredFrame](&c.Stack)
+	defer func() {
+		if !c.Unwinding() || f.X1.Running {
+			c.RunDefers(&f.X1, recover())
+		}
+	}()
+	if f.X1.Running {
+		return
+	}
+	switch {
+	case f.IP < 1:
+		f.X0 = 1
+		f.X1.Funcs = append(f.X1.Funcs, func() {
+			deferredCalls = append(deferredCalls, f.X0)
+		})
+		f.IP = 1
+		fallthrough
+	case f.IP < 2:
+		c.Yield(f.X0)
+		f.IP = 2
+	}
+}
+
+func TestStackCloneDefers(t *testing.T) {
+	deferredCalls = nil
+	c := New[int, any](deferred)
+
+	if !c.Next() || c.Recv() != 1 {
+		t.Fatal("coroutine did not yield 1")
+	}
+	snapshot, err := c.Context().Stack.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The closure deferred by the clone reads the local variable of the
+	// cloned frame, which is not modified along with the original frame.
+	c.Context().Stack.Frames[0].(*deferredFrame).X0 = 42
+	c.Context().Stack = snapshot
+	if c.Next() {
+		t.Fatal("coroutine did not complete")
+	}
+	if !reflect.DeepEqual(deferredCalls, []int{1}) {
+		t.Fatalf("wrong values read by deferred closures: want=[1] got=%v", deferredCalls)
+	}
+}
+
+type payloadFrame struct {
+	IP int `coroutine:"github.com/stealthrocket/coroutine.payload"`
+	X0 []byte
+}
+
+func init() {
+	types.RegisterFunc[func()]("github.com/stealthrocket/coroutine.payload")
+}
+
+// payload yields twice with a large local variable live across the first
+// yield point, and around a call to inner.
+func payload() {
+	c := LoadContext[int, any]()
+	f := Push[payloadFrame](&c.Stack)
+	defer func() {
+		if !c.Unwinding() {
+			Pop(&c.Stack)
+		}
+	}()
+	switch {
+	case f.IP < 1:
+		f.X0 = make([]byte, 1000)
+		f.IP = 1
+		fallthrough
+	case f.IP < 2:
+		inner()
+		f.IP = 2
+	}
+}
+
+func TestFrameSizes(t *testing.T) {
+	c := New[int, any](payload)
+	base, err := c.Context().MarshalSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Next()
+
+	sizes := c.Context().FrameSizes()
+	if len(sizes) != 2 {
+		t.Fatalf("wrong number of frame sizes: want=2 got=%d", len(sizes))
+	}
+	if sizes[0] < 1000 {
+		t.Errorf("frame holding 1000 bytes has size %d", sizes[0])
+	}
+	if sizes[1] <= 0 || sizes[1] >= 1000 {
+		t.Errorf("wrong size of the inner frame: %d", sizes[1])
+	}
+
+	total, err := c.Context().MarshalSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The rest of the serialized state is the same as for a coroutine
+	// which has not started, give or take the encoding of the stack.
+	sum := sizes[0] + sizes[1]
+	if diff := total - (base + sum); diff < -100 || diff > 100 {
+		t.Errorf("frame sizes do not add up to the marshaled size: base=%d sum=%d total=%d diff=%d", base, sum, total, diff)
+	}
+}
+
+func TestMarshalCompressed(t *testing.T) {
+	c := New[int, any](outer)
+	c.Next()
+	c.Next()
+
+	b, err := c.Context().MarshalCompressed(compress.Gzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append(b, "trailing bytes"...)
+
+	r := New[int, any](outer)
+	if _, err := r.Context().Unmarshal(b); err == nil {
+		t.Fatal("unmarshaling state with an unregistered compression did not return an error")
+	}
+
+	compress.Register(compress.Gzip)
+	n, err := r.Context().Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(b) - len("trailing bytes"); n != want {
+		t.Fatalf("wrong number of bytes read: want=%d got=%d", want, n)
+	}
+
+	var yields []int
+	for r.Next() {
+		yields = append(yields, r.Recv())
+	}
+	if want := []int{2, 3}; !reflect.DeepEqual(yields, want) {
+		t.Fatalf("wrong values yielded: want=%v got=%v", want, yields)
+	}
+
+	if _, err := r.Context().Unmarshal(append([]byte{0x7f}, b[1:]...)); err == nil {
+		t.Fatal("unmarshaling state with an unknown compression did not return an error")
+	}
+}
+
+func TestStrictUnmarshal(t *testing.T) {
+	c := New[int, any](outer)
+	c.Next()
+
+	b, err := c.Context().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	garbage := append(b[:len(b):len(b)], "garbage"...)
+
+	r := New[int, any](outer)
+	n, err := r.Context().Unmarshal(garbage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(b) {
+		t.Fatalf("wrong number of bytes read: want=%d got=%d", len(b), n)
+	}
+
+	r = New[int, any](outer)
+	r.Context().StrictUnmarshal(true)
+	if _, err := r.Context().Unmarshal(garbage); err == nil {
+		t.Fatal("unmarshaling state with trailing bytes did not return an error in strict mode")
+	}
+	if n, err := r.Context().Unmarshal(b); err != nil {
+		t.Fatal(err)
+	} else if n != len(b) {
+		t.Fatalf("wrong number of bytes read: want=%d got=%d", len(b), n)
+	}
+}
+
+type greetFrame struct {
+	IP int `coroutine:"github.com/stealthrocket/coroutine.greet"`
+	X0 string
+}
+
+func init() {
+	types.RegisterFunc[func()]("github.com/stealthrocket/coroutine.greet")
+}
+
+// greet yields the length of a string held in its frame across the yield.
+func greet() {
+	c := LoadContext[int, any]()
+	f := Push[greetFrame](&c.Stack)
+	defer func() {
+		if !c.Unwinding() {
+			Pop(&c.Stack)
+		}
+	}()
+	switch {
+	case f.IP < 1:
+		f.X0 = strings.Repeat("hello", 2)
+		f.IP = 1
+		fallthrough
+	case f.IP < 2:
+		c.Yield(len(f.X0))
+		f.IP = 2
+	}
+}
+
+func TestUnsafeUnmarshal(t *testing.T) {
+	c := New[int, any](greet)
+	c.Next()
+
+	b, err := c.Context().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The test overwrites the input after restoring the coroutine, which
+	// changes the string held in its frame only if it references the input.
+	for _, test := range []struct {
+		unsafe bool
+		want   string
+	}{
+		{unsafe: false, want: "hellohello"},
+		{unsafe: true, want: "HELLOHELLO"},
+	} {
+		input := bytes.Clone(b)
+		r := New[int, any](greet)
+		r.Context().UnsafeUnmarshal(test.unsafe)
+		if _, err := r.Context().Unmarshal(input); err != nil {
+			t.Fatal(err)
+		}
+		copy(input[bytes.Index(input, []byte("hellohello")):], "HELLOHELLO")
+
+		if s := r.Context().Stack.Frames[0].(*greetFrame).X0; s != test.want {
+			t.Errorf("wrong string restored with UnsafeUnmarshal(%t): want=%q got=%q", test.unsafe, test.want, s)
+		}
+		if r.Next() {
+			t.Error("restored coroutine did not complete")
+		}
+	}
+}
+
+func TestContextNotSerialized(t *testing.T) {
+	// Contexts that can be canceled hold channels, which cannot be
+	// serialized, and belong to the process that created them.
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+
+	c := New[int, any](outer)
+	c.Context().SetContext(ctx)
+	c.Next()
+
+	b, err := c.Context().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := New[int, any](outer)
+	if _, err := r.Context().Unmarshal(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Context().Context().Err(); err != nil {
+		t.Errorf("context restored by Unmarshal: %v", err)
+	}
+
+	// Clones live in the same process, and keep the context.
+	clone, err := c.Context().Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := clone.Context().Context().Err(); err == nil {
+		t.Error("context not kept by Clone")
+	}
+}
+
+func init() {
+	types.RegisterFunc[func()]("github.com/stealthrocket/coroutine.holdContext")
+}
+
+type holdContextFrame struct {
+	IP int `coroutine:"github.com/stealthrocket/coroutine.holdContext"`
+	X0 gocontext.Context
+}
+
+// holdContext is written the way the compiler generates code for a function
+// holding the context of its coroutine across a yield point, then yielding
+// whether the context was canceled.
+func holdContext() {
+	c := LoadContext[bool, any]()
+	f := Push[holdContextFrame](&c.Stack)
+	defer func() {
+		if !c.Unwinding() {
+			Pop(&c.Stack)
+		}
+	}()
+	switch {
+	case f.IP < 1:
+		f.X0 = c.Context()
+		f.IP = 1
+		fallthrough
+	case f.IP < 2:
+		c.Yield(false)
+		f.IP = 2
+		fallthrough
+	case f.IP < 3:
+		c.Yield(f.X0.Err() != nil)
+		f.IP = 3
+	}
+}
+
+func TestContextHeld(t *testing.T) {
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	defer cancel()
+
+	c := New[bool, any](holdContext)
+	c.Context().SetContext(ctx)
+	if !c.Next() {
+		t.Fatal("coroutine completed")
+	}
+	clone, err := c.Context().Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.Context().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The context held by the restored coroutine forwards to the context
+	// set when it resumes.
+	canceled, cancelRestored := gocontext.WithCancel(gocontext.Background())
+	cancelRestored()
+	r := New[bool, any](holdContext)
+	if _, err := r.Context().Unmarshal(b); err != nil {
+		t.Fatal(err)
+	}
+	r.Context().SetContext(canceled)
+	if !r.Next() || !r.Recv() {
+		t.Error("context held by the restored coroutine did not observe the cancellation")
+	}
+
+	// The clone has a context of its own, set to the context of c when it
+	// was cloned.
+	clone.Context().SetContext(canceled)
+	if !clone.Next() || !clone.Recv() {
+		t.Error("context held by the clone did not observe the cancellation")
+	}
+	if !c.Next() || c.Recv() {
+		t.Error("context held by the coroutine observed the cancellation of its clone")
+	}
+
+	// The contexts held in a cloned stack forward to the context of the
+	// coroutine that the stack was cloned from.
+	c = New[bool, any](holdContext)
+	c.Context().SetContext(ctx)
+	c.Next()
+	snapshot, err := c.Context().Stack.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	c.Context().Stack = snapshot
+	if !c.Next() || !c.Recv() {
+		t.Error("context held by the restored stack did not observe the cancellation")
+	}
+}
+
+func TestHooks(t *testing.T) {
+	var yields, resumes []int
+	c := New[int, any](outer)
+	c.Context().OnYield(func(depth int) { yields = append(yields, depth) })
+	c.Context().OnResume(func(depth int) { resumes = append(resumes, depth) })
+
+	for c.Next() {
+	}
+	if want := []int{1, 2, 2, 1}; !reflect.DeepEqual(yields, want) {
+		t.Errorf("wrong yield depths: want=%v got=%v", want, yields)
+	}
+	if want := []int{1, 2, 2, 1}; !reflect.DeepEqual(resumes, want) {
+		t.Errorf("wrong resume depths: want=%v got=%v", want, resumes)
+	}
+
+	// Clones keep the hooks of the coroutine they were cloned from.
+	yields, resumes = nil, nil
+	c = New[int, any](outer)
+	c.Context().OnYield(func(depth int) { yields = append(yields, depth) })
+	c.Context().OnResume(func(depth int) { resumes = append(resumes, depth) })
+	c.Next()
+	clone, err := c.Context().Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for clone.Next() {
+	}
+	if want := []int{1, 2, 2, 1}; !reflect.DeepEqual(yields, want) {
+		t.Errorf("wrong yield depths of the clone: want=%v got=%v", want, yields)
+	}
+	if want := []int{1, 2, 2, 1}; !reflect.DeepEqual(resumes, want) {
+		t.Errorf("wrong resume depths of the clone: want=%v got=%v", want, resumes)
+	}
+
+	// Unwinding a stopped coroutine does not resume it.
+	yields, resumes = nil, nil
+	c = New[int, any](outer)
+	c.Context().OnYield(func(depth int) { yields = append(yields, depth) })
+	c.Context().OnResume(func(depth int) { resumes = append(resumes, depth) })
+	c.Next()
+	c.Next()
+	c.Stop()
+	if c.Next() {
+		t.Fatal("stopped coroutine yielded")
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(yields, want) {
+		t.Errorf("wrong yield depths: want=%v got=%v", want, yields)
+	}
+	if want := []int{1}; !reflect.DeepEqual(resumes, want) {
+		t.Errorf("wrong resume depths: want=%v got=%v", want, resumes)
+	}
+}
@@ -1,6 +1,9 @@
 package coroutine
 
 import (
+	gocontext "context"
+	"errors"
+	"io"
 	"reflect"
 	"testing"
 )
@@ -46,3 +49,107 @@ func BenchmarkNew(b *testing.B) {
 		New[any, any](func() { _ = i }).Next()
 	}
 }
+
+func TestReset(t *testing.T) {
+	runs := 0
+	c := NewWithReturn[int, any](func() int {
+		runs++
+		return runs
+	})
+
+	for i := 1; i <= 3; i++ {
+		if c.Next() {
+			t.Fatal("coroutine yielded unexpectedly")
+		}
+		if !c.Done() {
+			t.Fatal("coroutine did not complete")
+		}
+		if r := c.Result(); r != i {
+			t.Errorf("wrong result at run %d: %d", i, r)
+		}
+		c.Context().Reset()
+		if c.Done() {
+			t.Fatal("coroutine still completed after reset")
+		}
+		if r := c.Result(); r != 0 {
+			t.Errorf("result not cleared after reset: %d", r)
+		}
+	}
+}
+
+func TestResetNotCompleted(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("resetting a coroutine that has not completed did not panic")
+		}
+	}()
+	New[int, any](func() {}).Context().Reset()
+}
+
+func TestSetContext(t *testing.T) {
+	c := New[int, any](func() {})
+	if ctx := c.Context().Context(); ctx.Done() != nil || ctx.Err() != nil {
+		t.Errorf("default context is not context.Background: %v", ctx)
+	}
+
+	type key struct{}
+	ctx, cancel := gocontext.WithCancel(gocontext.WithValue(gocontext.Background(), key{}, 42))
+	c.Context().SetContext(ctx)
+	cancel()
+	if err := c.Context().Context().Err(); err != gocontext.Canceled {
+		t.Errorf("cancellation not observed: %v", err)
+	}
+	if v := c.Context().Context().Value(key{}); v != 42 {
+		t.Errorf("wrong context value: %v", v)
+	}
+
+	c.Context().SetContext(nil)
+	if ctx := c.Context().Context(); ctx.Done() != nil || ctx.Err() != nil {
+		t.Errorf("default context not restored: %v", ctx)
+	}
+}
+
+func TestCapturePanics(t *testing.T) {
+	c := New[int, any](func() { panic(io.ErrUnexpectedEOF) })
+	c.Context().CapturePanics(true)
+
+	if c.Next() {
+		t.Fatal("coroutine yielded unexpectedly")
+	}
+	if !c.Done() {
+		t.Fatal("coroutine did not complete")
+	}
+	var perr *PanicError
+	if err := c.Context().Err(); !errors.As(err, &perr) {
+		t.Fatalf("wrong error: %v", err)
+	} else if perr.Value != io.ErrUnexpectedEOF {
+		t.Errorf("wrong panic value: %v", perr.Value)
+	}
+	if !errors.Is(c.Context().Err(), io.ErrUnexpectedEOF) {
+		t.Error("panic error does not wrap the value passed to panic")
+	}
+	if depth := c.Context().Depth(); depth != 0 {
+		t.Errorf("panicked coroutine has %d frames", depth)
+	}
+
+	c.Context().Reset()
+	if err := c.Context().Err(); err != nil {
+		t.Errorf("error not cleared after reset: %v", err)
+	}
+}
+
+func TestCapturePanicsDisabled(t *testing.T) {
+	if !Durable {
+		t.Skip("panics crash the program in volatile mode")
+	}
+	c := New[int, any](func() { panic("oops") })
+	defer func() {
+		if v := recover(); v != "oops" {
+			t.Errorf("wrong panic: %v", v)
+		}
+		if err := c.Context().Err(); err != nil {
+			t.Errorf("panic was captured: %v", err)
+		}
+	}()
+	c.Next()
+}
@@ -6,6 +6,8 @@ import (
 	"runtime"
 	"sync"
 	"unsafe"
+
+	"github.com/stealthrocket/coroutine/compress"
 )
 
 // Durable is a constant which takes the values true or false depending on
@@ -24,13 +26,28 @@ func New[R, S any](f func()) Coroutine[R, S] {
 func NewWithReturn[R, S any](f func() R) Coroutine[R, S] {
 	c := &Context[R, S]{
 		context: context[R]{
-			next: make(chan struct{}),
+			entry: f,
 		},
 	}
+	c.start()
+	return Coroutine[R, S]{ctx: c}
+}
+
+// start spawns the goroutine backing the coroutine, which waits for the first
+// call to Next before invoking the entry point.
+func (c *Context[R, S]) start() {
+	c.next = make(chan struct{})
 
 	go func() {
 		execute(c, func() {
 			defer func() {
+				// The goroutine exits without panicking when the
+				// coroutine is stopped, so recover returns nil.
+				if c.capture {
+					if v := recover(); v != nil {
+						c.err = &PanicError{Value: v}
+					}
+				}
 				c.done = true
 				close(c.next)
 			}()
@@ -38,12 +55,14 @@ func NewWithReturn[R, S any](f func() R) Coroutine[R, S] {
 			<-c.next
 
 			if !c.stop {
-				c.result = f()
+				c.result = c.entry()
 			}
 		})
 	}()
+}
 
-	return Coroutine[R, S]{ctx: c}
+func (c *Context[R, S]) reset() {
+	c.start()
 }
 
 // Next executes the coroutine until its next yield point, or until completion.
@@ -60,7 +79,8 @@ func (c Coroutine[R, S]) Next() bool {
 }
 
 type context[R any] struct {
-	next chan struct{}
+	entry func() R
+	next  chan struct{}
 }
 
 func (c *Context[R, S]) Yield(v R) S {
@@ -70,11 +90,17 @@ func (c *Context[R, S]) Yield(v R) S {
 	var zero S
 	c.send = zero
 	c.recv = v
+	if c.onYield != nil {
+		c.onYield(c.Depth())
+	}
 	c.next <- struct{}{}
 	<-c.next
 	if c.stop {
 		runtime.Goexit()
 	}
+	if c.onResume != nil {
+		c.onResume(c.Depth())
+	}
 	return c.send
 }
 
@@ -82,6 +108,46 @@ func (c *Context[R, S]) Marshal() ([]byte, error) {
 	return nil, ErrNotDurable
 }
 
+func (c *Context[R, S]) MarshalCompressed(compression *compress.Compression) ([]byte, error) {
+	return nil, ErrNotDurable
+}
+
+func (c *Context[R, S]) MarshalSize() (int, error) {
+	return 0, ErrNotDurable
+}
+
+// FrameSizes returns nil, volatile coroutines do not have stack frames.
+func (c *Context[R, S]) FrameSizes() []int {
+	return nil
+}
+
+// Frames returns nil, volatile coroutines do not have stack frames.
+func (c *Context[R, S]) Frames() []FrameInfo {
+	return nil
+}
+
+// Depth returns zero, volatile coroutines do not have stack frames.
+func (c *Context[R, S]) Depth() int {
+	return 0
+}
+
+// Top returns false, volatile coroutines do not have stack frames.
+func (c *Context[R, S]) Top() (FrameInfo, bool) {
+	return FrameInfo{}, false
+}
+
+// TruncateFrames returns ErrNotDurable, volatile coroutines do not have stack
+// frames.
+func (c *Context[R, S]) TruncateFrames(depth int) error {
+	return ErrNotDurable
+}
+
+// Clone returns ErrNotDurable, the state of volatile coroutines is held by the
+// goroutine that runs them and cannot be copied.
+func (c *Context[R, S]) Clone() (Coroutine[R, S], error) {
+	return Coroutine[R, S]{}, ErrNotDurable
+}
+
 func (c *Context[R, S]) Unmarshal(b []byte) (int, error) {
 	return 0, ErrNotDurable
 }
@@ -0,0 +1,234 @@
+// Package codecs contains serialization functions for types of the standard
+// library that cannot be serialized by reflection.
+//
+// The functions are not registered by default: programs call the Register
+// functions of the types they hold in coroutines during initialization, before
+// serializing or deserializing state. Registration determines the IDs of types
+// in the serialized output (see [types.Register]), so the programs exchanging
+// state must register the same codecs in the same order.
+package codecs
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/stealthrocket/coroutine/types"
+)
+
+// RegisterBigInt registers serialization functions for big.Int, which holds
+// its digits in a slice of machine words. Values are serialized with their gob
+// encoding, which does not depend on the size of words on the host.
+func RegisterBigInt() {
+	types.Register[big.Int](serializeBigInt, deserializeBigInt)
+}
+
+func serializeBigInt(s *types.Serializer, x *big.Int) error {
+	data, err := x.GobEncode()
+	if err != nil {
+		return fmt.Errorf("failed to marshal big.Int: %w", err)
+	}
+
+	types.SerializeT(s, data)
+	return nil
+}
+
+func deserializeBigInt(d *types.Deserializer, x *big.Int) error {
+	var b []byte
+	types.DeserializeTo(d, &b)
+	return x.GobDecode(b)
+}
+
+// RegisterBigRat registers serialization functions for big.Rat. Values are
+// serialized with their gob encoding, like big.Int values (see
+// [RegisterBigInt]).
+func RegisterBigRat() {
+	types.Register[big.Rat](serializeBigRat, deserializeBigRat)
+}
+
+func serializeBigRat(s *types.Serializer, x *big.Rat) error {
+	data, err := x.GobEncode()
+	if err != nil {
+		return fmt.Errorf("failed to marshal big.Rat: %w", err)
+	}
+
+	types.SerializeT(s, data)
+	return nil
+}
+
+func deserializeBigRat(d *types.Deserializer, x *big.Rat) error {
+	var b []byte
+	types.DeserializeTo(d, &b)
+	return x.GobDecode(b)
+}
+
+// RegisterNetipAddr registers serialization functions for netip.Addr and
+// netip.AddrPort, which hold unexported pointers that cannot be serialized by
+// reflection. Values are serialized with their binary encoding, which retains
+// the IPv6 zone of addresses.
+func RegisterNetipAddr() {
+	types.Register[netip.Addr](serializeNetipAddr, deserializeNetipAddr)
+	types.Register[netip.AddrPort](serializeNetipAddrPort, deserializeNetipAddrPort)
+}
+
+func serializeNetipAddr(s *types.Serializer, x *netip.Addr) error {
+	data, err := x.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to marshal netip.Addr: %w", err)
+	}
+
+	types.SerializeT(s, data)
+	return nil
+}
+
+func deserializeNetipAddr(d *types.Deserializer, x *netip.Addr) error {
+	var b []byte
+	types.DeserializeTo(d, &b)
+	return x.UnmarshalBinary(b)
+}
+
+func serializeNetipAddrPort(s *types.Serializer, x *netip.AddrPort) error {
+	data, err := x.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to marshal netip.AddrPort: %w", err)
+	}
+
+	types.SerializeT(s, data)
+	return nil
+}
+
+func deserializeNetipAddrPort(d *types.Deserializer, x *netip.AddrPort) error {
+	var b []byte
+	types.DeserializeTo(d, &b)
+	return x.UnmarshalBinary(b)
+}
+
+// RegisterURL registers serialization functions for url.URL. Values are
+// serialized with their binary encoding, which is the string form of the URL,
+// and are parsed again on deserialization. The deserialized URL is equivalent
+// to the original, including its user information and query, but fields that
+// only affect how the URL is written (e.g. RawPath) may be normalized.
+func RegisterURL() {
+	types.Register[url.URL](serializeURL, deserializeURL)
+}
+
+func serializeURL(s *types.Serializer, x *url.URL) error {
+	data, err := x.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to marshal url.URL: %w", err)
+	}
+
+	types.SerializeT(s, data)
+	return nil
+}
+
+func deserializeURL(d *types.Deserializer, x *url.URL) error {
+	var b []byte
+	types.DeserializeTo(d, &b)
+	return x.UnmarshalBinary(b)
+}
+
+// RegisterSyncOnce registers serialization functions for sync.Once, which
+// record whether the Once has fired. A Once that has fired before being
+// serialized is restored as fired, so its function is not called again by the
+// deserialized coroutine.
+//
+// The state of a Once whose function is still executing when it is serialized
+// cannot be captured: it is restored as not fired.
+func RegisterSyncOnce() {
+	done, err := syncOnceDone(reflect.TypeOf(sync.Once{}))
+	if err != nil {
+		panic(fmt.Errorf("cannot register sync.Once: %w", err))
+	}
+	types.Register[sync.Once](
+		func(s *types.Serializer, x *sync.Once) error {
+			types.SerializeT(s, done(unsafe.Pointer(x)))
+			return nil
+		},
+		deserializeSyncOnce)
+}
+
+// syncOnceDone returns a function reporting whether the sync.Once at an
+// address has fired. sync.Once does not expose it without firing, so the
+// function reads the unexported flag set after its function returns. The
+// layout of sync.Once is not part of its API, so the flag is looked up by
+// reflection and its type checked against those of past Go releases, rather
+// than reading memory that may hold something else.
+func syncOnceDone(t reflect.Type) (func(unsafe.Pointer) bool, error) {
+	f, ok := t.FieldByName("done")
+	if !ok {
+		return nil, fmt.Errorf("%s has no done field", t)
+	}
+	switch f.Type {
+	case reflect.TypeOf(atomic.Bool{}):
+		return func(p unsafe.Pointer) bool {
+			return (*atomic.Bool)(unsafe.Add(p, f.Offset)).Load()
+		}, nil
+	case reflect.TypeOf(atomic.Uint32{}), reflect.TypeOf(uint32(0)):
+		return func(p unsafe.Pointer) bool {
+			return (*atomic.Uint32)(unsafe.Add(p, f.Offset)).Load() != 0
+		}, nil
+	default:
+		return nil, fmt.Errorf("unexpected type %s of the done field of %s", f.Type, t)
+	}
+}
+
+func deserializeSyncOnce(d *types.Deserializer, x *sync.Once) error {
+	var done bool
+	types.DeserializeTo(d, &done)
+	if done {
+		x.Do(func() {})
+	}
+	return nil
+}
+
+// RegisterBytesBuffer registers serialization functions for bytes.Buffer,
+// which holds its content in unexported fields. The unread portion of the
+// buffer is serialized, and the deserialized buffer holds a copy of it, to
+// which writes append and from which reads resume. Bytes that were already read
+// are not retained, and neither is the last read operation, so UnreadByte and
+// UnreadRune fail until the next read.
+func RegisterBytesBuffer() {
+	types.Register[bytes.Buffer](serializeBytesBuffer, deserializeBytesBuffer)
+}
+
+func serializeBytesBuffer(s *types.Serializer, x *bytes.Buffer) error {
+	types.SerializeT(s, x.Bytes())
+	return nil
+}
+
+func deserializeBytesBuffer(d *types.Deserializer, x *bytes.Buffer) error {
+	var b []byte
+	types.DeserializeTo(d, &b)
+	*x = bytes.Buffer{}
+	x.Write(b)
+	return nil
+}
+
+// RegisterStringsBuilder registers serialization functions for
+// strings.Builder, which records its own address to detect copies and cannot
+// be restored by reflection. The accumulated string is serialized, and the
+// deserialized builder holds a copy of it to which writes append.
+func RegisterStringsBuilder() {
+	types.Register[strings.Builder](serializeStringsBuilder, deserializeStringsBuilder)
+}
+
+func serializeStringsBuilder(s *types.Serializer, x *strings.Builder) error {
+	types.SerializeT(s, x.String())
+	return nil
+}
+
+func deserializeStringsBuilder(d *types.Deserializer, x *strings.Builder) error {
+	var str string
+	types.DeserializeTo(d, &str)
+	*x = strings.Builder{}
+	x.WriteString(str)
+	return nil
+}
@@ -0,0 +1,283 @@
+package codecs
+
+import (
+	"bytes"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"unsafe"
+
+	"github.com/stealthrocket/coroutine/types"
+)
+
+func TestRegisterBig(t *testing.T) {
+	RegisterBigInt()
+	RegisterBigRat()
+
+	large, ok := new(big.Int).SetString("-123456789012345678901234567890123456789012345678901234567890", 10)
+	if !ok {
+		t.Fatal("invalid big.Int literal")
+	}
+
+	for _, x := range []*big.Int{new(big.Int), big.NewInt(42), large} {
+		b := types.Serialize(x)
+		out, _, err := types.Deserialize(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if y := out.(*big.Int); x.Cmp(y) != 0 {
+			t.Errorf("expected %v, got %v", x, y)
+		}
+	}
+
+	for _, x := range []*big.Rat{new(big.Rat), big.NewRat(1, 3), new(big.Rat).SetFrac(large, big.NewInt(7))} {
+		b := types.Serialize(x)
+		out, _, err := types.Deserialize(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if y := out.(*big.Rat); x.Cmp(y) != 0 {
+			t.Errorf("expected %v, got %v", x, y)
+		}
+	}
+
+	// Frames hold pointers to big numbers, which are shared when multiple
+	// variables reference the same value.
+	type frame struct {
+		X, Y *big.Int
+		Z    big.Int
+	}
+	f := frame{X: large, Y: large}
+	f.Z.Set(large)
+
+	out, _, err := types.Deserialize(types.Serialize(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := out.(frame)
+	if g.X.Cmp(large) != 0 || g.Z.Cmp(large) != 0 {
+		t.Errorf("expected %v, got %v and %v", large, g.X, &g.Z)
+	}
+	if g.X != g.Y {
+		t.Error("shared pointers to big.Int were not preserved")
+	}
+}
+
+func TestRegisterNetipAddr(t *testing.T) {
+	type frame struct {
+		Addrs []netip.Addr
+		Port  netip.AddrPort
+		IP    net.IP
+	}
+
+	t.Run("ipv4, ipv6 and zones", func(t *testing.T) {
+		RegisterNetipAddr()
+
+		in := &frame{
+			Addrs: []netip.Addr{
+				{},
+				netip.MustParseAddr("192.0.2.1"),
+				netip.MustParseAddr("2001:db8::1"),
+				netip.MustParseAddr("fe80::1%eth0"),
+				netip.MustParseAddr("::ffff:192.0.2.1"),
+			},
+			Port: netip.MustParseAddrPort("[fe80::2%lo]:8080"),
+			IP:   net.ParseIP("198.51.100.7"),
+		}
+
+		out, _, err := types.Deserialize(types.Serialize(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := out.(*frame)
+
+		if !slices.Equal(f.Addrs, in.Addrs) {
+			t.Errorf("expected %v, got %v", in.Addrs, f.Addrs)
+		}
+		if f.Port != in.Port {
+			t.Errorf("expected %v, got %v", in.Port, f.Port)
+		}
+		if !f.IP.Equal(in.IP) {
+			t.Errorf("expected %v, got %v", in.IP, f.IP)
+		}
+	})
+}
+
+func TestRegisterURL(t *testing.T) {
+	type frame struct {
+		URL  *url.URL
+		Base url.URL
+	}
+
+	t.Run("user info and query", func(t *testing.T) {
+		RegisterURL()
+
+		in := &frame{
+			URL: &url.URL{
+				Scheme:   "https",
+				User:     url.UserPassword("user", "p@ss:word"),
+				Host:     "example.com:8443",
+				Path:     "/a b/c",
+				RawQuery: "q=1&q=2&x=%2F",
+				Fragment: "frag",
+			},
+		}
+		in.Base = *in.URL
+		in.Base.User = url.User("anonymous")
+		in.Base.RawQuery = ""
+
+		out, _, err := types.Deserialize(types.Serialize(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := out.(*frame)
+
+		if f.URL.String() != in.URL.String() {
+			t.Errorf("expected %v, got %v", in.URL, f.URL)
+		}
+		if password, _ := f.URL.User.Password(); f.URL.User.Username() != "user" || password != "p@ss:word" {
+			t.Errorf("wrong user info: %v", f.URL.User)
+		}
+		if q := f.URL.Query(); !slices.Equal(q["q"], []string{"1", "2"}) || q.Get("x") != "/" {
+			t.Errorf("wrong query: %v", q)
+		}
+		if f.Base.String() != in.Base.String() {
+			t.Errorf("expected %v, got %v", &in.Base, &f.Base)
+		}
+	})
+}
+
+func TestRegisterSyncOnce(t *testing.T) {
+	type frame struct {
+		Fired    sync.Once
+		NotFired sync.Once
+	}
+
+	t.Run("fired and not fired", func(t *testing.T) {
+		RegisterSyncOnce()
+
+		in := &frame{}
+		in.Fired.Do(func() {})
+
+		out, _, err := types.Deserialize(types.Serialize(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := out.(*frame)
+
+		f.Fired.Do(func() { t.Error("fired sync.Once was called again") })
+
+		called := false
+		f.NotFired.Do(func() { called = true })
+		if !called {
+			t.Error("sync.Once that did not fire was not called")
+		}
+	})
+}
+
+func TestSyncOnceLayout(t *testing.T) {
+	type onceGo121 struct {
+		m    sync.Mutex
+		done uint32
+	}
+	type onceGo122 struct {
+		done atomic.Uint32
+		m    sync.Mutex
+	}
+	type onceGo125 struct {
+		_    struct{}
+		done atomic.Bool
+		m    sync.Mutex
+	}
+
+	var a onceGo121
+	a.done = 1
+	var b onceGo122
+	b.done.Store(1)
+	var c onceGo125
+	c.done.Store(true)
+
+	for _, test := range []struct {
+		t reflect.Type
+		p unsafe.Pointer
+	}{
+		{reflect.TypeOf(&a).Elem(), unsafe.Pointer(&a)},
+		{reflect.TypeOf(&b).Elem(), unsafe.Pointer(&b)},
+		{reflect.TypeOf(&c).Elem(), unsafe.Pointer(&c)},
+	} {
+		done, err := syncOnceDone(test.t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !done(test.p) {
+			t.Errorf("%s: done flag not found", test.t)
+		}
+		zero := reflect.New(test.t)
+		if done(zero.UnsafePointer()) {
+			t.Errorf("%s: zero value reported as done", test.t)
+		}
+	}
+
+	for _, typ := range []reflect.Type{
+		reflect.TypeOf(new(struct{ m sync.Mutex })).Elem(),
+		reflect.TypeOf(struct{ done int64 }{}),
+	} {
+		if _, err := syncOnceDone(typ); err == nil {
+			t.Errorf("%s: unexpected layout accepted", typ)
+		}
+	}
+
+	if _, err := syncOnceDone(reflect.TypeOf(sync.Once{})); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRegisterBytesBuffer(t *testing.T) {
+	type frame struct {
+		Buffer  bytes.Buffer
+		Pointer *bytes.Buffer
+		Builder strings.Builder
+	}
+
+	t.Run("half-filled buffer and builder", func(t *testing.T) {
+		RegisterBytesBuffer()
+		RegisterStringsBuilder()
+
+		in := &frame{Pointer: new(bytes.Buffer)}
+		in.Buffer.WriteString("hello, ")
+		in.Pointer.WriteString("skipped:unread")
+		in.Pointer.Next(len("skipped:"))
+		in.Builder.WriteString("hello, ")
+
+		out, _, err := types.Deserialize(types.Serialize(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := out.(*frame)
+
+		f.Buffer.WriteString("world")
+		if s := f.Buffer.String(); s != "hello, world" {
+			t.Errorf("expected %q, got %q", "hello, world", s)
+		}
+		if s := f.Pointer.String(); s != "unread" {
+			t.Errorf("expected %q, got %q", "unread", s)
+		}
+		f.Pointer.WriteByte('!')
+		if b, err := f.Pointer.ReadBytes('!'); err != nil || string(b) != "unread!" {
+			t.Errorf("expected %q, got %q (%v)", "unread!", b, err)
+		}
+		// Builders panic when written to through a copy, which the
+		// deserialized builder must not be.
+		f.Builder.WriteString("world")
+		if s := f.Builder.String(); s != "hello, world" {
+			t.Errorf("expected %q, got %q", "hello, world", s)
+		}
+	})
+}
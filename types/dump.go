@@ -0,0 +1,357 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// Dump returns a human-readable representation of b, which must be the output
+// of [Serialize] (e.g. the state of a coroutine returned by [Context.Marshal]).
+//
+// Values are decoded with the types registered in the program, then printed
+// with a syntax close to Go composite literals. Pointers are labeled with an
+// identifier the first time they are printed (&#1 T{...}) and referenced by
+// that identifier afterwards (#1), which makes sharing and cycles visible.
+// Functions are printed by name, followed by the variables captured by
+// closures. Values of types with custom serializers are printed with their
+// String method if they have one.
+//
+// The output is intended for debugging and its format is not stable.
+//
+// Values of types that are unknown to the program are printed as opaque. If
+// decoding cannot continue past a type (e.g. because its kind is unknown),
+// the type is printed as opaque along with the number of bytes that were not
+// decoded, so partially-understood blobs can still be inspected.
+func Dump(b []byte) (string, error) {
+	d, err := newDeserializer(b)
+	if err != nil {
+		return "", err
+	}
+
+	x, _, stop, err := decode(d)
+	if err != nil {
+		return "", err
+	}
+
+	p := &dumper{ids: map[unsafe.Pointer]int{}}
+	if stop != nil {
+		fmt.Fprintf(&p.b, "<opaque: unknown type %s>", stop.desc)
+	} else {
+		p.dump(reflect.ValueOf(&x).Elem())
+	}
+	p.b.WriteByte('\n')
+	if n := len(d.b); n > 0 {
+		fmt.Fprintf(&p.b, "<%d bytes not decoded>\n", n)
+	}
+	return p.b.String(), nil
+}
+
+// Verify checks that b, which must be the output of [Serialize] (e.g. the
+// state of a coroutine returned by [Context.Marshal]), only references types
+// that can be deserialized by the program, and returns the description of each
+// type that cannot. This allows detecting missing calls to [Register] before
+// attempting to resume a coroutine.
+//
+// Which types can be deserialized depends on the registrations made by the
+// program, so Verify must be called from the program that resumes coroutines
+// (e.g. on startup, or by a subcommand of its binary), not from another tool.
+//
+// An error is returned if b cannot be decoded for another reason, for example
+// if it was produced by a different build of the program.
+func Verify(b []byte) (unknownTypes []string, err error) {
+	d, err := newDeserializer(b)
+	if err != nil {
+		return nil, err
+	}
+	_, unknownTypes, stop, err := decode(d)
+	if err != nil {
+		return nil, err
+	}
+	if stop != nil && !slices.Contains(unknownTypes, stop.desc) {
+		unknownTypes = append(unknownTypes, stop.desc)
+	}
+	return unknownTypes, nil
+}
+
+// decode deserializes a value from d. Values of types that are unknown to the
+// program are skipped, and the descriptions of those types are returned as
+// unknown. If decoding cannot continue past a type, stop is the error
+// describing it.
+func decode(d *Deserializer) (x any, unknown []string, stop *unknownTypeError, err error) {
+	d.opaque = true
+	err = func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				if e, ok := r.(runtime.Error); ok {
+					panic(e)
+				}
+				if e, ok := r.(error); ok {
+					err = e
+				} else {
+					err = fmt.Errorf("%v", r)
+				}
+			}
+		}()
+		px := &x
+		deserializeInterface(d, reflect.TypeOf(px).Elem(), unsafe.Pointer(px))
+		return nil
+	}()
+	if err != nil {
+		if !errors.As(err, &stop) {
+			return nil, nil, nil, fmt.Errorf("cannot decode serialized value: %w", err)
+		}
+		x = nil
+	}
+	return x, d.unknown, stop, nil
+}
+
+// unknownTypeError is raised when deserializing a type that does not exist in
+// the program.
+type unknownTypeError struct {
+	desc string
+}
+
+func (e *unknownTypeError) Error() string {
+	return "unknown type " + e.desc
+}
+
+// opaque is the type of the placeholders that unknown custom types are
+// deserialized as (see opaqueType).
+type opaque struct{}
+
+var opaqueT = reflect.TypeOf(opaque{})
+
+// opaqueType returns a placeholder for the custom type described by desc,
+// which has no deserializer in the program. Values of custom types are
+// prefixed with their size (see serializeCustom), so values of the placeholder
+// can be skipped without knowing their type, which lets decoding continue past
+// them.
+func opaqueType(desc string) reflect.Type {
+	return reflect.StructOf([]reflect.StructField{{
+		Name: "Opaque",
+		Type: opaqueT,
+		Tag:  reflect.StructTag(desc),
+	}})
+}
+
+// opaqueDesc returns the description of the type that t is a placeholder for,
+// if t was created by opaqueType.
+func opaqueDesc(t reflect.Type) (string, bool) {
+	if t.Kind() != reflect.Struct || t.Size() != 0 || t.NumField() != 1 {
+		return "", false
+	}
+	f := t.Field(0)
+	return string(f.Tag), f.Type == opaqueT
+}
+
+// deserializeOpaque skips a value of an unknown custom type. It panics unless
+// the deserializer was created to inspect the input (see decode), since the
+// value cannot be restored.
+func deserializeOpaque(d *Deserializer, desc string) {
+	if !d.opaque {
+		panic(&unknownTypeError{desc: desc})
+	}
+	d.read(deserializeVarint(d))
+	if !slices.Contains(d.unknown, desc) {
+		d.unknown = append(d.unknown, desc)
+	}
+}
+
+type dumper struct {
+	b      strings.Builder
+	indent int
+	ids    map[unsafe.Pointer]int
+}
+
+func (p *dumper) newline() {
+	p.b.WriteByte('\n')
+	for i := 0; i < p.indent; i++ {
+		p.b.WriteString("  ")
+	}
+}
+
+func (p *dumper) dump(v reflect.Value) {
+	t := v.Type()
+
+	if _, ok := types.serdeOf(t); ok {
+		if s, ok := stringer(v); ok {
+			p.b.WriteString(t.String())
+			p.b.WriteByte('(')
+			p.b.WriteString(strconv.Quote(s))
+			p.b.WriteByte(')')
+			return
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		p.b.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		p.b.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		p.b.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		p.b.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, t.Bits()))
+	case reflect.Complex64, reflect.Complex128:
+		p.b.WriteString(strconv.FormatComplex(v.Complex(), 'g', -1, t.Bits()))
+	case reflect.String:
+		p.b.WriteString(strconv.Quote(v.String()))
+	case reflect.UnsafePointer:
+		fmt.Fprintf(&p.b, "unsafe.Pointer(%#x)", v.Pointer())
+	case reflect.Chan:
+		p.b.WriteString(t.String())
+	case reflect.Interface:
+		if v.IsNil() {
+			p.b.WriteString("nil")
+		} else {
+			p.dump(v.Elem())
+		}
+	case reflect.Pointer:
+		p.dumpPointer(v)
+	case reflect.Func:
+		p.dumpFunc(v)
+	case reflect.Struct:
+		if desc, ok := opaqueDesc(t); ok {
+			fmt.Fprintf(&p.b, "<opaque: unknown type %s>", desc)
+			return
+		}
+		p.b.WriteString(t.String())
+		p.open('{', t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			p.newline()
+			p.b.WriteString(t.Field(i).Name)
+			p.b.WriteString(": ")
+			p.dump(v.Field(i))
+			p.b.WriteByte(',')
+		}
+		p.close('}', t.NumField())
+	case reflect.Array, reflect.Slice:
+		if t.Kind() == reflect.Slice && v.IsNil() {
+			p.b.WriteString("nil")
+			return
+		}
+		if t.Elem().Kind() == reflect.Uint8 && (t.Kind() == reflect.Slice || v.CanAddr()) {
+			fmt.Fprintf(&p.b, "%s(%q)", t, v.Bytes())
+			return
+		}
+		p.b.WriteString(t.String())
+		p.open('{', v.Len())
+		for i := 0; i < v.Len(); i++ {
+			p.newline()
+			p.dump(v.Index(i))
+			p.b.WriteByte(',')
+		}
+		p.close('}', v.Len())
+	case reflect.Map:
+		if v.IsNil() {
+			p.b.WriteString("nil")
+			return
+		}
+		type entry struct {
+			key   string
+			value reflect.Value
+		}
+		entries := make([]entry, 0, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			k := &dumper{ids: p.ids}
+			k.dump(iter.Key())
+			entries = append(entries, entry{k.b.String(), iter.Value()})
+		}
+		slices.SortFunc(entries, func(a, b entry) int {
+			return strings.Compare(a.key, b.key)
+		})
+		p.b.WriteString(t.String())
+		p.open('{', len(entries))
+		for _, e := range entries {
+			p.newline()
+			p.b.WriteString(e.key)
+			p.b.WriteString(": ")
+			p.dump(e.value)
+			p.b.WriteByte(',')
+		}
+		p.close('}', len(entries))
+	default:
+		fmt.Fprintf(&p.b, "<%s>", t)
+	}
+}
+
+func (p *dumper) open(c byte, n int) {
+	p.b.WriteByte(c)
+	if n > 0 {
+		p.indent++
+	}
+}
+
+func (p *dumper) close(c byte, n int) {
+	if n > 0 {
+		p.indent--
+		p.newline()
+	}
+	p.b.WriteByte(c)
+}
+
+func (p *dumper) dumpPointer(v reflect.Value) {
+	if v.IsNil() {
+		p.b.WriteString("nil")
+		return
+	}
+	ptr := v.UnsafePointer()
+	if id, ok := p.ids[ptr]; ok {
+		fmt.Fprintf(&p.b, "#%d", id)
+		return
+	}
+	id := len(p.ids) + 1
+	p.ids[ptr] = id
+	fmt.Fprintf(&p.b, "&#%d ", id)
+	p.dump(v.Elem())
+}
+
+func (p *dumper) dumpFunc(v reflect.Value) {
+	if v.IsNil() {
+		p.b.WriteString("nil")
+		return
+	}
+	fn := FuncByAddr(v.Pointer())
+	if fn == nil {
+		fmt.Fprintf(&p.b, "func(%#x)", v.Pointer())
+		return
+	}
+	p.b.WriteString(fn.Name)
+	if fn.Closure == nil || !v.CanAddr() {
+		return
+	}
+
+	// Function values point to a closure, which starts with the address of
+	// the function followed by the captured variables.
+	closure := reflect.NewAt(fn.Closure, *(*unsafe.Pointer)(v.Addr().UnsafePointer())).Elem()
+	n := fn.Closure.NumField() - 1
+	p.open('{', n)
+	for i := 1; i <= n; i++ {
+		p.newline()
+		p.b.WriteString(fn.Closure.Field(i).Name)
+		p.b.WriteString(": ")
+		p.dump(closure.Field(i))
+		p.b.WriteByte(',')
+	}
+	p.close('}', n)
+}
+
+func stringer(v reflect.Value) (string, bool) {
+	if v.CanAddr() {
+		v = v.Addr()
+	}
+	if !v.CanInterface() {
+		return "", false
+	}
+	s, ok := v.Interface().(fmt.Stringer)
+	if !ok {
+		return "", false
+	}
+	return s.String(), true
+}
@@ -0,0 +1,196 @@
+package types
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDump(t *testing.T) {
+	type node struct {
+		Value int
+		Next  *node
+	}
+
+	type frame struct {
+		IP    int
+		Name  string
+		Tags  map[string]int
+		Bytes []byte
+		Err   error
+		List  *node
+		Alias *node
+		When  time.Time
+	}
+
+	list := &node{Value: 1}
+	list.Next = &node{Value: 2, Next: list}
+
+	when := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	f := &frame{
+		IP:    3,
+		Name:  "hello",
+		Tags:  map[string]int{"b": 2, "a": 1},
+		Bytes: []byte("raw"),
+		List:  list,
+		Alias: list.Next,
+		When:  when,
+	}
+
+	out, err := Dump(Serialize(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"IP: 3,",
+		`Name: "hello",`,
+		"Tags: map[string]int{\n",
+		`"a": 1,`,
+		`Bytes: []uint8("raw"),`,
+		"Err: nil,",
+		"List: &#2 types.node{",
+		"Next: &#3 types.node{",
+		"Next: #2,",
+		"Alias: #3,",
+		`When: time.Time("` + when.String() + `"),`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+	if a, b := strings.Index(out, `"a": 1`), strings.Index(out, `"b": 2`); a > b {
+		t.Errorf("map entries are not sorted:\n%s", out)
+	}
+}
+
+func TestDumpUnknownType(t *testing.T) {
+	type custom struct {
+		X int
+	}
+
+	testReflect(t, "unknown custom type", func(t *testing.T) {
+		Register[custom](
+			func(s *Serializer, x *custom) error {
+				SerializeT(s, x.X)
+				return nil
+			},
+			func(d *Deserializer, x *custom) error {
+				DeserializeTo(d, &x.X)
+				return nil
+			})
+
+		b := Serialize([]any{custom{X: 42}, "after"})
+
+		// Simulate a program where the type was not registered.
+		types = newTypemap()
+
+		out, err := Dump(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"<opaque: unknown type types.custom with custom serializer #0>,", `"after",`} {
+			if !strings.Contains(out, want) {
+				t.Errorf("output does not contain %q:\n%s", want, out)
+			}
+		}
+		if strings.Contains(out, "bytes not decoded>") {
+			t.Errorf("output was not fully decoded:\n%s", out)
+		}
+	})
+}
+
+func TestDumpInvalid(t *testing.T) {
+	if _, err := Dump([]byte("nope")); err == nil {
+		t.Error("expected an error dumping invalid data")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	type custom struct {
+		X int
+	}
+	type other struct {
+		S string
+	}
+
+	testReflect(t, "verify registered and unknown types", func(t *testing.T) {
+		Register[custom](
+			func(s *Serializer, x *custom) error {
+				SerializeT(s, x.X)
+				return nil
+			},
+			func(d *Deserializer, x *custom) error {
+				DeserializeTo(d, &x.X)
+				return nil
+			})
+		Register[other](
+			func(s *Serializer, x *other) error {
+				SerializeT(s, x.S)
+				return nil
+			},
+			func(d *Deserializer, x *other) error {
+				DeserializeTo(d, &x.S)
+				return nil
+			})
+
+		b := Serialize([]any{1, custom{X: 42}, "hello", other{S: "x"}, custom{X: 21}})
+
+		unknown, err := Verify(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(unknown) != 0 {
+			t.Errorf("unexpected unknown types: %q", unknown)
+		}
+
+		// Simulate a program where the types were not registered.
+		types = newTypemap()
+
+		unknown, err = Verify(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(unknown) != 2 || !strings.Contains(unknown[0], "types.custom") || !strings.Contains(unknown[1], "types.other") {
+			t.Errorf("unexpected unknown types: %q", unknown)
+		}
+
+		// Programs that do not verify the input cannot deserialize it.
+		func() {
+			defer func() {
+				if _, ok := recover().(*unknownTypeError); !ok {
+					t.Error("deserializing an unknown type did not panic")
+				}
+			}()
+			Deserialize(b)
+		}()
+	})
+
+	if _, err := Verify([]byte("nope")); err == nil {
+		t.Error("expected an error verifying invalid data")
+	}
+}
+
+func TestDeserializeCustomSize(t *testing.T) {
+	type custom struct {
+		X int
+	}
+
+	testReflect(t, "deserializer reading less than written", func(t *testing.T) {
+		Register[custom](
+			func(s *Serializer, x *custom) error {
+				SerializeT(s, x.X)
+				SerializeT(s, x.X)
+				return nil
+			},
+			func(d *Deserializer, x *custom) error {
+				DeserializeTo(d, &x.X)
+				return nil
+			})
+
+		if _, _, err := Deserialize(Serialize(custom{X: 42})); !errors.Is(err, ErrCorrupted) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
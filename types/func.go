@@ -120,6 +120,10 @@ func FuncByAddr(addr uintptr) *Func { return functionsByAddr[addr] }
 var (
 	functionsByName map[string]*Func
 	functionsByAddr map[uintptr]*Func
+
+	// Address of the text section in memory. Function entries of the symbol
+	// table are offsets from the start of the section.
+	textAddr uintptr
 )
 
 func initFunctionTables(pclntab, symtab []byte) {
@@ -132,6 +136,7 @@ func initFunctionTables(pclntab, symtab []byte) {
 
 	tableFunc := table.LookupFunc(sentinelName)
 	offset := uint64(sentinelAddr) - tableFunc.Entry
+	textAddr = uintptr(offset)
 
 	functions := make([]Func, len(table.Funcs))
 	for i, fn := range table.Funcs {
@@ -163,11 +168,12 @@ func readSection(r io.ReaderAt, size uint64) ([]byte, error) {
 	return b, nil
 }
 
+// sentinel returns the name and address of a function, used to locate the
+// symbol table in memory. The address is read from the function value since
+// runtime.Callers reports its own frame when it is not inlined (e.g. -race).
+//
 //go:noinline
 func sentinel() (name string, addr uintptr) {
-	pc := [1]uintptr{}
-	runtime.Callers(0, pc[:])
-
-	fn := runtime.FuncForPC(pc[0])
-	return fn.Name(), fn.Entry()
+	addr = reflect.ValueOf(sentinel).Pointer()
+	return runtime.FuncForPC(addr).Name(), addr
 }
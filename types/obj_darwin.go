@@ -15,6 +15,7 @@ func init() {
 	defer f.Close()
 
 	initMachOFunctionTables(f)
+	initMachOStaticSections(f)
 	initMachOBuildID(f)
 }
 
@@ -32,6 +33,18 @@ func initMachOFunctionTables(f *macho.File) {
 	initFunctionTables(pclntabData, symtabData)
 }
 
+func initMachOStaticSections(f *macho.File) {
+	text := f.Section("__text")
+	if text == nil {
+		return
+	}
+	for _, name := range []string{"__noptrdata", "__data", "__bss", "__noptrbss"} {
+		if s := f.Section(name); s != nil {
+			addStaticSection(text.Addr, s.Addr, s.Size)
+		}
+	}
+}
+
 func initMachOBuildID(f *macho.File) {
 	text := f.Section("__text")
 
@@ -14,6 +14,7 @@ func init() {
 	defer f.Close()
 
 	initELFFunctionTables(f)
+	initELFStaticSections(f)
 	initELFBuildID(f)
 }
 
@@ -31,6 +32,18 @@ func initELFFunctionTables(f *elf.File) {
 	initFunctionTables(pclntabData, symtabData)
 }
 
+func initELFStaticSections(f *elf.File) {
+	text := f.Section(".text")
+	if text == nil {
+		return
+	}
+	for _, name := range []string{".noptrdata", ".data", ".bss", ".noptrbss"} {
+		if s := f.Section(name); s != nil {
+			addStaticSection(text.Addr, s.Addr, s.Size)
+		}
+	}
+}
+
 func initELFBuildID(f *elf.File) {
 	noteSection := f.Section(".note.go.buildid")
 	note, err := readSection(noteSection, noteSection.Size)
@@ -1,10 +1,12 @@
 package types
 
 import (
+	"cmp"
 	"encoding/binary"
 	"fmt"
 	"math"
 	"reflect"
+	"slices"
 	"unsafe"
 )
 
@@ -15,20 +17,29 @@ func serializeType(s *Serializer, t reflect.Type) {
 
 func deserializeType(d *Deserializer) reflect.Type {
 	t := deserializePointedAt(d, typeinfoT).Interface().(*typeinfo)
+	types.cacheMutex.Lock()
+	defer types.cacheMutex.Unlock()
 	return t.reflectType(types)
 }
 
 func serializeAny(s *Serializer, t reflect.Type, p unsafe.Pointer) {
+	s.flush()
+
 	if serde, ok := types.serdeOf(t); ok {
-		serde.ser(s, p)
+		serializeCustom(s, serde, p)
 		return
 	}
 
 	switch t {
 	case reflectValueType:
 		v := *(*reflect.Value)(p)
-		serializeType(s, v.Type())
-		serializeReflectValue(s, v.Type(), v)
+		// The zero value (e.g. a variable of a coroutine frame that was
+		// not assigned yet) has no type.
+		serializeBool(s, v.IsValid())
+		if v.IsValid() {
+			serializeType(s, v.Type())
+			serializeReflectValue(s, v.Type(), v)
+		}
 		return
 	}
 
@@ -87,21 +98,29 @@ func serializeAny(s *Serializer, t reflect.Type, p unsafe.Pointer) {
 		serializeFunc(s, t, p)
 	// Chan
 	default:
+		if serde, ok := types.fallbackOf(t); ok {
+			serializeCustom(s, serde, p)
+			return
+		}
 		panic(fmt.Errorf("reflection cannot serialize type %s", t))
 	}
 }
 
 func deserializeAny(d *Deserializer, t reflect.Type, p unsafe.Pointer) {
 	if serde, ok := types.serdeOf(t); ok {
-		serde.des(d, p)
+		deserializeCustom(d, t, serde, p)
 		return
 	}
 
 	switch t {
 	case reflectValueType:
-		rt := deserializeType(d)
-		v := deserializeReflectValue(d, rt)
-		reflect.NewAt(reflectValueType, p).Elem().Set(reflect.ValueOf(v))
+		var valid bool
+		deserializeBool(d, &valid)
+		if valid {
+			rt := deserializeType(d)
+			v := deserializeReflectValue(d, rt)
+			reflect.NewAt(reflectValueType, p).Elem().Set(reflect.ValueOf(v))
+		}
 		return
 	}
 
@@ -159,13 +178,52 @@ func deserializeAny(d *Deserializer, t reflect.Type, p unsafe.Pointer) {
 	case reflect.Func:
 		deserializeFunc(d, t, p)
 	default:
+		if serde, ok := types.fallbackOf(t); ok {
+			deserializeCustom(d, t, serde, p)
+			return
+		}
 		panic(fmt.Errorf("reflection cannot deserialize type %s", t))
 	}
 }
 
 var reflectValueType = reflect.TypeOf(reflect.Value{})
 
+// Values written by custom serializers are prefixed with their size, so that
+// programs which cannot deserialize their type are able to skip them (see
+// Verify), and so that deserializers reading more or less than what was
+// written are detected.
+func serializeCustom(s *Serializer, serde serde, p unsafe.Pointer) {
+	start, size := len(s.b), s.size()
+	serde.ser(s, p)
+	n := s.size() - size
+
+	var prefix [binary.MaxVarintLen64]byte
+	k := binary.PutVarint(prefix[:], int64(n))
+	if s.sizeOnly {
+		s.n += k
+	} else {
+		s.b = slices.Insert(s.b, start, prefix[:k]...)
+	}
+}
+
+func deserializeCustom(d *Deserializer, t reflect.Type, serde serde, p unsafe.Pointer) {
+	n := deserializeVarint(d)
+	checkLength(d, byteT, n)
+	rest := len(d.b) - n
+	serde.des(d, p)
+	if len(d.b) != rest {
+		panic(fmt.Errorf("%w: deserializer of %s read %d bytes of a %d bytes value", ErrCorrupted, t, n+rest-len(d.b), n))
+	}
+}
+
 func serializeReflectValue(s *Serializer, t reflect.Type, v reflect.Value) {
+	s.flush()
+
+	if _, ok := types.serdeOf(t); ok {
+		serializeAny(s, t, reflectValueAddr(t, v))
+		return
+	}
+
 	switch t.Kind() {
 	case reflect.Invalid:
 		panic(fmt.Errorf("can't serialize reflect.Invalid"))
@@ -212,32 +270,40 @@ func serializeReflectValue(s *Serializer, t reflect.Type, v reflect.Value) {
 		serializeSlice(s, t, unsafe.Pointer(&sl))
 	case reflect.Map:
 		serializeMapReflect(s, t, v)
-	case reflect.Struct:
-		for i := 0; i < t.NumField(); i++ {
-			f := t.Field(i)
-			if !f.IsExported() {
-				panic("not implemented: serializing reflect.Value(struct) with unexported fields")
-			}
-			serializeReflectValue(s, f.Type, v.Field(i))
-		}
 	case reflect.Func:
-		if addr := v.Pointer(); addr != 0 {
-			if fn := FuncByAddr(addr); fn != nil && fn.Closure != nil {
-				panic("not implemented: serializing reflect.Value(closure)")
-			}
-			indirect := unsafe.Pointer(&addr)
-			serializeFunc(s, t, unsafe.Pointer(&indirect))
-		} else {
-			serializeFunc(s, t, unsafe.Pointer(&addr))
-		}
+		// Copy the function value to memory so it can be serialized like
+		// any other function, which takes care of capturing the values of
+		// closures.
+		fn := reflect.New(t)
+		fn.Elem().Set(v)
+		serializeFunc(s, t, fn.UnsafePointer())
 	case reflect.Pointer:
 		serializePointedAt(s, t.Elem(), v.UnsafePointer())
 	default:
-		panic(fmt.Sprintf("not implemented: serializing reflect.Value with type %s (%s)", t, t.Kind()))
+		// Structs, interfaces, and other values are serialized from
+		// memory like values of their type held anywhere else.
+		serializeAny(s, t, reflectValueAddr(t, v))
+	}
+}
+
+// reflectValueAddr returns a pointer to the value of v, or to a copy of it if
+// v is not addressable.
+func reflectValueAddr(t reflect.Type, v reflect.Value) unsafe.Pointer {
+	if v.CanAddr() {
+		return unsafe.Pointer(v.UnsafeAddr())
 	}
+	p := reflect.New(t)
+	p.Elem().Set(v)
+	return p.UnsafePointer()
 }
 
 func deserializeReflectValue(d *Deserializer, t reflect.Type) (v reflect.Value) {
+	if _, ok := types.serdeOf(t); ok {
+		v = reflect.New(t).Elem()
+		deserializeAny(d, t, unsafe.Pointer(v.UnsafeAddr()))
+		return
+	}
+
 	switch t.Kind() {
 	case reflect.Invalid:
 		panic(fmt.Errorf("can't deserialize reflect.Invalid"))
@@ -317,26 +383,16 @@ func deserializeReflectValue(d *Deserializer, t reflect.Type) (v reflect.Value)
 		v = reflect.New(t).Elem()
 		var p uintptr // FIXME: what should this be?
 		deserializeMapReflect(d, t, v, unsafe.Pointer(&p))
-	case reflect.Struct:
-		v = reflect.New(t).Elem()
-		for i := 0; i < t.NumField(); i++ {
-			fv := deserializeReflectValue(d, t.Field(i).Type)
-			v.Field(i).Set(fv)
-		}
 	case reflect.Func:
-		var fn *Func
-		deserializeFunc(d, t, unsafe.Pointer(&fn))
 		v = reflect.New(t).Elem()
-		if fn != nil {
-			p := unsafe.Pointer(v.UnsafeAddr())
-			*(*unsafe.Pointer)(p) = unsafe.Pointer(&fn.Addr)
-		}
+		deserializeFunc(d, t, unsafe.Pointer(v.UnsafeAddr()))
 	case reflect.Pointer:
 		ep := deserializePointedAt(d, t.Elem())
 		v = reflect.New(t).Elem()
 		v.Set(ep)
 	default:
-		panic(fmt.Sprintf("not implemented: deserializing reflect.Value with type %s", t))
+		v = reflect.New(t).Elem()
+		deserializeAny(d, t, unsafe.Pointer(v.UnsafeAddr()))
 	}
 	return
 }
@@ -350,8 +406,9 @@ func serializePointedAt(s *Serializer, t reflect.Type, p unsafe.Pointer) {
 
 	if static(p) {
 		serializeVarint(s, -1)
-		off := staticOffset(p)
-		serializeVarint(s, off)
+		section, offset := staticOffset(p)
+		serializeVarint(s, section)
+		serializeVarint(s, offset)
 		return
 	}
 
@@ -416,6 +473,9 @@ func deserializePointedAt(d *Deserializer, t reflect.Type) reflect.Value {
 	// Negative offset means this is either a container or a standalone
 	// value.
 	if offset < 0 {
+		if t.Kind() == reflect.Array {
+			checkLength(d, t.Elem(), t.Len())
+		}
 		e := reflect.New(t)
 		ep := e.UnsafePointer()
 		d.store(id, ep)
@@ -430,8 +490,14 @@ func deserializePointedAt(d *Deserializer, t reflect.Type) reflect.Value {
 	// cp is a pointer to the container
 	cp := deserializePointedAt(d, ct)
 
-	// Create the pointer with an offset into the container.
+	// Create the pointer with an offset into the container. The serializer
+	// only writes the ID of the pointer the next time it is encountered, so
+	// it must be remembered. When the offset is zero, the container was
+	// stored with the same ID already.
 	ep := unsafe.Add(cp.UnsafePointer(), offset)
+	if offset != 0 {
+		d.store(id, ep)
+	}
 	r := reflect.NewAt(t, ep)
 	return r
 }
@@ -459,6 +525,15 @@ func serializeMapReflect(s *Serializer, t reflect.Type, r reflect.Value) {
 
 	serializeVarint(s, size)
 
+	// Pointers are assigned IDs in the order they are first serialized, and
+	// the varint encoding of IDs grows with their value, so the size of the
+	// output depends on the order of entries holding pointers. Those are
+	// always sorted for Size to match the output of Serialize.
+	if sortMapKeys || !isPlainData(t.Key()) || !isPlainData(t.Elem()) {
+		serializeSortedMapEntries(s, t, r)
+		return
+	}
+
 	// TODO: allocs
 	iter := r.MapRange()
 	k := reflect.New(t.Key()).Elem()
@@ -471,6 +546,98 @@ func serializeMapReflect(s *Serializer, t reflect.Type, r reflect.Value) {
 	}
 }
 
+func serializeSortedMapEntries(s *Serializer, t reflect.Type, r reflect.Value) {
+	// The entries are collected instead of looking up values by key after
+	// sorting because NaN keys cannot be looked up.
+	type entry struct{ k, v reflect.Value }
+	entries := make([]entry, 0, r.Len())
+
+	iter := r.MapRange()
+	for iter.Next() {
+		k := reflect.New(t.Key()).Elem()
+		v := reflect.New(t.Elem()).Elem()
+		k.Set(iter.Key())
+		v.Set(iter.Value())
+		entries = append(entries, entry{k, v})
+	}
+
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		return compareMapKeys(a.k, b.k)
+	})
+
+	for _, e := range entries {
+		serializeAny(s, t.Key(), e.k.Addr().UnsafePointer())
+		serializeAny(s, t.Elem(), e.v.Addr().UnsafePointer())
+	}
+}
+
+// compareMapKeys orders map keys of any comparable type. Booleans, numbers
+// and strings are compared by value, and so are structs, arrays and interfaces
+// of those; pointers and channels are compared by address, and interfaces by
+// the address of their dynamic type first, which only orders them the same
+// way within a process.
+func compareMapKeys(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Bool:
+		x, y := a.Bool(), b.Bool()
+		switch {
+		case x == y:
+			return 0
+		case x:
+			return 1
+		default:
+			return -1
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.Complex64, reflect.Complex128:
+		x, y := a.Complex(), b.Complex()
+		if c := cmp.Compare(real(x), real(y)); c != 0 {
+			return c
+		}
+		return cmp.Compare(imag(x), imag(y))
+	case reflect.String:
+		return cmp.Compare(a.String(), b.String())
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Chan:
+		return cmp.Compare(a.Pointer(), b.Pointer())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if c := compareMapKeys(a.Field(i), b.Field(i)); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if c := compareMapKeys(a.Index(i), b.Index(i)); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case reflect.Interface:
+		switch {
+		case a.IsNil() && b.IsNil():
+			return 0
+		case a.IsNil():
+			return -1
+		case b.IsNil():
+			return 1
+		}
+		ta := reflect.ValueOf(a.Elem().Type()).Pointer()
+		tb := reflect.ValueOf(b.Elem().Type()).Pointer()
+		if c := cmp.Compare(ta, tb); c != 0 {
+			return c
+		}
+		return compareMapKeys(a.Elem(), b.Elem())
+	default:
+		panic(fmt.Errorf("cannot compare map keys of type %s", a.Type()))
+	}
+}
+
 func deserializeMap(d *Deserializer, t reflect.Type, p unsafe.Pointer) {
 	r := reflect.NewAt(t, p).Elem()
 	deserializeMapReflect(d, t, r, p)
@@ -520,6 +687,9 @@ func serializeSlice(s *Serializer, t reflect.Type, p unsafe.Pointer) {
 func deserializeSlice(d *Deserializer, t reflect.Type, p unsafe.Pointer) {
 	l := deserializeVarint(d)
 	c := deserializeVarint(d)
+	if l < 0 || l > c {
+		panic(fmt.Errorf("%w: slice length %d out of range [0:%d]", ErrCorrupted, l, c))
+	}
 
 	at := reflect.ArrayOf(c, t.Elem())
 	ar := deserializePointedAt(d, at)
@@ -535,6 +705,10 @@ func deserializeSlice(d *Deserializer, t reflect.Type, p unsafe.Pointer) {
 }
 
 func serializeArray(s *Serializer, t reflect.Type, p unsafe.Pointer) {
+	if isPlainData(t) {
+		serializePlainData(s, t, p)
+		return
+	}
 	n := t.Len()
 	te := t.Elem()
 	ts := int(te.Size())
@@ -545,6 +719,10 @@ func serializeArray(s *Serializer, t reflect.Type, p unsafe.Pointer) {
 }
 
 func deserializeArray(d *Deserializer, t reflect.Type, p unsafe.Pointer) {
+	if isPlainData(t) {
+		deserializePlainData(d, t, p)
+		return
+	}
 	size := int(t.Elem().Size())
 	te := t.Elem()
 	for i := 0; i < t.Len(); i++ {
@@ -586,13 +764,86 @@ func deserializeUnsafePointer(d *Deserializer, p unsafe.Pointer) {
 }
 
 func serializeStruct(s *Serializer, t reflect.Type, p unsafe.Pointer) {
+	if isPlainData(t) {
+		serializePlainData(s, t, p)
+		return
+	}
 	serializeStructFields(s, p, t.NumField(), t.Field)
 }
 
 func deserializeStruct(d *Deserializer, t reflect.Type, p unsafe.Pointer) {
+	if isPlainData(t) {
+		deserializePlainData(d, t, p)
+		return
+	}
+	if desc, ok := opaqueDesc(t); ok {
+		deserializeOpaque(d, desc)
+		return
+	}
 	deserializeStructFields(d, p, t.NumField(), t.Field)
 }
 
+// Values of plain data types are serialized as a copy of their memory, which
+// is faster than serializing their fields or elements one by one. A type is
+// plain data if serializing its fields or elements produces the same bytes as
+// its memory: they are fixed-size numbers, encoded in little endian like the
+// memory of the host, with no padding between them. Booleans are excluded so
+// that deserializing bytes other than 0 and 1 does not create invalid values.
+//
+// The output is the same as without the optimization, so the serialized state
+// remains portable across hosts.
+func isPlainData(t reflect.Type) bool {
+	if v, ok := types.plain.Load(t); ok {
+		return v.(bool)
+	}
+	plain := hostLittleEndian && plainData(t)
+	types.plain.Store(t, plain)
+	return plain
+}
+
+var hostLittleEndian = binary.NativeEndian.Uint16([]byte{1, 0}) == 1
+
+func plainData(t reflect.Type) bool {
+	if _, ok := types.serdeOf(t); ok {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Int, reflect.Uint, reflect.Uintptr:
+		// Serialized as 64 bits integers.
+		return t.Size() == 8
+	case reflect.Array:
+		return plainData(t.Elem())
+	case reflect.Struct:
+		if _, ok := opaqueDesc(t); ok {
+			return false
+		}
+		var size uintptr
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Offset != size || !plainData(f.Type) {
+				return false
+			}
+			size += f.Type.Size()
+		}
+		return size == t.Size()
+	default:
+		return false
+	}
+}
+
+func serializePlainData(s *Serializer, t reflect.Type, p unsafe.Pointer) {
+	s.b = append(s.b, unsafe.Slice((*byte)(p), t.Size())...)
+}
+
+func deserializePlainData(d *Deserializer, t reflect.Type, p unsafe.Pointer) {
+	n := int(t.Size())
+	copy(unsafe.Slice((*byte)(p), n), d.read(n))
+}
+
 func serializeStructFields(s *Serializer, p unsafe.Pointer, n int, field func(int) reflect.StructField) {
 	for i := 0; i < n; i++ {
 		ft := field(i)
@@ -727,6 +978,19 @@ func serializeString(s *Serializer, x *string) {
 	at := reflect.ArrayOf(l, byteT)
 	ap := unsafe.Pointer(unsafe.StringData(*x))
 
+	if internStrings {
+		// Strings with the same content are written as references to the
+		// data of the first one.
+		if p, ok := s.strings[*x]; ok {
+			ap = p
+		} else {
+			if s.strings == nil {
+				s.strings = make(map[string]unsafe.Pointer)
+			}
+			s.strings[*x] = ap
+		}
+	}
+
 	serializePointedAt(s, at, ap)
 }
 
@@ -736,6 +1000,14 @@ func deserializeString(d *Deserializer, x *string) {
 	if l == 0 {
 		return
 	}
+	if l < 0 {
+		panic(fmt.Errorf("%w: negative string length %d", ErrCorrupted, l))
+	}
+
+	if d.unsafeStrings {
+		*x = unsafe.String((*byte)(deserializeStringData(d, l)), l)
+		return
+	}
 
 	at := reflect.ArrayOf(l, byteT)
 	ar := deserializePointedAt(d, at)
@@ -743,6 +1015,35 @@ func deserializeString(d *Deserializer, x *string) {
 	*x = unsafe.String((*byte)(ar.UnsafePointer()), l)
 }
 
+// deserializeStringData is like deserializePointedAt for the l bytes of a
+// string, but the bytes are referenced in the input instead of being copied,
+// unless they are part of a container of another type than a byte array.
+func deserializeStringData(d *Deserializer, l int) unsafe.Pointer {
+	ptr, id := d.readPtr()
+	if ptr != nil {
+		return ptr
+	}
+	if id == 0 {
+		panic(fmt.Errorf("%w: nil string data", ErrCorrupted))
+	}
+
+	offset := deserializeVarint(d)
+	if offset >= 0 {
+		// The bytes of strings are containers of their own, which the
+		// string may point into (e.g. substrings).
+		ct := deserializeType(d)
+		if ct.Kind() == reflect.Array && ct.Elem() == byteT {
+			return unsafe.Add(deserializeStringData(d, ct.Len()), offset)
+		}
+		cp := deserializePointedAt(d, ct)
+		return unsafe.Add(cp.UnsafePointer(), offset)
+	}
+
+	p := unsafe.Pointer(unsafe.SliceData(d.read(l)))
+	d.store(id, p)
+	return p
+}
+
 func serializeBool(s *Serializer, x bool) {
 	c := byte(0)
 	if x {
@@ -752,8 +1053,7 @@ func serializeBool(s *Serializer, x bool) {
 }
 
 func deserializeBool(d *Deserializer, x *bool) {
-	*x = d.b[0] == 1
-	d.b = d.b[1:]
+	*x = d.read(1)[0] == 1
 }
 
 func serializeInt(s *Serializer, x int) {
@@ -761,8 +1061,7 @@ func serializeInt(s *Serializer, x int) {
 }
 
 func deserializeInt(d *Deserializer, x *int) {
-	*x = int(binary.LittleEndian.Uint64(d.b[:8]))
-	d.b = d.b[8:]
+	*x = int(binary.LittleEndian.Uint64(d.read(8)))
 }
 
 func serializeInt64(s *Serializer, x int64) {
@@ -770,8 +1069,7 @@ func serializeInt64(s *Serializer, x int64) {
 }
 
 func deserializeInt64(d *Deserializer, x *int64) {
-	*x = int64(binary.LittleEndian.Uint64(d.b[:8]))
-	d.b = d.b[8:]
+	*x = int64(binary.LittleEndian.Uint64(d.read(8)))
 }
 
 func serializeInt32(s *Serializer, x int32) {
@@ -779,8 +1077,7 @@ func serializeInt32(s *Serializer, x int32) {
 }
 
 func deserializeInt32(d *Deserializer, x *int32) {
-	*x = int32(binary.LittleEndian.Uint32(d.b[:4]))
-	d.b = d.b[4:]
+	*x = int32(binary.LittleEndian.Uint32(d.read(4)))
 }
 
 func serializeInt16(s *Serializer, x int16) {
@@ -788,8 +1085,7 @@ func serializeInt16(s *Serializer, x int16) {
 }
 
 func deserializeInt16(d *Deserializer, x *int16) {
-	*x = int16(binary.LittleEndian.Uint16(d.b[:2]))
-	d.b = d.b[2:]
+	*x = int16(binary.LittleEndian.Uint16(d.read(2)))
 }
 
 func serializeInt8(s *Serializer, x int8) {
@@ -797,8 +1093,7 @@ func serializeInt8(s *Serializer, x int8) {
 }
 
 func deserializeInt8(d *Deserializer, x *int8) {
-	*x = int8(d.b[0])
-	d.b = d.b[1:]
+	*x = int8(d.read(1)[0])
 }
 
 func serializeUint(s *Serializer, x uint) {
@@ -806,8 +1101,7 @@ func serializeUint(s *Serializer, x uint) {
 }
 
 func deserializeUint(d *Deserializer, x *uint) {
-	*x = uint(binary.LittleEndian.Uint64(d.b[:8]))
-	d.b = d.b[8:]
+	*x = uint(binary.LittleEndian.Uint64(d.read(8)))
 }
 
 func serializeUint64(s *Serializer, x uint64) {
@@ -815,8 +1109,7 @@ func serializeUint64(s *Serializer, x uint64) {
 }
 
 func deserializeUint64(d *Deserializer, x *uint64) {
-	*x = uint64(binary.LittleEndian.Uint64(d.b[:8]))
-	d.b = d.b[8:]
+	*x = uint64(binary.LittleEndian.Uint64(d.read(8)))
 }
 
 func serializeUint32(s *Serializer, x uint32) {
@@ -824,8 +1117,7 @@ func serializeUint32(s *Serializer, x uint32) {
 }
 
 func deserializeUint32(d *Deserializer, x *uint32) {
-	*x = uint32(binary.LittleEndian.Uint32(d.b[:4]))
-	d.b = d.b[4:]
+	*x = uint32(binary.LittleEndian.Uint32(d.read(4)))
 }
 
 func serializeUint16(s *Serializer, x uint16) {
@@ -833,8 +1125,7 @@ func serializeUint16(s *Serializer, x uint16) {
 }
 
 func deserializeUint16(d *Deserializer, x *uint16) {
-	*x = uint16(binary.LittleEndian.Uint16(d.b[:2]))
-	d.b = d.b[2:]
+	*x = uint16(binary.LittleEndian.Uint16(d.read(2)))
 }
 
 func serializeUint8(s *Serializer, x uint8) {
@@ -842,8 +1133,7 @@ func serializeUint8(s *Serializer, x uint8) {
 }
 
 func deserializeUint8(d *Deserializer, x *uint8) {
-	*x = uint8(d.b[0])
-	d.b = d.b[1:]
+	*x = uint8(d.read(1)[0])
 }
 
 func serializeUintptr(s *Serializer, x uintptr) {
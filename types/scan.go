@@ -259,6 +259,10 @@ func scan(s *Serializer, t reflect.Type, p unsafe.Pointer) {
 	case reflect.Array:
 		s.containers.add(t, p)
 		et := t.Elem()
+		if isPlainData(et) {
+			// Elements without pointers have nothing to scan.
+			return
+		}
 		es := int(et.Size())
 		for i := 0; i < t.Len(); i++ {
 			ep := unsafe.Add(p, es*i)
@@ -280,6 +284,9 @@ func scan(s *Serializer, t reflect.Type, p unsafe.Pointer) {
 		// Create a new type for the backing array.
 		xt := reflect.ArrayOf(sr.Cap(), t.Elem())
 		s.containers.add(xt, ep)
+		if isPlainData(et) {
+			return
+		}
 		for i := 0; i < sr.Len(); i++ {
 			ep := unsafe.Add(ep, es*i)
 			scan(s, et, ep)
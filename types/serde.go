@@ -20,12 +20,77 @@ type sID int64
 // to deserialize objects from another build.
 var ErrBuildIDMismatch = errors.New("build ID mismatch")
 
+// ErrCorrupted is an error that occurs when the input of a deserialization
+// is truncated, or declares lengths that exceed the size of the input.
+var ErrCorrupted = errors.New("corrupted input")
+
+// SortMapKeys controls whether the entries of maps are serialized in the order
+// of their keys.
+//
+// Go does not specify the iteration order of maps. Entries of maps whose keys
+// or values hold pointers are always sorted, because the order changes the
+// size of the output (see [Size]), but by default other maps are serialized in
+// iteration order, so serializing the same map twice may produce different
+// outputs. When enabled, the entries of all maps are sorted, so that
+// serializing the same state produces the same output. This is useful to store
+// serialized coroutines in content-addressed storage, or to compare them, but
+// it adds the cost of sorting map keys to serialization. Keys of boolean,
+// numeric, or string types, and structs and arrays of them, are ordered the
+// same way in every process; pointers are ordered by address.
+//
+// Like [Register], the function is intended to be called during program
+// initialization; it is not safe to call concurrently with serialization.
+func SortMapKeys(enable bool) { sortMapKeys = enable }
+
+var sortMapKeys bool
+
+// InternStrings controls whether strings with the same content are serialized
+// once.
+//
+// Strings sharing their bytes in memory are always serialized once. When
+// enabled, strings with identical content that are held in different memory
+// locations are deduplicated as well (e.g. map keys or enum names built at
+// runtime), which reduces the size of the output at the cost of hashing every
+// string. Repeated strings are written as references to the first one, using
+// the same format as shared memory, so the output can be deserialized whether
+// the option is enabled or not; the deserialized strings share their bytes.
+//
+// The function is intended to be called during program initialization; it is
+// not safe to call concurrently with serialization.
+func InternStrings(enable bool) { internStrings = enable }
+
+var internStrings bool
+
 // Serialize x.
 //
 // The output of Serialize can be reconstructed back to a Go value using
 // [Deserialize].
+//
+// Fixed-size numbers are encoded in little endian regardless of the byte
+// order of the host, and lengths and references in the varint encoding of
+// [binary.AppendVarint], so the output does not depend on the architecture.
 func Serialize(x any) []byte {
 	s := newSerializer()
+	serialize(s, x)
+	return s.b
+}
+
+// Size returns the number of bytes that [Serialize] produces for x.
+//
+// The size is computed by walking x the same way [Serialize] does, but the
+// output is discarded as it is produced instead of being accumulated in a
+// buffer. The result is exact as long as x is not modified in between: the
+// entries of maps that hold pointers are serialized in a deterministic order,
+// since the order changes the IDs assigned to pointers and the size of their
+// encoding.
+func Size(x any) int {
+	s := newSerializer()
+	s.sizeOnly = true
+	serialize(s, x)
+	return s.size()
+}
+
+func serialize(s *Serializer, x any) {
 	w := &x // w is *interface{}
 	wr := reflect.ValueOf(w)
 	p := wr.UnsafePointer() // *interface{}
@@ -36,16 +101,59 @@ func Serialize(x any) []byte {
 	clear(s.scanptrs)
 
 	serializeAny(s, t, p)
-	return s.b
 }
 
 // Deserialize value from b. Return left over bytes.
-func Deserialize(b []byte) (interface{}, []byte, error) {
+//
+// If b is truncated or corrupted, the error wraps [ErrCorrupted].
+func Deserialize(b []byte) (x interface{}, rest []byte, err error) {
+	return deserialize(b, false)
+}
+
+// DeserializeUnsafe is like [Deserialize], but the strings of the deserialized
+// value reference the bytes of b instead of copies, which avoids allocating
+// memory for each of them.
+//
+// This is unsafe: the caller must guarantee that b is never modified, and that
+// it is kept alive for as long as the deserialized value is used (e.g. for the
+// whole lifetime of a coroutine restored from b). Modifying b changes the value
+// of strings, which Go assumes to be immutable. Memory shared between a string
+// and a value of another type (e.g. with unsafe.String) references b as well.
+func DeserializeUnsafe(b []byte) (x interface{}, rest []byte, err error) {
+	return deserialize(b, true)
+}
+
+func deserialize(b []byte, unsafeStrings bool) (x interface{}, rest []byte, err error) {
 	d, err := newDeserializer(b)
 	if err != nil {
 		return nil, nil, err
 	}
-	var x interface{}
+	d.unsafeStrings = unsafeStrings
+	return d.Deserialize()
+}
+
+// Deserialize a value from the input of the deserializer, set with
+// [Deserializer.Reset]. Return left over bytes.
+//
+// It is equivalent to the [Deserialize] function, but reuses the memory of the
+// deserializer. It must not be called by custom deserialization functions,
+// which use [DeserializeTo] instead.
+func (d *Deserializer) Deserialize() (x interface{}, rest []byte, err error) {
+	defer func() {
+		switch r := recover().(type) {
+		case nil:
+		case error:
+			// Reads past the end of the input panic with
+			// ErrCorrupted (see read and checkLength), other
+			// errors are bugs of the program.
+			if !errors.Is(r, ErrCorrupted) {
+				panic(r)
+			}
+			x, rest, err = nil, nil, r
+		default:
+			panic(r)
+		}
+	}()
 	px := &x
 	t := reflect.TypeOf(px).Elem()
 	p := unsafe.Pointer(px)
@@ -60,35 +168,72 @@ type Deserializer struct {
 
 	// input
 	b []byte
+
+	// Whether strings reference the input (see DeserializeUnsafe).
+	unsafeStrings bool
+
+	// Whether values of unknown types are skipped instead of causing a panic
+	// (see Verify), and the descriptions of those types.
+	opaque  bool
+	unknown []string
+}
+
+// Remaining returns the number of bytes of the input that have not been
+// deserialized yet.
+func (d *Deserializer) Remaining() int {
+	return len(d.b)
 }
 
 func newDeserializer(b []byte) (*Deserializer, error) {
+	d := new(Deserializer)
+	if err := d.Reset(b); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Reset prepares the deserializer to deserialize b with
+// [Deserializer.Deserialize], so the same deserializer can be reused for
+// multiple inputs (e.g. when restoring many coroutines) instead of allocating
+// a new one for each of them.
+//
+// The pointers deserialized from the previous input are forgotten: values
+// deserialized from b never share memory with values deserialized before. If b
+// was not serialized by the same build, the error wraps [ErrBuildIDMismatch],
+// and the deserializer has no input.
+func (d *Deserializer) Reset(b []byte) error {
+	if d.ptrs == nil {
+		d.ptrs = make(map[sID]unsafe.Pointer)
+	} else {
+		clear(d.ptrs)
+	}
+	d.b = nil
+
 	buildIDLength, n := binary.Varint(b)
 	if n <= 0 || buildIDLength <= 0 || buildIDLength > int64(len(buildID)) || int64(len(b)-n) < buildIDLength {
-		return nil, fmt.Errorf("missing or invalid build ID")
+		return fmt.Errorf("missing or invalid build ID")
 	}
 	b = b[n:]
 	serializedBuildID := string(b[:buildIDLength])
 	b = b[buildIDLength:]
 	if serializedBuildID != buildID {
-		return nil, fmt.Errorf("%w: got %v, expect %v", ErrBuildIDMismatch, serializedBuildID, buildID)
+		return fmt.Errorf("%w: got %v, expect %v", ErrBuildIDMismatch, serializedBuildID, buildID)
 	}
 
-	return &Deserializer{
-		ptrs: make(map[sID]unsafe.Pointer),
-		b:    b,
-	}, nil
+	d.b = b
+	return nil
 }
 
 func (d *Deserializer) readPtr() (unsafe.Pointer, sID) {
-	x, n := binary.Varint(d.b)
-	d.b = d.b[n:]
+	x := deserializeVarint(d)
 
-	// pointer into static uint64 table
+	// pointer into static memory (see static)
 	if x == -1 {
-		x, n = binary.Varint(d.b)
-		d.b = d.b[n:]
-		p := staticPointer(int(x))
+		section := deserializeVarint(d)
+		p := staticPointer(section, deserializeVarint(d))
+		if p == nil {
+			panic(fmt.Errorf("%w: pointer outside of static memory", ErrCorrupted))
+		}
 		return p, 0
 	}
 
@@ -134,11 +279,20 @@ type Serializer struct {
 	ptrs       map[unsafe.Pointer]sID
 	containers containers
 
+	// Data of the strings serialized so far, indexed by their content, when
+	// strings are interned (see InternStrings).
+	strings map[string]unsafe.Pointer
+
 	// TODO: move out. just used temporarily by scan
 	scanptrs map[reflect.Value]struct{}
 
 	// Output
 	b []byte
+
+	// When sizeOnly is true, the output is discarded as it is produced and n
+	// counts the number of bytes that were discarded.
+	sizeOnly bool
+	n        int
 }
 
 func newSerializer() *Serializer {
@@ -153,6 +307,20 @@ func newSerializer() *Serializer {
 	}
 }
 
+// flush discards the output produced so far when only computing the size of
+// the serialized data.
+func (s *Serializer) flush() {
+	if s.sizeOnly {
+		s.n += len(s.b)
+		s.b = s.b[:0]
+	}
+}
+
+// Returns the total number of bytes produced by the serializer.
+func (s *Serializer) size() int {
+	return s.n + len(s.b)
+}
+
 // Returns true if it created a new ID (false if reused one).
 func (s *Serializer) assignPointerID(p unsafe.Pointer) (sID, bool) {
 	id, ok := s.ptrs[p]
@@ -169,10 +337,34 @@ func serializeVarint(s *Serializer, size int) {
 
 func deserializeVarint(d *Deserializer) int {
 	l, n := binary.Varint(d.b)
+	if n <= 0 {
+		panic(fmt.Errorf("%w: invalid varint", ErrCorrupted))
+	}
 	d.b = d.b[n:]
 	return int(l)
 }
 
+// read returns the next n bytes of the input and advances past them. It
+// panics with ErrCorrupted if fewer than n bytes are left.
+func (d *Deserializer) read(n int) []byte {
+	if n < 0 || n > len(d.b) {
+		panic(fmt.Errorf("%w: cannot read %d bytes, %d bytes left in the input", ErrCorrupted, n, len(d.b)))
+	}
+	b := d.b[:n:n]
+	d.b = d.b[n:]
+	return b
+}
+
+// checkLength panics with ErrCorrupted if n elements of type t cannot be
+// read from the remaining input. Every element of a type with a non-zero
+// size occupies at least one byte of the input, so the check prevents
+// corrupted lengths from causing large allocations.
+func checkLength(d *Deserializer, t reflect.Type, n int) {
+	if n < 0 || (t.Size() > 0 && n > len(d.b)) {
+		panic(fmt.Errorf("%w: length %d exceeds the %d bytes left in the input", ErrCorrupted, n, len(d.b)))
+	}
+}
+
 // Serialize a value. See [RegisterSerde].
 func SerializeT[T any](s *Serializer, x T) {
 	var p unsafe.Pointer
@@ -188,6 +380,38 @@ func SerializeT[T any](s *Serializer, x T) {
 	serializeAny(s, t, p)
 }
 
+// WriteBytes writes b as an opaque blob of bytes prefixed with its length.
+//
+// Unlike serializing a []byte with [SerializeT], the bytes are written as is:
+// they are not tracked to preserve sharing of the backing array, and their
+// capacity is not retained. It is intended for custom serializers that need to
+// embed data in another encoding (e.g. a pre-encoded protocol buffer).
+//
+// A nil slice and an empty slice are written differently, so the distinction
+// is preserved by [Deserializer.ReadBytes].
+func (s *Serializer) WriteBytes(b []byte) {
+	if b == nil {
+		serializeVarint(s, -1)
+		return
+	}
+	serializeVarint(s, len(b))
+	s.b = append(s.b, b...)
+}
+
+// ReadBytes reads a blob of bytes written by [Serializer.WriteBytes].
+//
+// The returned slice is a copy, it does not retain the input of the
+// deserializer. It is nil if a nil slice was written.
+func (d *Deserializer) ReadBytes() []byte {
+	n := deserializeVarint(d)
+	if n < 0 {
+		return nil
+	}
+	b := make([]byte, n)
+	copy(b, d.read(n))
+	return b
+}
+
 // Deserialize a value to the provided non-nil pointer. See [RegisterSerde].
 func DeserializeTo[T any](d *Deserializer, x *T) {
 	r := reflect.ValueOf(x)
@@ -9,7 +9,10 @@ import (
 	"math"
 	"net/http"
 	"reflect"
+	"runtime"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 	"unsafe"
@@ -51,6 +54,354 @@ func testSerdeTime(t *testing.T, x time.Time) {
 	}
 }
 
+func TestRegisterConcurrent(t *testing.T) {
+	testReflect(t, "register while serializing", func(t *testing.T) {
+		type pair struct {
+			A int
+			B string
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				RegisterReflect(reflect.ArrayOf(i, reflect.TypeOf("")),
+					func(s *Serializer, v reflect.Value) error { return nil },
+					func(d *Deserializer, v reflect.Value) error { return nil })
+			}(i)
+			go func(i int) {
+				defer wg.Done()
+				in := &pair{A: i, B: "hello"}
+				out, _, err := Deserialize(Serialize(in))
+				if err != nil {
+					t.Error(err)
+				} else if *out.(*pair) != *in {
+					t.Errorf("expected %v, got %v", in, out)
+				}
+				_ = RegisteredTypes()
+			}(i)
+		}
+		wg.Wait()
+
+		if n := len(RegisteredTypes()); n != 8 {
+			t.Errorf("expected 8 registered types, got %d", n)
+		}
+	})
+}
+
+func TestRegisterReflect(t *testing.T) {
+	type custom struct {
+		X int
+	}
+
+	var b []byte
+	testReflect(t, "generic registration", func(t *testing.T) {
+		Register[custom](
+			func(s *Serializer, x *custom) error {
+				SerializeT(s, x.X+1)
+				return nil
+			},
+			func(d *Deserializer, x *custom) error {
+				DeserializeTo(d, &x.X)
+				x.X--
+				return nil
+			})
+		b = Serialize([]any{custom{X: 41}, &custom{X: 42}})
+	})
+
+	testReflect(t, "reflect registration", func(t *testing.T) {
+		RegisterReflect(reflect.TypeOf(custom{}),
+			func(s *Serializer, v reflect.Value) error {
+				SerializeT(s, int(v.Field(0).Int())+1)
+				return nil
+			},
+			func(d *Deserializer, v reflect.Value) error {
+				var x int
+				DeserializeTo(d, &x)
+				v.Field(0).SetInt(int64(x - 1))
+				return nil
+			})
+
+		in := []any{custom{X: 41}, &custom{X: 42}}
+		if c := Serialize(in); !bytes.Equal(b, c) {
+			t.Errorf("serialized output differs from generic registration:\n%x\n%x", b, c)
+		}
+
+		out, _, err := Deserialize(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := out.([]any)
+		if res[0].(custom).X != 41 || res[1].(*custom).X != 42 {
+			t.Errorf("unexpected values: %v, %v", res[0], res[1])
+		}
+	})
+}
+
+func TestRegisteredTypes(t *testing.T) {
+	type a struct{ X int }
+	type b struct{ X int }
+
+	testReflect(t, "registration order", func(t *testing.T) {
+		ser := func(s *Serializer, v reflect.Value) error { return nil }
+		des := func(d *Deserializer, v reflect.Value) error { return nil }
+		RegisterReflect(reflect.TypeOf(b{}), ser, des)
+		RegisterReflect(reflect.TypeOf(a{}), ser, des)
+		// Registering a type again replaces its functions but keeps its ID.
+		RegisterReflect(reflect.TypeOf(b{}), ser, des)
+
+		want := []reflect.Type{reflect.TypeOf(b{}), reflect.TypeOf(a{})}
+		if got := RegisteredTypes(); !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong registered types: want=%v got=%v", want, got)
+		}
+	})
+}
+
+func TestRegisterFallback(t *testing.T) {
+	type withChan struct {
+		X  int
+		Ch chan int
+	}
+
+	var consulted []reflect.Type
+	fallback := func(t reflect.Type) (func(*Serializer, reflect.Value) error, func(*Deserializer, reflect.Value) error) {
+		consulted = append(consulted, t)
+		if t.Kind() != reflect.Chan {
+			return nil, nil
+		}
+		return func(s *Serializer, v reflect.Value) error {
+				SerializeT(s, v.Cap())
+				return nil
+			}, func(d *Deserializer, v reflect.Value) error {
+				var n int
+				DeserializeTo(d, &n)
+				v.Set(reflect.MakeChan(v.Type(), n))
+				return nil
+			}
+	}
+
+	testReflect(t, "channels", func(t *testing.T) {
+		RegisterFallback(fallback)
+
+		in := withChan{X: 42, Ch: make(chan int, 3)}
+		out, _, err := Deserialize(Serialize(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		x := out.(withChan)
+		if x.X != 42 || x.Ch == nil || cap(x.Ch) != 3 {
+			t.Errorf("unexpected value: %+v", x)
+		}
+		// The fallback is only consulted for the type that reflection cannot
+		// serialize, and only once.
+		if want := []reflect.Type{reflect.TypeOf(in.Ch)}; !reflect.DeepEqual(consulted, want) {
+			t.Errorf("fallback consulted for %v, want %v", consulted, want)
+		}
+	})
+
+	testReflect(t, "declined", func(t *testing.T) {
+		RegisterFallback(func(t reflect.Type) (func(*Serializer, reflect.Value) error, func(*Deserializer, reflect.Value) error) {
+			return nil, nil
+		})
+
+		defer func() {
+			if recover() == nil {
+				t.Error("serializing a declined type did not panic")
+			}
+		}()
+		Serialize(make(chan int))
+	})
+}
+
+func TestWriteBytes(t *testing.T) {
+	type blobs struct {
+		a, b, c []byte
+	}
+
+	testReflect(t, "nil, empty and non-empty blobs", func(t *testing.T) {
+		Register[blobs](
+			func(s *Serializer, x *blobs) error {
+				s.WriteBytes(x.a)
+				s.WriteBytes(x.b)
+				s.WriteBytes(x.c)
+				return nil
+			},
+			func(d *Deserializer, x *blobs) error {
+				x.a = d.ReadBytes()
+				x.b = d.ReadBytes()
+				x.c = d.ReadBytes()
+				return nil
+			})
+
+		in := &blobs{a: nil, b: []byte{}, c: []byte("\x00opaque\xff")}
+		out, rest, err := Deserialize(Serialize(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rest) != 0 {
+			t.Errorf("%d bytes left after deserialization", len(rest))
+		}
+
+		x := out.(*blobs)
+		if x.a != nil {
+			t.Errorf("nil slice deserialized as %#v", x.a)
+		}
+		if x.b == nil || len(x.b) != 0 {
+			t.Errorf("empty slice deserialized as %#v", x.b)
+		}
+		if !bytes.Equal(x.c, in.c) {
+			t.Errorf("expected %q, got %q", in.c, x.c)
+		}
+	})
+}
+
+func TestDeserializeTruncated(t *testing.T) {
+	type values struct {
+		s []int
+		m map[string][]byte
+		p *EasyStruct
+	}
+
+	in := &values{
+		s: []int{1, 2, 3, 4, 5},
+		m: map[string][]byte{"hello": []byte("world")},
+		p: &EasyStruct{A: 42, B: "answer"},
+	}
+	b := Serialize(in)
+	buildIDSize := len(Serialize(nil)) - 1
+
+	for n := buildIDSize; n < len(b); n++ {
+		_, _, err := Deserialize(b[:n])
+		if !errors.Is(err, ErrCorrupted) {
+			t.Fatalf("deserializing %d/%d bytes: expected ErrCorrupted, got %v", n, len(b), err)
+		}
+	}
+}
+
+func TestDeserializeRuntimeError(t *testing.T) {
+	type buggy struct{ x int }
+
+	testReflect(t, "runtime errors are not corrupted input", func(t *testing.T) {
+		Register[buggy](
+			func(s *Serializer, x *buggy) error {
+				SerializeT(s, x.x)
+				return nil
+			},
+			func(d *Deserializer, x *buggy) error {
+				var values []int
+				DeserializeTo(d, &x.x)
+				x.x = values[x.x]
+				return nil
+			})
+
+		defer func() {
+			if _, ok := recover().(runtime.Error); !ok {
+				t.Error("runtime error not propagated by Deserialize")
+			}
+		}()
+		_, _, err := Deserialize(Serialize(buggy{x: 1}))
+		t.Errorf("runtime error returned by Deserialize: %v", err)
+	})
+}
+
+func TestDeserializerRemaining(t *testing.T) {
+	b := append(Serialize(42), "garbage"...)
+
+	d, err := newDeserializer(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var x any
+	deserializeInterface(d, reflect.TypeOf(&x).Elem(), unsafe.Pointer(&x))
+	if x != 42 {
+		t.Fatalf("wrong value deserialized: %v", x)
+	}
+	if n := d.Remaining(); n != len("garbage") {
+		t.Fatalf("wrong number of remaining bytes: want=%d got=%d", len("garbage"), n)
+	}
+}
+
+func TestDeserializerReset(t *testing.T) {
+	one, two := 1, 2
+	b1 := Serialize(&one)
+	b2 := Serialize([]*int{&two, &two})
+
+	var d Deserializer
+	if err := d.Reset(b1); err != nil {
+		t.Fatal(err)
+	}
+	x1, _, err := d.Deserialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1 := x1.(*int)
+	if *p1 != 1 {
+		t.Fatalf("wrong value deserialized: %d", *p1)
+	}
+
+	// Both blobs use the same pointer IDs, the second one must not refer
+	// to the values of the first one.
+	if err := d.Reset(b2); err != nil {
+		t.Fatal(err)
+	}
+	x2, rest, err := d.Deserialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) > 0 {
+		t.Fatalf("leftover bytes: %d", len(rest))
+	}
+	p2 := x2.([]*int)
+	if *p2[0] != 2 || p2[0] != p2[1] {
+		t.Fatalf("wrong values deserialized: %d, %d", *p2[0], *p2[1])
+	}
+	if p2[0] == p1 {
+		t.Fatal("pointer of the previous input was reused")
+	}
+
+	if err := d.Reset([]byte("garbage")); err == nil {
+		t.Fatal("expected an error for an invalid input")
+	}
+	if n := d.Remaining(); n != 0 {
+		t.Fatalf("wrong number of remaining bytes after failed reset: %d", n)
+	}
+}
+
+func BenchmarkDeserializerReset(b *testing.B) {
+	type node struct {
+		Value int
+		Next  *node
+	}
+	var list *node
+	for i := 0; i < 16; i++ {
+		list = &node{Value: i, Next: list}
+	}
+	data := Serialize(list)
+
+	b.Run("new", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := Deserialize(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("reset", func(b *testing.B) {
+		b.ReportAllocs()
+		var d Deserializer
+		for i := 0; i < b.N; i++ {
+			if err := d.Reset(data); err != nil {
+				b.Fatal(err)
+			}
+			if _, _, err := d.Deserialize(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 type EasyStruct struct {
 	A int
 	B string
@@ -178,6 +529,10 @@ func TestReflect(t *testing.T) {
 			typ := reflect.TypeOf(x)
 			t.Run(fmt.Sprintf("%d-%s", i, typ), func(t *testing.T) {
 				b := Serialize(x)
+				if n := Size(x); n != len(b) {
+					t.Errorf("serialized size mismatch: want=%d got=%d", len(b), n)
+				}
+
 				out, b, err := Deserialize(b)
 				if err != nil {
 					t.Fatal(err)
@@ -193,6 +548,84 @@ func TestReflect(t *testing.T) {
 	})
 }
 
+func TestReflectValue(t *testing.T) {
+	// Values are held in structs like frames of coroutines, as opposed to
+	// passing reflect.Value to Serialize, which serializes what it holds.
+	type frame struct {
+		V reflect.Value
+	}
+
+	roundTrip := func(t *testing.T, v reflect.Value) reflect.Value {
+		t.Helper()
+		b := Serialize(frame{V: v})
+		if n := Size(frame{V: v}); n != len(b) {
+			t.Errorf("serialized size mismatch: want=%d got=%d", len(b), n)
+		}
+		out, b, err := Deserialize(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) > 0 {
+			t.Fatalf("leftover bytes: %d", len(b))
+		}
+		return out.(frame).V
+	}
+
+	testReflect(t, "zero value", func(t *testing.T) {
+		if v := roundTrip(t, reflect.Value{}); v.IsValid() {
+			t.Errorf("expected zero value, got %v", v)
+		}
+	})
+
+	testReflect(t, "registered struct", func(t *testing.T) {
+		type point struct {
+			x, y int
+		}
+
+		calls := 0
+		Register[point](
+			func(s *Serializer, p *point) error {
+				calls++
+				SerializeT(s, [2]int{p.x, p.y})
+				return nil
+			},
+			func(d *Deserializer, p *point) error {
+				var xy [2]int
+				DeserializeTo(d, &xy)
+				p.x, p.y = xy[0], xy[1]
+				return nil
+			})
+
+		v := roundTrip(t, reflect.ValueOf(point{1, 2}))
+		assertEqual(t, point{1, 2}, v.Interface())
+		if calls == 0 {
+			t.Error("custom serializer was not called")
+		}
+	})
+
+	testReflect(t, "struct with unexported fields", func(t *testing.T) {
+		type pair struct {
+			a int
+			b *string
+		}
+
+		b := "hello"
+		v := roundTrip(t, reflect.ValueOf(pair{1, &b}))
+		p := v.Interface().(pair)
+		if p.a != 1 || *p.b != "hello" {
+			t.Errorf("unexpected value: %+v", p)
+		}
+	})
+
+	testReflect(t, "interface", func(t *testing.T) {
+		err := errors.New("fail")
+		v := roundTrip(t, reflect.ValueOf(&err).Elem())
+		if v.Kind() != reflect.Interface || v.Interface().(error).Error() != "fail" {
+			t.Errorf("unexpected value: %v", v)
+		}
+	})
+}
+
 func TestReflectUnsafePointer(t *testing.T) {
 	type unsafePointerStruct struct{ p unsafe.Pointer }
 	var selfRef unsafePointerStruct
@@ -259,9 +692,6 @@ func TestReflectClosure(t *testing.T) {
 	})
 
 	t.Run("reflect-value", func(t *testing.T) {
-		// FIXME: get reflect.Value(closure) working
-		t.Skipf("reflect.Value(closure) is not working correctly")
-
 		b := Serialize(reflect.ValueOf(fn))
 
 		out, b, err := Deserialize(b)
@@ -278,12 +708,230 @@ func TestReflectClosure(t *testing.T) {
 	})
 }
 
+type customError struct {
+	Code int
+}
+
+func (e *customError) Error() string { return fmt.Sprintf("custom error %d", e.Code) }
+
 func TestErrors(t *testing.T) {
 	s := struct {
 		X5 error
 	}{}
 
 	assertRoundTrip(t, s)
+
+	errs := []error{
+		nil,
+		errors.New("test"),
+		fmt.Errorf("wrapped: %w", errors.New("test")),
+		&customError{Code: 42},
+	}
+
+	for _, err := range errs {
+		type frame struct{ Err error }
+
+		out := assertRoundTrip(t, frame{Err: err})
+		if err == nil {
+			if out.Err != nil {
+				t.Errorf("nil error deserialized as %v", out.Err)
+			}
+			continue
+		}
+
+		// The concrete error type is preserved, not only the message.
+		if reflect.TypeOf(out.Err) != reflect.TypeOf(err) {
+			t.Errorf("wrong error type: want=%T got=%T", err, out.Err)
+		}
+		if out.Err.Error() != err.Error() {
+			t.Errorf("wrong error message: want=%q got=%q", err, out.Err)
+		}
+	}
+}
+
+func TestComplex(t *testing.T) {
+	type numbers struct {
+		C64   complex64
+		C128  complex128
+		Slice []complex128
+		Array [2]complex64
+	}
+
+	s := numbers{
+		C64:   complex(1, -2),
+		C128:  complex(math.Pi, math.Inf(1)),
+		Slice: []complex128{complex(1, 2), complex(-3, 4.5), complex(0, -1)},
+		Array: [2]complex64{complex(5, 6), complex(0, 7)},
+	}
+
+	out := assertRoundTrip(t, s)
+
+	// Check the imaginary components explicitly since they are serialized
+	// separately from the real components.
+	for i, c := range out.Slice {
+		if imag(c) != imag(s.Slice[i]) {
+			t.Errorf("wrong imaginary component at index %d: want=%v got=%v", i, imag(s.Slice[i]), imag(c))
+		}
+	}
+	for i, c := range out.Array {
+		if imag(c) != imag(s.Array[i]) {
+			t.Errorf("wrong imaginary component at index %d: want=%v got=%v", i, imag(s.Array[i]), imag(c))
+		}
+	}
+}
+
+type plainPoint struct {
+	X, Y int32
+	Z    float64
+	ID   uint64
+}
+
+func TestPlainData(t *testing.T) {
+	type padded struct {
+		A int8
+		B int64
+	}
+	type withBool struct {
+		A, B int32
+		OK   bool
+	}
+
+	for _, test := range []struct {
+		value any
+		plain bool
+	}{
+		{plainPoint{}, true},
+		{[4]plainPoint{}, true},
+		{[2][3]complex64{}, true},
+		{padded{}, false},
+		{withBool{}, false},
+		{struct{ S []int32 }{}, false},
+	} {
+		if plain := isPlainData(reflect.TypeOf(test.value)); plain != test.plain {
+			t.Errorf("%T: want plain=%t, got %t", test.value, test.plain, plain)
+		}
+	}
+
+	var points [64]plainPoint
+	for i := range points {
+		points[i] = plainPoint{X: int32(i), Y: -int32(i), Z: float64(i) / 3, ID: math.MaxUint64 - uint64(i)}
+	}
+
+	// Copying the memory of plain data must produce the same output as
+	// serializing the fields one by one.
+	fast := newSerializer()
+	serializeAny(fast, reflect.TypeOf(points), unsafe.Pointer(&points))
+	slow := newSerializer()
+	for i := range points {
+		serializeStructFields(slow, unsafe.Pointer(&points[i]), 4, reflect.TypeOf(plainPoint{}).Field)
+	}
+	if !bytes.Equal(fast.b, slow.b) {
+		t.Errorf("plain data serialized differently:\n%x\n%x", fast.b, slow.b)
+	}
+
+	assertRoundTrip(t, points)
+	assertRoundTrip(t, padded{A: -1, B: math.MinInt64})
+
+	testReflect(t, "custom serializer", func(t *testing.T) {
+		isPlainData(reflect.TypeOf([4]plainPoint{}))
+		Register[plainPoint](
+			func(s *Serializer, x *plainPoint) error { return nil },
+			func(d *Deserializer, x *plainPoint) error { return nil })
+		if isPlainData(reflect.TypeOf([4]plainPoint{})) {
+			t.Error("array of a type with a custom serializer is plain data")
+		}
+	})
+}
+
+func BenchmarkSerializePlainData(b *testing.B) {
+	points := make([]plainPoint, 4096)
+	b.SetBytes(int64(len(points)) * int64(unsafe.Sizeof(plainPoint{})))
+	for i := 0; i < b.N; i++ {
+		Serialize(points)
+	}
+}
+
+func BenchmarkDeserializePlainData(b *testing.B) {
+	points := make([]plainPoint, 4096)
+	data := Serialize(points)
+	b.SetBytes(int64(len(points)) * int64(unsafe.Sizeof(plainPoint{})))
+	for i := 0; i < b.N; i++ {
+		if _, _, err := Deserialize(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDeserializeUnsafe(t *testing.T) {
+	type state struct {
+		Names []string
+		Sub   string
+		Same  string
+	}
+
+	name := strings.Repeat("x", 32)
+	in := state{
+		Names: []string{name, "hello", "world"},
+		Sub:   name[4:8],
+		Same:  name,
+	}
+	b := Serialize(in)
+
+	v, rest, err := DeserializeUnsafe(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 0 {
+		t.Fatalf("leftover bytes: %d", len(rest))
+	}
+	out := v.(state)
+	assertEqual(t, in, out)
+
+	// The strings reference the input, so they observe its modifications.
+	inInput := func(s string) bool {
+		p := uintptr(unsafe.Pointer(unsafe.StringData(s)))
+		start := uintptr(unsafe.Pointer(unsafe.SliceData(b)))
+		return p >= start && p < start+uintptr(len(b))
+	}
+	for _, s := range out.Names {
+		if !inInput(s) {
+			t.Errorf("string %q does not reference the input", s)
+		}
+	}
+	if unsafe.StringData(out.Same) != unsafe.StringData(out.Names[0]) {
+		t.Error("strings sharing memory do not share the input")
+	}
+	buildIDSize := len(Serialize(nil)) - 1
+	for n := buildIDSize; n < len(b); n++ {
+		if _, _, err := DeserializeUnsafe(b[:n]); !errors.Is(err, ErrCorrupted) {
+			t.Fatalf("deserializing %d/%d bytes: expected ErrCorrupted, got %v", n, len(b), err)
+		}
+	}
+}
+
+func BenchmarkDeserializeStrings(b *testing.B) {
+	names := make([]string, 1024)
+	for i := range names {
+		names[i] = strings.Repeat("name", 4) + strconv.Itoa(i)
+	}
+	data := Serialize(names)
+
+	for _, bench := range []struct {
+		name        string
+		deserialize func([]byte) (any, []byte, error)
+	}{
+		{"copy", Deserialize},
+		{"unsafe", DeserializeUnsafe},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := bench.deserialize(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
 }
 
 func TestEmptyStructs(t *testing.T) {
@@ -306,6 +954,38 @@ func TestEmptyStructs(t *testing.T) {
 	assertRoundTrip(t, Y{first: 42, last: struct{}{}})
 }
 
+type Box[T any] struct {
+	Value T
+}
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value *Box[V]
+	Items map[K][]Box[V]
+}
+
+func TestGenerics(t *testing.T) {
+	assertRoundTrip(t, Box[int]{Value: 42})
+	assertRoundTrip(t, Box[string]{Value: "hello"})
+	assertRoundTrip(t, Box[Box[int]]{Value: Box[int]{Value: 42}})
+
+	assertRoundTrip(t, Pair[string, int]{
+		Key:   "answer",
+		Value: &Box[int]{Value: 42},
+		Items: map[string][]Box[int]{"a": {{1}, {2}}},
+	})
+
+	// Instantiations of the same generic type must retain their identity when
+	// stored in interfaces, otherwise the type assertions below would fail.
+	out := assertRoundTrip(t, []any{Box[int]{Value: 1}, Box[string]{Value: "1"}})
+	if _, ok := out[0].(Box[int]); !ok {
+		t.Errorf("unexpected type for first element: %T", out[0])
+	}
+	if _, ok := out[1].(Box[string]); !ok {
+		t.Errorf("unexpected type for second element: %T", out[1])
+	}
+}
+
 func TestInt257(t *testing.T) {
 	one := 1
 	x := []any{
@@ -462,6 +1142,247 @@ func TestReflectCustom(t *testing.T) {
 	})
 }
 
+func TestSortMapKeys(t *testing.T) {
+	SortMapKeys(true)
+	defer SortMapKeys(false)
+
+	type state struct {
+		Ints    map[int]string
+		Strings map[string]*int
+		Floats  map[float64]bool
+		Bools   map[bool]int
+	}
+
+	s := state{
+		Ints:    map[int]string{},
+		Strings: map[string]*int{},
+		Floats:  map[float64]bool{math.Inf(-1): true, math.Inf(1): false},
+		Bools:   map[bool]int{true: 1, false: 0},
+	}
+	for i := 0; i < 100; i++ {
+		n := i
+		s.Ints[i-50] = strconv.Itoa(i)
+		s.Strings[strconv.Itoa(i)] = &n
+		s.Floats[float64(i)/3] = i%2 == 0
+	}
+
+	b := Serialize(s)
+	for i := 0; i < 10; i++ {
+		if !bytes.Equal(b, Serialize(s)) {
+			t.Fatal("serializing the same state produced different outputs")
+		}
+	}
+
+	assertRoundTrip(t, s)
+}
+
+func TestSizeOfMapsHoldingPointers(t *testing.T) {
+	type node struct {
+		N int
+	}
+
+	// Enough pointers that some IDs take two bytes, with some of the
+	// values referenced again after the map, so the size of the output
+	// depends on the IDs assigned to the values of the map.
+	m := map[string]any{}
+	var refs []*node
+	for i := 0; i < 100; i++ {
+		n := &node{N: i}
+		m[strconv.Itoa(i)] = n
+		if i%2 == 0 {
+			refs = append(refs, n, n, n)
+		}
+	}
+	s := []any{m, refs}
+
+	for i := 0; i < 10; i++ {
+		if n, b := Size(s), Serialize(s); n != len(b) {
+			t.Fatalf("serialized size mismatch: want=%d got=%d", len(b), n)
+		}
+	}
+}
+
+var staticVar struct {
+	N int
+	S []string
+}
+
+func TestStaticPointers(t *testing.T) {
+	type state struct {
+		P *int
+		S *[]string
+		A any
+	}
+
+	testReflect(t, "package-level variables", func(t *testing.T) {
+		in := state{P: &staticVar.N, S: &staticVar.S, A: &staticVar}
+
+		v, _, err := Deserialize(Serialize(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := v.(state)
+
+		if out.P != &staticVar.N || out.S != &staticVar.S || out.A != any(&staticVar) {
+			t.Errorf("pointers to package-level variables were not restored: %+v", out)
+		}
+
+		*out.P = 42
+		if staticVar.N != 42 {
+			t.Errorf("wrong value of package-level variable: %d", staticVar.N)
+		}
+	})
+}
+
+func TestMapStructKeys(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+	type ref struct {
+		Name string
+		P    *int
+	}
+	type state struct {
+		Points map[point]string
+		Refs   map[ref]int
+		Ptr    *int
+	}
+
+	testReflect(t, "comparable struct keys", func(t *testing.T) {
+		n := 42
+		s := state{
+			Points: map[point]string{{1, 2}: "a", {2, 1}: "b", {}: "zero"},
+			Refs:   map[ref]int{{"n", &n}: 1, {"n", nil}: 2},
+			Ptr:    &n,
+		}
+
+		// Keys holding pointers are not deeply equal after a round trip, so
+		// the maps are compared by looking up keys.
+		v, _, err := Deserialize(Serialize(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := v.(state)
+
+		if len(out.Points) != len(s.Points) {
+			t.Fatalf("wrong number of keys: want=%d got=%d", len(s.Points), len(out.Points))
+		}
+		for k, v := range s.Points {
+			if got, ok := out.Points[k]; !ok || got != v {
+				t.Errorf("wrong value for key %v: want=%q got=%q", k, v, got)
+			}
+		}
+
+		// Keys holding pointers are equal to keys built from the pointers
+		// deserialized elsewhere in the state.
+		if v, ok := out.Refs[ref{"n", out.Ptr}]; !ok || v != 1 {
+			t.Errorf("key with pointer not found: %v", out.Refs)
+		}
+		if v, ok := out.Refs[ref{"n", nil}]; !ok || v != 2 {
+			t.Errorf("key with nil pointer not found: %v", out.Refs)
+		}
+	})
+
+	SortMapKeys(true)
+	defer SortMapKeys(false)
+
+	testReflect(t, "sorted struct keys", func(t *testing.T) {
+		assertRoundTrip(t, map[point]string{{1, 2}: "a", {2, 1}: "b"})
+	})
+}
+
+func TestMapStringAny(t *testing.T) {
+	type endpoint struct {
+		Host string
+		Port int
+	}
+	type secret struct {
+		value string
+	}
+
+	testReflect(t, "values of mixed types", func(t *testing.T) {
+		// The unexported field of secret is only serialized by its
+		// registered functions.
+		Register[secret](
+			func(s *Serializer, x *secret) error {
+				SerializeT(s, x.value)
+				return nil
+			},
+			func(d *Deserializer, x *secret) error {
+				DeserializeTo(d, &x.value)
+				return nil
+			})
+
+		config := map[string]any{
+			"retries":  3,
+			"timeout":  int64(30),
+			"ratio":    0.5,
+			"name":     "worker",
+			"enabled":  true,
+			"endpoint": endpoint{"localhost", 8080},
+			"primary":  &endpoint{"example.com", 443},
+			"token":    secret{"s3cr3t"},
+			"tags":     []any{"a", 1},
+			"nested":   map[string]any{"depth": uint8(2)},
+			"missing":  nil,
+		}
+
+		out := assertRoundTrip(t, config)
+		for k, v := range config {
+			if want, got := reflect.TypeOf(v), reflect.TypeOf(out[k]); want != got {
+				t.Errorf("%s: want type %v, got %v", k, want, got)
+			}
+		}
+		if s := out["token"].(secret); s.value != "s3cr3t" {
+			t.Errorf("token: want %q, got %q", "s3cr3t", s.value)
+		}
+		if _, ok := out["missing"]; !ok {
+			t.Error("missing: key with nil value was not restored")
+		}
+	})
+}
+
+func TestInternStrings(t *testing.T) {
+	type state struct {
+		Names []string
+		Keys  map[string]int
+		Empty string
+	}
+
+	// Strings are built at runtime so that those with the same content do
+	// not share their bytes.
+	s := state{Keys: map[string]int{}}
+	for i := 0; i < 100; i++ {
+		name := strings.Repeat("name", 4) + strconv.Itoa(i%3)
+		s.Names = append(s.Names, name)
+		s.Keys[strconv.Itoa(i%3)+strings.Repeat("key", 4)] = i
+	}
+
+	// Map keys are sorted for the outputs to be comparable.
+	SortMapKeys(true)
+	defer SortMapKeys(false)
+	plain := Serialize(s)
+
+	InternStrings(true)
+	defer InternStrings(false)
+	interned := Serialize(s)
+	if n := Size(s); n != len(interned) {
+		t.Errorf("serialized size mismatch: want=%d got=%d", len(interned), n)
+	}
+	out := assertRoundTrip(t, s)
+	InternStrings(false)
+
+	if len(interned) >= len(plain) {
+		t.Errorf("interning strings did not reduce the size: %d >= %d", len(interned), len(plain))
+	}
+	if !bytes.Equal(plain, Serialize(s)) {
+		t.Error("disabling string interning changed the output")
+	}
+	if unsafe.StringData(out.Names[0]) != unsafe.StringData(out.Names[3]) {
+		t.Error("interned strings do not share their bytes")
+	}
+}
+
 func TestReflectSharing(t *testing.T) {
 	testReflect(t, "maps of ints", func(t *testing.T) {
 		m := map[int]int{1: 2, 3: 4}
@@ -607,6 +1528,34 @@ func TestReflectSharing(t *testing.T) {
 		assertEqual(t, 11, *out.B.P)
 	})
 
+	testReflect(t, "struct fields pointed at twice", func(t *testing.T) {
+		type A struct {
+			X, Y int
+		}
+
+		type B struct {
+			P, Q *int
+		}
+
+		type X struct {
+			A *A
+			B *B
+		}
+
+		x := X{
+			A: new(A),
+			B: new(B),
+		}
+		x.B.P = &x.A.Y
+		x.B.Q = &x.A.Y
+
+		out := assertRoundTrip(t, x)
+
+		out.A.Y = 11
+		assertEqual(t, 11, *out.B.P)
+		assertEqual(t, 11, *out.B.Q)
+	})
+
 	testReflect(t, "struct with pointer to itself", func(t *testing.T) {
 		type X struct {
 			z *X
@@ -621,6 +1570,30 @@ func TestReflectSharing(t *testing.T) {
 		assertEqual(t, out, out.z)
 	})
 
+	testReflect(t, "doubly linked list cycle", func(t *testing.T) {
+		type node struct {
+			value      int
+			prev, next *node
+		}
+
+		a := &node{value: 1}
+		b := &node{value: 2}
+		a.next, a.prev = b, b
+		b.next, b.prev = a, a
+
+		out := assertRoundTrip(t, a)
+
+		if out.next.value != 2 || out.next == out {
+			t.Fatalf("wrong second node: %+v", out.next)
+		}
+		if out.next.next != out || out.next.prev != out {
+			t.Error("second node does not point back to the first node")
+		}
+		if out.prev != out.next {
+			t.Error("first node points to different second nodes")
+		}
+	})
+
 	testReflect(t, "nested struct fields", func(t *testing.T) {
 		type Z struct {
 			v int64
@@ -685,6 +1658,32 @@ func TestReflectSharing(t *testing.T) {
 		assertEqual(t, "test", out.y.z.v)
 	})
 
+	testReflect(t, "slices starting at the same element", func(t *testing.T) {
+		data := make([]int, 10)
+		for i := range data {
+			data[i] = i
+		}
+
+		type X struct {
+			s1 []int
+			s2 []int
+			s3 []int
+		}
+
+		orig := X{
+			s1: data,
+			s2: data[4:6],
+			s3: data[4:8],
+		}
+
+		out := assertRoundTrip(t, orig)
+
+		// verify the result's underlying array is shared
+		out.s2[1] = 42
+		assertEqual(t, 42, out.s1[5])
+		assertEqual(t, 42, out.s3[1])
+	})
+
 	testReflect(t, "slices with same backing array but no joined cap", func(t *testing.T) {
 		data := make([]int, 10)
 		for i := range data {
@@ -852,6 +1851,10 @@ func assertRoundTrip[T any](t *testing.T, orig T) T {
 	t.Helper()
 
 	b := Serialize(orig)
+	if n := Size(orig); n != len(b) {
+		t.Errorf("serialized size mismatch: want=%d got=%d", len(b), n)
+	}
+
 	out, b, err := Deserialize(b)
 	if err != nil {
 		t.Fatal(err)
@@ -3,6 +3,8 @@ package types
 import (
 	"fmt"
 	"reflect"
+	"slices"
+	"sync"
 	"unsafe"
 )
 
@@ -27,7 +29,11 @@ type DeserializerFunc[T any] func(*Deserializer, *T) error
 //
 // Go basic types, structs, interfaces, slices, arrays, or any combination of
 // them have built-in serialization and deserialization mechanisms. Channels and
-// sync values do not.
+// sync values do not, with the exception of sync.Once which can be registered
+// with RegisterSyncOnce of the types/codecs package. Values held in interfaces, such as error values, are
+// serialized along with their concrete type, which is restored on
+// deserialization. So are values held in reflect.Value, using the functions
+// attached to their type if any.
 //
 // Custom serializer and deserializer functions can be attached to types using
 // [Register] to control how they are serialized, and possibly perform
@@ -40,6 +46,11 @@ type DeserializerFunc[T any] func(*Deserializer, *T) error
 // result, slices sharing the same backing array are deserialized into one array
 // with two shared slices, just like the original state was. Elements between
 // length and capacity are also preserved.
+//
+// Register is safe to call concurrently with serialization and with other
+// registrations (e.g. when loading plugins lazily). Registration determines the
+// IDs of types in the serialized output, so programs exchanging state must
+// register the same types in the same order.
 func Register[T any](
 	serializer SerializerFunc[T],
 	deserializer DeserializerFunc[T]) {
@@ -67,6 +78,92 @@ func registerSerde[T any](tm *typemap,
 	tm.attach(t, s, d)
 }
 
+// RegisterReflect attaches custom serialization and deserialization functions
+// to type t. It is equivalent to [Register], for types that are only known at
+// runtime (e.g. types of values loaded from plugins).
+//
+// The functions receive an addressable value of type t. The deserializer must
+// set the value it receives.
+func RegisterReflect(t reflect.Type,
+	serializer func(*Serializer, reflect.Value) error,
+	deserializer func(*Deserializer, reflect.Value) error) {
+	registerReflect(types, t, serializer, deserializer)
+}
+
+func registerReflect(tm *typemap, t reflect.Type,
+	serializer func(*Serializer, reflect.Value) error,
+	deserializer func(*Deserializer, reflect.Value) error) {
+
+	if serializer == nil || deserializer == nil {
+		panic("both serializer and deserializer need to be provided")
+	}
+
+	s, d := reflectSerde(t, serializer, deserializer)
+	tm.attach(t, s, d)
+}
+
+func reflectSerde(t reflect.Type,
+	serializer func(*Serializer, reflect.Value) error,
+	deserializer func(*Deserializer, reflect.Value) error) (serializerFunc, deserializerFunc) {
+
+	s := func(s *Serializer, p unsafe.Pointer) {
+		if err := serializer(s, reflect.NewAt(t, p).Elem()); err != nil {
+			panic(fmt.Errorf("serializing %s: %w", t, err))
+		}
+	}
+
+	d := func(d *Deserializer, p unsafe.Pointer) {
+		if err := deserializer(d, reflect.NewAt(t, p).Elem()); err != nil {
+			panic(fmt.Errorf("deserializing %s: %w", t, err))
+		}
+	}
+
+	return s, d
+}
+
+// FallbackFunc is the signature of functions installed with
+// [RegisterFallback]. The function returns the serialization and
+// deserialization functions to use for values of type t, or nil functions to
+// decline handling the type.
+type FallbackFunc func(t reflect.Type) (
+	serializer func(*Serializer, reflect.Value) error,
+	deserializer func(*Deserializer, reflect.Value) error)
+
+// RegisterFallback installs a function providing serialization and
+// deserialization functions for types that would otherwise cause a panic: types
+// that reflection cannot serialize (e.g. channels), and types that were written
+// by a custom serializer which is not registered in the program deserializing
+// them.
+//
+// The function is consulted lazily, only after the registry of functions
+// attached with [Register] and [RegisterReflect] misses, and at most once for
+// each type it accepts. When it declines a type, serializing or deserializing
+// it panics as if no fallback was installed. Only one fallback can be
+// installed; installing another one replaces it.
+//
+// Like [Register], the function is safe to call concurrently with
+// serialization, but it should be called during program initialization for the
+// output of a program to be consistent.
+func RegisterFallback(f FallbackFunc) {
+	types.mutex.Lock()
+	defer types.mutex.Unlock()
+	types.fallback = f
+}
+
+// RegisteredTypes returns the types that custom serialization functions are
+// attached to (see [Register] and [RegisterReflect]), including those
+// registered by this package for types of the standard library.
+//
+// The types are returned in the order that they were first registered, which
+// determines the IDs that identify them in the serialized output. Programs
+// registering the same types in the same order report identical lists, which
+// helps verify that a program can deserialize the output of another.
+func RegisteredTypes() []reflect.Type {
+	types.mutex.RLock()
+	defer types.mutex.RUnlock()
+	return slices.Clone(types.custom)
+}
+
 type serializerFunc func(*Serializer, unsafe.Pointer)
 type deserializerFunc func(d *Deserializer, p unsafe.Pointer)
 
@@ -77,9 +174,21 @@ type serde struct {
 }
 
 type typemap struct {
-	custom []reflect.Type
-	cache  doublemap[reflect.Type, *typeinfo]
-	serdes map[reflect.Type]serde
+	// The registry of custom types is guarded by mutex, since types may be
+	// registered while other goroutines serialize values.
+	mutex    sync.RWMutex
+	custom   []reflect.Type
+	serdes   map[reflect.Type]serde
+	fallback FallbackFunc
+
+	// The cache is guarded by cacheMutex, it is updated when serializing
+	// types. Locking cacheMutex then mutex is allowed, not the other way
+	// around.
+	cacheMutex sync.Mutex
+	cache      doublemap[reflect.Type, *typeinfo]
+
+	plain     sync.Map // reflect.Type => bool, see isPlainData
+	fallbacks sync.Map // reflect.Type => serde, accepted by fallback
 }
 
 func newTypemap() *typemap {
@@ -94,6 +203,9 @@ func (m *typemap) attach(t reflect.Type, ser serializerFunc, des deserializerFun
 		panic("both serializer and deserializer need to be provided")
 	}
 
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
 	s, exists := m.serdes[t]
 	if !exists {
 		s.id = len(m.custom)
@@ -103,13 +215,69 @@ func (m *typemap) attach(t reflect.Type, ser serializerFunc, des deserializerFun
 	s.des = des
 
 	m.serdes[t] = s
+
+	// Types embedding t are no longer plain data.
+	m.plain.Range(func(k, _ any) bool {
+		m.plain.Delete(k)
+		return true
+	})
 }
 
 func (m *typemap) serdeOf(x reflect.Type) (serde, bool) {
+	m.mutex.RLock()
+	s, ok := m.serdes[x]
+	fallback := m.fallback
+	m.mutex.RUnlock()
+
+	if !ok && fallback != nil {
+		if v, found := m.fallbacks.Load(x); found {
+			return v.(serde), true
+		}
+	}
+	return s, ok
+}
+
+// registered returns the serde attached to type x, ignoring the fallback.
+func (m *typemap) registered(x reflect.Type) (serde, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 	s, ok := m.serdes[x]
 	return s, ok
 }
 
+// customType returns the type that the custom serde with the given id is
+// attached to.
+func (m *typemap) customType(id int) (reflect.Type, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if id < 0 || id >= len(m.custom) {
+		return nil, false
+	}
+	return m.custom[id], true
+}
+
+// fallbackOf consults the fallback function for type x, which has no
+// registered serde.
+func (m *typemap) fallbackOf(x reflect.Type) (serde, bool) {
+	m.mutex.RLock()
+	fallback := m.fallback
+	m.mutex.RUnlock()
+
+	if fallback == nil {
+		return serde{}, false
+	}
+	if v, ok := m.fallbacks.Load(x); ok {
+		return v.(serde), true
+	}
+	serializer, deserializer := fallback(x)
+	if serializer == nil || deserializer == nil {
+		return serde{}, false
+	}
+	s, d := reflectSerde(x, serializer, deserializer)
+	v, _ := m.fallbacks.LoadOrStore(x, serde{id: -1, ser: s, des: d})
+	return v.(serde), true
+}
+
 type doublemap[K, V comparable] struct {
 	fromK map[K]V
 	fromV map[V]K
@@ -57,14 +57,29 @@ const (
 
 func (t *typeinfo) reflectType(tm *typemap) reflect.Type {
 	if t.offset != 0 {
-		return typeForOffset(t.offset)
+		x := typeForOffset(t.offset)
+		if t.kind == typeCustom {
+			// Values of custom types were written by their serializer, they
+			// cannot be read without the matching deserializer.
+			if _, ok := tm.serdeOf(x); !ok {
+				if _, ok := tm.fallbackOf(x); ok {
+					return x
+				}
+				return opaqueType(fmt.Sprintf("%s with custom serializer #%d", x, t.val))
+			}
+		}
+		return x
 	}
 
 	switch t.kind {
 	case typeNone:
 		return nil
 	case typeCustom:
-		return tm.custom[t.val]
+		x, ok := tm.customType(t.val)
+		if !ok {
+			return opaqueType(fmt.Sprintf("with custom serializer #%d", t.val))
+		}
+		return x
 	case typeBasic:
 		switch reflect.Kind(t.val) {
 		case reflect.Bool:
@@ -110,13 +125,13 @@ func (t *typeinfo) reflectType(tm *typemap) reflect.Type {
 		if t.elem == nil {
 			return reflect.TypeOf(unsafe.Pointer(nil))
 		}
-		return reflect.PointerTo(tm.ToReflect(t.elem))
+		return reflect.PointerTo(tm.toReflect(t.elem))
 	case typeMap:
-		return reflect.MapOf(tm.ToReflect(t.key), tm.ToReflect(t.elem))
+		return reflect.MapOf(tm.toReflect(t.key), tm.toReflect(t.elem))
 	case typeArray:
-		return reflect.ArrayOf(t.val, tm.ToReflect(t.elem))
+		return reflect.ArrayOf(t.val, tm.toReflect(t.elem))
 	case typeSlice:
-		return reflect.SliceOf(tm.ToReflect(t.elem))
+		return reflect.SliceOf(tm.toReflect(t.elem))
 	case typeStruct:
 		fields := make([]reflect.StructField, len(t.fields))
 		for i, f := range t.fields {
@@ -125,7 +140,7 @@ func (t *typeinfo) reflectType(tm *typemap) reflect.Type {
 			fields[i].Index = f.index
 			fields[i].Offset = f.offset
 			fields[i].Anonymous = f.anon
-			fields[i].Type = tm.ToReflect(f.typ)
+			fields[i].Type = tm.toReflect(f.typ)
 		}
 		return reflect.StructOf(fields)
 	case typeFunc:
@@ -133,7 +148,7 @@ func (t *typeinfo) reflectType(tm *typemap) reflect.Type {
 		in := t.val >> 1
 		insouts := make([]reflect.Type, len(t.args))
 		for i, t := range t.args {
-			insouts[i] = tm.ToReflect(t)
+			insouts[i] = tm.toReflect(t)
 		}
 		return reflect.FuncOf(insouts[:in], insouts[in:], variadic)
 	case typeChan:
@@ -146,9 +161,9 @@ func (t *typeinfo) reflectType(tm *typemap) reflect.Type {
 		case bothDir:
 			dir = reflect.BothDir
 		}
-		return reflect.ChanOf(dir, tm.ToReflect(t.elem))
+		return reflect.ChanOf(dir, tm.toReflect(t.elem))
 	}
-	panic(fmt.Errorf("unknown typekind: %d", t.kind))
+	panic(&unknownTypeError{desc: fmt.Sprintf("of kind %d", t.kind)})
 }
 
 type Field struct {
@@ -160,7 +175,14 @@ type Field struct {
 	tag    string
 }
 
+// ToReflect returns the reflect.Type described by t.
 func (m *typemap) ToReflect(t *typeinfo) reflect.Type {
+	m.cacheMutex.Lock()
+	defer m.cacheMutex.Unlock()
+	return m.toReflect(t)
+}
+
+func (m *typemap) toReflect(t *typeinfo) reflect.Type {
 	if x, ok := m.cache.getV(t); ok {
 		return x
 	}
@@ -169,7 +191,14 @@ func (m *typemap) ToReflect(t *typeinfo) reflect.Type {
 	return x
 }
 
+// ToType returns the typeinfo describing t.
 func (m *typemap) ToType(t reflect.Type) *typeinfo {
+	m.cacheMutex.Lock()
+	defer m.cacheMutex.Unlock()
+	return m.toType(t)
+}
+
+func (m *typemap) toType(t reflect.Type) *typeinfo {
 	if x, ok := m.cache.getK(t); ok {
 		return x
 	}
@@ -186,7 +215,7 @@ func (m *typemap) ToType(t reflect.Type) *typeinfo {
 		// rest of the type information.
 	}
 
-	if s, ok := m.serdes[t]; ok {
+	if s, ok := m.registered(t); ok {
 		return m.cache.add(t, &typeinfo{
 			kind:   typeCustom,
 			offset: offset,
@@ -222,20 +251,20 @@ func (m *typemap) ToType(t reflect.Type) *typeinfo {
 	case reflect.Array:
 		ti.kind = typeArray
 		ti.val = t.Len()
-		ti.elem = m.ToType(t.Elem())
+		ti.elem = m.toType(t.Elem())
 	case reflect.Map:
 		ti.kind = typeMap
-		ti.key = m.ToType(t.Key())
-		ti.elem = m.ToType(t.Elem())
+		ti.key = m.toType(t.Key())
+		ti.elem = m.toType(t.Elem())
 	case reflect.Pointer:
 		ti.kind = typePointer
-		ti.elem = m.ToType(t.Elem())
+		ti.elem = m.toType(t.Elem())
 	case reflect.UnsafePointer:
 		ti.kind = typePointer
 		ti.elem = nil
 	case reflect.Slice:
 		ti.kind = typeSlice
-		ti.elem = m.ToType(t.Elem())
+		ti.elem = m.toType(t.Elem())
 	case reflect.Struct:
 		n := t.NumField()
 		fields := make([]Field, n)
@@ -249,7 +278,7 @@ func (m *typemap) ToType(t reflect.Type) *typeinfo {
 			fields[i].index = f.Index
 			fields[i].offset = f.Offset
 			fields[i].tag = string(f.Tag)
-			fields[i].typ = m.ToType(f.Type)
+			fields[i].typ = m.toType(f.Type)
 		}
 		ti.kind = typeStruct
 		ti.fields = fields
@@ -258,17 +287,17 @@ func (m *typemap) ToType(t reflect.Type) *typeinfo {
 		nout := t.NumOut()
 		types := make([]*typeinfo, nin+nout)
 		for i := 0; i < nin; i++ {
-			types[i] = m.ToType(t.In(i))
+			types[i] = m.toType(t.In(i))
 		}
 		for i := 0; i < nout; i++ {
-			types[nin+i] = m.ToType(t.Out(i))
+			types[nin+i] = m.toType(t.Out(i))
 		}
 		ti.kind = typeFunc
 		ti.val = nin<<1 | boolint(t.IsVariadic())
 		ti.args = types
 	case reflect.Chan:
 		ti.kind = typeChan
-		ti.elem = m.ToType(t.Elem())
+		ti.elem = m.toType(t.Elem())
 		switch t.ChanDir() {
 		case reflect.RecvDir:
 			ti.dir = recvDir
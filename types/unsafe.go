@@ -37,24 +37,93 @@ func inlined(t reflect.Type) bool {
 	}
 }
 
+// Address of the runtime's table of the 256 uint64 values that single byte
+// values converted to interfaces point to.
 var staticuint64s unsafe.Pointer
 
+// staticByte is a variable so the compiler does not convert it to an interface
+// holding a pointer to a read-only constant instead of the runtime's table.
+var staticByte byte
+
 func init() {
-	zero := 0
-	var x interface{} = zero
+	var x interface{} = staticByte
 	staticuint64s = (*iface)(unsafe.Pointer(&x)).ptr
 }
 
+// Sections of the program holding package-level variables. Pointers to static
+// memory are serialized as the index of their section and their offset from
+// the start of the section, so they are restored as pointers to the same
+// variables instead of copies of their values. The index 0 designates the
+// runtime's staticuint64s table, and the following indexes the sections of
+// the object file.
+//
+// Pointers are rebuilt from the start of their own section, which the Go
+// runtime considers an allocation of its own when checking pointer arithmetic
+// (e.g. when testing with -race).
+var staticSections []staticSection
+
+type staticSection struct {
+	base unsafe.Pointer
+	size uintptr
+}
+
+func (s staticSection) contains(p unsafe.Pointer) bool {
+	return uintptr(p) >= uintptr(s.base) && uintptr(p)-uintptr(s.base) < s.size
+}
+
+// addStaticSection registers the section of the object file at addr of the
+// given size as holding package-level variables. The address of the section in
+// memory is computed relative to the text section, which is at text in the
+// object file.
+func addStaticSection(text, addr, size uint64) {
+	if size > 0 {
+		start := textAddr + uintptr(addr-text)
+		base := *(*unsafe.Pointer)(unsafe.Pointer(&start))
+		staticSections = append(staticSections, staticSection{base, uintptr(size)})
+	}
+}
+
+// staticSectionOf returns the index of the section of static memory that p
+// points into, or -1 if p does not point to static memory.
+func staticSectionOf(p unsafe.Pointer) int {
+	if (staticSection{staticuint64s, 256 * 8}).contains(p) {
+		return 0
+	}
+	for i, s := range staticSections {
+		if s.contains(p) {
+			return i + 1
+		}
+	}
+	return -1
+}
+
 func static(p unsafe.Pointer) bool {
-	return uintptr(p) >= uintptr(staticuint64s) && uintptr(p) < uintptr(staticuint64s)+256
+	return staticSectionOf(p) >= 0
 }
 
-func staticOffset(p unsafe.Pointer) int {
-	return int(uintptr(p) - uintptr(staticuint64s))
+// staticOffset returns the index of the section of static memory that p
+// points into, and the offset of p from the start of the section.
+func staticOffset(p unsafe.Pointer) (section, offset int) {
+	section = staticSectionOf(p)
+	if section == 0 {
+		return 0, int(uintptr(p) - uintptr(staticuint64s))
+	}
+	return section, int(uintptr(p) - uintptr(staticSections[section-1].base))
 }
 
-func staticPointer(offset int) unsafe.Pointer {
-	return unsafe.Add(staticuint64s, offset)
+// staticPointer returns the pointer at offset in the given section of static
+// memory, or nil if the section does not exist or is smaller than offset.
+func staticPointer(section, offset int) unsafe.Pointer {
+	s := staticSection{staticuint64s, 256 * 8}
+	if section > 0 && section <= len(staticSections) {
+		s = staticSections[section-1]
+	} else if section != 0 {
+		return nil
+	}
+	if offset < 0 || uintptr(offset) >= s.size {
+		return nil
+	}
+	return unsafe.Add(s.base, offset)
 }
 
 // namedType offset is the number of bytes from the address of the 'byte' type